@@ -3,20 +3,38 @@ package main
 import (
 	"context"
 	"crypto/tls"
+	"fmt"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/gofiber/fiber/v2/middleware/requestid"
 	"github.com/gofiber/swagger"
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 	"idm/docs"
+	"idm/inner/accessrequest"
+	"idm/inner/account"
+	"idm/inner/apikey"
+	"idm/inner/application"
+	"idm/inner/assignment"
+	"idm/inner/audit"
+	"idm/inner/birthright"
+	"idm/inner/breakglass"
+	"idm/inner/certification"
 	"idm/inner/common"
 	"idm/inner/database"
+	"idm/inner/delegation"
 	"idm/inner/employee"
+	"idm/inner/explain"
 	"idm/inner/info"
 	"idm/inner/middleware"
+	"idm/inner/policy"
+	"idm/inner/ratelimit"
+	"idm/inner/reconciliation"
+	"idm/inner/relation"
 	"idm/inner/role"
+	"idm/inner/scheduler"
 	"idm/inner/validator"
 	"idm/inner/web"
+	"os"
 	"os/signal"
 	"sync"
 	"syscall"
@@ -32,6 +50,13 @@ import (
 // @tokenUrl http://localhost:9990/realms/idm/protocol/openid-connect/token
 // @scope openid Access everything
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "token" {
+		if err := mintToken(common.GetTokenConfig(".env"), os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 	cfg := common.GetConfig(".env")
 	docs.SwaggerInfo.Version = cfg.AppVersion
 	var logger = common.NewLogger(cfg)
@@ -40,6 +65,9 @@ func main() {
 		logger.Panic("failed certificate loading: %s", zap.Error(err))
 	}
 	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cer}}
+	if err = web.ConfigureClientAuth(tlsConfig, cfg); err != nil {
+		logger.Panic("failed client CA loading", zap.Error(err))
+	}
 	defer func() { _ = logger.Sync() }()
 	db := database.ConnectDbWithCfg(cfg)
 	defer func() {
@@ -47,7 +75,10 @@ func main() {
 			logger.Error("error closing db", zap.Error(err))
 		}
 	}()
-	var server = build(cfg, logger, db)
+	var jobs = scheduler.New(logger)
+	var server = build(cfg, logger, db, jobs)
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	jobs.Start(jobsCtx)
 	go func() {
 		ln, err := tls.Listen("tcp", ":8080", tlsConfig)
 		if err != nil {
@@ -62,6 +93,8 @@ func main() {
 	wg.Add(1)
 	go gracefulShutdown(server, wg, logger)
 	wg.Wait()
+	stopJobs()
+	jobs.Wait()
 	logger.Info("Graceful shutdown complete.")
 }
 
@@ -87,23 +120,180 @@ func build(
 	cfg common.Config,
 	logger *common.Logger,
 	db *sqlx.DB,
+	jobs *scheduler.Scheduler,
 ) *web.Server {
-	var server = web.NewServer()
+	var server = web.NewServerWithCfg(cfg)
 	server.App.Use(requestid.New())
 	server.App.Use(middleware.LoggerMiddleware(logger))
 	server.App.Use(recover.New())
 	server.App.Use("/swagger/*", swagger.HandlerDefault)
-	server.GroupApiV1.Use(web.AuthMiddleware(logger))
+	var vld = validator.New()
+	var apiKeyService = apikey.NewService(apikey.NewRepository(db), vld)
+	var auth = web.WithApiKeys(logger, web.AuthMiddleware(logger, cfg), apiKeyService)
+	if cfg.TlsClientIdentities != "" {
+		identities, err := web.LoadClientIdentities(cfg.TlsClientIdentities)
+		if err != nil {
+			logger.Panic("failed client identities loading", zap.Error(err))
+		}
+		auth = web.WithClientCertificates(identities, auth)
+	}
+	ipLimits, apiLimits, internalLimits := rateLimitRules(cfg, logger)
+	var limitStore ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.RateLimitStore == "postgres" {
+		limitStore = ratelimit.NewRepository(db)
+	}
+	// до аутентификации клиент ещё неизвестен, лимит по IP не даёт перебирать токены и ключи без ограничений
+	server.GroupApiV1.Use(ratelimit.Middleware(limitStore, ipLimits))
+	server.GroupApiV1.Use(auth)
+	server.GroupApiV1.Use(ratelimit.Middleware(limitStore, apiLimits))
+	server.GroupInternal.Use(ratelimit.Middleware(limitStore, internalLimits))
+	jobs.Add(scheduler.Job{
+		Name:     "purge full rate limit buckets",
+		Interval: time.Minute,
+		Run: func(ctx context.Context) error {
+			var longest = max(ipLimits.Longest(), apiLimits.Longest(), internalLimits.Longest())
+			return limitStore.Purge(ctx, time.Now().Add(-longest))
+		},
+	})
 	var employeeRepo = employee.NewRepository(db)
 	var roleRepo = role.NewRepository(db)
-	var vld = validator.New()
-	var employeeService = employee.NewService(employeeRepo, vld)
-	var employeeController = employee.NewController(server, employeeService)
+	var assignmentRepo = assignment.NewRepository(db)
+	var accessRequestRepo = accessrequest.NewRepository(db)
+	var certificationRepo = certification.NewRepository(db)
+	var relationRepo = relation.NewRepository(db)
+	var delegationRepo = delegation.NewRepository(db)
+	var breakGlassRepo = breakglass.NewRepository(db)
+	var auditRepo = audit.NewRepository(db)
+	var birthrightRepo = birthright.NewRepository(db)
+	var applicationRepo = application.NewRepository(db)
+	var accountRepo = account.NewRepository(db)
+	var reconciliationRepo = reconciliation.NewRepository(db)
+	var explainRepo = explain.NewRepository(db)
+	var policyEngine = policy.NewEngine(logger)
+	if cfg.PolicyFile != "" {
+		if err := policyEngine.Load(cfg.PolicyFile); err != nil {
+			logger.Panic("failed policy loading", zap.Error(err))
+		}
+		jobs.Add(scheduler.Job{
+			Name:     "reload changed policies",
+			Interval: cfg.PolicyReloadInterval,
+			Run:      policyEngine.ReloadIfChanged,
+		})
+	}
+	var policyController = policy.NewController(server, policyEngine)
+	policyController.RegisterRoutes()
+	var relationService = relation.NewService(relationRepo, vld)
+	var relationController = relation.NewController(server, relationService)
+	relationController.RegisterRoutes()
+	var delegationService = delegation.NewService(delegationRepo, vld, []string{web.IdmAdmin})
+	var delegationController = delegation.NewController(server, delegationService)
+	delegationController.RegisterRoutes()
+	var birthrightService = birthright.NewService(birthrightRepo, vld)
+	var birthrightController = birthright.NewController(server, birthrightService)
+	birthrightController.RegisterRoutes()
+	var breakGlassService = breakglass.NewService(
+		breakGlassRepo,
+		assignmentRepo,
+		auditRepo,
+		vld,
+		logger,
+		cfg.BreakGlassRole,
+		cfg.BreakGlassWindow,
+		[]string{cfg.BreakGlassGroup},
+	)
+	var employeeService = employee.NewService(employeeRepo, birthrightRepo, assignmentRepo, vld)
+	var employeeController = employee.NewController(
+		server,
+		employeeService,
+		policyEngine,
+		relationService,
+		delegationService,
+		breakGlassService,
+	)
 	employeeController.RegisterRoutes()
 	var roleService = role.NewService(roleRepo, vld)
-	var roleController = role.NewController(server, roleService, logger)
+	var roleController = role.NewController(server, roleService, logger, delegationService)
 	roleController.RegisterRoutes()
+	var accessRequestService = accessrequest.NewService(
+		accessRequestRepo,
+		assignmentRepo,
+		relationService,
+		vld,
+		cfg.AccessRequestTtl,
+		[]string{web.IdmAdmin, cfg.AccessApproverGroup},
+	)
+	var accessRequestController = accessrequest.NewController(server, accessRequestService, delegationService)
+	accessRequestController.RegisterRoutes()
+	jobs.Add(scheduler.Job{
+		Name:     "expire access requests",
+		Interval: time.Minute,
+		Run: func(ctx context.Context) error {
+			_, err := accessRequestService.ExpireOverdue(ctx)
+			return err
+		},
+	})
+	var certificationService = certification.NewService(certificationRepo, assignmentRepo, vld, []string{web.IdmAdmin})
+	var certificationController = certification.NewController(server, certificationService)
+	certificationController.RegisterRoutes()
+	jobs.Add(scheduler.Job{
+		Name:     "close overdue certification campaigns",
+		Interval: time.Minute,
+		Run:      certificationService.CloseOverdue,
+	})
+	var breakGlassController = breakglass.NewController(server, breakGlassService)
+	breakGlassController.RegisterRoutes()
+	jobs.Add(scheduler.Job{
+		Name:     "revoke expired break-glass access",
+		Interval: time.Minute,
+		Run:      breakGlassService.RevokeExpired,
+	})
+	var applicationService = application.NewService(applicationRepo, vld)
+	var applicationController = application.NewController(server, applicationService)
+	applicationController.RegisterRoutes()
+	var accountService = account.NewService(accountRepo, vld, cfg.AccountDormantAfter)
+	var accountController = account.NewController(server, accountService)
+	accountController.RegisterRoutes()
+	var reconciliationService = reconciliation.NewService(reconciliationRepo, vld)
+	var reconciliationController = reconciliation.NewController(server, reconciliationService)
+	reconciliationController.RegisterRoutes()
+	var explainService = explain.NewService(explainRepo, vld)
+	var explainController = explain.NewController(server, explainService)
+	explainController.RegisterRoutes()
+	var apiKeyController = apikey.NewController(server, apiKeyService)
+	apiKeyController.RegisterRoutes()
 	var infoController = info.NewController(server, cfg, db, logger)
 	infoController.RegisterRoutes()
 	return server
 }
+
+// rateLimitRules лимиты групп маршрутов из конфигурации
+func rateLimitRules(
+	cfg common.Config,
+	logger *common.Logger,
+) (ip ratelimit.Rules, api ratelimit.Rules, internal ratelimit.Rules) {
+	var parse = func(spec string) *ratelimit.Rule {
+		rule, err := ratelimit.ParseRule(spec)
+		if err != nil {
+			logger.Panic("failed rate limits loading", zap.Error(err))
+		}
+		return rule
+	}
+	api = ratelimit.Rules{
+		Group: "api",
+		Read:  parse(cfg.RateLimitApiRead),
+		Write: parse(cfg.RateLimitApiWrite),
+		Bulk:  parse(cfg.RateLimitApiBulk),
+		BulkRoutes: []string{
+			"DELETE /api/v1/employees/delete",
+			"DELETE /api/v1/roles/delete",
+			"POST /api/v1/applications/:id/accounts/import",
+			"POST /api/v1/applications/:id/reconcile",
+			"POST /api/v1/certifications",
+		},
+	}
+	var ipRule = parse(cfg.RateLimitApiIp)
+	ip = ratelimit.Rules{Group: "api-ip", Read: ipRule, Write: ipRule, Bulk: ipRule}
+	var internalRule = parse(cfg.RateLimitInternal)
+	internal = ratelimit.Rules{Group: "internal", Read: internalRule, Write: internalRule, Bulk: internalRule}
+	return ip, api, internal
+}
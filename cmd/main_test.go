@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"idm/inner/application"
+	"idm/inner/common"
+	"idm/inner/scheduler"
+	"idm/inner/web"
+	"idm/inner/web/oidctest"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestBuildWithSignedTokens весь сервер из build() с токенами, подписанными поддельным OIDC-провайдером
+func TestBuildWithSignedTokens(t *testing.T) {
+	var a = assert.New(t)
+	var provider = oidctest.NewProvider()
+	defer provider.Close()
+	provider.AddUser("admin", oidctest.User{Password: "admin", Roles: []string{web.IdmAdmin}})
+	provider.AddUser("user", oidctest.User{Password: "user", Roles: []string{web.IdmUser}})
+	mockDb, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = mockDb.Close() }()
+	var logger = &common.Logger{Logger: zap.NewNop()}
+	var cfg = provider.Config(common.Config{AppName: "idm", AppVersion: "test"})
+	var server = build(cfg, logger, sqlx.NewDb(mockDb, "sqlmock"), scheduler.New(logger))
+	var get = func(path string, token string) *http.Response {
+		request := httptest.NewRequest(fiber.MethodGet, path, nil)
+		if token != "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := server.App.Test(request)
+		require.NoError(t, err)
+		return resp
+	}
+	t.Run("request without token", func(t *testing.T) {
+		a.Equal(http.StatusUnauthorized, get("/api/v1/applications", "").StatusCode)
+	})
+	t.Run("admin lists applications", func(t *testing.T) {
+		token, err := provider.Login("admin", "admin")
+		require.NoError(t, err)
+		sqlMock.ExpectQuery("SELECT \\* FROM application").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "GitLab"))
+		resp := get("/api/v1/applications", token)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		var response common.Response[[]application.Response]
+		require.NoError(t, json.Unmarshal(body, &response))
+		a.Equal("GitLab", response.Data[0].Name)
+		a.Nil(sqlMock.ExpectationsWereMet())
+	})
+	t.Run("user cannot run reconciliation", func(t *testing.T) {
+		token, err := provider.Login("user", "user")
+		require.NoError(t, err)
+		request := httptest.NewRequest(fiber.MethodPost, "/api/v1/applications/1/reconcile", nil)
+		request.Header.Set("Authorization", "Bearer "+token)
+		resp, err := server.App.Test(request)
+		require.NoError(t, err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+	})
+	t.Run("token of rotated key", func(t *testing.T) {
+		provider.Rotate(true)
+		token, err := provider.Login("user", "user")
+		require.NoError(t, err)
+		sqlMock.ExpectQuery("SELECT \\* FROM application").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+		a.Equal(http.StatusOK, get("/api/v1/applications", token).StatusCode)
+	})
+}
+
+// TestUnauthenticatedRequestsAreLimited запросы без токена ограничиваются по IP до аутентификации
+func TestUnauthenticatedRequestsAreLimited(t *testing.T) {
+	var a = assert.New(t)
+	var provider = oidctest.NewProvider()
+	defer provider.Close()
+	mockDb, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = mockDb.Close() }()
+	var logger = &common.Logger{Logger: zap.NewNop()}
+	var cfg = provider.Config(common.Config{AppName: "idm", AppVersion: "test", RateLimitApiIp: "2/1m"})
+	var server = build(cfg, logger, sqlx.NewDb(mockDb, "sqlmock"), scheduler.New(logger))
+	var get = func() *http.Response {
+		request := httptest.NewRequest(fiber.MethodGet, "/api/v1/applications", nil)
+		request.Header.Set("Authorization", "Bearer forged")
+		resp, err := server.App.Test(request)
+		require.NoError(t, err)
+		return resp
+	}
+	a.Equal(http.StatusUnauthorized, get().StatusCode)
+	a.Equal(http.StatusUnauthorized, get().StatusCode)
+	resp := get()
+	a.Equal(http.StatusTooManyRequests, resp.StatusCode)
+	a.NotEmpty(resp.Header.Get(fiber.HeaderRetryAfter))
+}
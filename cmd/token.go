@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"idm/inner/common"
+	"idm/inner/web"
+	"io"
+	"strings"
+	"time"
+)
+
+// mintToken подкоманда token: выпустить токен для режима разработки, например
+// go run ./cmd token -sub alice -roles IDM_ADMIN,IDM_USER -ttl 8h
+func mintToken(cfg common.Config, args []string, out io.Writer) error {
+	var flags = flag.NewFlagSet("token", flag.ContinueOnError)
+	flags.SetOutput(out)
+	var subject = flags.String("sub", "dev", "subject of the token")
+	var roles = flags.String("roles", web.IdmUser, "comma separated realm_access.roles")
+	var ttl = flags.Duration("ttl", time.Hour, "token lifetime")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	var realmRoles []string
+	for _, role := range strings.Split(*roles, ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			realmRoles = append(realmRoles, role)
+		}
+	}
+	token, err := web.MintDevToken(cfg, *subject, realmRoles, *ttl)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, token)
+	return err
+}
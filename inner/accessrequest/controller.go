@@ -0,0 +1,287 @@
+package accessrequest
+
+import (
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"idm/inner/common"
+	"idm/inner/delegation"
+	"idm/inner/middleware"
+	"idm/inner/web"
+	"slices"
+	"strconv"
+)
+
+type Controller struct {
+	server               *web.Server
+	accessRequestService Svc
+	delegations          Delegations
+}
+
+type Delegations interface {
+	Scope(ctx context.Context, subject string, roles []string) (delegation.Scope, error)
+}
+
+type Svc interface {
+	Create(ctx context.Context, request CreateRequest) (Response, error)
+	FindById(request IdRequest) (Response, error)
+	FindAll(request FindRequest) ([]Response, error)
+	Approve(ctx context.Context, request DecisionRequest) (Response, error)
+	Reject(ctx context.Context, request DecisionRequest) (Response, error)
+	Cancel(ctx context.Context, request DecisionRequest) (Response, error)
+}
+
+func NewController(
+	server *web.Server,
+	accessRequestService Svc,
+	delegations Delegations,
+) *Controller {
+	return &Controller{
+		server:               server,
+		accessRequestService: accessRequestService,
+		delegations:          delegations,
+	}
+}
+
+func (c *Controller) RegisterRoutes() {
+	c.server.GroupApiV1.Post("/access-requests", c.CreateAccessRequest)
+	c.server.GroupApiV1.Get("/access-requests", c.FindAll)
+	c.server.GroupApiV1.Get("/access-requests/:id", c.FindById)
+	c.server.GroupApiV1.Post("/access-requests/:id/approve", c.Approve)
+	c.server.GroupApiV1.Post("/access-requests/:id/reject", c.Reject)
+	c.server.GroupApiV1.Post("/access-requests/:id/cancel", c.Cancel)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/access-requests"
+// @Summary request a role
+// @Description Create an access request for a role with a justification, available to any authenticated user
+// @Description for their own employee record; for other employees with roles: admin, or a delegated admin of their department
+// @Tags access-request
+// @Security OAuth2Password
+// @Accept json
+// @Produce json
+// @Param request body accessrequest.CreateRequest true "create access request"
+// @Success 200 {object} common.Response[int64]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /access-requests [post]
+func (c *Controller) CreateAccessRequest(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	logger := middleware.GetLogger(ctx)
+	var request CreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		logger.ErrorCtx(ctx.Context(), "body parse error: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	request.RequestedBy = claims.Subject
+	scope, err := c.delegations.Scope(ctx.Context(), claims.Subject, claims.RealmAccess.Roles)
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "create access request: checking delegated scope", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+	request.Scope = scope
+	logger.InfoCtx(ctx.Context(), "create access request: received request", zap.Any("request", request))
+	response, err := c.accessRequestService.Create(ctx.Context(), request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}) || errors.As(err, &common.AlreadyExistsError{}):
+			logger.ErrorCtx(ctx.Context(), "error creating access request: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.As(err, &common.ForbiddenError{}):
+			logger.ErrorCtx(ctx.Context(), "error creating access request: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusForbidden, err.Error())
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), "error creating access request: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "error creating access request: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response.Id)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/access-requests"
+// @Summary Get access requests
+// @Description returns access requests filtered by state (optional) with roles: admin, user
+// @Tags access-request
+// @Security OAuth2Password
+// @Accept json
+// @Produce json
+// @Param state query string false "State of access requests: pending, approved, rejected, cancelled, expired"
+// @Success 200 {object} common.Response[[]accessrequest.Response]
+// @Failure 400 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /access-requests [get]
+func (c *Controller) FindAll(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !(slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) ||
+		slices.Contains(claims.RealmAccess.Roles, web.IdmUser)) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	var request = FindRequest{State: ctx.Query("state", "")}
+	logger.InfoCtx(ctx.Context(), "find access requests: received request", zap.Any("request", request))
+	response, err := c.accessRequestService.FindAll(request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			logger.ErrorCtx(ctx.Context(), "find access requests: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), "find access requests: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "find access requests: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/access-requests/:id"
+// @Summary Get access request by ID
+// @Description returns access request with its state history with roles: admin, user
+// @Tags access-request
+// @Security OAuth2Password
+// @Accept json
+// @Produce json
+// @Param id path int true "Access request ID"
+// @Success 200 {object} common.Response[accessrequest.Response]
+// @Failure 400 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /access-requests/{id} [get]
+func (c *Controller) FindById(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !(slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) ||
+		slices.Contains(claims.RealmAccess.Roles, web.IdmUser)) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error parsing id: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	request := IdRequest{Id: int64(id)}
+	logger.InfoCtx(ctx.Context(), "find by id access request: received request", zap.Any("request", request))
+	response, err := c.accessRequestService.FindById(request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			logger.ErrorCtx(ctx.Context(), "find by id access request: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), "find by id access request: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "find by id access request: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/access-requests/:id/approve"
+// @Summary Approve access request
+// @Description Approves a pending access request and grants the role, available to the role owner and approvers
+// @Tags access-request
+// @Security OAuth2Password
+// @Accept json
+// @Produce json
+// @Param id path int true "Access request ID"
+// @Param request body accessrequest.DecisionRequest false "decision comment"
+// @Success 200 {object} common.Response[accessrequest.Response]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /access-requests/{id}/approve [post]
+func (c *Controller) Approve(ctx *fiber.Ctx) error {
+	return c.decide(ctx, "approve access request", c.accessRequestService.Approve)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/access-requests/:id/reject"
+// @Summary Reject access request
+// @Description Rejects a pending access request, available to the role owner and approvers
+// @Tags access-request
+// @Security OAuth2Password
+// @Accept json
+// @Produce json
+// @Param id path int true "Access request ID"
+// @Param request body accessrequest.DecisionRequest false "decision comment"
+// @Success 200 {object} common.Response[accessrequest.Response]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /access-requests/{id}/reject [post]
+func (c *Controller) Reject(ctx *fiber.Ctx) error {
+	return c.decide(ctx, "reject access request", c.accessRequestService.Reject)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/access-requests/:id/cancel"
+// @Summary Cancel access request
+// @Description Cancels a pending access request, available to the requester and approvers
+// @Tags access-request
+// @Security OAuth2Password
+// @Accept json
+// @Produce json
+// @Param id path int true "Access request ID"
+// @Param request body accessrequest.DecisionRequest false "cancel comment"
+// @Success 200 {object} common.Response[accessrequest.Response]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /access-requests/{id}/cancel [post]
+func (c *Controller) Cancel(ctx *fiber.Ctx) error {
+	return c.decide(ctx, "cancel access request", c.accessRequestService.Cancel)
+}
+
+func (c *Controller) decide(
+	ctx *fiber.Ctx,
+	action string,
+	decision func(ctx context.Context, request DecisionRequest) (Response, error),
+) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	logger := middleware.GetLogger(ctx)
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error parsing id: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request DecisionRequest
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&request); err != nil {
+			logger.ErrorCtx(ctx.Context(), "body parse error: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		}
+	}
+	request.Id = int64(id)
+	request.Actor = claims.Subject
+	request.ActorRoles = claims.RealmAccess.Roles
+	logger.InfoCtx(ctx.Context(), action+": received request", zap.Any("request", request))
+	response, err := decision(ctx.Context(), request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			logger.ErrorCtx(ctx.Context(), action+": ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.As(err, &common.ForbiddenError{}):
+			logger.ErrorCtx(ctx.Context(), action+": ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusForbidden, err.Error())
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), action+": ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), action+": ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response)
+}
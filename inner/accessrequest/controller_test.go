@@ -0,0 +1,208 @@
+package accessrequest
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"idm/inner/common"
+	"idm/inner/delegation"
+	"idm/inner/web"
+	"idm/inner/web/webtest"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) Create(ctx context.Context, request CreateRequest) (Response, error) {
+	args := svc.Called(ctx, request)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) FindById(request IdRequest) (Response, error) {
+	args := svc.Called(request)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) FindAll(request FindRequest) ([]Response, error) {
+	args := svc.Called(request)
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (svc *MockService) Approve(ctx context.Context, request DecisionRequest) (Response, error) {
+	args := svc.Called(ctx, request)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) Reject(ctx context.Context, request DecisionRequest) (Response, error) {
+	args := svc.Called(ctx, request)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) Cancel(ctx context.Context, request DecisionRequest) (Response, error) {
+	args := svc.Called(ctx, request)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+// MockDelegations полномочия делегированных администраторов по subject
+type MockDelegations map[string]delegation.Scope
+
+func (d MockDelegations) Scope(ctx context.Context, subject string, roles []string) (delegation.Scope, error) {
+	if slices.Contains(roles, web.IdmAdmin) {
+		return delegation.Scope{Admin: true}, nil
+	}
+	return d[subject], nil
+}
+
+var delegations = MockDelegations{"sales-admin": {Departments: []string{"Sales"}}}
+
+func newServer(svc Svc, subject string, roles ...string) *web.Server {
+	server := webtest.NewServer(subject, roles...)
+	NewController(server, svc, delegations).RegisterRoutes()
+	return server
+}
+
+func TestCreateAccessRequest(t *testing.T) {
+	var a = assert.New(t)
+	t.Run("create access request with subject from token", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "user-1")
+		var body = strings.NewReader("{\"employee_id\": 1, \"role_id\": 2, \"justification\": \"need reports\"}")
+		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/access-requests", body)
+		request.Header.Add("Content-Type", "application/json")
+		svc.On("Create", mock.Anything, CreateRequest{
+			EmployeeId:    1,
+			RoleId:        2,
+			Justification: "need reports",
+			RequestedBy:   "user-1",
+		}).Return(Response{Id: int64(5)}, nil)
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		bytesData, err := io.ReadAll(resp.Body)
+		a.Nil(err)
+		var responseBody common.Response[int64]
+		a.Nil(json.Unmarshal(bytesData, &responseBody))
+		a.Equal(int64(5), responseBody.Data)
+	})
+	t.Run("create access request with scope of delegated admin", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "sales-admin")
+		var body = strings.NewReader("{\"employee_id\": 3, \"role_id\": 2, \"justification\": \"new hire\"}")
+		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/access-requests", body)
+		request.Header.Add("Content-Type", "application/json")
+		svc.On("Create", mock.Anything, CreateRequest{
+			EmployeeId:    3,
+			RoleId:        2,
+			Justification: "new hire",
+			RequestedBy:   "sales-admin",
+			Scope:         delegation.Scope{Departments: []string{"Sales"}},
+		}).Return(Response{Id: int64(6)}, nil)
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+	})
+	t.Run("create access request for other employee is forbidden", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "user-1")
+		var body = strings.NewReader("{\"employee_id\": 3, \"role_id\": 2, \"justification\": \"need reports\"}")
+		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/access-requests", body)
+		request.Header.Add("Content-Type", "application/json")
+		svc.On("Create", mock.Anything, mock.AnythingOfType("CreateRequest")).
+			Return(Response{}, common.ForbiddenError{Message: "access for employee 3 can be requested by the employee"})
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+	})
+	t.Run("create access request - duplicate", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "user-1")
+		var body = strings.NewReader("{\"employee_id\": 1, \"role_id\": 2, \"justification\": \"need reports\"}")
+		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/access-requests", body)
+		request.Header.Add("Content-Type", "application/json")
+		svc.On("Create", mock.Anything, mock.AnythingOfType("CreateRequest")).
+			Return(Response{}, common.AlreadyExistsError{Message: "pending access request already exists"})
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestFindAccessRequests(t *testing.T) {
+	var a = assert.New(t)
+	t.Run("find access requests by state", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "user-1", web.IdmUser)
+		var request = httptest.NewRequest(fiber.MethodGet, "/api/v1/access-requests?state=pending", nil)
+		svc.On("FindAll", FindRequest{State: StatePending}).Return([]Response{{Id: 1}, {Id: 2}}, nil)
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		bytesData, err := io.ReadAll(resp.Body)
+		a.Nil(err)
+		var responseBody common.Response[[]Response]
+		a.Nil(json.Unmarshal(bytesData, &responseBody))
+		a.Len(responseBody.Data, 2)
+	})
+	t.Run("find access requests - permission denied", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "user-1")
+		var request = httptest.NewRequest(fiber.MethodGet, "/api/v1/access-requests", nil)
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		a.True(svc.AssertNumberOfCalls(t, "FindAll", 0))
+	})
+}
+
+func TestDecideAccessRequest(t *testing.T) {
+	var a = assert.New(t)
+	t.Run("approve passes actor and roles from token", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "approver-1", "IDM_APPROVER")
+		var body = strings.NewReader("{\"comment\": \"ok\"}")
+		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/access-requests/7/approve", body)
+		request.Header.Add("Content-Type", "application/json")
+		svc.On("Approve", mock.Anything, DecisionRequest{
+			Id:         7,
+			Comment:    "ok",
+			Actor:      "approver-1",
+			ActorRoles: []string{"IDM_APPROVER"},
+		}).Return(Response{Id: 7, State: StateApproved}, nil)
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		bytesData, err := io.ReadAll(resp.Body)
+		a.Nil(err)
+		var responseBody common.Response[Response]
+		a.Nil(json.Unmarshal(bytesData, &responseBody))
+		a.Equal(StateApproved, responseBody.Data.State)
+	})
+	t.Run("reject without body - forbidden", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "user-2")
+		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/access-requests/7/reject", nil)
+		svc.On("Reject", mock.Anything, mock.AnythingOfType("DecisionRequest")).
+			Return(Response{}, common.ForbiddenError{Message: "only the role owner or an approver can decide access request"})
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+	})
+	t.Run("cancel - incorrect id", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "user-1")
+		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/access-requests/abc/cancel", nil)
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusBadRequest, resp.StatusCode)
+		a.True(svc.AssertNumberOfCalls(t, "Cancel", 0))
+	})
+}
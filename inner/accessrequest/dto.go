@@ -0,0 +1,133 @@
+package accessrequest
+
+import (
+	"idm/inner/delegation"
+	"slices"
+	"time"
+)
+
+// состояния заявки на доступ
+const (
+	StatePending   = "pending"
+	StateApproved  = "approved"
+	StateRejected  = "rejected"
+	StateCancelled = "cancelled"
+	StateExpired   = "expired"
+)
+
+// transitions допустимые переходы между состояниями заявки, все состояния кроме pending конечные
+var transitions = map[string][]string{
+	StatePending: {StateApproved, StateRejected, StateCancelled, StateExpired},
+}
+
+func canTransition(from string, to string) bool {
+	return slices.Contains(transitions[from], to)
+}
+
+type Entity struct {
+	Id            int64     `db:"id"`
+	EmployeeId    int64     `db:"employee_id"`
+	RoleId        int64     `db:"role_id"`
+	Justification string    `db:"justification"`
+	State         string    `db:"state"`
+	RequestedBy   string    `db:"requested_by"`
+	DecidedBy     string    `db:"decided_by"`
+	ExpiresAt     time.Time `db:"expires_at"`
+	CreatedAt     time.Time `db:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at"`
+}
+
+// EmployeeEntity сотрудник, для которого запрашивается роль
+type EmployeeEntity struct {
+	Subject    string `db:"subject"`
+	Department string `db:"department"`
+}
+
+type HistoryEntity struct {
+	Id        int64     `db:"id"`
+	RequestId int64     `db:"request_id"`
+	State     string    `db:"state"`
+	Actor     string    `db:"actor"`
+	Comment   string    `db:"comment"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type Response struct {
+	Id            int64             `json:"id"`
+	EmployeeId    int64             `json:"employee_id"`
+	RoleId        int64             `json:"role_id"`
+	Justification string            `json:"justification"`
+	State         string            `json:"state"`
+	RequestedBy   string            `json:"requested_by"`
+	DecidedBy     string            `json:"decided_by"`
+	ExpiresAt     time.Time         `json:"expires_at"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+	History       []HistoryResponse `json:"history,omitempty"`
+}
+
+type HistoryResponse struct {
+	State     string    `json:"state"`
+	Actor     string    `json:"actor"`
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateRequest struct {
+	EmployeeId    int64  `json:"employee_id" validate:"required,min=1"`
+	RoleId        int64  `json:"role_id" validate:"required,min=1"`
+	Justification string `json:"justification" validate:"required,minnows3,max=1000"`
+	RequestedBy   string `json:"-" validate:"required"`
+	// Scope полномочия автора заявки: за другого сотрудника просит администратор или делегат его подразделения
+	Scope delegation.Scope `json:"-"`
+}
+
+type DecisionRequest struct {
+	Id         int64    `json:"-" validate:"required,min=1"`
+	Comment    string   `json:"comment" validate:"max=1000"`
+	Actor      string   `json:"-" validate:"required"`
+	ActorRoles []string `json:"-"`
+}
+
+type IdRequest struct {
+	Id int64 `json:"id" validate:"required,min=1"`
+}
+
+type FindRequest struct {
+	State string `validate:"omitempty,oneof=pending approved rejected cancelled expired"`
+}
+
+func (e *Entity) toResponse() Response {
+	return Response{
+		Id:            e.Id,
+		EmployeeId:    e.EmployeeId,
+		RoleId:        e.RoleId,
+		Justification: e.Justification,
+		State:         e.State,
+		RequestedBy:   e.RequestedBy,
+		DecidedBy:     e.DecidedBy,
+		ExpiresAt:     e.ExpiresAt,
+		CreatedAt:     e.CreatedAt,
+		UpdatedAt:     e.UpdatedAt,
+	}
+}
+
+func (e *HistoryEntity) toResponse() HistoryResponse {
+	return HistoryResponse{
+		State:     e.State,
+		Actor:     e.Actor,
+		Comment:   e.Comment,
+		CreatedAt: e.CreatedAt,
+	}
+}
+
+func (req *CreateRequest) ToEntity(expiresAt time.Time) Entity {
+	return Entity{
+		EmployeeId:    req.EmployeeId,
+		RoleId:        req.RoleId,
+		Justification: req.Justification,
+		State:         StatePending,
+		RequestedBy:   req.RequestedBy,
+		ExpiresAt:     expiresAt,
+	}
+}
@@ -0,0 +1,106 @@
+package accessrequest
+
+import (
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{
+		db: database,
+	}
+}
+
+func (r *Repository) BeginTransaction() (*sqlx.Tx, error) {
+	return r.db.Beginx()
+}
+
+func (r *Repository) Save(tx *sqlx.Tx, e Entity) (int64, error) {
+	var id int64
+	err := tx.QueryRow(
+		"INSERT INTO access_request (employee_id, role_id, justification, state, requested_by, expires_at) "+
+			"VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
+		e.EmployeeId, e.RoleId, e.Justification, e.State, e.RequestedBy, e.ExpiresAt).Scan(&id)
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
+func (r *Repository) FindEmployee(tx *sqlx.Tx, id int64) (employee EmployeeEntity, err error) {
+	err = tx.Get(&employee, "SELECT subject, department FROM employee WHERE id = $1", id)
+	return employee, err
+}
+
+func (r *Repository) ExistsPending(tx *sqlx.Tx, employeeId int64, roleId int64) (isExist bool, err error) {
+	err = tx.Get(
+		&isExist,
+		"SELECT EXISTS(SELECT 1 FROM access_request WHERE employee_id = $1 AND role_id = $2 AND state = $3)",
+		employeeId, roleId, StatePending,
+	)
+	if err != nil {
+		return false, err
+	}
+	return isExist, nil
+}
+
+func (r *Repository) FindById(id int64) (res Entity, err error) {
+	err = r.db.Get(&res, "SELECT * FROM access_request WHERE id = $1", id)
+	return res, err
+}
+
+// FindByIdForUpdate найти заявку и заблокировать её до конца транзакции
+func (r *Repository) FindByIdForUpdate(tx *sqlx.Tx, id int64) (res Entity, err error) {
+	err = tx.Get(&res, "SELECT * FROM access_request WHERE id = $1 FOR UPDATE", id)
+	return res, err
+}
+
+func (r *Repository) FindAll(state string) ([]Entity, error) {
+	var requests []Entity
+	query := "SELECT * FROM access_request"
+	var args []interface{}
+	if state != "" {
+		query += " WHERE state = $1"
+		args = append(args, state)
+	}
+	query += " ORDER BY id"
+	err := r.db.Select(&requests, query, args...)
+	return requests, err
+}
+
+func (r *Repository) UpdateState(tx *sqlx.Tx, id int64, state string, decidedBy string) error {
+	_, err := tx.Exec(
+		"UPDATE access_request SET state = $1, decided_by = $2, updated_at = NOW() WHERE id = $3",
+		state, decidedBy, id)
+	return err
+}
+
+// ExpirePending перевести просроченные заявки в состояние expired и вернуть их идентификаторы
+func (r *Repository) ExpirePending(tx *sqlx.Tx, now time.Time) ([]int64, error) {
+	var ids []int64
+	err := tx.Select(
+		&ids,
+		"UPDATE access_request SET state = $1, updated_at = NOW() WHERE state = $2 AND expires_at <= $3 RETURNING id",
+		StateExpired, StatePending, now)
+	return ids, err
+}
+
+func (r *Repository) SaveHistory(tx *sqlx.Tx, e HistoryEntity) error {
+	_, err := tx.Exec(
+		"INSERT INTO access_request_history (request_id, state, actor, comment) VALUES ($1, $2, $3, $4)",
+		e.RequestId, e.State, e.Actor, e.Comment)
+	return err
+}
+
+func (r *Repository) FindHistory(requestId int64) ([]HistoryEntity, error) {
+	var history []HistoryEntity
+	err := r.db.Select(
+		&history,
+		"SELECT * FROM access_request_history WHERE request_id = $1 ORDER BY id",
+		requestId)
+	return history, err
+}
@@ -0,0 +1,286 @@
+package accessrequest
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"idm/inner/assignment"
+	"idm/inner/common"
+	"idm/inner/database"
+	"idm/inner/relation"
+	"slices"
+	"time"
+)
+
+type Service struct {
+	repo          Repo
+	assignments   AssignmentRepo
+	relations     Relations
+	validator     Validator
+	ttl           time.Duration
+	approverRoles []string
+}
+
+type Repo interface {
+	BeginTransaction() (*sqlx.Tx, error)
+	Save(tx *sqlx.Tx, e Entity) (int64, error)
+	FindEmployee(tx *sqlx.Tx, id int64) (EmployeeEntity, error)
+	ExistsPending(tx *sqlx.Tx, employeeId int64, roleId int64) (bool, error)
+	FindById(id int64) (Entity, error)
+	FindByIdForUpdate(tx *sqlx.Tx, id int64) (Entity, error)
+	FindAll(state string) ([]Entity, error)
+	UpdateState(tx *sqlx.Tx, id int64, state string, decidedBy string) error
+	ExpirePending(tx *sqlx.Tx, now time.Time) ([]int64, error)
+	SaveHistory(tx *sqlx.Tx, e HistoryEntity) error
+	FindHistory(requestId int64) ([]HistoryEntity, error)
+}
+
+type AssignmentRepo interface {
+	Save(tx *sqlx.Tx, e assignment.Entity) error
+}
+
+type Relations interface {
+	Check(ctx context.Context, subject string, relation string, object string) (bool, error)
+}
+
+type Validator interface {
+	Validate(request any) error
+}
+
+// NewService создать сервис заявок на доступ;
+// approverRoles - роли из токена, владельцы которых могут согласовывать любую заявку
+func NewService(
+	repo Repo,
+	assignments AssignmentRepo,
+	relations Relations,
+	validator Validator,
+	ttl time.Duration,
+	approverRoles []string,
+) *Service {
+	return &Service{
+		repo:          repo,
+		assignments:   assignments,
+		relations:     relations,
+		validator:     validator,
+		ttl:           ttl,
+		approverRoles: approverRoles,
+	}
+}
+
+func (s *Service) Create(ctx context.Context, request CreateRequest) (Response, error) {
+	err := s.validator.Validate(request)
+	if err != nil {
+		return Response{}, common.RequestValidationError{Message: err.Error()}
+	}
+	var id int64
+	err = database.InTransaction(s.repo.BeginTransaction, "creating access request", func(tx *sqlx.Tx) error {
+		employee, err := s.repo.FindEmployee(tx, request.EmployeeId)
+		if err != nil {
+			return common.NotFoundError{Message: fmt.Sprintf("error finding employee with id %d: %v", request.EmployeeId, err)}
+		}
+		if employee.Subject != request.RequestedBy && !request.Scope.AllowsEmployee(employee.Department) {
+			return common.ForbiddenError{Message: fmt.Sprintf(
+				"access for employee %d can be requested by the employee, an admin or a delegated admin of its department",
+				request.EmployeeId)}
+		}
+		isExist, err := s.repo.ExistsPending(tx, request.EmployeeId, request.RoleId)
+		if err != nil {
+			return fmt.Errorf("error finding pending access request: %w", err)
+		}
+		if isExist {
+			return common.AlreadyExistsError{Message: fmt.Sprintf(
+				"pending access request already exists: employee %d, role %d", request.EmployeeId, request.RoleId)}
+		}
+		id, err = s.repo.Save(tx, request.ToEntity(time.Now().Add(s.ttl)))
+		if err != nil {
+			return fmt.Errorf("error saving access request: %w", err)
+		}
+		err = s.repo.SaveHistory(tx, HistoryEntity{
+			RequestId: id,
+			State:     StatePending,
+			Actor:     request.RequestedBy,
+			Comment:   request.Justification,
+		})
+		if err != nil {
+			return fmt.Errorf("error saving access request history: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return Response{}, err
+	}
+	return Response{Id: id}, nil
+}
+
+func (s *Service) FindById(request IdRequest) (Response, error) {
+	var err = s.validator.Validate(request)
+	if err != nil {
+		return Response{}, common.RequestValidationError{Message: err.Error()}
+	}
+	entity, err := s.repo.FindById(request.Id)
+	if err != nil {
+		return Response{}, common.NotFoundError{Message: fmt.Sprintf("error finding access request with id %d: %v", request.Id, err)}
+	}
+	history, err := s.repo.FindHistory(request.Id)
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding access request history with id %d: %w", request.Id, err)
+	}
+	var response = entity.toResponse()
+	for _, item := range history {
+		response.History = append(response.History, item.toResponse())
+	}
+	return response, nil
+}
+
+func (s *Service) FindAll(request FindRequest) ([]Response, error) {
+	if err := s.validator.Validate(request); err != nil {
+		return nil, common.RequestValidationError{Message: err.Error()}
+	}
+	var requests, err = s.repo.FindAll(request.State)
+	if err != nil {
+		return nil, common.NotFoundError{Message: fmt.Sprintf("error finding access requests: %v", err)}
+	}
+	var response []Response
+	for _, entity := range requests {
+		response = append(response, entity.toResponse())
+	}
+	return response, nil
+}
+
+// Approve согласовать заявку и выдать сотруднику запрошенную роль в той же транзакции
+func (s *Service) Approve(ctx context.Context, request DecisionRequest) (Response, error) {
+	return s.decide(ctx, request, StateApproved, s.checkApprover, func(tx *sqlx.Tx, entity Entity) error {
+		err := s.assignments.Save(tx, assignment.Entity{
+			EmployeeId: entity.EmployeeId,
+			RoleId:     entity.RoleId,
+			Source:     assignment.SourceRequest,
+			CreatedBy:  request.Actor,
+		})
+		if err != nil {
+			return fmt.Errorf("error granting role %d to employee %d: %w", entity.RoleId, entity.EmployeeId, err)
+		}
+		return nil
+	})
+}
+
+func (s *Service) Reject(ctx context.Context, request DecisionRequest) (Response, error) {
+	return s.decide(ctx, request, StateRejected, s.checkApprover, nil)
+}
+
+// Cancel отозвать заявку может её автор или согласующий
+func (s *Service) Cancel(ctx context.Context, request DecisionRequest) (Response, error) {
+	return s.decide(ctx, request, StateCancelled, func(ctx context.Context, tx *sqlx.Tx, request DecisionRequest, entity Entity) error {
+		if request.Actor == entity.RequestedBy || s.hasApproverRole(request.ActorRoles) {
+			return nil
+		}
+		return common.ForbiddenError{Message: "only the requester or an approver can cancel access request"}
+	}, nil)
+}
+
+// ExpireOverdue перевести в состояние expired заявки, не согласованные до истечения срока
+func (s *Service) ExpireOverdue(ctx context.Context) (expired int, err error) {
+	err = database.InTransaction(s.repo.BeginTransaction, "expiring access requests", func(tx *sqlx.Tx) error {
+		ids, err := s.repo.ExpirePending(tx, time.Now())
+		if err != nil {
+			return fmt.Errorf("error expiring access requests: %w", err)
+		}
+		for _, id := range ids {
+			err = s.repo.SaveHistory(tx, HistoryEntity{RequestId: id, State: StateExpired, Actor: "system"})
+			if err != nil {
+				return fmt.Errorf("error saving access request history: %w", err)
+			}
+		}
+		expired = len(ids)
+		return nil
+	})
+	return expired, err
+}
+
+func (s *Service) decide(
+	ctx context.Context,
+	request DecisionRequest,
+	target string,
+	authorize func(ctx context.Context, tx *sqlx.Tx, request DecisionRequest, entity Entity) error,
+	apply func(tx *sqlx.Tx, entity Entity) error,
+) (Response, error) {
+	err := s.validator.Validate(request)
+	if err != nil {
+		return Response{}, common.RequestValidationError{Message: err.Error()}
+	}
+	var entity Entity
+	err = database.InTransaction(s.repo.BeginTransaction, "deciding access request", func(tx *sqlx.Tx) error {
+		entity, err = s.repo.FindByIdForUpdate(tx, request.Id)
+		if err != nil {
+			return common.NotFoundError{Message: fmt.Sprintf("error finding access request with id %d: %v", request.Id, err)}
+		}
+		if !canTransition(entity.State, target) {
+			return common.RequestValidationError{Message: fmt.Sprintf(
+				"access request %d cannot be %s: current state is %s", entity.Id, target, entity.State)}
+		}
+		// просроченную заявку фоновая задача ещё могла не перевести в expired, но согласовать её уже нельзя
+		if target == StateApproved && time.Now().After(entity.ExpiresAt) {
+			return common.RequestValidationError{Message: fmt.Sprintf(
+				"access request %d cannot be %s: it expired at %s", entity.Id, target, entity.ExpiresAt.Format(time.RFC3339))}
+		}
+		if err = authorize(ctx, tx, request, entity); err != nil {
+			return err
+		}
+		if err = s.repo.UpdateState(tx, entity.Id, target, request.Actor); err != nil {
+			return fmt.Errorf("error updating access request state: %w", err)
+		}
+		err = s.repo.SaveHistory(tx, HistoryEntity{
+			RequestId: entity.Id,
+			State:     target,
+			Actor:     request.Actor,
+			Comment:   request.Comment,
+		})
+		if err != nil {
+			return fmt.Errorf("error saving access request history: %w", err)
+		}
+		if apply != nil {
+			return apply(tx, entity)
+		}
+		return nil
+	})
+	if err != nil {
+		return Response{}, err
+	}
+	entity.State = target
+	entity.DecidedBy = request.Actor
+	return entity.toResponse(), nil
+}
+
+// checkApprover согласовать заявку может член группы согласующих или тот, кто управляет ролью по отношениям
+// (например, её владелец), но не сам автор заявки и не сотрудник, которому запрошен доступ
+func (s *Service) checkApprover(ctx context.Context, tx *sqlx.Tx, request DecisionRequest, entity Entity) error {
+	if request.Actor == entity.RequestedBy {
+		return common.ForbiddenError{Message: "requester cannot decide own access request"}
+	}
+	employee, err := s.repo.FindEmployee(tx, entity.EmployeeId)
+	if err != nil {
+		return fmt.Errorf("error finding employee with id %d: %w", entity.EmployeeId, err)
+	}
+	if employee.Subject != "" && employee.Subject == request.Actor {
+		return common.ForbiddenError{Message: "employee cannot decide access request for own access"}
+	}
+	if s.hasApproverRole(request.ActorRoles) {
+		return nil
+	}
+	manages, err := s.relations.Check(ctx, relation.User(request.Actor), relation.PermissionManage, relation.Role(entity.RoleId))
+	if err != nil {
+		return fmt.Errorf("error checking relation of %s to role %d: %w", request.Actor, entity.RoleId, err)
+	}
+	if manages {
+		return nil
+	}
+	return common.ForbiddenError{Message: "only the role owner or an approver can decide access request"}
+}
+
+func (s *Service) hasApproverRole(roles []string) bool {
+	for _, role := range roles {
+		if slices.Contains(s.approverRoles, role) {
+			return true
+		}
+	}
+	return false
+}
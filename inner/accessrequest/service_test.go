@@ -0,0 +1,416 @@
+package accessrequest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"idm/inner/assignment"
+	"idm/inner/common"
+	"idm/inner/database/dbtest"
+	"idm/inner/delegation"
+	"idm/inner/validator"
+	"slices"
+	"testing"
+	"time"
+)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+func (r *MockRepo) BeginTransaction() (*sqlx.Tx, error) {
+	args := r.Called()
+	return args.Get(0).(*sqlx.Tx), args.Error(1)
+}
+
+func (r *MockRepo) Save(tx *sqlx.Tx, e Entity) (int64, error) {
+	args := r.Called(tx, e)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (r *MockRepo) FindEmployee(tx *sqlx.Tx, id int64) (EmployeeEntity, error) {
+	args := r.Called(tx, id)
+	return args.Get(0).(EmployeeEntity), args.Error(1)
+}
+
+func (r *MockRepo) ExistsPending(tx *sqlx.Tx, employeeId int64, roleId int64) (bool, error) {
+	args := r.Called(tx, employeeId, roleId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (r *MockRepo) FindById(id int64) (Entity, error) {
+	args := r.Called(id)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (r *MockRepo) FindByIdForUpdate(tx *sqlx.Tx, id int64) (Entity, error) {
+	args := r.Called(tx, id)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (r *MockRepo) FindAll(state string) ([]Entity, error) {
+	args := r.Called(state)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (r *MockRepo) UpdateState(tx *sqlx.Tx, id int64, state string, decidedBy string) error {
+	args := r.Called(tx, id, state, decidedBy)
+	return args.Error(0)
+}
+
+func (r *MockRepo) ExpirePending(tx *sqlx.Tx, now time.Time) ([]int64, error) {
+	args := r.Called(tx, now)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (r *MockRepo) SaveHistory(tx *sqlx.Tx, e HistoryEntity) error {
+	args := r.Called(tx, e)
+	return args.Error(0)
+}
+
+func (r *MockRepo) FindHistory(requestId int64) ([]HistoryEntity, error) {
+	args := r.Called(requestId)
+	return args.Get(0).([]HistoryEntity), args.Error(1)
+}
+
+type MockAssignmentRepo struct {
+	mock.Mock
+}
+
+func (r *MockAssignmentRepo) Save(tx *sqlx.Tx, e assignment.Entity) error {
+	args := r.Called(tx, e)
+	return args.Error(0)
+}
+
+// MockRelations отношения пользователей к объектам в виде "subject relation object"
+type MockRelations []string
+
+func (r MockRelations) Check(ctx context.Context, subject string, relation string, object string) (bool, error) {
+	return slices.Contains(r, subject+" "+relation+" "+object), nil
+}
+
+var owners = MockRelations{"user:owner-1 manage role:2"}
+
+var approverRoles = []string{"IDM_ADMIN", "IDM_APPROVER"}
+
+func TestCreate(t *testing.T) {
+	var request = CreateRequest{
+		EmployeeId:    1,
+		RoleId:        2,
+		Justification: "need access to reports",
+		RequestedBy:   "user-1",
+	}
+	t.Run("should create pending access request", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, true)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockAssignmentRepo), owners, validator.New(), time.Hour, approverRoles)
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("FindEmployee", tx, int64(1)).Return(EmployeeEntity{Subject: "user-1"}, nil)
+		repo.On("ExistsPending", tx, int64(1), int64(2)).Return(false, nil)
+		repo.On("Save", tx, mock.MatchedBy(func(e Entity) bool {
+			return e.State == StatePending && e.RequestedBy == "user-1" && e.ExpiresAt.After(time.Now())
+		})).Return(int64(10), nil)
+		repo.On("SaveHistory", tx, HistoryEntity{
+			RequestId: 10,
+			State:     StatePending,
+			Actor:     "user-1",
+			Comment:   "need access to reports",
+		}).Return(nil)
+		got, err := svc.Create(context.Background(), request)
+		a.Nil(err)
+		a.Equal(Response{Id: 10}, got)
+		a.True(repo.AssertNumberOfCalls(t, "SaveHistory", 1))
+	})
+	t.Run("should return already exists error for duplicate pending request", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, false)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockAssignmentRepo), owners, validator.New(), time.Hour, approverRoles)
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("FindEmployee", tx, int64(1)).Return(EmployeeEntity{Subject: "user-1"}, nil)
+		repo.On("ExistsPending", tx, int64(1), int64(2)).Return(true, nil)
+		_, err := svc.Create(context.Background(), request)
+		a.NotNil(err)
+		a.True(errors.As(err, &common.AlreadyExistsError{}))
+		a.True(repo.AssertNumberOfCalls(t, "Save", 0))
+	})
+	t.Run("should forbid request for other employee without scope", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, false)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockAssignmentRepo), owners, validator.New(), time.Hour, approverRoles)
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("FindEmployee", tx, int64(1)).Return(EmployeeEntity{Subject: "user-2", Department: "Sales"}, nil)
+		_, err := svc.Create(context.Background(), request)
+		a.True(errors.As(err, &common.ForbiddenError{}))
+		a.True(repo.AssertNumberOfCalls(t, "Save", 0))
+	})
+	t.Run("should allow delegated admin to request for employee of department", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, true)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockAssignmentRepo), owners, validator.New(), time.Hour, approverRoles)
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("FindEmployee", tx, int64(1)).Return(EmployeeEntity{Department: "Sales"}, nil)
+		repo.On("ExistsPending", tx, int64(1), int64(2)).Return(false, nil)
+		repo.On("Save", tx, mock.Anything).Return(int64(10), nil)
+		repo.On("SaveHistory", tx, mock.Anything).Return(nil)
+		var delegated = request
+		delegated.Scope = delegation.Scope{Departments: []string{"Sales"}}
+		got, err := svc.Create(context.Background(), delegated)
+		a.Nil(err)
+		a.Equal(Response{Id: 10}, got)
+	})
+	t.Run("should return validation error without justification", func(t *testing.T) {
+		a := assert.New(t)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockAssignmentRepo), owners, validator.New(), time.Hour, approverRoles)
+		_, err := svc.Create(context.Background(), CreateRequest{EmployeeId: 1, RoleId: 2, RequestedBy: "user-1"})
+		a.NotNil(err)
+		a.True(errors.As(err, &common.RequestValidationError{}))
+		a.True(repo.AssertNumberOfCalls(t, "BeginTransaction", 0))
+	})
+}
+
+func TestApprove(t *testing.T) {
+	var pending = Entity{
+		Id:          10,
+		EmployeeId:  1,
+		RoleId:      2,
+		State:       StatePending,
+		RequestedBy: "user-1",
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+	var employee = EmployeeEntity{Subject: "employee-1", Department: "Sales"}
+	t.Run("should approve and grant role when actor is approver", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, true)
+		var repo = new(MockRepo)
+		var assignments = new(MockAssignmentRepo)
+		var svc = NewService(repo, assignments, owners, validator.New(), time.Hour, approverRoles)
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("FindByIdForUpdate", tx, int64(10)).Return(pending, nil)
+		repo.On("FindEmployee", tx, int64(1)).Return(employee, nil)
+		repo.On("UpdateState", tx, int64(10), StateApproved, "approver-1").Return(nil)
+		repo.On("SaveHistory", tx, HistoryEntity{
+			RequestId: 10,
+			State:     StateApproved,
+			Actor:     "approver-1",
+			Comment:   "ok",
+		}).Return(nil)
+		assignments.On("Save", tx, assignment.Entity{
+			EmployeeId: 1,
+			RoleId:     2,
+			Source:     assignment.SourceRequest,
+			CreatedBy:  "approver-1",
+		}).Return(nil)
+		got, err := svc.Approve(context.Background(), DecisionRequest{
+			Id:         10,
+			Comment:    "ok",
+			Actor:      "approver-1",
+			ActorRoles: []string{"IDM_APPROVER"},
+		})
+		a.Nil(err)
+		a.Equal(StateApproved, got.State)
+		a.Equal("approver-1", got.DecidedBy)
+		a.True(assignments.AssertNumberOfCalls(t, "Save", 1))
+	})
+	t.Run("should approve when actor is role owner", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, true)
+		var repo = new(MockRepo)
+		var assignments = new(MockAssignmentRepo)
+		var svc = NewService(repo, assignments, owners, validator.New(), time.Hour, approverRoles)
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("FindByIdForUpdate", tx, int64(10)).Return(pending, nil)
+		repo.On("FindEmployee", tx, int64(1)).Return(employee, nil)
+		repo.On("UpdateState", tx, int64(10), StateApproved, "owner-1").Return(nil)
+		repo.On("SaveHistory", tx, mock.Anything).Return(nil)
+		assignments.On("Save", tx, mock.Anything).Return(nil)
+		got, err := svc.Approve(context.Background(), DecisionRequest{Id: 10, Actor: "owner-1"})
+		a.Nil(err)
+		a.Equal(StateApproved, got.State)
+	})
+	t.Run("should forbid approval by requester", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, false)
+		var repo = new(MockRepo)
+		var assignments = new(MockAssignmentRepo)
+		var svc = NewService(repo, assignments, owners, validator.New(), time.Hour, approverRoles)
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("FindByIdForUpdate", tx, int64(10)).Return(pending, nil)
+		_, err := svc.Approve(context.Background(), DecisionRequest{
+			Id:         10,
+			Actor:      "user-1",
+			ActorRoles: []string{"IDM_ADMIN"},
+		})
+		a.NotNil(err)
+		a.True(errors.As(err, &common.ForbiddenError{}))
+		a.True(assignments.AssertNumberOfCalls(t, "Save", 0))
+	})
+	t.Run("should forbid approval by employee the access is requested for", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, false)
+		var repo = new(MockRepo)
+		var assignments = new(MockAssignmentRepo)
+		var svc = NewService(repo, assignments, owners, validator.New(), time.Hour, approverRoles)
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("FindByIdForUpdate", tx, int64(10)).Return(pending, nil)
+		repo.On("FindEmployee", tx, int64(1)).Return(employee, nil)
+		_, err := svc.Approve(context.Background(), DecisionRequest{
+			Id:         10,
+			Actor:      "employee-1",
+			ActorRoles: []string{"IDM_APPROVER"},
+		})
+		a.NotNil(err)
+		a.True(errors.As(err, &common.ForbiddenError{}))
+		a.True(repo.AssertNumberOfCalls(t, "UpdateState", 0))
+		a.True(assignments.AssertNumberOfCalls(t, "Save", 0))
+	})
+	t.Run("should reject approval of overdue request", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, false)
+		var repo = new(MockRepo)
+		var assignments = new(MockAssignmentRepo)
+		var svc = NewService(repo, assignments, owners, validator.New(), time.Hour, approverRoles)
+		var overdue = pending
+		overdue.ExpiresAt = time.Now().Add(-time.Minute)
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("FindByIdForUpdate", tx, int64(10)).Return(overdue, nil)
+		_, err := svc.Approve(context.Background(), DecisionRequest{
+			Id:         10,
+			Actor:      "approver-1",
+			ActorRoles: []string{"IDM_APPROVER"},
+		})
+		a.NotNil(err)
+		a.True(errors.As(err, &common.RequestValidationError{}))
+		a.True(assignments.AssertNumberOfCalls(t, "Save", 0))
+	})
+	t.Run("should forbid approval by someone who is not owner or approver", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, false)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockAssignmentRepo), owners, validator.New(), time.Hour, approverRoles)
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("FindByIdForUpdate", tx, int64(10)).Return(pending, nil)
+		repo.On("FindEmployee", tx, int64(1)).Return(employee, nil)
+		_, err := svc.Approve(context.Background(), DecisionRequest{Id: 10, Actor: "user-2"})
+		a.NotNil(err)
+		a.True(errors.As(err, &common.ForbiddenError{}))
+		a.True(repo.AssertNumberOfCalls(t, "UpdateState", 0))
+	})
+	t.Run("should reject transition from final state", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, false)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockAssignmentRepo), owners, validator.New(), time.Hour, approverRoles)
+		var rejected = pending
+		rejected.State = StateRejected
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("FindByIdForUpdate", tx, int64(10)).Return(rejected, nil)
+		_, err := svc.Approve(context.Background(), DecisionRequest{
+			Id:         10,
+			Actor:      "approver-1",
+			ActorRoles: []string{"IDM_APPROVER"},
+		})
+		a.NotNil(err)
+		a.Equal(common.RequestValidationError{
+			Message: "access request 10 cannot be approved: current state is rejected",
+		}, err)
+	})
+	t.Run("should rollback when grant fails", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, false)
+		var repo = new(MockRepo)
+		var assignments = new(MockAssignmentRepo)
+		var svc = NewService(repo, assignments, owners, validator.New(), time.Hour, approverRoles)
+		var dbErr = errors.New("database error")
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("FindByIdForUpdate", tx, int64(10)).Return(pending, nil)
+		repo.On("FindEmployee", tx, int64(1)).Return(employee, nil)
+		repo.On("UpdateState", tx, int64(10), StateApproved, "approver-1").Return(nil)
+		repo.On("SaveHistory", tx, mock.Anything).Return(nil)
+		assignments.On("Save", tx, mock.Anything).Return(dbErr)
+		_, err := svc.Approve(context.Background(), DecisionRequest{
+			Id:         10,
+			Actor:      "approver-1",
+			ActorRoles: []string{"IDM_APPROVER"},
+		})
+		a.Equal(fmt.Errorf("error granting role %d to employee %d: %w", 2, 1, dbErr), err)
+	})
+}
+
+func TestCancel(t *testing.T) {
+	var pending = Entity{Id: 10, EmployeeId: 1, RoleId: 2, State: StatePending, RequestedBy: "user-1"}
+	t.Run("should cancel own request", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, true)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockAssignmentRepo), owners, validator.New(), time.Hour, approverRoles)
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("FindByIdForUpdate", tx, int64(10)).Return(pending, nil)
+		repo.On("UpdateState", tx, int64(10), StateCancelled, "user-1").Return(nil)
+		repo.On("SaveHistory", tx, mock.Anything).Return(nil)
+		got, err := svc.Cancel(context.Background(), DecisionRequest{Id: 10, Actor: "user-1"})
+		a.Nil(err)
+		a.Equal(StateCancelled, got.State)
+	})
+	t.Run("should forbid cancelling someone else's request", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, false)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockAssignmentRepo), owners, validator.New(), time.Hour, approverRoles)
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("FindByIdForUpdate", tx, int64(10)).Return(pending, nil)
+		_, err := svc.Cancel(context.Background(), DecisionRequest{Id: 10, Actor: "user-2"})
+		a.True(errors.As(err, &common.ForbiddenError{}))
+	})
+}
+
+func TestExpireOverdue(t *testing.T) {
+	t.Run("should expire overdue requests and write history", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, true)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockAssignmentRepo), owners, validator.New(), time.Hour, approverRoles)
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("ExpirePending", tx, mock.AnythingOfType("time.Time")).Return([]int64{3, 4}, nil)
+		repo.On("SaveHistory", tx, mock.MatchedBy(func(e HistoryEntity) bool {
+			return e.State == StateExpired && e.Actor == "system"
+		})).Return(nil)
+		got, err := svc.ExpireOverdue(context.Background())
+		a.Nil(err)
+		a.Equal(2, got)
+		a.True(repo.AssertNumberOfCalls(t, "SaveHistory", 2))
+	})
+}
+
+func TestFindById(t *testing.T) {
+	t.Run("should return request with history", func(t *testing.T) {
+		a := assert.New(t)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockAssignmentRepo), owners, validator.New(), time.Hour, approverRoles)
+		repo.On("FindById", int64(10)).Return(Entity{Id: 10, State: StateApproved}, nil)
+		repo.On("FindHistory", int64(10)).Return([]HistoryEntity{
+			{RequestId: 10, State: StatePending, Actor: "user-1"},
+			{RequestId: 10, State: StateApproved, Actor: "approver-1"},
+		}, nil)
+		got, err := svc.FindById(IdRequest{Id: 10})
+		a.Nil(err)
+		a.Len(got.History, 2)
+		a.Equal(StateApproved, got.History[1].State)
+	})
+	t.Run("should return not found error", func(t *testing.T) {
+		a := assert.New(t)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockAssignmentRepo), owners, validator.New(), time.Hour, approverRoles)
+		var err = errors.New("database error")
+		repo.On("FindById", int64(10)).Return(Entity{}, err)
+		_, got := svc.FindById(IdRequest{Id: 10})
+		a.Equal(common.NotFoundError{Message: fmt.Sprintf("error finding access request with id %d: %v", 10, err)}, got)
+	})
+}
@@ -0,0 +1,221 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"idm/inner/common"
+	"idm/inner/middleware"
+	"idm/inner/web"
+	"slices"
+	"strconv"
+)
+
+type Controller struct {
+	server         *web.Server
+	accountService Svc
+}
+
+type Svc interface {
+	Import(ctx context.Context, request ImportRequest) (ImportResponse, error)
+	FindByApplication(request IdRequest) ([]Response, error)
+	FindByEmployee(request IdRequest) ([]Response, error)
+	FindOrphans() ([]Response, error)
+	FindDormant() ([]Response, error)
+}
+
+func NewController(
+	server *web.Server,
+	accountService Svc,
+) *Controller {
+	return &Controller{
+		server:         server,
+		accountService: accountService,
+	}
+}
+
+func (c *Controller) RegisterRoutes() {
+	c.server.GroupApiV1.Post("/applications/:id/accounts/import", c.Import)
+	c.server.GroupApiV1.Get("/applications/:id/accounts", c.FindByApplication)
+	c.server.GroupApiV1.Get("/employees/:id/accounts", c.FindByEmployee)
+	c.server.GroupApiV1.Get("/accounts/orphans", c.FindOrphans)
+	c.server.GroupApiV1.Get("/accounts/dormant", c.FindDormant)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/applications/:id/accounts/import"
+// @Summary import accounts of application
+// @Description Load accounts exported from the target system (JSON array or CSV with header) and link them to employees, with roles: admin
+// @Tags account
+// @Security OAuth2Password
+// @Accept json
+// @Accept text/csv
+// @Produce json
+// @Param id path int true "Application ID"
+// @Param request body []account.ImportRow true "accounts snapshot"
+// @Success 200 {object} common.Response[account.ImportResponse]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /applications/{id}/accounts/import [post]
+func (c *Controller) Import(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error parsing id: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	rows, err := ParseRows(ctx.Get(fiber.HeaderContentType), ctx.Body())
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "body parse error: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request = ImportRequest{ApplicationId: id, Rows: rows}
+	logger.InfoCtx(ctx.Context(), "import accounts: received request",
+		zap.Int64("application_id", id), zap.Int("rows", len(rows)))
+	response, err := c.accountService.Import(ctx.Context(), request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			logger.ErrorCtx(ctx.Context(), "error importing accounts: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), "error importing accounts: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "error importing accounts: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/applications/:id/accounts"
+// @Summary Get accounts of application
+// @Description returns accounts of the application with owners and last sync time, with roles: admin, user
+// @Tags account
+// @Security OAuth2Password
+// @Produce json
+// @Param id path int true "Application ID"
+// @Success 200 {object} common.Response[[]account.Response]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /applications/{id}/accounts [get]
+func (c *Controller) FindByApplication(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !(slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) ||
+		slices.Contains(claims.RealmAccess.Roles, web.IdmUser)) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error parsing id: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request = IdRequest{Id: id}
+	logger.InfoCtx(ctx.Context(), "find accounts of application: received request", zap.Any("request", request))
+	response, err := c.accountService.FindByApplication(request)
+	return c.accountsResponse(ctx, "find accounts of application: ", response, err)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/employees/:id/accounts"
+// @Summary Get accounts of employee
+// @Description returns target-system accounts owned by the employee, with roles: admin, user
+// @Tags account
+// @Security OAuth2Password
+// @Produce json
+// @Param id path int true "Employee ID"
+// @Success 200 {object} common.Response[[]account.Response]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /employees/{id}/accounts [get]
+func (c *Controller) FindByEmployee(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !(slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) ||
+		slices.Contains(claims.RealmAccess.Roles, web.IdmUser)) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error parsing id: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request = IdRequest{Id: id}
+	logger.InfoCtx(ctx.Context(), "find accounts of employee: received request", zap.Any("request", request))
+	response, err := c.accountService.FindByEmployee(request)
+	return c.accountsResponse(ctx, "find accounts of employee: ", response, err)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/accounts/orphans"
+// @Summary Get orphan accounts
+// @Description returns accounts without an owner or left by removed employees, with roles: admin
+// @Tags account
+// @Security OAuth2Password
+// @Produce json
+// @Success 200 {object} common.Response[[]account.Response]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /accounts/orphans [get]
+func (c *Controller) FindOrphans(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	logger.InfoCtx(ctx.Context(), "find orphan accounts: received request")
+	response, err := c.accountService.FindOrphans()
+	return c.accountsResponse(ctx, "find orphan accounts: ", response, err)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/accounts/dormant"
+// @Summary Get dormant accounts
+// @Description returns active accounts without logins for the configured period, with roles: admin
+// @Tags account
+// @Security OAuth2Password
+// @Produce json
+// @Success 200 {object} common.Response[[]account.Response]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /accounts/dormant [get]
+func (c *Controller) FindDormant(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	logger.InfoCtx(ctx.Context(), "find dormant accounts: received request")
+	response, err := c.accountService.FindDormant()
+	return c.accountsResponse(ctx, "find dormant accounts: ", response, err)
+}
+
+func (c *Controller) accountsResponse(ctx *fiber.Ctx, operation string, response []Response, err error) error {
+	if err != nil {
+		logger := middleware.GetLogger(ctx)
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			logger.ErrorCtx(ctx.Context(), operation, zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), operation, zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), operation, zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response)
+}
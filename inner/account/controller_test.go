@@ -0,0 +1,133 @@
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"idm/inner/common"
+	"idm/inner/web"
+	"idm/inner/web/webtest"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) Import(ctx context.Context, request ImportRequest) (ImportResponse, error) {
+	args := svc.Called(ctx, request)
+	return args.Get(0).(ImportResponse), args.Error(1)
+}
+
+func (svc *MockService) FindByApplication(request IdRequest) ([]Response, error) {
+	args := svc.Called(request)
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (svc *MockService) FindByEmployee(request IdRequest) ([]Response, error) {
+	args := svc.Called(request)
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (svc *MockService) FindOrphans() ([]Response, error) {
+	args := svc.Called()
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (svc *MockService) FindDormant() ([]Response, error) {
+	args := svc.Called()
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func newServer(svc Svc, subject string, roles ...string) *web.Server {
+	server := webtest.NewServer(subject, roles...)
+	NewController(server, svc).RegisterRoutes()
+	return server
+}
+
+func TestImportAccounts(t *testing.T) {
+	var a = assert.New(t)
+	t.Run("import csv snapshot", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "admin-1", web.IdmAdmin)
+		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/applications/3/accounts/import",
+			strings.NewReader("login,subject\njdoe,kc-1\n"))
+		request.Header.Add("Content-Type", "text/csv")
+		var want = ImportResponse{Total: 1, Linked: 1}
+		svc.On("Import", mock.Anything, ImportRequest{
+			ApplicationId: 3,
+			Rows:          []ImportRow{{Login: "jdoe", Subject: "kc-1", Status: StatusActive}},
+		}).Return(want, nil)
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		bytesData, err := io.ReadAll(resp.Body)
+		a.Nil(err)
+		var responseBody common.Response[ImportResponse]
+		err = json.Unmarshal(bytesData, &responseBody)
+		a.Nil(err)
+		a.Equal(want, responseBody.Data)
+	})
+	t.Run("import malformed snapshot", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "admin-1", web.IdmAdmin)
+		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/applications/3/accounts/import",
+			strings.NewReader("{\"login\": \"jdoe\"}"))
+		request.Header.Add("Content-Type", "application/json")
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusBadRequest, resp.StatusCode)
+		a.True(svc.AssertNumberOfCalls(t, "Import", 0))
+	})
+	t.Run("import without role admin", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "user-1", web.IdmUser)
+		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/applications/3/accounts/import",
+			strings.NewReader("[]"))
+		request.Header.Add("Content-Type", "application/json")
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+	})
+}
+
+func TestAccountReports(t *testing.T) {
+	var a = assert.New(t)
+	t.Run("admin gets orphan accounts", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "admin-1", web.IdmAdmin)
+		var want = []Response{{Id: 1, Login: "svc-backup", Status: StatusActive, OrphanReason: OrphanUnowned}}
+		svc.On("FindOrphans").Return(want, nil)
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/accounts/orphans", nil))
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		bytesData, err := io.ReadAll(resp.Body)
+		a.Nil(err)
+		var responseBody common.Response[[]Response]
+		err = json.Unmarshal(bytesData, &responseBody)
+		a.Nil(err)
+		a.Equal(want, responseBody.Data)
+	})
+	t.Run("user can not get dormant accounts", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "user-1", web.IdmUser)
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/accounts/dormant", nil))
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		a.True(svc.AssertNumberOfCalls(t, "FindDormant", 0))
+	})
+	t.Run("user gets accounts of employee", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "user-1", web.IdmUser)
+		svc.On("FindByEmployee", IdRequest{Id: 4}).Return([]Response{{Id: 1, Login: "jdoe"}}, nil)
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/4/accounts", nil))
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+	})
+}
@@ -0,0 +1,88 @@
+package account
+
+import "time"
+
+// состояния учётной записи в целевой системе
+const (
+	StatusActive   = "active"
+	StatusDisabled = "disabled"
+)
+
+// причины, по которым учётная запись считается бесхозной
+const (
+	OrphanUnowned    = "unowned"
+	OrphanTerminated = "terminated"
+)
+
+// Entity учётная запись сотрудника в приложении. LastEmployeeId хранит последнего владельца,
+// чтобы после удаления сотрудника отличать его учётные записи от никогда не связанных
+type Entity struct {
+	Id             int64      `db:"id"`
+	ApplicationId  int64      `db:"application_id"`
+	Login          string     `db:"login"`
+	EmployeeId     *int64     `db:"employee_id"`
+	LastEmployeeId *int64     `db:"last_employee_id"`
+	Status         string     `db:"status"`
+	LastLoginAt    *time.Time `db:"last_login_at"`
+	LastSyncAt     time.Time  `db:"last_sync_at"`
+	CreatedAt      time.Time  `db:"created_at"`
+}
+
+type Response struct {
+	Id            int64      `json:"id"`
+	ApplicationId int64      `json:"application_id"`
+	Login         string     `json:"login"`
+	EmployeeId    *int64     `json:"employee_id,omitempty"`
+	Status        string     `json:"status"`
+	LastLoginAt   *time.Time `json:"last_login_at,omitempty"`
+	LastSyncAt    time.Time  `json:"last_sync_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	OrphanReason  string     `json:"orphan_reason,omitempty"`
+}
+
+// ImportRow строка выгрузки учётных записей из целевой системы. Владелец определяется
+// по id сотрудника, а если он не указан - по subject из identity provider.
+// Entitlements используются только при сверке, импорт учётных записей их не сохраняет
+type ImportRow struct {
+	Login        string     `json:"login" validate:"required,max=255" log:"mask"`
+	EmployeeId   int64      `json:"employee_id" validate:"min=0"`
+	Subject      string     `json:"subject" validate:"max=255"`
+	Status       string     `json:"status" validate:"required,oneof=active disabled"`
+	LastLoginAt  *time.Time `json:"last_login_at"`
+	Entitlements []string   `json:"entitlements,omitempty" validate:"dive,required,max=255"`
+}
+
+type ImportRequest struct {
+	ApplicationId int64       `validate:"required,min=1"`
+	Rows          []ImportRow `validate:"required,min=1,dive"`
+}
+
+type ImportResponse struct {
+	Total    int `json:"total"`
+	Linked   int `json:"linked"`
+	Unlinked int `json:"unlinked"`
+}
+
+type IdRequest struct {
+	Id int64 `json:"id" validate:"required,min=1"`
+}
+
+func (e *Entity) toResponse() Response {
+	var response = Response{
+		Id:            e.Id,
+		ApplicationId: e.ApplicationId,
+		Login:         e.Login,
+		EmployeeId:    e.EmployeeId,
+		Status:        e.Status,
+		LastLoginAt:   e.LastLoginAt,
+		LastSyncAt:    e.LastSyncAt,
+		CreatedAt:     e.CreatedAt,
+	}
+	if e.EmployeeId == nil {
+		response.OrphanReason = OrphanUnowned
+		if e.LastEmployeeId != nil {
+			response.OrphanReason = OrphanTerminated
+		}
+	}
+	return response
+}
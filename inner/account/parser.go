@@ -0,0 +1,90 @@
+package account
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseRows разобрать выгрузку учётных записей: CSV с заголовком или JSON-массив.
+// Пустой статус считается active
+func ParseRows(contentType string, body []byte) ([]ImportRow, error) {
+	var rows []ImportRow
+	var err error
+	if strings.HasPrefix(contentType, "text/csv") {
+		rows, err = parseCsv(body)
+	} else {
+		err = json.Unmarshal(body, &rows)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		if rows[i].Status == "" {
+			rows[i].Status = StatusActive
+		}
+	}
+	return rows, nil
+}
+
+// parseCsv колонки определяются по заголовку, обязательна только login;
+// права в колонке entitlements перечисляются через точку с запятой
+func parseCsv(body []byte) ([]ImportRow, error) {
+	var reader = csv.NewReader(bytes.NewReader(body))
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading csv header: %w", err)
+	}
+	var columns = make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["login"]; !ok {
+		return nil, fmt.Errorf("csv header has no login column")
+	}
+	var value = func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	var rows []ImportRow
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading csv line %d: %w", line, err)
+		}
+		var row = ImportRow{
+			Login:   value(record, "login"),
+			Subject: value(record, "subject"),
+			Status:  value(record, "status"),
+		}
+		if id := value(record, "employee_id"); id != "" {
+			if row.EmployeeId, err = strconv.ParseInt(id, 10, 64); err != nil {
+				return nil, fmt.Errorf("csv line %d: invalid employee_id %q", line, id)
+			}
+		}
+		for _, name := range strings.Split(value(record, "entitlements"), ";") {
+			if name = strings.TrimSpace(name); name != "" {
+				row.Entitlements = append(row.Entitlements, name)
+			}
+		}
+		if lastLogin := value(record, "last_login_at"); lastLogin != "" {
+			parsed, err := time.Parse(time.RFC3339, lastLogin)
+			if err != nil {
+				return nil, fmt.Errorf("csv line %d: invalid last_login_at %q", line, lastLogin)
+			}
+			row.LastLoginAt = &parsed
+		}
+		rows = append(rows, row)
+	}
+}
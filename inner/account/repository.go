@@ -0,0 +1,84 @@
+package account
+
+import (
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{
+		db: database,
+	}
+}
+
+func (r *Repository) BeginTransaction() (*sqlx.Tx, error) {
+	return r.db.Beginx()
+}
+
+func (r *Repository) ApplicationExists(tx *sqlx.Tx, applicationId int64) (isExist bool, err error) {
+	err = tx.Get(&isExist, "SELECT EXISTS(SELECT 1 FROM application WHERE id = $1)", applicationId)
+	return isExist, err
+}
+
+// Upsert сохранить учётную запись из выгрузки и связать её с сотрудником; вернуть true, если владелец найден.
+// Если владелец не найден, последний известный владелец сохраняется.
+// Строка без employee_id и subject не несёт сведений о владельце, поэтому существующая связь не меняется
+func (r *Repository) Upsert(tx *sqlx.Tx, applicationId int64, row ImportRow) (linked bool, err error) {
+	err = tx.QueryRow(
+		"WITH owner AS (SELECT e.id FROM employee e "+
+			"WHERE e.id = $3 OR ($3 = 0 AND e.subject <> '' AND e.subject = $4) LIMIT 1) "+
+			"INSERT INTO account (application_id, login, employee_id, last_employee_id, status, last_login_at, last_sync_at) "+
+			"VALUES ($1, $2, (SELECT id FROM owner), (SELECT id FROM owner), $5, $6, NOW()) "+
+			"ON CONFLICT (application_id, login) DO UPDATE SET "+
+			"employee_id = CASE WHEN $3 = 0 AND $4 = '' THEN account.employee_id ELSE EXCLUDED.employee_id END, "+
+			"last_employee_id = COALESCE(EXCLUDED.employee_id, account.last_employee_id), "+
+			"status = EXCLUDED.status, "+
+			"last_login_at = COALESCE(EXCLUDED.last_login_at, account.last_login_at), "+
+			"last_sync_at = EXCLUDED.last_sync_at "+
+			"RETURNING employee_id IS NOT NULL",
+		applicationId, row.Login, row.EmployeeId, row.Subject, row.Status, row.LastLoginAt,
+	).Scan(&linked)
+	return linked, err
+}
+
+func (r *Repository) FindByApplication(applicationId int64) ([]Entity, error) {
+	var accounts []Entity
+	err := r.db.Select(&accounts, "SELECT * FROM account WHERE application_id = $1 ORDER BY login", applicationId)
+	return accounts, err
+}
+
+func (r *Repository) FindByEmployee(employeeId int64) ([]Entity, error) {
+	var accounts []Entity
+	err := r.db.Select(
+		&accounts,
+		"SELECT * FROM account WHERE employee_id = $1 ORDER BY application_id, login",
+		employeeId,
+	)
+	return accounts, err
+}
+
+// FindOrphans учётные записи без владельца: не связанные ни с кем или оставшиеся от удалённых сотрудников
+func (r *Repository) FindOrphans() ([]Entity, error) {
+	var accounts []Entity
+	err := r.db.Select(
+		&accounts,
+		"SELECT * FROM account WHERE employee_id IS NULL ORDER BY application_id, login",
+	)
+	return accounts, err
+}
+
+// FindDormant активные учётные записи, в которые не входили с момента before
+func (r *Repository) FindDormant(before time.Time) ([]Entity, error) {
+	var accounts []Entity
+	err := r.db.Select(
+		&accounts,
+		"SELECT * FROM account WHERE status = $1 AND COALESCE(last_login_at, created_at) < $2 "+
+			"ORDER BY application_id, login",
+		StatusActive, before,
+	)
+	return accounts, err
+}
@@ -0,0 +1,117 @@
+package account
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"idm/inner/common"
+	"idm/inner/database"
+	"time"
+)
+
+type Service struct {
+	repo         Repo
+	validator    Validator
+	dormantAfter time.Duration
+}
+
+type Repo interface {
+	BeginTransaction() (*sqlx.Tx, error)
+	ApplicationExists(tx *sqlx.Tx, applicationId int64) (bool, error)
+	Upsert(tx *sqlx.Tx, applicationId int64, row ImportRow) (bool, error)
+	FindByApplication(applicationId int64) ([]Entity, error)
+	FindByEmployee(employeeId int64) ([]Entity, error)
+	FindOrphans() ([]Entity, error)
+	FindDormant(before time.Time) ([]Entity, error)
+}
+
+type Validator interface {
+	Validate(request any) error
+}
+
+// NewService dormantAfter - через сколько времени без входа активная учётная запись считается неиспользуемой
+func NewService(repo Repo, validator Validator, dormantAfter time.Duration) *Service {
+	return &Service{
+		repo:         repo,
+		validator:    validator,
+		dormantAfter: dormantAfter,
+	}
+}
+
+// Import загрузить выгрузку учётных записей приложения одной транзакцией
+func (s *Service) Import(ctx context.Context, request ImportRequest) (ImportResponse, error) {
+	if err := s.validator.Validate(request); err != nil {
+		return ImportResponse{}, common.RequestValidationError{Message: err.Error()}
+	}
+	var response = ImportResponse{Total: len(request.Rows)}
+	err := database.InTransaction(s.repo.BeginTransaction, "importing accounts", func(tx *sqlx.Tx) error {
+		isExist, err := s.repo.ApplicationExists(tx, request.ApplicationId)
+		if err != nil {
+			return fmt.Errorf("error finding application %d: %w", request.ApplicationId, err)
+		}
+		if !isExist {
+			return common.NotFoundError{Message: fmt.Sprintf("application with id %d not found", request.ApplicationId)}
+		}
+		for _, row := range request.Rows {
+			linked, err := s.repo.Upsert(tx, request.ApplicationId, row)
+			if err != nil {
+				return fmt.Errorf("error saving account %s: %w", row.Login, err)
+			}
+			if linked {
+				response.Linked++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return ImportResponse{}, err
+	}
+	response.Unlinked = response.Total - response.Linked
+	return response, nil
+}
+
+func (s *Service) FindByApplication(request IdRequest) ([]Response, error) {
+	if err := s.validator.Validate(request); err != nil {
+		return nil, common.RequestValidationError{Message: err.Error()}
+	}
+	accounts, err := s.repo.FindByApplication(request.Id)
+	if err != nil {
+		return nil, common.NotFoundError{Message: fmt.Sprintf("error finding accounts of application %d: %v", request.Id, err)}
+	}
+	return toResponses(accounts), nil
+}
+
+func (s *Service) FindByEmployee(request IdRequest) ([]Response, error) {
+	if err := s.validator.Validate(request); err != nil {
+		return nil, common.RequestValidationError{Message: err.Error()}
+	}
+	accounts, err := s.repo.FindByEmployee(request.Id)
+	if err != nil {
+		return nil, common.NotFoundError{Message: fmt.Sprintf("error finding accounts of employee %d: %v", request.Id, err)}
+	}
+	return toResponses(accounts), nil
+}
+
+func (s *Service) FindOrphans() ([]Response, error) {
+	accounts, err := s.repo.FindOrphans()
+	if err != nil {
+		return nil, common.NotFoundError{Message: fmt.Sprintf("error finding orphan accounts: %v", err)}
+	}
+	return toResponses(accounts), nil
+}
+
+func (s *Service) FindDormant() ([]Response, error) {
+	accounts, err := s.repo.FindDormant(time.Now().Add(-s.dormantAfter))
+	if err != nil {
+		return nil, common.NotFoundError{Message: fmt.Sprintf("error finding dormant accounts: %v", err)}
+	}
+	return toResponses(accounts), nil
+}
+
+func toResponses(accounts []Entity) []Response {
+	var response []Response
+	for _, entity := range accounts {
+		response = append(response, entity.toResponse())
+	}
+	return response
+}
@@ -0,0 +1,164 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"idm/inner/common"
+	"idm/inner/database/dbtest"
+	"idm/inner/validator"
+	"testing"
+	"time"
+)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+func (r *MockRepo) BeginTransaction() (*sqlx.Tx, error) {
+	args := r.Called()
+	return args.Get(0).(*sqlx.Tx), args.Error(1)
+}
+
+func (r *MockRepo) ApplicationExists(tx *sqlx.Tx, applicationId int64) (bool, error) {
+	args := r.Called(tx, applicationId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (r *MockRepo) Upsert(tx *sqlx.Tx, applicationId int64, row ImportRow) (bool, error) {
+	args := r.Called(tx, applicationId, row)
+	return args.Bool(0), args.Error(1)
+}
+
+func (r *MockRepo) FindByApplication(applicationId int64) ([]Entity, error) {
+	args := r.Called(applicationId)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (r *MockRepo) FindByEmployee(employeeId int64) ([]Entity, error) {
+	args := r.Called(employeeId)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (r *MockRepo) FindOrphans() ([]Entity, error) {
+	args := r.Called()
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (r *MockRepo) FindDormant(before time.Time) ([]Entity, error) {
+	args := r.Called(before)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func TestImport(t *testing.T) {
+	var rows = []ImportRow{
+		{Login: "jdoe", Subject: "kc-1", Status: StatusActive},
+		{Login: "svc-backup", Status: StatusDisabled},
+	}
+	t.Run("should save accounts and count linked owners", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, true)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New(), time.Hour)
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("ApplicationExists", tx, int64(3)).Return(true, nil)
+		repo.On("Upsert", tx, int64(3), rows[0]).Return(true, nil)
+		repo.On("Upsert", tx, int64(3), rows[1]).Return(false, nil)
+		got, err := svc.Import(context.Background(), ImportRequest{ApplicationId: 3, Rows: rows})
+		a.Nil(err)
+		a.Equal(ImportResponse{Total: 2, Linked: 1, Unlinked: 1}, got)
+	})
+	t.Run("should return not found error for unknown application", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, false)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New(), time.Hour)
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("ApplicationExists", tx, int64(3)).Return(false, nil)
+		_, err := svc.Import(context.Background(), ImportRequest{ApplicationId: 3, Rows: rows})
+		a.True(errors.As(err, &common.NotFoundError{}))
+		a.True(repo.AssertNumberOfCalls(t, "Upsert", 0))
+	})
+	t.Run("should reject rows without login", func(t *testing.T) {
+		a := assert.New(t)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New(), time.Hour)
+		_, err := svc.Import(context.Background(), ImportRequest{
+			ApplicationId: 3,
+			Rows:          []ImportRow{{Status: StatusActive}},
+		})
+		a.True(errors.As(err, &common.RequestValidationError{}))
+	})
+}
+
+func TestFindOrphans(t *testing.T) {
+	var a = assert.New(t)
+	var repo = new(MockRepo)
+	var svc = NewService(repo, validator.New(), time.Hour)
+	var removedId = int64(7)
+	repo.On("FindOrphans").Return([]Entity{
+		{Id: 1, Login: "svc-backup", Status: StatusActive},
+		{Id: 2, Login: "jdoe", Status: StatusActive, LastEmployeeId: &removedId},
+	}, nil)
+	got, err := svc.FindOrphans()
+	a.Nil(err)
+	a.Len(got, 2)
+	a.Equal(OrphanUnowned, got[0].OrphanReason)
+	a.Equal(OrphanTerminated, got[1].OrphanReason)
+}
+
+func TestFindDormant(t *testing.T) {
+	var a = assert.New(t)
+	var repo = new(MockRepo)
+	var svc = NewService(repo, validator.New(), 30*24*time.Hour)
+	var ownerId = int64(4)
+	repo.On("FindDormant", mock.MatchedBy(func(before time.Time) bool {
+		var want = time.Now().Add(-30 * 24 * time.Hour)
+		return before.Sub(want).Abs() < time.Minute
+	})).Return([]Entity{{Id: 1, Login: "jdoe", EmployeeId: &ownerId, Status: StatusActive}}, nil)
+	got, err := svc.FindDormant()
+	a.Nil(err)
+	a.Len(got, 1)
+	a.Empty(got[0].OrphanReason)
+}
+
+func TestParseRows(t *testing.T) {
+	t.Run("should parse csv by header", func(t *testing.T) {
+		a := assert.New(t)
+		var body = "status,login,employee_id,last_login_at\n" +
+			"disabled,jdoe,4,2025-01-02T10:00:00Z\n" +
+			",svc-backup,,\n"
+		got, err := ParseRows("text/csv; charset=utf-8", []byte(body))
+		a.Nil(err)
+		a.Len(got, 2)
+		a.Equal("jdoe", got[0].Login)
+		a.Equal(int64(4), got[0].EmployeeId)
+		a.Equal(StatusDisabled, got[0].Status)
+		a.Equal(time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC), got[0].LastLoginAt.UTC())
+		a.Equal(ImportRow{Login: "svc-backup", Status: StatusActive}, got[1])
+	})
+	t.Run("should split csv entitlements", func(t *testing.T) {
+		a := assert.New(t)
+		got, err := ParseRows("text/csv", []byte("login,entitlements\njdoe,push; reviewers;\n"))
+		a.Nil(err)
+		a.Equal([]string{"push", "reviewers"}, got[0].Entitlements)
+	})
+	t.Run("should parse json array", func(t *testing.T) {
+		a := assert.New(t)
+		got, err := ParseRows("application/json", []byte(`[{"login": "jdoe", "subject": "kc-1"}]`))
+		a.Nil(err)
+		a.Equal([]ImportRow{{Login: "jdoe", Subject: "kc-1", Status: StatusActive}}, got)
+	})
+	t.Run("should reject csv without login column", func(t *testing.T) {
+		a := assert.New(t)
+		_, err := ParseRows("text/csv", []byte("name,status\njdoe,active\n"))
+		a.NotNil(err)
+	})
+	t.Run("should reject invalid employee id", func(t *testing.T) {
+		a := assert.New(t)
+		_, err := ParseRows("text/csv", []byte("login,employee_id\njdoe,abc\n"))
+		a.EqualError(err, "csv line 2: invalid employee_id \"abc\"")
+	})
+}
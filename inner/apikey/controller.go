@@ -0,0 +1,203 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"idm/inner/common"
+	"idm/inner/middleware"
+	"idm/inner/web"
+	"slices"
+	"strconv"
+)
+
+type Controller struct {
+	server        *web.Server
+	apiKeyService Svc
+}
+
+type Svc interface {
+	Create(ctx context.Context, request CreateRequest) (SecretResponse, error)
+	FindAll() ([]Response, error)
+	Rotate(ctx context.Context, request RotateRequest) (SecretResponse, error)
+	Revoke(ctx context.Context, request RevokeRequest) error
+}
+
+func NewController(
+	server *web.Server,
+	apiKeyService Svc,
+) *Controller {
+	return &Controller{
+		server:        server,
+		apiKeyService: apiKeyService,
+	}
+}
+
+func (c *Controller) RegisterRoutes() {
+	c.server.GroupApiV1.Post("/api-keys", c.CreateApiKey)
+	c.server.GroupApiV1.Get("/api-keys", c.FindAll)
+	c.server.GroupApiV1.Post("/api-keys/:id/rotate", c.Rotate)
+	c.server.GroupApiV1.Post("/api-keys/:id/revoke", c.Revoke)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/api-keys"
+// @Summary create API key
+// @Description Issue an API key for a service client; the key is returned only once, with roles: admin
+// @Tags api-key
+// @Security OAuth2Password
+// @Accept json
+// @Produce json
+// @Param request body apikey.CreateRequest true "create API key request"
+// @Success 200 {object} common.Response[apikey.SecretResponse]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /api-keys [post]
+func (c *Controller) CreateApiKey(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	var request CreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		logger.ErrorCtx(ctx.Context(), "body parse error: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	request.CreatedBy = claims.Subject
+	logger.InfoCtx(ctx.Context(), "create API key: received request", zap.Any("request", request))
+	var response, err = c.apiKeyService.Create(ctx.Context(), request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			logger.ErrorCtx(ctx.Context(), "error creating API key: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "error creating API key: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/api-keys"
+// @Summary Get API keys
+// @Description returns API keys without secrets, with roles: admin
+// @Tags api-key
+// @Security OAuth2Password
+// @Produce json
+// @Success 200 {object} common.Response[[]apikey.Response]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /api-keys [get]
+func (c *Controller) FindAll(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	logger.InfoCtx(ctx.Context(), "find API keys: received request")
+	response, err := c.apiKeyService.FindAll()
+	if err != nil {
+		switch {
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), "find API keys: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "find API keys: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/api-keys/:id/rotate"
+// @Summary Rotate API key
+// @Description Replaces the secret of an API key; the old key stops working, the new one is returned only once,
+// @Description with roles: admin
+// @Tags api-key
+// @Security OAuth2Password
+// @Produce json
+// @Param id path int true "API key ID"
+// @Success 200 {object} common.Response[apikey.SecretResponse]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /api-keys/{id}/rotate [post]
+func (c *Controller) Rotate(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error parsing id: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request = RotateRequest{Id: id, RotatedBy: claims.Subject}
+	logger.InfoCtx(ctx.Context(), "rotate API key: received request", zap.Any("request", request))
+	response, err := c.apiKeyService.Rotate(ctx.Context(), request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			logger.ErrorCtx(ctx.Context(), "rotate API key: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), "rotate API key: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "rotate API key: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/api-keys/:id/revoke"
+// @Summary Revoke API key
+// @Description Revokes an API key before its expiry, with roles: admin
+// @Tags api-key
+// @Security OAuth2Password
+// @Produce json
+// @Param id path int true "API key ID"
+// @Success 200 {object} common.Response[int64]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /api-keys/{id}/revoke [post]
+func (c *Controller) Revoke(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error parsing id: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request = RevokeRequest{Id: id, RevokedBy: claims.Subject}
+	logger.InfoCtx(ctx.Context(), "revoke API key: received request", zap.Any("request", request))
+	err = c.apiKeyService.Revoke(ctx.Context(), request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			logger.ErrorCtx(ctx.Context(), "revoke API key: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), "revoke API key: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "revoke API key: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, id)
+}
@@ -0,0 +1,104 @@
+package apikey
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"idm/inner/common"
+	"idm/inner/web"
+	"idm/inner/web/webtest"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) Create(ctx context.Context, request CreateRequest) (SecretResponse, error) {
+	args := svc.Called(ctx, request)
+	return args.Get(0).(SecretResponse), args.Error(1)
+}
+
+func (svc *MockService) FindAll() ([]Response, error) {
+	args := svc.Called()
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (svc *MockService) Rotate(ctx context.Context, request RotateRequest) (SecretResponse, error) {
+	args := svc.Called(ctx, request)
+	return args.Get(0).(SecretResponse), args.Error(1)
+}
+
+func (svc *MockService) Revoke(ctx context.Context, request RevokeRequest) error {
+	args := svc.Called(ctx, request)
+	return args.Error(0)
+}
+
+func newServer(svc Svc, subject string, roles ...string) *web.Server {
+	server := webtest.NewServer(subject, roles...)
+	NewController(server, svc).RegisterRoutes()
+	return server
+}
+
+func TestCreateApiKey(t *testing.T) {
+	var a = assert.New(t)
+	var body = "{\"name\": \"hr-sync\", \"owner\": \"hr-team\", \"scopes\": [\"IDM_USER\"], \"expires_at\": \"2030-01-01T00:00:00Z\"}"
+	t.Run("admin gets key once", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "admin-1", web.IdmAdmin)
+		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/api-keys", strings.NewReader(body))
+		request.Header.Add("Content-Type", "application/json")
+		svc.On("Create", mock.Anything, CreateRequest{
+			Name:      "hr-sync",
+			Owner:     "hr-team",
+			Scopes:    []string{web.IdmUser},
+			ExpiresAt: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+			CreatedBy: "admin-1",
+		}).Return(SecretResponse{Id: 1, Key: "idm_abc_secret"}, nil)
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		bytesData, err := io.ReadAll(resp.Body)
+		a.Nil(err)
+		var responseBody common.Response[SecretResponse]
+		a.Nil(json.Unmarshal(bytesData, &responseBody))
+		a.Equal("idm_abc_secret", responseBody.Data.Key)
+	})
+	t.Run("user is forbidden", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "user-1", web.IdmUser)
+		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/api-keys", strings.NewReader(body))
+		request.Header.Add("Content-Type", "application/json")
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		a.True(svc.AssertNumberOfCalls(t, "Create", 0))
+	})
+}
+
+func TestRotateAndRevokeApiKey(t *testing.T) {
+	var a = assert.New(t)
+	var svc = new(MockService)
+	server := newServer(svc, "admin-1", web.IdmAdmin)
+	svc.On("Rotate", mock.Anything, RotateRequest{Id: 3, RotatedBy: "admin-1"}).Return(SecretResponse{Id: 3, Key: "idm_def_secret"}, nil)
+	svc.On("Revoke", mock.Anything, RevokeRequest{Id: 4, RevokedBy: "admin-1"}).
+		Return(common.NotFoundError{Message: "active API key with id 4 not found"})
+	resp, err := server.App.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/api-keys/3/rotate", nil))
+	a.Nil(err)
+	a.Equal(http.StatusOK, resp.StatusCode)
+	resp, err = server.App.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/api-keys/4/revoke", nil))
+	a.Nil(err)
+	a.Equal(http.StatusOK, resp.StatusCode)
+	bytesData, err := io.ReadAll(resp.Body)
+	a.Nil(err)
+	var responseBody common.Response[int64]
+	a.Nil(json.Unmarshal(bytesData, &responseBody))
+	a.False(responseBody.Success)
+}
@@ -0,0 +1,99 @@
+package apikey
+
+import (
+	"github.com/lib/pq"
+	"time"
+)
+
+type Entity struct {
+	Id         int64          `db:"id"`
+	Name       string         `db:"name"`
+	Owner      string         `db:"owner"`
+	Prefix     string         `db:"prefix"`
+	Salt       string         `db:"salt"`
+	Hash       string         `db:"hash"`
+	Scopes     pq.StringArray `db:"scopes"`
+	ExpiresAt  time.Time      `db:"expires_at"`
+	LastUsedAt *time.Time     `db:"last_used_at"`
+	RotatedBy  string         `db:"rotated_by"`
+	RotatedAt  *time.Time     `db:"rotated_at"`
+	RevokedBy  string         `db:"revoked_by"`
+	RevokedAt  *time.Time     `db:"revoked_at"`
+	CreatedBy  string         `db:"created_by"`
+	CreatedAt  time.Time      `db:"created_at"`
+}
+
+// Response описание ключа без секрета
+type Response struct {
+	Id         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Owner      string     `json:"owner"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RotatedBy  string     `json:"rotated_by,omitempty"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty"`
+	RevokedBy  string     `json:"revoked_by,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Active     bool       `json:"active"`
+}
+
+// SecretResponse выпущенный ключ; Key показывается только один раз и нигде не хранится
+type SecretResponse struct {
+	Id  int64  `json:"id"`
+	Key string `json:"key" log:"redact"`
+}
+
+// CreateRequest Scopes - роли IDM, с которыми ключ проходит те же проверки, что и токен
+type CreateRequest struct {
+	Name      string    `json:"name" validate:"required,max=155"`
+	Owner     string    `json:"owner" validate:"required,max=255"`
+	Scopes    []string  `json:"scopes" validate:"required,min=1,dive,required,max=255"`
+	ExpiresAt time.Time `json:"expires_at" validate:"required"`
+	CreatedBy string    `json:"-" validate:"required"`
+}
+
+type RotateRequest struct {
+	Id        int64  `validate:"required,min=1"`
+	RotatedBy string `validate:"required"`
+}
+
+type RevokeRequest struct {
+	Id        int64  `validate:"required,min=1"`
+	RevokedBy string `validate:"required"`
+}
+
+func (e *Entity) toResponse(now time.Time) Response {
+	return Response{
+		Id:         e.Id,
+		Name:       e.Name,
+		Owner:      e.Owner,
+		Prefix:     e.Prefix,
+		Scopes:     e.Scopes,
+		ExpiresAt:  e.ExpiresAt,
+		LastUsedAt: e.LastUsedAt,
+		RotatedBy:  e.RotatedBy,
+		RotatedAt:  e.RotatedAt,
+		RevokedBy:  e.RevokedBy,
+		RevokedAt:  e.RevokedAt,
+		CreatedBy:  e.CreatedBy,
+		CreatedAt:  e.CreatedAt,
+		Active:     e.RevokedAt == nil && e.ExpiresAt.After(now),
+	}
+}
+
+func (req *CreateRequest) ToEntity(secret Secret) Entity {
+	return Entity{
+		Name:      req.Name,
+		Owner:     req.Owner,
+		Prefix:    secret.Prefix,
+		Salt:      secret.Salt,
+		Hash:      secret.Hash,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+		CreatedBy: req.CreatedBy,
+	}
+}
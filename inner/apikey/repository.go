@@ -0,0 +1,71 @@
+package apikey
+
+import (
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{
+		db: database,
+	}
+}
+
+func (r *Repository) Save(e Entity) (int64, error) {
+	var id int64
+	err := r.db.QueryRow(
+		"INSERT INTO api_key (name, owner, prefix, salt, hash, scopes, expires_at, created_by) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id",
+		e.Name, e.Owner, e.Prefix, e.Salt, e.Hash, e.Scopes, e.ExpiresAt, e.CreatedBy).Scan(&id)
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
+func (r *Repository) FindAll() ([]Entity, error) {
+	var keys []Entity
+	err := r.db.Select(&keys, "SELECT * FROM api_key ORDER BY id")
+	return keys, err
+}
+
+func (r *Repository) FindByPrefix(prefix string) (key Entity, err error) {
+	err = r.db.Get(&key, "SELECT * FROM api_key WHERE prefix = $1", prefix)
+	return key, err
+}
+
+// Rotate заменить секрет не отозванного ключа и запомнить, кто это сделал; вернуть false, если такого ключа нет
+func (r *Repository) Rotate(id int64, secret Secret, rotatedBy string) (bool, error) {
+	result, err := r.db.Exec(
+		"UPDATE api_key SET prefix = $1, salt = $2, hash = $3, rotated_by = $4, rotated_at = NOW() "+
+			"WHERE id = $5 AND revoked_at IS NULL",
+		secret.Prefix, secret.Salt, secret.Hash, rotatedBy, id,
+	)
+	if err != nil {
+		return false, err
+	}
+	rotated, err := result.RowsAffected()
+	return rotated > 0, err
+}
+
+// Revoke отозвать ключ; вернуть false, если он не найден или уже отозван
+func (r *Repository) Revoke(id int64, revokedBy string) (bool, error) {
+	result, err := r.db.Exec(
+		"UPDATE api_key SET revoked_by = $1, revoked_at = NOW() WHERE id = $2 AND revoked_at IS NULL",
+		revokedBy, id,
+	)
+	if err != nil {
+		return false, err
+	}
+	revoked, err := result.RowsAffected()
+	return revoked > 0, err
+}
+
+func (r *Repository) Touch(id int64, usedAt time.Time) error {
+	_, err := r.db.Exec("UPDATE api_key SET last_used_at = $1 WHERE id = $2", usedAt, id)
+	return err
+}
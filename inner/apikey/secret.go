@@ -0,0 +1,68 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+)
+
+// keyPrefix по нему ключи IDM легко найти в логах и сканерах секретов
+const keyPrefix = "idm"
+
+// Secret новый ключ: Key отдаётся клиенту, в базе остаются Prefix, Salt и Hash
+type Secret struct {
+	Key    string
+	Prefix string
+	Salt   string
+	Hash   string
+}
+
+// newSecret ключ вида idm_<prefix>_<secret>
+func newSecret() (Secret, error) {
+	prefix, err := randomHex(6)
+	if err != nil {
+		return Secret{}, err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return Secret{}, err
+	}
+	salt, err := randomHex(16)
+	if err != nil {
+		return Secret{}, err
+	}
+	return Secret{
+		Key:    keyPrefix + "_" + prefix + "_" + secret,
+		Prefix: prefix,
+		Salt:   salt,
+		Hash:   hashSecret(salt, secret),
+	}, nil
+}
+
+// parseKey разобрать ключ на открытую часть и секрет
+func parseKey(key string) (prefix string, secret string, ok bool) {
+	var parts = strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != keyPrefix || parts[1] == "" || parts[2] == "" {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+func hashSecret(salt string, secret string) string {
+	var sum = sha256.Sum256([]byte(salt + secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func (e *Entity) matches(secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(hashSecret(e.Salt, secret)), []byte(e.Hash)) == 1
+}
+
+func randomHex(size int) (string, error) {
+	var buf = make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,138 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"idm/inner/common"
+	"idm/inner/web"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrInvalidKey = errors.New("invalid API key")
+	ErrExpiredKey = errors.New("API key is expired")
+	ErrRevokedKey = errors.New("API key is revoked")
+)
+
+type Service struct {
+	repo      Repo
+	validator Validator
+}
+
+type Repo interface {
+	Save(e Entity) (int64, error)
+	FindAll() ([]Entity, error)
+	FindByPrefix(prefix string) (Entity, error)
+	Rotate(id int64, secret Secret, rotatedBy string) (bool, error)
+	Revoke(id int64, revokedBy string) (bool, error)
+	Touch(id int64, usedAt time.Time) error
+}
+
+type Validator interface {
+	Validate(request any) error
+}
+
+func NewService(repo Repo, validator Validator) *Service {
+	return &Service{
+		repo:      repo,
+		validator: validator,
+	}
+}
+
+// Create выпустить ключ; сам ключ возвращается только здесь, в базе хранится его хеш
+func (s *Service) Create(ctx context.Context, request CreateRequest) (SecretResponse, error) {
+	if err := s.validator.Validate(request); err != nil {
+		return SecretResponse{}, common.RequestValidationError{Message: err.Error()}
+	}
+	if !request.ExpiresAt.After(time.Now()) {
+		return SecretResponse{}, common.RequestValidationError{Message: "API key expiry must be in the future"}
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return SecretResponse{}, fmt.Errorf("error generating API key: %w", err)
+	}
+	id, err := s.repo.Save(request.ToEntity(secret))
+	if err != nil {
+		return SecretResponse{}, fmt.Errorf("error saving API key: %w", err)
+	}
+	return SecretResponse{Id: id, Key: secret.Key}, nil
+}
+
+func (s *Service) FindAll() ([]Response, error) {
+	var now = time.Now()
+	keys, err := s.repo.FindAll()
+	if err != nil {
+		return nil, common.NotFoundError{Message: fmt.Sprintf("error finding API keys: %v", err)}
+	}
+	var response []Response
+	for _, entity := range keys {
+		response = append(response, entity.toResponse(now))
+	}
+	return response, nil
+}
+
+// Rotate выпустить новый секрет вместо прежнего; прежний ключ сразу перестаёт действовать
+func (s *Service) Rotate(ctx context.Context, request RotateRequest) (SecretResponse, error) {
+	if err := s.validator.Validate(request); err != nil {
+		return SecretResponse{}, common.RequestValidationError{Message: err.Error()}
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return SecretResponse{}, fmt.Errorf("error generating API key: %w", err)
+	}
+	rotated, err := s.repo.Rotate(request.Id, secret, request.RotatedBy)
+	if err != nil {
+		return SecretResponse{}, fmt.Errorf("error rotating API key %d: %w", request.Id, err)
+	}
+	if !rotated {
+		return SecretResponse{}, common.NotFoundError{Message: fmt.Sprintf("active API key with id %d not found", request.Id)}
+	}
+	return SecretResponse{Id: request.Id, Key: secret.Key}, nil
+}
+
+func (s *Service) Revoke(ctx context.Context, request RevokeRequest) error {
+	if err := s.validator.Validate(request); err != nil {
+		return common.RequestValidationError{Message: err.Error()}
+	}
+	revoked, err := s.repo.Revoke(request.Id, request.RevokedBy)
+	if err != nil {
+		return fmt.Errorf("error revoking API key %d: %w", request.Id, err)
+	}
+	if !revoked {
+		return common.NotFoundError{Message: fmt.Sprintf("active API key with id %d not found", request.Id)}
+	}
+	return nil
+}
+
+// Authenticate проверить ключ и вернуть claims, с которыми запрос проходит обычные проверки ролей:
+// subject - api-key:<id>, роли - scopes ключа
+func (s *Service) Authenticate(ctx context.Context, key string) (*web.IdmClaims, error) {
+	prefix, secret, ok := parseKey(key)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+	entity, err := s.repo.FindByPrefix(prefix)
+	if err != nil || !entity.matches(secret) {
+		return nil, ErrInvalidKey
+	}
+	var now = time.Now()
+	if entity.RevokedAt != nil {
+		return nil, ErrRevokedKey
+	}
+	if !entity.ExpiresAt.After(now) {
+		return nil, ErrExpiredKey
+	}
+	if err = s.repo.Touch(entity.Id, now); err != nil {
+		return nil, fmt.Errorf("error tracking API key %d usage: %w", entity.Id, err)
+	}
+	return &web.IdmClaims{
+		RealmAccess: web.RealmAccessClaims{Roles: entity.Scopes},
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "api-key:" + strconv.FormatInt(entity.Id, 10),
+			ExpiresAt: jwt.NewNumericDate(entity.ExpiresAt),
+		},
+	}, nil
+}
@@ -0,0 +1,164 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"idm/inner/common"
+	"idm/inner/validator"
+	"idm/inner/web"
+	"strings"
+	"testing"
+	"time"
+)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+func (r *MockRepo) Save(e Entity) (int64, error) {
+	args := r.Called(e)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (r *MockRepo) FindAll() ([]Entity, error) {
+	args := r.Called()
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (r *MockRepo) FindByPrefix(prefix string) (Entity, error) {
+	args := r.Called(prefix)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (r *MockRepo) Rotate(id int64, secret Secret, rotatedBy string) (bool, error) {
+	args := r.Called(id, secret, rotatedBy)
+	return args.Bool(0), args.Error(1)
+}
+
+func (r *MockRepo) Revoke(id int64, revokedBy string) (bool, error) {
+	args := r.Called(id, revokedBy)
+	return args.Bool(0), args.Error(1)
+}
+
+func (r *MockRepo) Touch(id int64, usedAt time.Time) error {
+	args := r.Called(id, usedAt)
+	return args.Error(0)
+}
+
+func TestCreate(t *testing.T) {
+	var a = assert.New(t)
+	var ctx = context.Background()
+	var request = CreateRequest{
+		Name:      "hr-sync",
+		Owner:     "hr-team",
+		Scopes:    []string{web.IdmUser},
+		ExpiresAt: time.Now().Add(24 * time.Hour),
+		CreatedBy: "admin",
+	}
+	t.Run("key is returned once and only its hash is saved", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		var saved Entity
+		repo.On("Save", mock.AnythingOfType("Entity")).Run(func(args mock.Arguments) {
+			saved = args.Get(0).(Entity)
+		}).Return(int64(3), nil)
+		got, err := svc.Create(ctx, request)
+		a.Nil(err)
+		a.Equal(int64(3), got.Id)
+		prefix, secret, ok := parseKey(got.Key)
+		a.True(ok)
+		a.Equal(saved.Prefix, prefix)
+		a.NotContains(saved.Hash, secret)
+		a.True(saved.matches(secret))
+		a.Equal("hr-team", saved.Owner)
+	})
+	t.Run("expiry in the past", func(t *testing.T) {
+		var svc = NewService(new(MockRepo), validator.New())
+		var expired = request
+		expired.ExpiresAt = time.Now().Add(-time.Minute)
+		_, err := svc.Create(ctx, expired)
+		a.True(errors.As(err, &common.RequestValidationError{}))
+	})
+	t.Run("scopes are required", func(t *testing.T) {
+		var svc = NewService(new(MockRepo), validator.New())
+		var unscoped = request
+		unscoped.Scopes = nil
+		_, err := svc.Create(ctx, unscoped)
+		a.True(errors.As(err, &common.RequestValidationError{}))
+	})
+}
+
+func TestRotateAndRevoke(t *testing.T) {
+	var a = assert.New(t)
+	var ctx = context.Background()
+	var repo = new(MockRepo)
+	var svc = NewService(repo, validator.New())
+	repo.On("Rotate", int64(3), mock.AnythingOfType("Secret"), "admin").Return(true, nil)
+	repo.On("Rotate", int64(4), mock.AnythingOfType("Secret"), "admin").Return(false, nil)
+	repo.On("Revoke", int64(3), "admin").Return(true, nil)
+	repo.On("Revoke", int64(4), "admin").Return(false, nil)
+	got, err := svc.Rotate(ctx, RotateRequest{Id: 3, RotatedBy: "admin"})
+	a.Nil(err)
+	a.True(strings.HasPrefix(got.Key, keyPrefix+"_"))
+	_, err = svc.Rotate(ctx, RotateRequest{Id: 4, RotatedBy: "admin"})
+	a.True(errors.As(err, &common.NotFoundError{}))
+	a.Nil(svc.Revoke(ctx, RevokeRequest{Id: 3, RevokedBy: "admin"}))
+	err = svc.Revoke(ctx, RevokeRequest{Id: 4, RevokedBy: "admin"})
+	a.True(errors.As(err, &common.NotFoundError{}))
+}
+
+func TestAuthenticate(t *testing.T) {
+	var a = assert.New(t)
+	var ctx = context.Background()
+	secret, err := newSecret()
+	a.Nil(err)
+	var entity = Entity{
+		Id:        3,
+		Prefix:    secret.Prefix,
+		Salt:      secret.Salt,
+		Hash:      secret.Hash,
+		Scopes:    []string{web.IdmAdmin},
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	t.Run("valid key gives claims with scopes and tracks usage", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		repo.On("FindByPrefix", secret.Prefix).Return(entity, nil)
+		repo.On("Touch", int64(3), mock.AnythingOfType("time.Time")).Return(nil)
+		claims, err := svc.Authenticate(ctx, secret.Key)
+		a.Nil(err)
+		a.Equal("api-key:3", claims.Subject)
+		a.Equal([]string{web.IdmAdmin}, claims.RealmAccess.Roles)
+		repo.AssertExpectations(t)
+	})
+	t.Run("wrong secret", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		repo.On("FindByPrefix", secret.Prefix).Return(entity, nil)
+		_, err := svc.Authenticate(ctx, keyPrefix+"_"+secret.Prefix+"_forged")
+		a.ErrorIs(err, ErrInvalidKey)
+		repo.AssertNotCalled(t, "Touch", mock.Anything, mock.Anything)
+	})
+	t.Run("malformed key", func(t *testing.T) {
+		var svc = NewService(new(MockRepo), validator.New())
+		_, err := svc.Authenticate(ctx, "not-a-key")
+		a.ErrorIs(err, ErrInvalidKey)
+	})
+	t.Run("revoked and expired keys", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		var revokedAt = time.Now()
+		var revoked = entity
+		revoked.RevokedAt = &revokedAt
+		repo.On("FindByPrefix", secret.Prefix).Return(revoked, nil).Once()
+		_, err := svc.Authenticate(ctx, secret.Key)
+		a.ErrorIs(err, ErrRevokedKey)
+		var expired = entity
+		expired.ExpiresAt = time.Now().Add(-time.Minute)
+		repo.On("FindByPrefix", secret.Prefix).Return(expired, nil).Once()
+		_, err = svc.Authenticate(ctx, secret.Key)
+		a.ErrorIs(err, ErrExpiredKey)
+	})
+}
@@ -0,0 +1,398 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"idm/inner/common"
+	"idm/inner/middleware"
+	"idm/inner/web"
+	"slices"
+	"strconv"
+)
+
+type Controller struct {
+	server             *web.Server
+	applicationService Svc
+}
+
+type Svc interface {
+	Create(ctx context.Context, request CreateRequest) (Response, error)
+	FindAll() ([]Response, error)
+	CreateEntitlement(ctx context.Context, request EntitlementRequest) (EntitlementResponse, error)
+	FindEntitlements(request IdRequest) ([]EntitlementResponse, error)
+	Bundle(ctx context.Context, request BundleRequest) error
+	Unbundle(request UnbundleRequest) error
+	FindByRole(request IdRequest) ([]EntitlementResponse, error)
+	FindEffective(request IdRequest) ([]EffectiveResponse, error)
+}
+
+func NewController(
+	server *web.Server,
+	applicationService Svc,
+) *Controller {
+	return &Controller{
+		server:             server,
+		applicationService: applicationService,
+	}
+}
+
+func (c *Controller) RegisterRoutes() {
+	c.server.GroupApiV1.Post("/applications", c.CreateApplication)
+	c.server.GroupApiV1.Get("/applications", c.FindAll)
+	c.server.GroupApiV1.Post("/applications/:id/entitlements", c.CreateEntitlement)
+	c.server.GroupApiV1.Get("/applications/:id/entitlements", c.FindEntitlements)
+	c.server.GroupApiV1.Post("/roles/:id/entitlements", c.Bundle)
+	c.server.GroupApiV1.Get("/roles/:id/entitlements", c.FindByRole)
+	c.server.GroupApiV1.Delete("/roles/:id/entitlements/:entitlementId", c.Unbundle)
+	c.server.GroupApiV1.Get("/employees/:id/entitlements", c.FindEffective)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/applications"
+// @Summary create application
+// @Description Register a downstream application in the catalog with roles: admin
+// @Tags application
+// @Security OAuth2Password
+// @Accept json
+// @Produce json
+// @Param request body application.CreateRequest true "create application request"
+// @Success 200 {object} common.Response[int64]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /applications [post]
+func (c *Controller) CreateApplication(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	var request CreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		logger.ErrorCtx(ctx.Context(), "body parse error: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	logger.InfoCtx(ctx.Context(), "create application: received request", zap.Any("request", request))
+	var response, err = c.applicationService.Create(ctx.Context(), request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}) || errors.As(err, &common.AlreadyExistsError{}):
+			logger.ErrorCtx(ctx.Context(), "error creating application: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "error creating application: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response.Id)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/applications"
+// @Summary Get applications
+// @Description returns application catalog with roles: admin, user
+// @Tags application
+// @Security OAuth2Password
+// @Produce json
+// @Success 200 {object} common.Response[[]application.Response]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /applications [get]
+func (c *Controller) FindAll(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !(slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) ||
+		slices.Contains(claims.RealmAccess.Roles, web.IdmUser)) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	logger.InfoCtx(ctx.Context(), "find all applications: received request")
+	response, err := c.applicationService.FindAll()
+	if err != nil {
+		switch {
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), "find all applications: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "find all applications: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/applications/:id/entitlements"
+// @Summary create entitlement
+// @Description Add a group or permission offered by the application with roles: admin
+// @Tags application
+// @Security OAuth2Password
+// @Accept json
+// @Produce json
+// @Param id path int true "Application ID"
+// @Param request body application.EntitlementRequest true "create entitlement request"
+// @Success 200 {object} common.Response[int64]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /applications/{id}/entitlements [post]
+func (c *Controller) CreateEntitlement(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error parsing id: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request EntitlementRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		logger.ErrorCtx(ctx.Context(), "body parse error: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	request.ApplicationId = id
+	logger.InfoCtx(ctx.Context(), "create entitlement: received request", zap.Any("request", request))
+	response, err := c.applicationService.CreateEntitlement(ctx.Context(), request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}) || errors.As(err, &common.AlreadyExistsError{}):
+			logger.ErrorCtx(ctx.Context(), "error creating entitlement: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), "error creating entitlement: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "error creating entitlement: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response.Id)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/applications/:id/entitlements"
+// @Summary Get entitlements of application
+// @Description returns groups and permissions offered by the application with roles: admin, user
+// @Tags application
+// @Security OAuth2Password
+// @Produce json
+// @Param id path int true "Application ID"
+// @Success 200 {object} common.Response[[]application.EntitlementResponse]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /applications/{id}/entitlements [get]
+func (c *Controller) FindEntitlements(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !(slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) ||
+		slices.Contains(claims.RealmAccess.Roles, web.IdmUser)) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error parsing id: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request = IdRequest{Id: id}
+	logger.InfoCtx(ctx.Context(), "find entitlements of application: received request", zap.Any("request", request))
+	response, err := c.applicationService.FindEntitlements(request)
+	return c.entitlementsResponse(ctx, "find entitlements of application: ", response, err)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/roles/:id/entitlements"
+// @Summary bundle entitlements into role
+// @Description Employees holding the role effectively receive the entitlements, with roles: admin
+// @Tags application
+// @Security OAuth2Password
+// @Accept json
+// @Produce json
+// @Param id path int true "Role ID"
+// @Param request body application.BundleRequest true "bundle entitlements request"
+// @Success 200 {object} common.Response[int64]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /roles/{id}/entitlements [post]
+func (c *Controller) Bundle(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error parsing id: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request BundleRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		logger.ErrorCtx(ctx.Context(), "body parse error: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	request.RoleId = id
+	request.CreatedBy = claims.Subject
+	logger.InfoCtx(ctx.Context(), "bundle entitlements: received request", zap.Any("request", request))
+	err = c.applicationService.Bundle(ctx.Context(), request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			logger.ErrorCtx(ctx.Context(), "error bundling entitlements: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), "error bundling entitlements: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "error bundling entitlements: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, id)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/roles/:id/entitlements"
+// @Summary Get entitlements of role
+// @Description returns entitlements bundled into the role with roles: admin, user
+// @Tags application
+// @Security OAuth2Password
+// @Produce json
+// @Param id path int true "Role ID"
+// @Success 200 {object} common.Response[[]application.EntitlementResponse]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /roles/{id}/entitlements [get]
+func (c *Controller) FindByRole(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !(slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) ||
+		slices.Contains(claims.RealmAccess.Roles, web.IdmUser)) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error parsing id: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request = IdRequest{Id: id}
+	logger.InfoCtx(ctx.Context(), "find entitlements of role: received request", zap.Any("request", request))
+	response, err := c.applicationService.FindByRole(request)
+	return c.entitlementsResponse(ctx, "find entitlements of role: ", response, err)
+}
+
+// Функция-хендлер, которая будет вызываться при DELETE запросе по маршруту "/api/v1/roles/:id/entitlements/:entitlementId"
+// @Summary Remove entitlement from role
+// @Description Removes an entitlement from the role bundle with roles: admin
+// @Tags application
+// @Security OAuth2Password
+// @Produce json
+// @Param id path int true "Role ID"
+// @Param entitlementId path int true "Entitlement ID"
+// @Success 200 {object} common.Response[int64]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /roles/{id}/entitlements/{entitlementId} [delete]
+func (c *Controller) Unbundle(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	roleId, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error parsing id: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	entitlementId, err := strconv.ParseInt(ctx.Params("entitlementId"), 10, 64)
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error parsing entitlement id: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request = UnbundleRequest{RoleId: roleId, EntitlementId: entitlementId}
+	logger.InfoCtx(ctx.Context(), "remove entitlement from role: received request", zap.Any("request", request))
+	err = c.applicationService.Unbundle(request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			logger.ErrorCtx(ctx.Context(), "remove entitlement from role: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), "remove entitlement from role: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "remove entitlement from role: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, entitlementId)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/employees/:id/entitlements"
+// @Summary Get effective entitlements of employee
+// @Description returns application entitlements the employee holds through roles, with roles: admin, user
+// @Tags application
+// @Security OAuth2Password
+// @Produce json
+// @Param id path int true "Employee ID"
+// @Success 200 {object} common.Response[[]application.EffectiveResponse]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /employees/{id}/entitlements [get]
+func (c *Controller) FindEffective(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !(slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) ||
+		slices.Contains(claims.RealmAccess.Roles, web.IdmUser)) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error parsing id: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request = IdRequest{Id: id}
+	logger.InfoCtx(ctx.Context(), "find effective entitlements: received request", zap.Any("request", request))
+	response, err := c.applicationService.FindEffective(request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			logger.ErrorCtx(ctx.Context(), "find effective entitlements: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), "find effective entitlements: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "find effective entitlements: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response)
+}
+
+func (c *Controller) entitlementsResponse(ctx *fiber.Ctx, operation string, response []EntitlementResponse, err error) error {
+	if err != nil {
+		logger := middleware.GetLogger(ctx)
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			logger.ErrorCtx(ctx.Context(), operation, zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), operation, zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), operation, zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response)
+}
@@ -0,0 +1,152 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"idm/inner/common"
+	"idm/inner/web"
+	"idm/inner/web/webtest"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) Create(ctx context.Context, request CreateRequest) (Response, error) {
+	args := svc.Called(ctx, request)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) FindAll() ([]Response, error) {
+	args := svc.Called()
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (svc *MockService) CreateEntitlement(ctx context.Context, request EntitlementRequest) (EntitlementResponse, error) {
+	args := svc.Called(ctx, request)
+	return args.Get(0).(EntitlementResponse), args.Error(1)
+}
+
+func (svc *MockService) FindEntitlements(request IdRequest) ([]EntitlementResponse, error) {
+	args := svc.Called(request)
+	return args.Get(0).([]EntitlementResponse), args.Error(1)
+}
+
+func (svc *MockService) Bundle(ctx context.Context, request BundleRequest) error {
+	args := svc.Called(ctx, request)
+	return args.Error(0)
+}
+
+func (svc *MockService) Unbundle(request UnbundleRequest) error {
+	args := svc.Called(request)
+	return args.Error(0)
+}
+
+func (svc *MockService) FindByRole(request IdRequest) ([]EntitlementResponse, error) {
+	args := svc.Called(request)
+	return args.Get(0).([]EntitlementResponse), args.Error(1)
+}
+
+func (svc *MockService) FindEffective(request IdRequest) ([]EffectiveResponse, error) {
+	args := svc.Called(request)
+	return args.Get(0).([]EffectiveResponse), args.Error(1)
+}
+
+func newServer(svc Svc, subject string, roles ...string) *web.Server {
+	server := webtest.NewServer(subject, roles...)
+	NewController(server, svc).RegisterRoutes()
+	return server
+}
+
+func TestCreateApplication(t *testing.T) {
+	var a = assert.New(t)
+	var body = "{\"name\": \"GitLab\"}"
+	t.Run("create application", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "admin-1", web.IdmAdmin)
+		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/applications", strings.NewReader(body))
+		request.Header.Add("Content-Type", "application/json")
+		svc.On("Create", mock.Anything, CreateRequest{Name: "GitLab"}).Return(Response{Id: 3}, nil)
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+	})
+	t.Run("create application without role admin", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "user-1", web.IdmUser)
+		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/applications", strings.NewReader(body))
+		request.Header.Add("Content-Type", "application/json")
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		a.True(svc.AssertNumberOfCalls(t, "Create", 0))
+	})
+}
+
+func TestBundleEntitlements(t *testing.T) {
+	var a = assert.New(t)
+	var body = "{\"entitlement_ids\": [4, 7]}"
+	t.Run("bundle entitlements into role", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "admin-1", web.IdmAdmin)
+		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/roles/2/entitlements", strings.NewReader(body))
+		request.Header.Add("Content-Type", "application/json")
+		svc.On("Bundle", mock.Anything, BundleRequest{RoleId: 2, EntitlementIds: []int64{4, 7}, CreatedBy: "admin-1"}).
+			Return(nil)
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+	})
+	t.Run("remove entitlement from role", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "admin-1", web.IdmAdmin)
+		svc.On("Unbundle", UnbundleRequest{RoleId: 2, EntitlementId: 4}).Return(nil)
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodDelete, "/api/v1/roles/2/entitlements/4", nil))
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		resp, err = server.App.Test(httptest.NewRequest(fiber.MethodDelete, "/api/v1/roles/2/entitlements/abc", nil))
+		a.Nil(err)
+		a.Equal(http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestFindEffectiveEntitlements(t *testing.T) {
+	var a = assert.New(t)
+	t.Run("user gets effective entitlements of employee", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "user-1", web.IdmUser)
+		var want = []EffectiveResponse{{
+			ApplicationId:   3,
+			ApplicationName: "GitLab",
+			EntitlementId:   4,
+			EntitlementName: "push",
+			Type:            EntitlementPermission,
+			RoleIds:         []int64{2},
+		}}
+		svc.On("FindEffective", IdRequest{Id: 9}).Return(want, nil)
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/9/entitlements", nil))
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		bytesData, err := io.ReadAll(resp.Body)
+		a.Nil(err)
+		var responseBody common.Response[[]EffectiveResponse]
+		err = json.Unmarshal(bytesData, &responseBody)
+		a.Nil(err)
+		a.Equal(want, responseBody.Data)
+	})
+	t.Run("caller without idm roles is forbidden", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "someone")
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/9/entitlements", nil))
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+	})
+}
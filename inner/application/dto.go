@@ -0,0 +1,148 @@
+package application
+
+import (
+	"github.com/lib/pq"
+	"time"
+)
+
+// типы прав приложения
+const (
+	EntitlementGroup      = "group"
+	EntitlementPermission = "permission"
+)
+
+// Entity приложение, доступ к которому выдаёт IDM
+type Entity struct {
+	Id          int64     `db:"id"`
+	Name        string    `db:"name"`
+	Description string    `db:"description"`
+	Owner       string    `db:"owner"`
+	CreatedAt   time.Time `db:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at"`
+}
+
+type Response struct {
+	Id          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Owner       string    `json:"owner,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// EntitlementEntity право приложения: группа или разрешение
+type EntitlementEntity struct {
+	Id            int64     `db:"id"`
+	ApplicationId int64     `db:"application_id"`
+	Name          string    `db:"name"`
+	Type          string    `db:"type"`
+	Description   string    `db:"description"`
+	CreatedAt     time.Time `db:"created_at"`
+}
+
+type EntitlementResponse struct {
+	Id            int64     `json:"id"`
+	ApplicationId int64     `json:"application_id"`
+	Name          string    `json:"name"`
+	Type          string    `json:"type"`
+	Description   string    `json:"description,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// EffectiveEntity право, которое сотрудник получает через свои роли
+type EffectiveEntity struct {
+	ApplicationId   int64         `db:"application_id"`
+	ApplicationName string        `db:"application_name"`
+	EntitlementId   int64         `db:"entitlement_id"`
+	EntitlementName string        `db:"entitlement_name"`
+	Type            string        `db:"type"`
+	RoleIds         pq.Int64Array `db:"role_ids"`
+}
+
+type EffectiveResponse struct {
+	ApplicationId   int64   `json:"application_id"`
+	ApplicationName string  `json:"application_name"`
+	EntitlementId   int64   `json:"entitlement_id"`
+	EntitlementName string  `json:"entitlement_name"`
+	Type            string  `json:"type"`
+	RoleIds         []int64 `json:"role_ids"`
+}
+
+type CreateRequest struct {
+	Name        string `json:"name" validate:"required,min=2,max=155"`
+	Description string `json:"description" validate:"max=500"`
+	Owner       string `json:"owner" validate:"max=255"`
+}
+
+type EntitlementRequest struct {
+	ApplicationId int64  `json:"-" validate:"required,min=1"`
+	Name          string `json:"name" validate:"required,min=1,max=255"`
+	Type          string `json:"type" validate:"required,oneof=group permission"`
+	Description   string `json:"description" validate:"max=500"`
+}
+
+// BundleRequest включить права приложений в роль
+type BundleRequest struct {
+	RoleId         int64   `json:"-" validate:"required,min=1"`
+	EntitlementIds []int64 `json:"entitlement_ids" validate:"required,min=1,dive,min=1"`
+	CreatedBy      string  `json:"-" validate:"required"`
+}
+
+type UnbundleRequest struct {
+	RoleId        int64 `validate:"required,min=1"`
+	EntitlementId int64 `validate:"required,min=1"`
+}
+
+type IdRequest struct {
+	Id int64 `json:"id" validate:"required,min=1"`
+}
+
+func (e *Entity) toResponse() Response {
+	return Response{
+		Id:          e.Id,
+		Name:        e.Name,
+		Description: e.Description,
+		Owner:       e.Owner,
+		CreatedAt:   e.CreatedAt,
+		UpdatedAt:   e.UpdatedAt,
+	}
+}
+
+func (e *EntitlementEntity) toResponse() EntitlementResponse {
+	return EntitlementResponse{
+		Id:            e.Id,
+		ApplicationId: e.ApplicationId,
+		Name:          e.Name,
+		Type:          e.Type,
+		Description:   e.Description,
+		CreatedAt:     e.CreatedAt,
+	}
+}
+
+func (e *EffectiveEntity) toResponse() EffectiveResponse {
+	return EffectiveResponse{
+		ApplicationId:   e.ApplicationId,
+		ApplicationName: e.ApplicationName,
+		EntitlementId:   e.EntitlementId,
+		EntitlementName: e.EntitlementName,
+		Type:            e.Type,
+		RoleIds:         e.RoleIds,
+	}
+}
+
+func (req *CreateRequest) ToEntity() Entity {
+	return Entity{
+		Name:        req.Name,
+		Description: req.Description,
+		Owner:       req.Owner,
+	}
+}
+
+func (req *EntitlementRequest) ToEntity() EntitlementEntity {
+	return EntitlementEntity{
+		ApplicationId: req.ApplicationId,
+		Name:          req.Name,
+		Type:          req.Type,
+		Description:   req.Description,
+	}
+}
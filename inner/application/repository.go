@@ -0,0 +1,141 @@
+package application
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{
+		db: database,
+	}
+}
+
+func (r *Repository) BeginTransaction() (*sqlx.Tx, error) {
+	return r.db.Beginx()
+}
+
+func (r *Repository) Save(tx *sqlx.Tx, e Entity) (int64, error) {
+	var id int64
+	err := tx.QueryRow(
+		"INSERT INTO application (name, description, owner) VALUES ($1, $2, $3) RETURNING id",
+		e.Name, e.Description, e.Owner).Scan(&id)
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
+func (r *Repository) ExistsByName(tx *sqlx.Tx, name string) (isExist bool, err error) {
+	err = tx.Get(&isExist, "SELECT EXISTS(SELECT 1 FROM application WHERE name = $1)", name)
+	return isExist, err
+}
+
+func (r *Repository) ExistsById(tx *sqlx.Tx, id int64) (isExist bool, err error) {
+	err = tx.Get(&isExist, "SELECT EXISTS(SELECT 1 FROM application WHERE id = $1)", id)
+	return isExist, err
+}
+
+func (r *Repository) FindAll() ([]Entity, error) {
+	var applications []Entity
+	err := r.db.Select(&applications, "SELECT * FROM application ORDER BY name")
+	return applications, err
+}
+
+func (r *Repository) SaveEntitlement(tx *sqlx.Tx, e EntitlementEntity) (int64, error) {
+	var id int64
+	err := tx.QueryRow(
+		"INSERT INTO entitlement (application_id, name, type, description) VALUES ($1, $2, $3, $4) RETURNING id",
+		e.ApplicationId, e.Name, e.Type, e.Description).Scan(&id)
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
+func (r *Repository) ExistsEntitlement(tx *sqlx.Tx, applicationId int64, name string) (isExist bool, err error) {
+	err = tx.Get(
+		&isExist,
+		"SELECT EXISTS(SELECT 1 FROM entitlement WHERE application_id = $1 AND name = $2)",
+		applicationId, name,
+	)
+	return isExist, err
+}
+
+func (r *Repository) FindEntitlements(applicationId int64) ([]EntitlementEntity, error) {
+	var entitlements []EntitlementEntity
+	err := r.db.Select(
+		&entitlements,
+		"SELECT * FROM entitlement WHERE application_id = $1 ORDER BY name",
+		applicationId,
+	)
+	return entitlements, err
+}
+
+func (r *Repository) RoleExists(tx *sqlx.Tx, roleId int64) (isExist bool, err error) {
+	err = tx.Get(&isExist, "SELECT EXISTS(SELECT 1 FROM role WHERE id = $1)", roleId)
+	return isExist, err
+}
+
+// CountEntitlements сколько из переданных прав существует
+func (r *Repository) CountEntitlements(tx *sqlx.Tx, ids []int64) (count int64, err error) {
+	err = tx.Get(&count, "SELECT COUNT(*) FROM entitlement WHERE id = ANY($1)", pq.Int64Array(ids))
+	return count, err
+}
+
+// Bundle включить права в роль; уже включённые права пропускаются
+func (r *Repository) Bundle(tx *sqlx.Tx, roleId int64, entitlementIds []int64, createdBy string) error {
+	_, err := tx.Exec(
+		"INSERT INTO role_entitlement (role_id, entitlement_id, created_by) "+
+			"SELECT $1, UNNEST($2::BIGINT[]), $3 "+
+			"ON CONFLICT (role_id, entitlement_id) DO NOTHING",
+		roleId, pq.Int64Array(entitlementIds), createdBy)
+	return err
+}
+
+// Unbundle исключить право из роли; вернуть false, если право не было включено
+func (r *Repository) Unbundle(roleId int64, entitlementId int64) (bool, error) {
+	result, err := r.db.Exec(
+		"DELETE FROM role_entitlement WHERE role_id = $1 AND entitlement_id = $2",
+		roleId, entitlementId)
+	if err != nil {
+		return false, err
+	}
+	deleted, err := result.RowsAffected()
+	return deleted > 0, err
+}
+
+func (r *Repository) FindByRole(roleId int64) ([]EntitlementEntity, error) {
+	var entitlements []EntitlementEntity
+	err := r.db.Select(
+		&entitlements,
+		"SELECT en.* FROM entitlement en JOIN role_entitlement re ON re.entitlement_id = en.id "+
+			"WHERE re.role_id = $1 ORDER BY en.application_id, en.name",
+		roleId,
+	)
+	return entitlements, err
+}
+
+// FindEffective права приложений, которые сотрудник получает через все свои назначения ролей
+func (r *Repository) FindEffective(employeeId int64) ([]EffectiveEntity, error) {
+	var entitlements []EffectiveEntity
+	err := r.db.Select(
+		&entitlements,
+		"SELECT a.id AS application_id, a.name AS application_name, "+
+			"en.id AS entitlement_id, en.name AS entitlement_name, en.type, "+
+			"ARRAY_AGG(DISTINCT re.role_id ORDER BY re.role_id) AS role_ids "+
+			"FROM employee_role er "+
+			"JOIN role_entitlement re ON re.role_id = er.role_id "+
+			"JOIN entitlement en ON en.id = re.entitlement_id "+
+			"JOIN application a ON a.id = en.application_id "+
+			"WHERE er.employee_id = $1 "+
+			"GROUP BY a.id, a.name, en.id, en.name, en.type "+
+			"ORDER BY a.name, en.name",
+		employeeId,
+	)
+	return entitlements, err
+}
@@ -0,0 +1,206 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"idm/inner/common"
+	"idm/inner/database"
+	"slices"
+)
+
+type Service struct {
+	repo      Repo
+	validator Validator
+}
+
+type Repo interface {
+	BeginTransaction() (*sqlx.Tx, error)
+	Save(tx *sqlx.Tx, e Entity) (int64, error)
+	ExistsByName(tx *sqlx.Tx, name string) (bool, error)
+	ExistsById(tx *sqlx.Tx, id int64) (bool, error)
+	FindAll() ([]Entity, error)
+	SaveEntitlement(tx *sqlx.Tx, e EntitlementEntity) (int64, error)
+	ExistsEntitlement(tx *sqlx.Tx, applicationId int64, name string) (bool, error)
+	FindEntitlements(applicationId int64) ([]EntitlementEntity, error)
+	RoleExists(tx *sqlx.Tx, roleId int64) (bool, error)
+	CountEntitlements(tx *sqlx.Tx, ids []int64) (int64, error)
+	Bundle(tx *sqlx.Tx, roleId int64, entitlementIds []int64, createdBy string) error
+	Unbundle(roleId int64, entitlementId int64) (bool, error)
+	FindByRole(roleId int64) ([]EntitlementEntity, error)
+	FindEffective(employeeId int64) ([]EffectiveEntity, error)
+}
+
+type Validator interface {
+	Validate(request any) error
+}
+
+func NewService(repo Repo, validator Validator) *Service {
+	return &Service{
+		repo:      repo,
+		validator: validator,
+	}
+}
+
+func (s *Service) Create(ctx context.Context, request CreateRequest) (Response, error) {
+	err := s.validator.Validate(request)
+	if err != nil {
+		return Response{}, common.RequestValidationError{Message: err.Error()}
+	}
+	var id int64
+	err = database.InTransaction(s.repo.BeginTransaction, "creating application", func(tx *sqlx.Tx) error {
+		isExist, err := s.repo.ExistsByName(tx, request.Name)
+		if err != nil {
+			return fmt.Errorf("error finding application by name: %s, %w", request.Name, err)
+		}
+		if isExist {
+			return common.AlreadyExistsError{Message: fmt.Sprintf("application with name %s already exists", request.Name)}
+		}
+		id, err = s.repo.Save(tx, request.ToEntity())
+		if err != nil {
+			return fmt.Errorf("error saving application: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return Response{}, err
+	}
+	return Response{Id: id}, nil
+}
+
+func (s *Service) FindAll() ([]Response, error) {
+	applications, err := s.repo.FindAll()
+	if err != nil {
+		return nil, common.NotFoundError{Message: fmt.Sprintf("error finding applications: %v", err)}
+	}
+	var response []Response
+	for _, entity := range applications {
+		response = append(response, entity.toResponse())
+	}
+	return response, nil
+}
+
+func (s *Service) CreateEntitlement(ctx context.Context, request EntitlementRequest) (EntitlementResponse, error) {
+	err := s.validator.Validate(request)
+	if err != nil {
+		return EntitlementResponse{}, common.RequestValidationError{Message: err.Error()}
+	}
+	var id int64
+	err = database.InTransaction(s.repo.BeginTransaction, "creating entitlement", func(tx *sqlx.Tx) error {
+		isExist, err := s.repo.ExistsById(tx, request.ApplicationId)
+		if err != nil {
+			return fmt.Errorf("error finding application %d: %w", request.ApplicationId, err)
+		}
+		if !isExist {
+			return common.NotFoundError{Message: fmt.Sprintf("application with id %d not found", request.ApplicationId)}
+		}
+		isExist, err = s.repo.ExistsEntitlement(tx, request.ApplicationId, request.Name)
+		if err != nil {
+			return fmt.Errorf("error finding entitlement by name: %s, %w", request.Name, err)
+		}
+		if isExist {
+			return common.AlreadyExistsError{Message: fmt.Sprintf(
+				"entitlement %s already exists in application %d", request.Name, request.ApplicationId)}
+		}
+		id, err = s.repo.SaveEntitlement(tx, request.ToEntity())
+		if err != nil {
+			return fmt.Errorf("error saving entitlement: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return EntitlementResponse{}, err
+	}
+	return EntitlementResponse{Id: id}, nil
+}
+
+func (s *Service) FindEntitlements(request IdRequest) ([]EntitlementResponse, error) {
+	if err := s.validator.Validate(request); err != nil {
+		return nil, common.RequestValidationError{Message: err.Error()}
+	}
+	entitlements, err := s.repo.FindEntitlements(request.Id)
+	if err != nil {
+		return nil, common.NotFoundError{Message: fmt.Sprintf("error finding entitlements of application %d: %v", request.Id, err)}
+	}
+	return toEntitlementResponses(entitlements), nil
+}
+
+// Bundle включить права приложений в роль; все права и роль должны существовать
+func (s *Service) Bundle(ctx context.Context, request BundleRequest) error {
+	if err := s.validator.Validate(request); err != nil {
+		return common.RequestValidationError{Message: err.Error()}
+	}
+	var ids = slices.Clone(request.EntitlementIds)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	return database.InTransaction(s.repo.BeginTransaction, "bundling entitlements", func(tx *sqlx.Tx) error {
+		isExist, err := s.repo.RoleExists(tx, request.RoleId)
+		if err != nil {
+			return fmt.Errorf("error finding role %d: %w", request.RoleId, err)
+		}
+		if !isExist {
+			return common.NotFoundError{Message: fmt.Sprintf("role with id %d not found", request.RoleId)}
+		}
+		count, err := s.repo.CountEntitlements(tx, ids)
+		if err != nil {
+			return fmt.Errorf("error finding entitlements: %w", err)
+		}
+		if count != int64(len(ids)) {
+			return common.NotFoundError{Message: fmt.Sprintf("entitlements not found: %v", ids)}
+		}
+		if err = s.repo.Bundle(tx, request.RoleId, ids, request.CreatedBy); err != nil {
+			return fmt.Errorf("error bundling entitlements into role %d: %w", request.RoleId, err)
+		}
+		return nil
+	})
+}
+
+func (s *Service) Unbundle(request UnbundleRequest) error {
+	if err := s.validator.Validate(request); err != nil {
+		return common.RequestValidationError{Message: err.Error()}
+	}
+	deleted, err := s.repo.Unbundle(request.RoleId, request.EntitlementId)
+	if err != nil {
+		return fmt.Errorf("error removing entitlement %d from role %d: %w", request.EntitlementId, request.RoleId, err)
+	}
+	if !deleted {
+		return common.NotFoundError{Message: fmt.Sprintf(
+			"entitlement %d is not bundled into role %d", request.EntitlementId, request.RoleId)}
+	}
+	return nil
+}
+
+func (s *Service) FindByRole(request IdRequest) ([]EntitlementResponse, error) {
+	if err := s.validator.Validate(request); err != nil {
+		return nil, common.RequestValidationError{Message: err.Error()}
+	}
+	entitlements, err := s.repo.FindByRole(request.Id)
+	if err != nil {
+		return nil, common.NotFoundError{Message: fmt.Sprintf("error finding entitlements of role %d: %v", request.Id, err)}
+	}
+	return toEntitlementResponses(entitlements), nil
+}
+
+// FindEffective права приложений, которые сотрудник получает через свои роли
+func (s *Service) FindEffective(request IdRequest) ([]EffectiveResponse, error) {
+	if err := s.validator.Validate(request); err != nil {
+		return nil, common.RequestValidationError{Message: err.Error()}
+	}
+	entitlements, err := s.repo.FindEffective(request.Id)
+	if err != nil {
+		return nil, common.NotFoundError{Message: fmt.Sprintf("error finding entitlements of employee %d: %v", request.Id, err)}
+	}
+	var response []EffectiveResponse
+	for _, entity := range entitlements {
+		response = append(response, entity.toResponse())
+	}
+	return response, nil
+}
+
+func toEntitlementResponses(entitlements []EntitlementEntity) []EntitlementResponse {
+	var response []EntitlementResponse
+	for _, entity := range entitlements {
+		response = append(response, entity.toResponse())
+	}
+	return response
+}
@@ -0,0 +1,218 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"idm/inner/common"
+	"idm/inner/database/dbtest"
+	"idm/inner/validator"
+	"testing"
+)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+func (r *MockRepo) BeginTransaction() (*sqlx.Tx, error) {
+	args := r.Called()
+	return args.Get(0).(*sqlx.Tx), args.Error(1)
+}
+
+func (r *MockRepo) Save(tx *sqlx.Tx, e Entity) (int64, error) {
+	args := r.Called(tx, e)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (r *MockRepo) ExistsByName(tx *sqlx.Tx, name string) (bool, error) {
+	args := r.Called(tx, name)
+	return args.Bool(0), args.Error(1)
+}
+
+func (r *MockRepo) ExistsById(tx *sqlx.Tx, id int64) (bool, error) {
+	args := r.Called(tx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (r *MockRepo) FindAll() ([]Entity, error) {
+	args := r.Called()
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (r *MockRepo) SaveEntitlement(tx *sqlx.Tx, e EntitlementEntity) (int64, error) {
+	args := r.Called(tx, e)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (r *MockRepo) ExistsEntitlement(tx *sqlx.Tx, applicationId int64, name string) (bool, error) {
+	args := r.Called(tx, applicationId, name)
+	return args.Bool(0), args.Error(1)
+}
+
+func (r *MockRepo) FindEntitlements(applicationId int64) ([]EntitlementEntity, error) {
+	args := r.Called(applicationId)
+	return args.Get(0).([]EntitlementEntity), args.Error(1)
+}
+
+func (r *MockRepo) RoleExists(tx *sqlx.Tx, roleId int64) (bool, error) {
+	args := r.Called(tx, roleId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (r *MockRepo) CountEntitlements(tx *sqlx.Tx, ids []int64) (int64, error) {
+	args := r.Called(tx, ids)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (r *MockRepo) Bundle(tx *sqlx.Tx, roleId int64, entitlementIds []int64, createdBy string) error {
+	args := r.Called(tx, roleId, entitlementIds, createdBy)
+	return args.Error(0)
+}
+
+func (r *MockRepo) Unbundle(roleId int64, entitlementId int64) (bool, error) {
+	args := r.Called(roleId, entitlementId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (r *MockRepo) FindByRole(roleId int64) ([]EntitlementEntity, error) {
+	args := r.Called(roleId)
+	return args.Get(0).([]EntitlementEntity), args.Error(1)
+}
+
+func (r *MockRepo) FindEffective(employeeId int64) ([]EffectiveEntity, error) {
+	args := r.Called(employeeId)
+	return args.Get(0).([]EffectiveEntity), args.Error(1)
+}
+
+func TestCreate(t *testing.T) {
+	var request = CreateRequest{Name: "GitLab", Owner: "dev-platform"}
+	t.Run("should save application", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, true)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("ExistsByName", tx, "GitLab").Return(false, nil)
+		repo.On("Save", tx, request.ToEntity()).Return(int64(3), nil)
+		got, err := svc.Create(context.Background(), request)
+		a.Nil(err)
+		a.Equal(Response{Id: 3}, got)
+	})
+	t.Run("should return already exists error", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, false)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("ExistsByName", tx, "GitLab").Return(true, nil)
+		_, err := svc.Create(context.Background(), request)
+		a.True(errors.As(err, &common.AlreadyExistsError{}))
+		a.True(repo.AssertNumberOfCalls(t, "Save", 0))
+	})
+}
+
+func TestCreateEntitlement(t *testing.T) {
+	var request = EntitlementRequest{ApplicationId: 3, Name: "push", Type: EntitlementPermission}
+	t.Run("should save entitlement", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, true)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("ExistsById", tx, int64(3)).Return(true, nil)
+		repo.On("ExistsEntitlement", tx, int64(3), "push").Return(false, nil)
+		repo.On("SaveEntitlement", tx, request.ToEntity()).Return(int64(7), nil)
+		got, err := svc.CreateEntitlement(context.Background(), request)
+		a.Nil(err)
+		a.Equal(int64(7), got.Id)
+	})
+	t.Run("should return not found error for unknown application", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, false)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("ExistsById", tx, int64(3)).Return(false, nil)
+		_, err := svc.CreateEntitlement(context.Background(), request)
+		a.True(errors.As(err, &common.NotFoundError{}))
+		a.True(repo.AssertNumberOfCalls(t, "SaveEntitlement", 0))
+	})
+	t.Run("should reject unknown entitlement type", func(t *testing.T) {
+		a := assert.New(t)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		_, err := svc.CreateEntitlement(context.Background(), EntitlementRequest{ApplicationId: 3, Name: "push", Type: "role"})
+		a.True(errors.As(err, &common.RequestValidationError{}))
+	})
+}
+
+func TestBundle(t *testing.T) {
+	t.Run("should bundle distinct entitlements into role", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, true)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("RoleExists", tx, int64(2)).Return(true, nil)
+		repo.On("CountEntitlements", tx, []int64{4, 7}).Return(int64(2), nil)
+		repo.On("Bundle", tx, int64(2), []int64{4, 7}, "admin").Return(nil)
+		err := svc.Bundle(context.Background(), BundleRequest{RoleId: 2, EntitlementIds: []int64{7, 4, 7}, CreatedBy: "admin"})
+		a.Nil(err)
+	})
+	t.Run("should return not found error for unknown entitlement", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, false)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("RoleExists", tx, int64(2)).Return(true, nil)
+		repo.On("CountEntitlements", tx, []int64{4, 7}).Return(int64(1), nil)
+		err := svc.Bundle(context.Background(), BundleRequest{RoleId: 2, EntitlementIds: []int64{4, 7}, CreatedBy: "admin"})
+		a.True(errors.As(err, &common.NotFoundError{}))
+		a.True(repo.AssertNumberOfCalls(t, "Bundle", 0))
+	})
+	t.Run("should require entitlements", func(t *testing.T) {
+		a := assert.New(t)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		err := svc.Bundle(context.Background(), BundleRequest{RoleId: 2, CreatedBy: "admin"})
+		a.True(errors.As(err, &common.RequestValidationError{}))
+	})
+}
+
+func TestUnbundle(t *testing.T) {
+	var a = assert.New(t)
+	var repo = new(MockRepo)
+	var svc = NewService(repo, validator.New())
+	repo.On("Unbundle", int64(2), int64(4)).Return(true, nil)
+	repo.On("Unbundle", int64(2), int64(5)).Return(false, nil)
+	a.Nil(svc.Unbundle(UnbundleRequest{RoleId: 2, EntitlementId: 4}))
+	err := svc.Unbundle(UnbundleRequest{RoleId: 2, EntitlementId: 5})
+	a.True(errors.As(err, &common.NotFoundError{}))
+}
+
+func TestFindEffective(t *testing.T) {
+	var a = assert.New(t)
+	var repo = new(MockRepo)
+	var svc = NewService(repo, validator.New())
+	repo.On("FindEffective", int64(9)).Return([]EffectiveEntity{{
+		ApplicationId:   3,
+		ApplicationName: "GitLab",
+		EntitlementId:   4,
+		EntitlementName: "push",
+		Type:            EntitlementPermission,
+		RoleIds:         []int64{2, 5},
+	}}, nil)
+	got, err := svc.FindEffective(IdRequest{Id: 9})
+	a.Nil(err)
+	a.Equal([]EffectiveResponse{{
+		ApplicationId:   3,
+		ApplicationName: "GitLab",
+		EntitlementId:   4,
+		EntitlementName: "push",
+		Type:            EntitlementPermission,
+		RoleIds:         []int64{2, 5},
+	}}, got)
+}
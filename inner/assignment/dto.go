@@ -0,0 +1,41 @@
+package assignment
+
+import "time"
+
+// источники, из которых сотрудник получил роль
+const (
+	SourcePrimary    = "primary"
+	SourceRequest    = "request"
+	SourceBreakGlass = "break-glass"
+	SourceBirthright = "birthright"
+	SourceDirect     = "direct"
+)
+
+type Entity struct {
+	Id         int64     `db:"id"`
+	EmployeeId int64     `db:"employee_id"`
+	RoleId     int64     `db:"role_id"`
+	Source     string    `db:"source"`
+	CreatedBy  string    `db:"created_by"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+type Response struct {
+	Id         int64     `json:"id"`
+	EmployeeId int64     `json:"employee_id"`
+	RoleId     int64     `json:"role_id"`
+	Source     string    `json:"source"`
+	CreatedBy  string    `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (e *Entity) ToResponse() Response {
+	return Response{
+		Id:         e.Id,
+		EmployeeId: e.EmployeeId,
+		RoleId:     e.RoleId,
+		Source:     e.Source,
+		CreatedBy:  e.CreatedBy,
+		CreatedAt:  e.CreatedAt,
+	}
+}
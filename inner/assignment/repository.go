@@ -0,0 +1,50 @@
+package assignment
+
+import (
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{
+		db: database,
+	}
+}
+
+// Save выдать роль сотруднику; повторная выдача из того же источника игнорируется
+func (r *Repository) Save(tx *sqlx.Tx, e Entity) error {
+	_, err := tx.Exec(
+		"INSERT INTO employee_role (employee_id, role_id, source, created_by) VALUES ($1, $2, $3, $4) "+
+			"ON CONFLICT (employee_id, role_id, source) DO NOTHING",
+		e.EmployeeId, e.RoleId, e.Source, e.CreatedBy)
+	return err
+}
+
+func (r *Repository) FindByEmployeeId(employeeId int64) ([]Entity, error) {
+	var assignments []Entity
+	err := r.db.Select(&assignments, "SELECT * FROM employee_role WHERE employee_id = $1 ORDER BY id", employeeId)
+	return assignments, err
+}
+
+func (r *Repository) Delete(tx *sqlx.Tx, employeeId int64, roleId int64, source string) error {
+	_, err := tx.Exec(
+		"DELETE FROM employee_role WHERE employee_id = $1 AND role_id = $2 AND source = $3",
+		employeeId, roleId, source)
+	return err
+}
+
+// IsPrimary является ли роль основной ролью сотрудника (employee.role_id)
+func (r *Repository) IsPrimary(tx *sqlx.Tx, employeeId int64, roleId int64) (primary bool, err error) {
+	err = tx.Get(&primary, "SELECT EXISTS(SELECT 1 FROM employee WHERE id = $1 AND role_id = $2)", employeeId, roleId)
+	return primary, err
+}
+
+// Revoke отозвать роль у сотрудника, из какого бы источника она ни была выдана.
+// Основную роль так отзывать нельзя: employee.role_id ссылался бы на отозванную роль, это проверяет IsPrimary
+func (r *Repository) Revoke(tx *sqlx.Tx, employeeId int64, roleId int64) error {
+	_, err := tx.Exec("DELETE FROM employee_role WHERE employee_id = $1 AND role_id = $2", employeeId, roleId)
+	return err
+}
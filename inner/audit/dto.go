@@ -0,0 +1,19 @@
+package audit
+
+import "time"
+
+// уровни важности событий аудита
+const (
+	SeverityInfo = "info"
+	SeverityHigh = "high"
+)
+
+type Entity struct {
+	Id        int64     `db:"id"`
+	Severity  string    `db:"severity"`
+	Action    string    `db:"action"`
+	Actor     string    `db:"actor"`
+	Object    string    `db:"object"`
+	Details   string    `db:"details"`
+	CreatedAt time.Time `db:"created_at"`
+}
@@ -0,0 +1,23 @@
+package audit
+
+import (
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{
+		db: database,
+	}
+}
+
+// Save записать событие аудита в транзакции изменения, которое оно описывает
+func (r *Repository) Save(tx *sqlx.Tx, e Entity) error {
+	_, err := tx.Exec(
+		"INSERT INTO audit_event (severity, action, actor, object, details) VALUES ($1, $2, $3, $4, $5)",
+		e.Severity, e.Action, e.Actor, e.Object, e.Details)
+	return err
+}
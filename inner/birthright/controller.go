@@ -0,0 +1,157 @@
+package birthright
+
+import (
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"idm/inner/common"
+	"idm/inner/middleware"
+	"idm/inner/web"
+	"slices"
+	"strconv"
+)
+
+type Controller struct {
+	server            *web.Server
+	birthrightService Svc
+}
+
+type Svc interface {
+	Create(ctx context.Context, request CreateRequest) (Response, error)
+	FindAll() ([]Response, error)
+	DeleteById(ctx context.Context, request IdRequest) error
+}
+
+func NewController(
+	server *web.Server,
+	birthrightService Svc,
+) *Controller {
+	return &Controller{
+		server:            server,
+		birthrightService: birthrightService,
+	}
+}
+
+func (c *Controller) RegisterRoutes() {
+	c.server.GroupApiV1.Post("/birthright-rules", c.CreateRule)
+	c.server.GroupApiV1.Get("/birthright-rules", c.FindAll)
+	c.server.GroupApiV1.Delete("/birthright-rules/:id", c.DeleteById)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/birthright-rules"
+// @Summary create birthright rule
+// @Description Assign a role automatically to employees of a department, or to everyone if department is empty, with roles: admin
+// @Tags birthright
+// @Security OAuth2Password
+// @Accept json
+// @Produce json
+// @Param request body birthright.CreateRequest true "create birthright rule request"
+// @Success 200 {object} common.Response[int64]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /birthright-rules [post]
+func (c *Controller) CreateRule(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	var request CreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		logger.ErrorCtx(ctx.Context(), "body parse error: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	request.CreatedBy = claims.Subject
+	logger.InfoCtx(ctx.Context(), "create birthright rule: received request", zap.Any("request", request))
+	var response, err = c.birthrightService.Create(ctx.Context(), request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}) || errors.As(err, &common.AlreadyExistsError{}):
+			logger.ErrorCtx(ctx.Context(), "error creating birthright rule: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "error creating birthright rule: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response.Id)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/birthright-rules"
+// @Summary Get birthright rules
+// @Description returns all birthright rules, with roles: admin
+// @Tags birthright
+// @Security OAuth2Password
+// @Produce json
+// @Success 200 {object} common.Response[[]birthright.Response]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /birthright-rules [get]
+func (c *Controller) FindAll(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	logger.InfoCtx(ctx.Context(), "find birthright rules: received request")
+	response, err := c.birthrightService.FindAll()
+	if err != nil {
+		switch {
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), "find birthright rules: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "find birthright rules: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// Функция-хендлер, которая будет вызываться при DELETE запросе по маршруту "/api/v1/birthright-rules/:id"
+// @Summary Delete birthright rule
+// @Description Deletes a rule and revokes roles no longer granted by any rule, with roles: admin
+// @Tags birthright
+// @Security OAuth2Password
+// @Produce json
+// @Param id path int true "Rule ID"
+// @Success 200 {object} common.Response[int64]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /birthright-rules/{id} [delete]
+func (c *Controller) DeleteById(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error parsing id: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request = IdRequest{Id: id}
+	logger.InfoCtx(ctx.Context(), "delete birthright rule: received request", zap.Any("request", request))
+	err = c.birthrightService.DeleteById(ctx.Context(), request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			logger.ErrorCtx(ctx.Context(), "delete birthright rule: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), "delete birthright rule: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "delete birthright rule: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, id)
+}
@@ -0,0 +1,90 @@
+package birthright
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"idm/inner/common"
+	"idm/inner/web"
+	"idm/inner/web/webtest"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) Create(ctx context.Context, request CreateRequest) (Response, error) {
+	args := svc.Called(ctx, request)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) FindAll() ([]Response, error) {
+	args := svc.Called()
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (svc *MockService) DeleteById(ctx context.Context, request IdRequest) error {
+	args := svc.Called(ctx, request)
+	return args.Error(0)
+}
+
+func newServer(svc Svc, subject string, roles ...string) *web.Server {
+	server := webtest.NewServer(subject, roles...)
+	NewController(server, svc).RegisterRoutes()
+	return server
+}
+
+func TestCreateRule(t *testing.T) {
+	var a = assert.New(t)
+	var body = "{\"department\": \"Engineering\", \"role_id\": 2}"
+	t.Run("create rule", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "admin-1", web.IdmAdmin)
+		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/birthright-rules", strings.NewReader(body))
+		request.Header.Add("Content-Type", "application/json")
+		svc.On("Create", mock.Anything, CreateRequest{Department: "Engineering", RoleId: 2, CreatedBy: "admin-1"}).
+			Return(Response{Id: 1}, nil)
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+	})
+	t.Run("duplicate rule", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "admin-1", web.IdmAdmin)
+		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/birthright-rules", strings.NewReader(body))
+		request.Header.Add("Content-Type", "application/json")
+		svc.On("Create", mock.Anything, mock.Anything).
+			Return(Response{}, common.AlreadyExistsError{Message: "birthright rule already exists"})
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusBadRequest, resp.StatusCode)
+	})
+	t.Run("create rule without role admin", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "user-1", web.IdmUser)
+		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/birthright-rules", strings.NewReader(body))
+		request.Header.Add("Content-Type", "application/json")
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		a.True(svc.AssertNumberOfCalls(t, "Create", 0))
+	})
+}
+
+func TestDeleteRule(t *testing.T) {
+	var a = assert.New(t)
+	var svc = new(MockService)
+	server := newServer(svc, "admin-1", web.IdmAdmin)
+	svc.On("DeleteById", mock.Anything, IdRequest{Id: 4}).Return(nil)
+	resp, err := server.App.Test(httptest.NewRequest(fiber.MethodDelete, "/api/v1/birthright-rules/4", nil))
+	a.Nil(err)
+	a.Equal(http.StatusOK, resp.StatusCode)
+	resp, err = server.App.Test(httptest.NewRequest(fiber.MethodDelete, "/api/v1/birthright-rules/abc", nil))
+	a.Nil(err)
+	a.Equal(http.StatusBadRequest, resp.StatusCode)
+}
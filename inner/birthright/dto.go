@@ -0,0 +1,48 @@
+package birthright
+
+import "time"
+
+// Entity правило автоматической выдачи роли; пустое подразделение - роль выдаётся всем сотрудникам
+type Entity struct {
+	Id         int64     `db:"id"`
+	Department string    `db:"department"`
+	RoleId     int64     `db:"role_id"`
+	CreatedBy  string    `db:"created_by"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+type Response struct {
+	Id         int64     `json:"id"`
+	Department string    `json:"department"`
+	RoleId     int64     `json:"role_id"`
+	CreatedBy  string    `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type CreateRequest struct {
+	Department string `json:"department" validate:"max=155"`
+	RoleId     int64  `json:"role_id" validate:"required,min=1"`
+	CreatedBy  string `json:"-" validate:"required"`
+}
+
+type IdRequest struct {
+	Id int64 `json:"id" validate:"required,min=1"`
+}
+
+func (e *Entity) toResponse() Response {
+	return Response{
+		Id:         e.Id,
+		Department: e.Department,
+		RoleId:     e.RoleId,
+		CreatedBy:  e.CreatedBy,
+		CreatedAt:  e.CreatedAt,
+	}
+}
+
+func (req *CreateRequest) ToEntity() Entity {
+	return Entity{
+		Department: req.Department,
+		RoleId:     req.RoleId,
+		CreatedBy:  req.CreatedBy,
+	}
+}
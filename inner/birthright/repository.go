@@ -0,0 +1,80 @@
+package birthright
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"idm/inner/assignment"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{
+		db: database,
+	}
+}
+
+func (r *Repository) BeginTransaction() (*sqlx.Tx, error) {
+	return r.db.Beginx()
+}
+
+func (r *Repository) Save(tx *sqlx.Tx, e Entity) (int64, error) {
+	var id int64
+	err := tx.QueryRow(
+		"INSERT INTO birthright_rule (department, role_id, created_by) VALUES ($1, $2, $3) RETURNING id",
+		e.Department, e.RoleId, e.CreatedBy).Scan(&id)
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
+func (r *Repository) Exists(tx *sqlx.Tx, department string, roleId int64) (isExist bool, err error) {
+	err = tx.Get(
+		&isExist,
+		"SELECT EXISTS(SELECT 1 FROM birthright_rule WHERE department = $1 AND role_id = $2)",
+		department, roleId,
+	)
+	return isExist, err
+}
+
+func (r *Repository) FindAll() ([]Entity, error) {
+	var rules []Entity
+	err := r.db.Select(&rules, "SELECT * FROM birthright_rule ORDER BY id")
+	return rules, err
+}
+
+// DeleteById удалить правило; вернуть false, если правило не найдено
+func (r *Repository) DeleteById(tx *sqlx.Tx, id int64) (bool, error) {
+	result, err := tx.Exec("DELETE FROM birthright_rule WHERE id = $1", id)
+	if err != nil {
+		return false, err
+	}
+	deleted, err := result.RowsAffected()
+	return deleted > 0, err
+}
+
+// Apply привести роли, выданные правилами, в соответствие с текущими правилами и атрибутами сотрудников.
+// Пустой employeeIds - пересчитать всех сотрудников. Назначения из других источников не затрагиваются
+func (r *Repository) Apply(tx *sqlx.Tx, employeeIds []int64) error {
+	var ids = pq.Int64Array(employeeIds)
+	_, err := tx.Exec(
+		"DELETE FROM employee_role er USING employee e "+
+			"WHERE e.id = er.employee_id AND er.source = $1 AND ($2::BIGINT[] IS NULL OR er.employee_id = ANY($2)) "+
+			"AND NOT EXISTS (SELECT 1 FROM birthright_rule br "+
+			"WHERE br.role_id = er.role_id AND br.department IN ('', e.department))",
+		assignment.SourceBirthright, ids)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(
+		"INSERT INTO employee_role (employee_id, role_id, source, created_by) "+
+			"SELECT e.id, br.role_id, $1, 'rule:' || br.id "+
+			"FROM employee e JOIN birthright_rule br ON br.department IN ('', e.department) "+
+			"WHERE $2::BIGINT[] IS NULL OR e.id = ANY($2) "+
+			"ON CONFLICT (employee_id, role_id, source) DO NOTHING",
+		assignment.SourceBirthright, ids)
+	return err
+}
@@ -0,0 +1,97 @@
+package birthright
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"idm/inner/common"
+	"idm/inner/database"
+)
+
+type Service struct {
+	repo      Repo
+	validator Validator
+}
+
+type Repo interface {
+	BeginTransaction() (*sqlx.Tx, error)
+	Save(tx *sqlx.Tx, e Entity) (int64, error)
+	Exists(tx *sqlx.Tx, department string, roleId int64) (bool, error)
+	FindAll() ([]Entity, error)
+	DeleteById(tx *sqlx.Tx, id int64) (bool, error)
+	Apply(tx *sqlx.Tx, employeeIds []int64) error
+}
+
+type Validator interface {
+	Validate(request any) error
+}
+
+func NewService(repo Repo, validator Validator) *Service {
+	return &Service{
+		repo:      repo,
+		validator: validator,
+	}
+}
+
+// Create добавить правило и сразу выдать роль подходящим сотрудникам
+func (s *Service) Create(ctx context.Context, request CreateRequest) (Response, error) {
+	err := s.validator.Validate(request)
+	if err != nil {
+		return Response{}, common.RequestValidationError{Message: err.Error()}
+	}
+	var id int64
+	err = database.InTransaction(s.repo.BeginTransaction, "creating birthright rule", func(tx *sqlx.Tx) error {
+		isExist, err := s.repo.Exists(tx, request.Department, request.RoleId)
+		if err != nil {
+			return fmt.Errorf("error finding birthright rule: %w", err)
+		}
+		if isExist {
+			return common.AlreadyExistsError{Message: fmt.Sprintf(
+				"birthright rule already exists: department %q, role %d", request.Department, request.RoleId)}
+		}
+		id, err = s.repo.Save(tx, request.ToEntity())
+		if err != nil {
+			return fmt.Errorf("error saving birthright rule: %w", err)
+		}
+		if err = s.repo.Apply(tx, nil); err != nil {
+			return fmt.Errorf("error applying birthright rules: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return Response{}, err
+	}
+	return Response{Id: id}, nil
+}
+
+func (s *Service) FindAll() ([]Response, error) {
+	rules, err := s.repo.FindAll()
+	if err != nil {
+		return nil, common.NotFoundError{Message: fmt.Sprintf("error finding birthright rules: %v", err)}
+	}
+	var response []Response
+	for _, entity := range rules {
+		response = append(response, entity.toResponse())
+	}
+	return response, nil
+}
+
+// DeleteById удалить правило и отозвать роли, которые больше не выдаются ни одним правилом
+func (s *Service) DeleteById(ctx context.Context, request IdRequest) error {
+	if err := s.validator.Validate(request); err != nil {
+		return common.RequestValidationError{Message: err.Error()}
+	}
+	return database.InTransaction(s.repo.BeginTransaction, "deleting birthright rule", func(tx *sqlx.Tx) error {
+		deleted, err := s.repo.DeleteById(tx, request.Id)
+		if err != nil {
+			return fmt.Errorf("error deleting birthright rule %d: %w", request.Id, err)
+		}
+		if !deleted {
+			return common.NotFoundError{Message: fmt.Sprintf("birthright rule with id %d not found", request.Id)}
+		}
+		if err = s.repo.Apply(tx, nil); err != nil {
+			return fmt.Errorf("error applying birthright rules: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,107 @@
+package birthright
+
+import (
+	"context"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"idm/inner/common"
+	"idm/inner/database/dbtest"
+	"idm/inner/validator"
+	"testing"
+)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+func (r *MockRepo) BeginTransaction() (*sqlx.Tx, error) {
+	args := r.Called()
+	return args.Get(0).(*sqlx.Tx), args.Error(1)
+}
+
+func (r *MockRepo) Save(tx *sqlx.Tx, e Entity) (int64, error) {
+	args := r.Called(tx, e)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (r *MockRepo) Exists(tx *sqlx.Tx, department string, roleId int64) (bool, error) {
+	args := r.Called(tx, department, roleId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (r *MockRepo) FindAll() ([]Entity, error) {
+	args := r.Called()
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (r *MockRepo) DeleteById(tx *sqlx.Tx, id int64) (bool, error) {
+	args := r.Called(tx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (r *MockRepo) Apply(tx *sqlx.Tx, employeeIds []int64) error {
+	args := r.Called(tx, employeeIds)
+	return args.Error(0)
+}
+
+func TestCreate(t *testing.T) {
+	var request = CreateRequest{Department: "Engineering", RoleId: 2, CreatedBy: "admin"}
+	t.Run("should save rule and apply it to all employees", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, true)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("Exists", tx, "Engineering", int64(2)).Return(false, nil)
+		repo.On("Save", tx, request.ToEntity()).Return(int64(5), nil)
+		repo.On("Apply", tx, []int64(nil)).Return(nil)
+		got, err := svc.Create(context.Background(), request)
+		a.Nil(err)
+		a.Equal(Response{Id: 5}, got)
+		a.True(repo.AssertNumberOfCalls(t, "Apply", 1))
+	})
+	t.Run("should return already exists error for duplicate rule", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, false)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("Exists", tx, "Engineering", int64(2)).Return(true, nil)
+		_, err := svc.Create(context.Background(), request)
+		a.True(errors.As(err, &common.AlreadyExistsError{}))
+		a.True(repo.AssertNumberOfCalls(t, "Save", 0))
+	})
+	t.Run("should require role", func(t *testing.T) {
+		a := assert.New(t)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		_, err := svc.Create(context.Background(), CreateRequest{CreatedBy: "admin"})
+		a.True(errors.As(err, &common.RequestValidationError{}))
+	})
+}
+
+func TestDeleteById(t *testing.T) {
+	t.Run("should delete rule and revoke derived roles", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, true)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("DeleteById", tx, int64(5)).Return(true, nil)
+		repo.On("Apply", tx, []int64(nil)).Return(nil)
+		a.Nil(svc.DeleteById(context.Background(), IdRequest{Id: 5}))
+	})
+	t.Run("should return not found error", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, false)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("DeleteById", tx, int64(5)).Return(false, nil)
+		err := svc.DeleteById(context.Background(), IdRequest{Id: 5})
+		a.True(errors.As(err, &common.NotFoundError{}))
+		a.True(repo.AssertNumberOfCalls(t, "Apply", 0))
+	})
+}
@@ -0,0 +1,117 @@
+package breakglass
+
+import (
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"idm/inner/common"
+	"idm/inner/middleware"
+	"idm/inner/web"
+	"slices"
+)
+
+type Controller struct {
+	server            *web.Server
+	breakGlassService Svc
+}
+
+type Svc interface {
+	Activate(ctx context.Context, request ActivateRequest) (Response, error)
+	FindAll(request FindRequest) ([]Response, error)
+}
+
+func NewController(
+	server *web.Server,
+	breakGlassService Svc,
+) *Controller {
+	return &Controller{
+		server:            server,
+		breakGlassService: breakGlassService,
+	}
+}
+
+func (c *Controller) RegisterRoutes() {
+	c.server.GroupApiV1.Post("/break-glass", c.Activate)
+	c.server.GroupApiV1.Get("/break-glass", c.FindAll)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/break-glass"
+// @Summary break-glass access
+// @Description Grants the caller the preconfigured privileged role for a short fixed window, available to on-call roles
+// @Tags break-glass
+// @Security OAuth2Password
+// @Accept json
+// @Produce json
+// @Param request body breakglass.ActivateRequest true "break-glass reason"
+// @Success 200 {object} common.Response[breakglass.Response]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /break-glass [post]
+func (c *Controller) Activate(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	logger := middleware.GetLogger(ctx)
+	var request ActivateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		logger.ErrorCtx(ctx.Context(), "body parse error: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	request.Subject = claims.Subject
+	request.Roles = claims.RealmAccess.Roles
+	logger.InfoCtx(ctx.Context(), "activate break-glass access: received request", zap.Any("request", request))
+	response, err := c.breakGlassService.Activate(ctx.Context(), request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}) || errors.As(err, &common.AlreadyExistsError{}):
+			logger.ErrorCtx(ctx.Context(), "error activating break-glass access: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.As(err, &common.ForbiddenError{}):
+			logger.ErrorCtx(ctx.Context(), "error activating break-glass access: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusForbidden, err.Error())
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), "error activating break-glass access: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "error activating break-glass access: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/break-glass"
+// @Summary Get break-glass access
+// @Description returns break-glass grants, including revoked unless active=true, with roles: admin
+// @Tags break-glass
+// @Security OAuth2Password
+// @Produce json
+// @Param active query bool false "Only active grants"
+// @Success 200 {object} common.Response[[]breakglass.Response]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /break-glass [get]
+func (c *Controller) FindAll(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	var request = FindRequest{ActiveOnly: ctx.QueryBool("active", false)}
+	logger.InfoCtx(ctx.Context(), "find break-glass access: received request", zap.Any("request", request))
+	response, err := c.breakGlassService.FindAll(request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), "find break-glass access: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "find break-glass access: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response)
+}
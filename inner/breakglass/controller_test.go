@@ -0,0 +1,78 @@
+package breakglass
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"idm/inner/common"
+	"idm/inner/web"
+	"idm/inner/web/webtest"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) Activate(ctx context.Context, request ActivateRequest) (Response, error) {
+	args := svc.Called(ctx, request)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) FindAll(request FindRequest) ([]Response, error) {
+	args := svc.Called(request)
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func newServer(svc Svc, subject string, roles ...string) *web.Server {
+	server := webtest.NewServer(subject, roles...)
+	NewController(server, svc).RegisterRoutes()
+	return server
+}
+
+func TestActivateBreakGlass(t *testing.T) {
+	var a = assert.New(t)
+	var activate = func(server *web.Server) *http.Response {
+		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/break-glass",
+			strings.NewReader("{\"reason\": \"production outage\"}"))
+		request.Header.Add("Content-Type", "application/json")
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		return resp
+	}
+	t.Run("on-call activates break-glass access", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "kc-1", "IDM_ON_CALL")
+		svc.On("Activate", mock.Anything, ActivateRequest{
+			Reason:  "production outage",
+			Subject: "kc-1",
+			Roles:   []string{"IDM_ON_CALL"},
+		}).Return(Response{Id: 1}, nil)
+		a.Equal(http.StatusOK, activate(server).StatusCode)
+	})
+	t.Run("caller without on-call role", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "kc-2", web.IdmUser)
+		svc.On("Activate", mock.Anything, mock.Anything).
+			Return(Response{}, common.ForbiddenError{Message: "break-glass access is allowed only for on-call roles"})
+		a.Equal(http.StatusForbidden, activate(server).StatusCode)
+	})
+}
+
+func TestFindBreakGlass(t *testing.T) {
+	var a = assert.New(t)
+	var svc = new(MockService)
+	server := newServer(svc, "admin-1", web.IdmAdmin)
+	svc.On("FindAll", FindRequest{ActiveOnly: true}).Return([]Response{{Id: 1}}, nil)
+	resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/break-glass?active=true", nil))
+	a.Nil(err)
+	a.Equal(http.StatusOK, resp.StatusCode)
+	server = newServer(svc, "kc-1", "IDM_ON_CALL")
+	resp, err = server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/break-glass", nil))
+	a.Nil(err)
+	a.Equal(http.StatusForbidden, resp.StatusCode)
+}
@@ -0,0 +1,57 @@
+package breakglass
+
+import "time"
+
+// действия break-glass в журнале аудита
+const (
+	ActionActivate = "break-glass:activate"
+	ActionRevoke   = "break-glass:revoke"
+)
+
+type Entity struct {
+	Id         int64      `db:"id"`
+	EmployeeId int64      `db:"employee_id"`
+	RoleId     int64      `db:"role_id"`
+	Subject    string     `db:"subject"`
+	Reason     string     `db:"reason"`
+	ExpiresAt  time.Time  `db:"expires_at"`
+	RevokedBy  string     `db:"revoked_by"`
+	RevokedAt  *time.Time `db:"revoked_at"`
+	CreatedAt  time.Time  `db:"created_at"`
+}
+
+type Response struct {
+	Id         int64      `json:"id"`
+	EmployeeId int64      `json:"employee_id"`
+	RoleId     int64      `json:"role_id"`
+	Subject    string     `json:"subject"`
+	Reason     string     `json:"reason"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedBy  string     `json:"revoked_by,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type ActivateRequest struct {
+	Reason  string   `json:"reason" validate:"required,minnows3,max=1000"`
+	Subject string   `json:"-" validate:"required"`
+	Roles   []string `json:"-"`
+}
+
+type FindRequest struct {
+	ActiveOnly bool
+}
+
+func (e *Entity) toResponse() Response {
+	return Response{
+		Id:         e.Id,
+		EmployeeId: e.EmployeeId,
+		RoleId:     e.RoleId,
+		Subject:    e.Subject,
+		Reason:     e.Reason,
+		ExpiresAt:  e.ExpiresAt,
+		RevokedBy:  e.RevokedBy,
+		RevokedAt:  e.RevokedAt,
+		CreatedAt:  e.CreatedAt,
+	}
+}
@@ -0,0 +1,73 @@
+package breakglass
+
+import (
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{
+		db: database,
+	}
+}
+
+func (r *Repository) BeginTransaction() (*sqlx.Tx, error) {
+	return r.db.Beginx()
+}
+
+func (r *Repository) Save(tx *sqlx.Tx, e Entity) (int64, error) {
+	var id int64
+	err := tx.QueryRow(
+		"INSERT INTO break_glass (employee_id, role_id, subject, reason, expires_at) "+
+			"VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		e.EmployeeId, e.RoleId, e.Subject, e.Reason, e.ExpiresAt).Scan(&id)
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
+// FindEmployeeIdForUpdate найти сотрудника по subject и заблокировать его строку до конца транзакции,
+// чтобы одновременные активации одного сотрудника выполнялись по очереди
+func (r *Repository) FindEmployeeIdForUpdate(tx *sqlx.Tx, subject string) (id int64, err error) {
+	err = tx.Get(&id, "SELECT id FROM employee WHERE subject = $1 FOR UPDATE", subject)
+	return id, err
+}
+
+func (r *Repository) FindRoleId(tx *sqlx.Tx, name string) (id int64, err error) {
+	err = tx.Get(&id, "SELECT id FROM role WHERE name = $1", name)
+	return id, err
+}
+
+func (r *Repository) ExistsActive(tx *sqlx.Tx, employeeId int64) (isExist bool, err error) {
+	err = tx.Get(
+		&isExist,
+		"SELECT EXISTS(SELECT 1 FROM break_glass WHERE employee_id = $1 AND revoked_at IS NULL)",
+		employeeId,
+	)
+	return isExist, err
+}
+
+func (r *Repository) FindAll(activeOnly bool) ([]Entity, error) {
+	var grants []Entity
+	err := r.db.Select(
+		&grants,
+		"SELECT * FROM break_glass WHERE NOT $1 OR revoked_at IS NULL ORDER BY id DESC",
+		activeOnly)
+	return grants, err
+}
+
+// RevokeExpired пометить отозванными доступы с истёкшим окном и вернуть их
+func (r *Repository) RevokeExpired(tx *sqlx.Tx, now time.Time, revokedBy string) ([]Entity, error) {
+	var grants []Entity
+	err := tx.Select(
+		&grants,
+		"UPDATE break_glass SET revoked_at = NOW(), revoked_by = $1 "+
+			"WHERE revoked_at IS NULL AND expires_at <= $2 RETURNING *",
+		revokedBy, now)
+	return grants, err
+}
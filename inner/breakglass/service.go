@@ -0,0 +1,202 @@
+package breakglass
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+	"idm/inner/assignment"
+	"idm/inner/audit"
+	"idm/inner/common"
+	"idm/inner/database"
+	"slices"
+	"time"
+)
+
+type Service struct {
+	repo        Repo
+	assignments AssignmentRepo
+	audit       AuditRepo
+	validator   Validator
+	logger      *common.Logger
+	roleName    string
+	window      time.Duration
+	callerRoles []string
+}
+
+type Repo interface {
+	BeginTransaction() (*sqlx.Tx, error)
+	Save(tx *sqlx.Tx, e Entity) (int64, error)
+	FindEmployeeIdForUpdate(tx *sqlx.Tx, subject string) (int64, error)
+	FindRoleId(tx *sqlx.Tx, name string) (int64, error)
+	ExistsActive(tx *sqlx.Tx, employeeId int64) (bool, error)
+	FindAll(activeOnly bool) ([]Entity, error)
+	RevokeExpired(tx *sqlx.Tx, now time.Time, revokedBy string) ([]Entity, error)
+}
+
+type AssignmentRepo interface {
+	Save(tx *sqlx.Tx, e assignment.Entity) error
+	Delete(tx *sqlx.Tx, employeeId int64, roleId int64, source string) error
+}
+
+type AuditRepo interface {
+	Save(tx *sqlx.Tx, e audit.Entity) error
+}
+
+type Validator interface {
+	Validate(request any) error
+}
+
+// NewService создать сервис экстренного доступа;
+// roleName - привилегированная роль, которая выдаётся на время window,
+// callerRoles - роли из токена, владельцы которых могут запросить экстренный доступ
+func NewService(
+	repo Repo,
+	assignments AssignmentRepo,
+	audit AuditRepo,
+	validator Validator,
+	logger *common.Logger,
+	roleName string,
+	window time.Duration,
+	callerRoles []string,
+) *Service {
+	return &Service{
+		repo:        repo,
+		assignments: assignments,
+		audit:       audit,
+		validator:   validator,
+		logger:      logger,
+		roleName:    roleName,
+		window:      window,
+		callerRoles: callerRoles,
+	}
+}
+
+// Available может ли владелец ролей roles запросить экстренный доступ
+func (s *Service) Available(roles []string) bool {
+	return s.roleName != "" && slices.ContainsFunc(roles, func(role string) bool { return slices.Contains(s.callerRoles, role) })
+}
+
+// Activate выдать вызывающему привилегированную роль на фиксированное окно
+// и записать событие аудита высокой важности в той же транзакции
+func (s *Service) Activate(ctx context.Context, request ActivateRequest) (Response, error) {
+	err := s.validator.Validate(request)
+	if err != nil {
+		return Response{}, common.RequestValidationError{Message: err.Error()}
+	}
+	if s.roleName == "" {
+		return Response{}, common.ForbiddenError{Message: "break-glass access is not configured"}
+	}
+	if !s.Available(request.Roles) {
+		return Response{}, common.ForbiddenError{Message: "break-glass access is allowed only for on-call roles"}
+	}
+	var entity = Entity{
+		Subject:   request.Subject,
+		Reason:    request.Reason,
+		ExpiresAt: time.Now().Add(s.window),
+	}
+	err = database.InTransaction(s.repo.BeginTransaction, "activating break-glass access", func(tx *sqlx.Tx) error {
+		entity.EmployeeId, err = s.repo.FindEmployeeIdForUpdate(tx, request.Subject)
+		if err != nil {
+			return common.NotFoundError{Message: fmt.Sprintf("no employee linked to subject %s: %v", request.Subject, err)}
+		}
+		entity.RoleId, err = s.repo.FindRoleId(tx, s.roleName)
+		if err != nil {
+			return fmt.Errorf("error finding break-glass role %s: %w", s.roleName, err)
+		}
+		isExist, err := s.repo.ExistsActive(tx, entity.EmployeeId)
+		if err != nil {
+			return fmt.Errorf("error finding active break-glass access: %w", err)
+		}
+		if isExist {
+			return common.AlreadyExistsError{Message: fmt.Sprintf(
+				"break-glass access is already active for employee %d", entity.EmployeeId)}
+		}
+		entity.Id, err = s.repo.Save(tx, entity)
+		if err != nil {
+			return fmt.Errorf("error saving break-glass access: %w", err)
+		}
+		err = s.assignments.Save(tx, assignment.Entity{
+			EmployeeId: entity.EmployeeId,
+			RoleId:     entity.RoleId,
+			Source:     assignment.SourceBreakGlass,
+			CreatedBy:  request.Subject,
+		})
+		if err != nil {
+			return fmt.Errorf("error granting role %d to employee %d: %w", entity.RoleId, entity.EmployeeId, err)
+		}
+		return s.saveAudit(tx, ActionActivate, request.Subject, entity)
+	})
+	if err != nil {
+		return Response{}, err
+	}
+	s.logger.WarnCtx(ctx, "break-glass access granted",
+		zap.String("severity", audit.SeverityHigh),
+		zap.String("subject", entity.Subject),
+		zap.Int64("employee_id", entity.EmployeeId),
+		zap.String("role", s.roleName),
+		zap.Time("expires_at", entity.ExpiresAt),
+		zap.String("reason", entity.Reason),
+	)
+	return entity.toResponse(), nil
+}
+
+func (s *Service) FindAll(request FindRequest) ([]Response, error) {
+	grants, err := s.repo.FindAll(request.ActiveOnly)
+	if err != nil {
+		return nil, common.NotFoundError{Message: fmt.Sprintf("error finding break-glass access: %v", err)}
+	}
+	var response []Response
+	for _, entity := range grants {
+		response = append(response, entity.toResponse())
+	}
+	return response, nil
+}
+
+// RevokeExpired отозвать роли, окно экстренного доступа которых закончилось
+func (s *Service) RevokeExpired(ctx context.Context) error {
+	var revoked []Entity
+	err := database.InTransaction(s.repo.BeginTransaction, "revoking break-glass access", func(tx *sqlx.Tx) error {
+		var err error
+		revoked, err = s.repo.RevokeExpired(tx, time.Now(), "system")
+		if err != nil {
+			return fmt.Errorf("error revoking expired break-glass access: %w", err)
+		}
+		for _, entity := range revoked {
+			err = s.assignments.Delete(tx, entity.EmployeeId, entity.RoleId, assignment.SourceBreakGlass)
+			if err != nil {
+				return fmt.Errorf("error revoking role %d from employee %d: %w", entity.RoleId, entity.EmployeeId, err)
+			}
+			if err = s.saveAudit(tx, ActionRevoke, "system", entity); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, entity := range revoked {
+		s.logger.WarnCtx(ctx, "break-glass access revoked",
+			zap.String("severity", audit.SeverityHigh),
+			zap.String("subject", entity.Subject),
+			zap.Int64("employee_id", entity.EmployeeId),
+			zap.Int64("role_id", entity.RoleId),
+		)
+	}
+	return nil
+}
+
+func (s *Service) saveAudit(tx *sqlx.Tx, action string, actor string, entity Entity) error {
+	err := s.audit.Save(tx, audit.Entity{
+		Severity: audit.SeverityHigh,
+		Action:   action,
+		Actor:    actor,
+		Object:   fmt.Sprintf("employee:%d", entity.EmployeeId),
+		Details:  fmt.Sprintf("role %d until %s: %s", entity.RoleId, entity.ExpiresAt.Format(time.RFC3339), entity.Reason),
+	})
+	if err != nil {
+		return fmt.Errorf("error saving audit event: %w", err)
+	}
+	return nil
+}
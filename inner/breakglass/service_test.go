@@ -0,0 +1,184 @@
+package breakglass
+
+import (
+	"context"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"idm/inner/assignment"
+	"idm/inner/audit"
+	"idm/inner/common"
+	"idm/inner/database/dbtest"
+	"idm/inner/validator"
+	"testing"
+	"time"
+)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+func (r *MockRepo) BeginTransaction() (*sqlx.Tx, error) {
+	args := r.Called()
+	return args.Get(0).(*sqlx.Tx), args.Error(1)
+}
+
+func (r *MockRepo) Save(tx *sqlx.Tx, e Entity) (int64, error) {
+	args := r.Called(tx, e)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (r *MockRepo) FindEmployeeIdForUpdate(tx *sqlx.Tx, subject string) (int64, error) {
+	args := r.Called(tx, subject)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (r *MockRepo) FindRoleId(tx *sqlx.Tx, name string) (int64, error) {
+	args := r.Called(tx, name)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (r *MockRepo) ExistsActive(tx *sqlx.Tx, employeeId int64) (bool, error) {
+	args := r.Called(tx, employeeId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (r *MockRepo) FindAll(activeOnly bool) ([]Entity, error) {
+	args := r.Called(activeOnly)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (r *MockRepo) RevokeExpired(tx *sqlx.Tx, now time.Time, revokedBy string) ([]Entity, error) {
+	args := r.Called(tx, now, revokedBy)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+type MockAssignmentRepo struct {
+	mock.Mock
+}
+
+func (r *MockAssignmentRepo) Save(tx *sqlx.Tx, e assignment.Entity) error {
+	args := r.Called(tx, e)
+	return args.Error(0)
+}
+
+func (r *MockAssignmentRepo) Delete(tx *sqlx.Tx, employeeId int64, roleId int64, source string) error {
+	args := r.Called(tx, employeeId, roleId, source)
+	return args.Error(0)
+}
+
+type MockAuditRepo struct {
+	mock.Mock
+}
+
+func (r *MockAuditRepo) Save(tx *sqlx.Tx, e audit.Entity) error {
+	args := r.Called(tx, e)
+	return args.Error(0)
+}
+
+func newService(repo Repo, assignments AssignmentRepo, auditRepo AuditRepo, logger *common.Logger) *Service {
+	return NewService(repo, assignments, auditRepo, validator.New(), logger, "Emergency Admin", time.Hour,
+		[]string{"IDM_ON_CALL"})
+}
+
+func TestActivate(t *testing.T) {
+	var request = ActivateRequest{Reason: "production outage", Subject: "kc-1", Roles: []string{"IDM_ON_CALL"}}
+	t.Run("should grant role and write high severity audit", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, true)
+		var repo = new(MockRepo)
+		var assignments = new(MockAssignmentRepo)
+		var auditRepo = new(MockAuditRepo)
+		core, logs := observer.New(zapcore.InfoLevel)
+		var svc = newService(repo, assignments, auditRepo, &common.Logger{Logger: zap.New(core)})
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("FindEmployeeIdForUpdate", tx, "kc-1").Return(int64(3), nil)
+		repo.On("FindRoleId", tx, "Emergency Admin").Return(int64(9), nil)
+		repo.On("ExistsActive", tx, int64(3)).Return(false, nil)
+		repo.On("Save", tx, mock.MatchedBy(func(e Entity) bool {
+			return e.EmployeeId == 3 && e.RoleId == 9 && e.ExpiresAt.After(time.Now().Add(59*time.Minute))
+		})).Return(int64(1), nil)
+		assignments.On("Save", tx, assignment.Entity{
+			EmployeeId: 3,
+			RoleId:     9,
+			Source:     assignment.SourceBreakGlass,
+			CreatedBy:  "kc-1",
+		}).Return(nil)
+		auditRepo.On("Save", tx, mock.MatchedBy(func(e audit.Entity) bool {
+			return e.Severity == audit.SeverityHigh && e.Action == ActionActivate && e.Object == "employee:3"
+		})).Return(nil)
+		got, err := svc.Activate(context.Background(), request)
+		a.Nil(err)
+		a.Equal(int64(1), got.Id)
+		a.Equal(int64(9), got.RoleId)
+		var entries = logs.FilterMessage("break-glass access granted").All()
+		a.Len(entries, 1)
+		a.Equal(zapcore.WarnLevel, entries[0].Level)
+		a.Equal(audit.SeverityHigh, entries[0].ContextMap()["severity"])
+		a.Equal("production outage", entries[0].ContextMap()["reason"])
+	})
+	t.Run("should reject caller without on-call role", func(t *testing.T) {
+		a := assert.New(t)
+		var repo = new(MockRepo)
+		var svc = newService(repo, new(MockAssignmentRepo), new(MockAuditRepo), &common.Logger{Logger: zap.NewNop()})
+		_, err := svc.Activate(context.Background(), ActivateRequest{Reason: "outage", Subject: "kc-1"})
+		a.True(errors.As(err, &common.ForbiddenError{}))
+		a.True(repo.AssertNumberOfCalls(t, "BeginTransaction", 0))
+	})
+	t.Run("should require reason", func(t *testing.T) {
+		a := assert.New(t)
+		var svc = newService(new(MockRepo), new(MockAssignmentRepo), new(MockAuditRepo), &common.Logger{Logger: zap.NewNop()})
+		_, err := svc.Activate(context.Background(), ActivateRequest{Subject: "kc-1", Roles: []string{"IDM_ON_CALL"}})
+		a.True(errors.As(err, &common.RequestValidationError{}))
+	})
+	t.Run("should not stack active break-glass access", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, false)
+		var repo = new(MockRepo)
+		var assignments = new(MockAssignmentRepo)
+		var svc = newService(repo, assignments, new(MockAuditRepo), &common.Logger{Logger: zap.NewNop()})
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("FindEmployeeIdForUpdate", tx, "kc-1").Return(int64(3), nil)
+		repo.On("FindRoleId", tx, "Emergency Admin").Return(int64(9), nil)
+		repo.On("ExistsActive", tx, int64(3)).Return(true, nil)
+		_, err := svc.Activate(context.Background(), request)
+		a.True(errors.As(err, &common.AlreadyExistsError{}))
+		a.True(assignments.AssertNumberOfCalls(t, "Save", 0))
+	})
+}
+
+func TestAvailable(t *testing.T) {
+	a := assert.New(t)
+	var logger = &common.Logger{Logger: zap.NewNop()}
+	var svc = newService(new(MockRepo), new(MockAssignmentRepo), new(MockAuditRepo), logger)
+	a.True(svc.Available([]string{"IDM_USER", "IDM_ON_CALL"}))
+	a.False(svc.Available([]string{"IDM_USER"}))
+	var unconfigured = NewService(new(MockRepo), new(MockAssignmentRepo), new(MockAuditRepo), validator.New(), logger, "",
+		time.Hour, []string{"IDM_ON_CALL"})
+	a.False(unconfigured.Available([]string{"IDM_ON_CALL"}))
+}
+
+func TestRevokeExpired(t *testing.T) {
+	a := assert.New(t)
+	var tx = dbtest.NewTx(t, true)
+	var repo = new(MockRepo)
+	var assignments = new(MockAssignmentRepo)
+	var auditRepo = new(MockAuditRepo)
+	core, logs := observer.New(zapcore.InfoLevel)
+	var svc = newService(repo, assignments, auditRepo, &common.Logger{Logger: zap.New(core)})
+	repo.On("BeginTransaction").Return(tx, nil)
+	repo.On("RevokeExpired", tx, mock.Anything, "system").Return([]Entity{
+		{Id: 1, EmployeeId: 3, RoleId: 9, Subject: "kc-1"},
+	}, nil)
+	assignments.On("Delete", tx, int64(3), int64(9), assignment.SourceBreakGlass).Return(nil)
+	auditRepo.On("Save", tx, mock.MatchedBy(func(e audit.Entity) bool {
+		return e.Action == ActionRevoke && e.Actor == "system"
+	})).Return(nil)
+	a.Nil(svc.RevokeExpired(context.Background()))
+	a.True(assignments.AssertNumberOfCalls(t, "Delete", 1))
+	a.Len(logs.FilterMessage("break-glass access revoked").All(), 1)
+}
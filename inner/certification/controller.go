@@ -0,0 +1,296 @@
+package certification
+
+import (
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"idm/inner/common"
+	"idm/inner/middleware"
+	"idm/inner/web"
+	"slices"
+	"strconv"
+)
+
+type Controller struct {
+	server               *web.Server
+	certificationService Svc
+}
+
+type Svc interface {
+	Create(ctx context.Context, request CreateRequest) (CampaignResponse, error)
+	FindById(request IdRequest) (CampaignResponse, error)
+	FindAll() ([]CampaignResponse, error)
+	FindItems(request ItemsRequest) ([]ItemResponse, error)
+	Decide(ctx context.Context, request DecisionRequest) (ItemResponse, error)
+	Close(ctx context.Context, request CloseRequest) (CampaignResponse, error)
+}
+
+func NewController(
+	server *web.Server,
+	certificationService Svc,
+) *Controller {
+	return &Controller{
+		server:               server,
+		certificationService: certificationService,
+	}
+}
+
+func (c *Controller) RegisterRoutes() {
+	c.server.GroupApiV1.Post("/certifications", c.CreateCampaign)
+	c.server.GroupApiV1.Get("/certifications", c.FindAll)
+	c.server.GroupApiV1.Post("/certifications/items/:id/decision", c.Decide)
+	c.server.GroupApiV1.Get("/certifications/:id", c.FindById)
+	c.server.GroupApiV1.Get("/certifications/:id/items", c.FindItems)
+	c.server.GroupApiV1.Post("/certifications/:id/close", c.Close)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/certifications"
+// @Summary create a certification campaign
+// @Description Create a campaign scoped by role or department and generate review items with roles: admin
+// @Tags certification
+// @Security OAuth2Password
+// @Accept json
+// @Produce json
+// @Param request body certification.CreateRequest true "create campaign request"
+// @Success 200 {object} common.Response[certification.CampaignResponse]
+// @Failure 400 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /certifications [post]
+func (c *Controller) CreateCampaign(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	var request CreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		logger.ErrorCtx(ctx.Context(), "body parse error: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	request.CreatedBy = claims.Subject
+	logger.InfoCtx(ctx.Context(), "create campaign: received request", zap.Any("request", request))
+	var response, err = c.certificationService.Create(ctx.Context(), request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			logger.ErrorCtx(ctx.Context(), "error creating campaign: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "error creating campaign: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/certifications"
+// @Summary Get all certification campaigns
+// @Description returns campaigns with their progress with roles: admin, user
+// @Tags certification
+// @Security OAuth2Password
+// @Accept json
+// @Produce json
+// @Success 200 {object} common.Response[[]certification.CampaignResponse]
+// @Failure 500 {object} common.Response[string]
+// @Router /certifications [get]
+func (c *Controller) FindAll(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !(slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) ||
+		slices.Contains(claims.RealmAccess.Roles, web.IdmUser)) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	logger.InfoCtx(ctx.Context(), "find all campaigns: ")
+	response, err := c.certificationService.FindAll()
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "find all campaigns: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/certifications/:id"
+// @Summary Get certification campaign by ID
+// @Description returns campaign with its progress with roles: admin, user
+// @Tags certification
+// @Security OAuth2Password
+// @Accept json
+// @Produce json
+// @Param id path int true "Campaign ID"
+// @Success 200 {object} common.Response[certification.CampaignResponse]
+// @Failure 400 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /certifications/{id} [get]
+func (c *Controller) FindById(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !(slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) ||
+		slices.Contains(claims.RealmAccess.Roles, web.IdmUser)) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error parsing id: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	request := IdRequest{Id: int64(id)}
+	logger.InfoCtx(ctx.Context(), "find by id campaign: received request", zap.Any("request", request))
+	response, err := c.certificationService.FindById(request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			logger.ErrorCtx(ctx.Context(), "find by id campaign: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), "find by id campaign: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "find by id campaign: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/certifications/:id/items"
+// @Summary Get review items of a certification campaign
+// @Description returns all items of the campaign for admin, otherwise only items assigned to the caller
+// @Tags certification
+// @Security OAuth2Password
+// @Accept json
+// @Produce json
+// @Param id path int true "Campaign ID"
+// @Success 200 {object} common.Response[[]certification.ItemResponse]
+// @Failure 400 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /certifications/{id}/items [get]
+func (c *Controller) FindItems(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	logger := middleware.GetLogger(ctx)
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error parsing id: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	request := ItemsRequest{CampaignId: int64(id)}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		request.Reviewer = claims.Subject
+	}
+	logger.InfoCtx(ctx.Context(), "find campaign items: received request", zap.Any("request", request))
+	response, err := c.certificationService.FindItems(request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			logger.ErrorCtx(ctx.Context(), "find campaign items: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), "find campaign items: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "find campaign items: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/certifications/items/:id/decision"
+// @Summary Keep or revoke a review item
+// @Description Saves the reviewer decision for an item, available to the assigned reviewer and admin
+// @Tags certification
+// @Security OAuth2Password
+// @Accept json
+// @Produce json
+// @Param id path int true "Item ID"
+// @Param request body certification.DecisionRequest true "decision: keep or revoke"
+// @Success 200 {object} common.Response[certification.ItemResponse]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /certifications/items/{id}/decision [post]
+func (c *Controller) Decide(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	logger := middleware.GetLogger(ctx)
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error parsing id: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request DecisionRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		logger.ErrorCtx(ctx.Context(), "body parse error: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	request.ItemId = int64(id)
+	request.Actor = claims.Subject
+	request.ActorRoles = claims.RealmAccess.Roles
+	logger.InfoCtx(ctx.Context(), "decide campaign item: received request", zap.Any("request", request))
+	response, err := c.certificationService.Decide(ctx.Context(), request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			logger.ErrorCtx(ctx.Context(), "decide campaign item: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.As(err, &common.ForbiddenError{}):
+			logger.ErrorCtx(ctx.Context(), "decide campaign item: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusForbidden, err.Error())
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), "decide campaign item: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "decide campaign item: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/certifications/:id/close"
+// @Summary Close a certification campaign
+// @Description Closes the campaign and revokes every role marked for revocation with roles: admin
+// @Tags certification
+// @Security OAuth2Password
+// @Accept json
+// @Produce json
+// @Param id path int true "Campaign ID"
+// @Success 200 {object} common.Response[certification.CampaignResponse]
+// @Failure 400 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /certifications/{id}/close [post]
+func (c *Controller) Close(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error parsing id: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	request := CloseRequest{Id: int64(id), Actor: claims.Subject}
+	logger.InfoCtx(ctx.Context(), "close campaign: received request", zap.Any("request", request))
+	response, err := c.certificationService.Close(ctx.Context(), request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			logger.ErrorCtx(ctx.Context(), "close campaign: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), "close campaign: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "close campaign: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response)
+}
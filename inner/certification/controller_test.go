@@ -0,0 +1,137 @@
+package certification
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"idm/inner/common"
+	"idm/inner/web"
+	"idm/inner/web/webtest"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) Create(ctx context.Context, request CreateRequest) (CampaignResponse, error) {
+	args := svc.Called(ctx, request)
+	return args.Get(0).(CampaignResponse), args.Error(1)
+}
+
+func (svc *MockService) FindById(request IdRequest) (CampaignResponse, error) {
+	args := svc.Called(request)
+	return args.Get(0).(CampaignResponse), args.Error(1)
+}
+
+func (svc *MockService) FindAll() ([]CampaignResponse, error) {
+	args := svc.Called()
+	return args.Get(0).([]CampaignResponse), args.Error(1)
+}
+
+func (svc *MockService) FindItems(request ItemsRequest) ([]ItemResponse, error) {
+	args := svc.Called(request)
+	return args.Get(0).([]ItemResponse), args.Error(1)
+}
+
+func (svc *MockService) Decide(ctx context.Context, request DecisionRequest) (ItemResponse, error) {
+	args := svc.Called(ctx, request)
+	return args.Get(0).(ItemResponse), args.Error(1)
+}
+
+func (svc *MockService) Close(ctx context.Context, request CloseRequest) (CampaignResponse, error) {
+	args := svc.Called(ctx, request)
+	return args.Get(0).(CampaignResponse), args.Error(1)
+}
+
+func newServer(svc Svc, subject string, roles ...string) *web.Server {
+	server := webtest.NewServer(subject, roles...)
+	NewController(server, svc).RegisterRoutes()
+	return server
+}
+
+func TestCreateCampaign(t *testing.T) {
+	var a = assert.New(t)
+	t.Run("create campaign", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "admin-1", web.IdmAdmin)
+		var body = strings.NewReader("{\"name\": \"Q3\", \"scope_type\": \"role\", \"scope_value\": \"2\", " +
+			"\"reviewer\": \"reviewer-1\", \"due_at\": \"2026-12-31T00:00:00Z\"}")
+		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/certifications", body)
+		request.Header.Add("Content-Type", "application/json")
+		svc.On("Create", mock.Anything, mock.MatchedBy(func(r CreateRequest) bool {
+			return r.CreatedBy == "admin-1" && r.ScopeType == ScopeRole && !r.DueAt.IsZero()
+		})).Return(CampaignResponse{Id: 3, Progress: Progress{Total: 4, Pending: 4}}, nil)
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		bytesData, err := io.ReadAll(resp.Body)
+		a.Nil(err)
+		var responseBody common.Response[CampaignResponse]
+		a.Nil(json.Unmarshal(bytesData, &responseBody))
+		a.Equal(int64(4), responseBody.Data.Progress.Total)
+	})
+	t.Run("create campaign - permission denied", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "user-1", web.IdmUser)
+		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/certifications", strings.NewReader("{}"))
+		request.Header.Add("Content-Type", "application/json")
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+	})
+}
+
+func TestFindItems(t *testing.T) {
+	var a = assert.New(t)
+	t.Run("reviewer sees only own items", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "reviewer-1", web.IdmUser)
+		var request = httptest.NewRequest(fiber.MethodGet, "/api/v1/certifications/3/items", nil)
+		svc.On("FindItems", ItemsRequest{CampaignId: 3, Reviewer: "reviewer-1"}).Return([]ItemResponse{{Id: 7}}, nil)
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+	})
+	t.Run("admin sees all items", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "admin-1", web.IdmAdmin)
+		var request = httptest.NewRequest(fiber.MethodGet, "/api/v1/certifications/3/items", nil)
+		svc.On("FindItems", ItemsRequest{CampaignId: 3}).Return([]ItemResponse{{Id: 7}, {Id: 8}}, nil)
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+	})
+}
+
+func TestDecideItem(t *testing.T) {
+	var a = assert.New(t)
+	t.Run("decide item - forbidden", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "user-2", web.IdmUser)
+		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/certifications/items/7/decision",
+			strings.NewReader("{\"decision\": \"revoke\"}"))
+		request.Header.Add("Content-Type", "application/json")
+		svc.On("Decide", mock.Anything, mock.AnythingOfType("DecisionRequest")).
+			Return(ItemResponse{}, common.ForbiddenError{Message: "campaign item is assigned to another reviewer"})
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+	})
+	t.Run("close campaign", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "admin-1", web.IdmAdmin)
+		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/certifications/3/close", nil)
+		svc.On("Close", mock.Anything, CloseRequest{Id: 3, Actor: "admin-1"}).
+			Return(CampaignResponse{Id: 3, State: StateClosed}, nil)
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+	})
+}
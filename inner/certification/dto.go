@@ -0,0 +1,159 @@
+package certification
+
+import "time"
+
+// область кампании: сотрудники одной роли или одного подразделения
+const (
+	ScopeRole       = "role"
+	ScopeDepartment = "department"
+)
+
+const (
+	StateOpen   = "open"
+	StateClosed = "closed"
+)
+
+const (
+	DecisionPending = "pending"
+	DecisionKeep    = "keep"
+	DecisionRevoke  = "revoke"
+)
+
+type CampaignEntity struct {
+	Id         int64      `db:"id"`
+	Name       string     `db:"name"`
+	ScopeType  string     `db:"scope_type"`
+	ScopeValue string     `db:"scope_value"`
+	Reviewer   string     `db:"reviewer"`
+	State      string     `db:"state"`
+	DueAt      time.Time  `db:"due_at"`
+	CreatedBy  string     `db:"created_by"`
+	CreatedAt  time.Time  `db:"created_at"`
+	ClosedBy   string     `db:"closed_by"`
+	ClosedAt   *time.Time `db:"closed_at"`
+	Progress
+}
+
+// Progress счётчики элементов кампании по решениям
+type Progress struct {
+	Total   int64 `db:"total" json:"total"`
+	Pending int64 `db:"pending" json:"pending"`
+	Kept    int64 `db:"kept" json:"kept"`
+	Revoked int64 `db:"revoked" json:"revoked"`
+}
+
+type ItemEntity struct {
+	Id         int64      `db:"id"`
+	CampaignId int64      `db:"campaign_id"`
+	EmployeeId int64      `db:"employee_id"`
+	RoleId     int64      `db:"role_id"`
+	Reviewer   string     `db:"reviewer"`
+	Decision   string     `db:"decision"`
+	Comment    string     `db:"comment"`
+	DecidedBy  string     `db:"decided_by"`
+	DecidedAt  *time.Time `db:"decided_at"`
+	Applied    bool       `db:"applied"`
+}
+
+type CampaignResponse struct {
+	Id         int64      `json:"id"`
+	Name       string     `json:"name"`
+	ScopeType  string     `json:"scope_type"`
+	ScopeValue string     `json:"scope_value"`
+	Reviewer   string     `json:"reviewer"`
+	State      string     `json:"state"`
+	DueAt      time.Time  `json:"due_at"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ClosedBy   string     `json:"closed_by"`
+	ClosedAt   *time.Time `json:"closed_at"`
+	Progress   Progress   `json:"progress"`
+}
+
+type ItemResponse struct {
+	Id         int64      `json:"id"`
+	CampaignId int64      `json:"campaign_id"`
+	EmployeeId int64      `json:"employee_id"`
+	RoleId     int64      `json:"role_id"`
+	Reviewer   string     `json:"reviewer"`
+	Decision   string     `json:"decision"`
+	Comment    string     `json:"comment"`
+	DecidedBy  string     `json:"decided_by"`
+	DecidedAt  *time.Time `json:"decided_at"`
+	Applied    bool       `json:"applied"`
+}
+
+type CreateRequest struct {
+	Name       string    `json:"name" validate:"required,min=2,max=155"`
+	ScopeType  string    `json:"scope_type" validate:"required,oneof=role department"`
+	ScopeValue string    `json:"scope_value" validate:"required,max=155"`
+	Reviewer   string    `json:"reviewer" validate:"required,max=255"`
+	DueAt      time.Time `json:"due_at" validate:"required"`
+	CreatedBy  string    `json:"-" validate:"required"`
+}
+
+type IdRequest struct {
+	Id int64 `json:"id" validate:"required,min=1"`
+}
+
+type ItemsRequest struct {
+	CampaignId int64  `validate:"required,min=1"`
+	Reviewer   string `validate:"max=255"`
+}
+
+type DecisionRequest struct {
+	ItemId     int64    `json:"-" validate:"required,min=1"`
+	Decision   string   `json:"decision" validate:"required,oneof=keep revoke"`
+	Comment    string   `json:"comment" validate:"max=1000"`
+	Actor      string   `json:"-" validate:"required"`
+	ActorRoles []string `json:"-"`
+}
+
+type CloseRequest struct {
+	Id    int64  `validate:"required,min=1"`
+	Actor string `validate:"required"`
+}
+
+func (e *CampaignEntity) toResponse() CampaignResponse {
+	return CampaignResponse{
+		Id:         e.Id,
+		Name:       e.Name,
+		ScopeType:  e.ScopeType,
+		ScopeValue: e.ScopeValue,
+		Reviewer:   e.Reviewer,
+		State:      e.State,
+		DueAt:      e.DueAt,
+		CreatedBy:  e.CreatedBy,
+		CreatedAt:  e.CreatedAt,
+		ClosedBy:   e.ClosedBy,
+		ClosedAt:   e.ClosedAt,
+		Progress:   e.Progress,
+	}
+}
+
+func (e *ItemEntity) toResponse() ItemResponse {
+	return ItemResponse{
+		Id:         e.Id,
+		CampaignId: e.CampaignId,
+		EmployeeId: e.EmployeeId,
+		RoleId:     e.RoleId,
+		Reviewer:   e.Reviewer,
+		Decision:   e.Decision,
+		Comment:    e.Comment,
+		DecidedBy:  e.DecidedBy,
+		DecidedAt:  e.DecidedAt,
+		Applied:    e.Applied,
+	}
+}
+
+func (req *CreateRequest) ToEntity() CampaignEntity {
+	return CampaignEntity{
+		Name:       req.Name,
+		ScopeType:  req.ScopeType,
+		ScopeValue: req.ScopeValue,
+		Reviewer:   req.Reviewer,
+		State:      StateOpen,
+		DueAt:      req.DueAt,
+		CreatedBy:  req.CreatedBy,
+	}
+}
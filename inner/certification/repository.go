@@ -0,0 +1,129 @@
+package certification
+
+import (
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{
+		db: database,
+	}
+}
+
+const campaignWithProgress = "SELECT c.*, " +
+	"COUNT(i.id) AS total, " +
+	"COUNT(i.id) FILTER (WHERE i.decision = 'pending') AS pending, " +
+	"COUNT(i.id) FILTER (WHERE i.decision = 'keep') AS kept, " +
+	"COUNT(i.id) FILTER (WHERE i.decision = 'revoke') AS revoked " +
+	"FROM certification_campaign c LEFT JOIN certification_item i ON i.campaign_id = c.id"
+
+func (r *Repository) BeginTransaction() (*sqlx.Tx, error) {
+	return r.db.Beginx()
+}
+
+func (r *Repository) SaveCampaign(tx *sqlx.Tx, e CampaignEntity) (int64, error) {
+	var id int64
+	err := tx.QueryRow(
+		"INSERT INTO certification_campaign (name, scope_type, scope_value, reviewer, state, due_at, created_by) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		e.Name, e.ScopeType, e.ScopeValue, e.Reviewer, e.State, e.DueAt, e.CreatedBy).Scan(&id)
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
+// GenerateItems создать элементы пересмотра для каждой пары сотрудник-роль из области кампании;
+// элемент назначается владельцу роли, а если владельца нет - рецензенту кампании
+func (r *Repository) GenerateItems(tx *sqlx.Tx, campaign CampaignEntity) (int64, error) {
+	result, err := tx.Exec(
+		"INSERT INTO certification_item (campaign_id, employee_id, role_id, reviewer) "+
+			"SELECT DISTINCT $1::bigint, er.employee_id, er.role_id, COALESCE(NULLIF(r.owner, ''), $2) "+
+			"FROM employee_role er "+
+			"JOIN employee e ON e.id = er.employee_id "+
+			"JOIN role r ON r.id = er.role_id "+
+			"WHERE ($3 = 'role' AND r.id::text = $4) OR ($3 = 'department' AND e.department = $4) "+
+			"ON CONFLICT (campaign_id, employee_id, role_id) DO NOTHING",
+		campaign.Id, campaign.Reviewer, campaign.ScopeType, campaign.ScopeValue)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (r *Repository) FindCampaignById(id int64) (res CampaignEntity, err error) {
+	err = r.db.Get(&res, campaignWithProgress+" WHERE c.id = $1 GROUP BY c.id", id)
+	return res, err
+}
+
+func (r *Repository) FindCampaignByIdForUpdate(tx *sqlx.Tx, id int64) (res CampaignEntity, err error) {
+	err = tx.Get(&res, "SELECT * FROM certification_campaign WHERE id = $1 FOR UPDATE", id)
+	return res, err
+}
+
+func (r *Repository) FindCampaigns() ([]CampaignEntity, error) {
+	var campaigns []CampaignEntity
+	err := r.db.Select(&campaigns, campaignWithProgress+" GROUP BY c.id ORDER BY c.id")
+	return campaigns, err
+}
+
+// FindOverdueCampaigns найти открытые кампании, срок которых истёк
+func (r *Repository) FindOverdueCampaigns(now time.Time) ([]int64, error) {
+	var ids []int64
+	err := r.db.Select(
+		&ids,
+		"SELECT id FROM certification_campaign WHERE state = $1 AND due_at <= $2 ORDER BY id",
+		StateOpen, now)
+	return ids, err
+}
+
+func (r *Repository) CloseCampaign(tx *sqlx.Tx, id int64, closedBy string) error {
+	_, err := tx.Exec(
+		"UPDATE certification_campaign SET state = $1, closed_by = $2, closed_at = NOW() WHERE id = $3",
+		StateClosed, closedBy, id)
+	return err
+}
+
+func (r *Repository) FindItems(campaignId int64, reviewer string) ([]ItemEntity, error) {
+	var items []ItemEntity
+	query := "SELECT * FROM certification_item WHERE campaign_id = $1"
+	var args = []interface{}{campaignId}
+	if reviewer != "" {
+		query += " AND reviewer = $2"
+		args = append(args, reviewer)
+	}
+	query += " ORDER BY id"
+	err := r.db.Select(&items, query, args...)
+	return items, err
+}
+
+func (r *Repository) FindItemForUpdate(tx *sqlx.Tx, id int64) (res ItemEntity, err error) {
+	err = tx.Get(&res, "SELECT * FROM certification_item WHERE id = $1 FOR UPDATE", id)
+	return res, err
+}
+
+func (r *Repository) FindItemsByDecision(tx *sqlx.Tx, campaignId int64, decision string) ([]ItemEntity, error) {
+	var items []ItemEntity
+	err := tx.Select(
+		&items,
+		"SELECT * FROM certification_item WHERE campaign_id = $1 AND decision = $2 AND NOT applied ORDER BY id",
+		campaignId, decision)
+	return items, err
+}
+
+func (r *Repository) UpdateItemDecision(tx *sqlx.Tx, id int64, decision string, comment string, decidedBy string) error {
+	_, err := tx.Exec(
+		"UPDATE certification_item SET decision = $1, comment = $2, decided_by = $3, decided_at = NOW() WHERE id = $4",
+		decision, comment, decidedBy, id)
+	return err
+}
+
+func (r *Repository) MarkItemApplied(tx *sqlx.Tx, id int64) error {
+	_, err := tx.Exec("UPDATE certification_item SET applied = TRUE WHERE id = $1", id)
+	return err
+}
@@ -0,0 +1,237 @@
+package certification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"idm/inner/common"
+	"idm/inner/database"
+	"slices"
+	"time"
+)
+
+type Service struct {
+	repo        Repo
+	assignments AssignmentRepo
+	validator   Validator
+	adminRoles  []string
+}
+
+type Repo interface {
+	BeginTransaction() (*sqlx.Tx, error)
+	SaveCampaign(tx *sqlx.Tx, e CampaignEntity) (int64, error)
+	GenerateItems(tx *sqlx.Tx, campaign CampaignEntity) (int64, error)
+	FindCampaignById(id int64) (CampaignEntity, error)
+	FindCampaignByIdForUpdate(tx *sqlx.Tx, id int64) (CampaignEntity, error)
+	FindCampaigns() ([]CampaignEntity, error)
+	FindOverdueCampaigns(now time.Time) ([]int64, error)
+	CloseCampaign(tx *sqlx.Tx, id int64, closedBy string) error
+	FindItems(campaignId int64, reviewer string) ([]ItemEntity, error)
+	FindItemForUpdate(tx *sqlx.Tx, id int64) (ItemEntity, error)
+	FindItemsByDecision(tx *sqlx.Tx, campaignId int64, decision string) ([]ItemEntity, error)
+	UpdateItemDecision(tx *sqlx.Tx, id int64, decision string, comment string, decidedBy string) error
+	MarkItemApplied(tx *sqlx.Tx, id int64) error
+}
+
+type AssignmentRepo interface {
+	IsPrimary(tx *sqlx.Tx, employeeId int64, roleId int64) (bool, error)
+	Revoke(tx *sqlx.Tx, employeeId int64, roleId int64) error
+}
+
+type Validator interface {
+	Validate(request any) error
+}
+
+// NewService создать сервис кампаний пересмотра доступа;
+// adminRoles - роли из токена, владельцы которых могут принимать решения по любому элементу
+func NewService(repo Repo, assignments AssignmentRepo, validator Validator, adminRoles []string) *Service {
+	return &Service{
+		repo:        repo,
+		assignments: assignments,
+		validator:   validator,
+		adminRoles:  adminRoles,
+	}
+}
+
+// Create создать кампанию и сгенерировать элементы пересмотра в одной транзакции
+func (s *Service) Create(ctx context.Context, request CreateRequest) (CampaignResponse, error) {
+	err := s.validator.Validate(request)
+	if err != nil {
+		return CampaignResponse{}, common.RequestValidationError{Message: err.Error()}
+	}
+	var campaign = request.ToEntity()
+	err = database.InTransaction(s.repo.BeginTransaction, "creating campaign", func(tx *sqlx.Tx) error {
+		campaign.Id, err = s.repo.SaveCampaign(tx, campaign)
+		if err != nil {
+			return fmt.Errorf("error saving campaign: %w", err)
+		}
+		campaign.Total, err = s.repo.GenerateItems(tx, campaign)
+		if err != nil {
+			return fmt.Errorf("error generating campaign items: %w", err)
+		}
+		campaign.Pending = campaign.Total
+		return nil
+	})
+	if err != nil {
+		return CampaignResponse{}, err
+	}
+	return campaign.toResponse(), nil
+}
+
+func (s *Service) FindById(request IdRequest) (CampaignResponse, error) {
+	var err = s.validator.Validate(request)
+	if err != nil {
+		return CampaignResponse{}, common.RequestValidationError{Message: err.Error()}
+	}
+	entity, err := s.repo.FindCampaignById(request.Id)
+	if err != nil {
+		return CampaignResponse{}, common.NotFoundError{Message: fmt.Sprintf("error finding campaign with id %d: %v", request.Id, err)}
+	}
+	return entity.toResponse(), nil
+}
+
+func (s *Service) FindAll() ([]CampaignResponse, error) {
+	var campaigns, err = s.repo.FindCampaigns()
+	if err != nil {
+		return nil, common.NotFoundError{Message: fmt.Sprintf("error finding campaigns: %v", err)}
+	}
+	var response []CampaignResponse
+	for _, campaign := range campaigns {
+		response = append(response, campaign.toResponse())
+	}
+	return response, nil
+}
+
+func (s *Service) FindItems(request ItemsRequest) ([]ItemResponse, error) {
+	if err := s.validator.Validate(request); err != nil {
+		return nil, common.RequestValidationError{Message: err.Error()}
+	}
+	var items, err = s.repo.FindItems(request.CampaignId, request.Reviewer)
+	if err != nil {
+		return nil, common.NotFoundError{Message: fmt.Sprintf("error finding items of campaign %d: %v", request.CampaignId, err)}
+	}
+	var response []ItemResponse
+	for _, item := range items {
+		response = append(response, item.toResponse())
+	}
+	return response, nil
+}
+
+// Decide сохранить решение рецензента по элементу; роль отзывается только при закрытии кампании.
+// Основную роль сотрудника отозвать нельзя, такое решение отклоняется
+func (s *Service) Decide(ctx context.Context, request DecisionRequest) (ItemResponse, error) {
+	err := s.validator.Validate(request)
+	if err != nil {
+		return ItemResponse{}, common.RequestValidationError{Message: err.Error()}
+	}
+	var item ItemEntity
+	err = database.InTransaction(s.repo.BeginTransaction, "deciding campaign item", func(tx *sqlx.Tx) error {
+		item, err = s.repo.FindItemForUpdate(tx, request.ItemId)
+		if err != nil {
+			return common.NotFoundError{Message: fmt.Sprintf("error finding campaign item with id %d: %v", request.ItemId, err)}
+		}
+		if item.Reviewer != request.Actor && !s.isAdmin(request.ActorRoles) {
+			return common.ForbiddenError{Message: "campaign item is assigned to another reviewer"}
+		}
+		campaign, err := s.repo.FindCampaignByIdForUpdate(tx, item.CampaignId)
+		if err != nil {
+			return fmt.Errorf("error finding campaign with id %d: %w", item.CampaignId, err)
+		}
+		if campaign.State != StateOpen {
+			return common.RequestValidationError{Message: fmt.Sprintf("campaign %d is already closed", campaign.Id)}
+		}
+		if request.Decision == DecisionRevoke {
+			primary, err := s.assignments.IsPrimary(tx, item.EmployeeId, item.RoleId)
+			if err != nil {
+				return fmt.Errorf("error checking primary role of employee %d: %w", item.EmployeeId, err)
+			}
+			if primary {
+				return common.RequestValidationError{Message: fmt.Sprintf(
+					"role %d is the primary role of employee %d and can not be revoked", item.RoleId, item.EmployeeId)}
+			}
+		}
+		err = s.repo.UpdateItemDecision(tx, item.Id, request.Decision, request.Comment, request.Actor)
+		if err != nil {
+			return fmt.Errorf("error updating campaign item decision: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return ItemResponse{}, err
+	}
+	item.Decision = request.Decision
+	item.Comment = request.Comment
+	item.DecidedBy = request.Actor
+	return item.toResponse(), nil
+}
+
+// Close закрыть кампанию и отозвать роли по всем элементам с решением revoke.
+// Если роль успела стать основной ролью сотрудника, она не отзывается и элемент остаётся с applied = false
+func (s *Service) Close(ctx context.Context, request CloseRequest) (CampaignResponse, error) {
+	err := s.validator.Validate(request)
+	if err != nil {
+		return CampaignResponse{}, common.RequestValidationError{Message: err.Error()}
+	}
+	err = database.InTransaction(s.repo.BeginTransaction, "closing campaign", func(tx *sqlx.Tx) error {
+		campaign, err := s.repo.FindCampaignByIdForUpdate(tx, request.Id)
+		if err != nil {
+			return common.NotFoundError{Message: fmt.Sprintf("error finding campaign with id %d: %v", request.Id, err)}
+		}
+		if campaign.State != StateOpen {
+			return common.RequestValidationError{Message: fmt.Sprintf("campaign %d is already closed", campaign.Id)}
+		}
+		items, err := s.repo.FindItemsByDecision(tx, campaign.Id, DecisionRevoke)
+		if err != nil {
+			return fmt.Errorf("error finding revoked campaign items: %w", err)
+		}
+		for _, item := range items {
+			primary, err := s.assignments.IsPrimary(tx, item.EmployeeId, item.RoleId)
+			if err != nil {
+				return fmt.Errorf("error checking primary role of employee %d: %w", item.EmployeeId, err)
+			}
+			if primary {
+				continue
+			}
+			if err = s.assignments.Revoke(tx, item.EmployeeId, item.RoleId); err != nil {
+				return fmt.Errorf("error revoking role %d from employee %d: %w", item.RoleId, item.EmployeeId, err)
+			}
+			if err = s.repo.MarkItemApplied(tx, item.Id); err != nil {
+				return fmt.Errorf("error marking campaign item applied: %w", err)
+			}
+		}
+		if err = s.repo.CloseCampaign(tx, campaign.Id, request.Actor); err != nil {
+			return fmt.Errorf("error closing campaign: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return CampaignResponse{}, err
+	}
+	return s.FindById(IdRequest{Id: request.Id})
+}
+
+// CloseOverdue закрыть кампании, срок которых истёк. Ошибка одной кампании не мешает закрыть остальные,
+// ошибки всех кампаний возвращаются вместе и попадают в журнал фоновой задачи
+func (s *Service) CloseOverdue(ctx context.Context) error {
+	ids, err := s.repo.FindOverdueCampaigns(time.Now())
+	if err != nil {
+		return fmt.Errorf("error finding overdue campaigns: %w", err)
+	}
+	var errs []error
+	for _, id := range ids {
+		if _, err = s.Close(ctx, CloseRequest{Id: id, Actor: "system"}); err != nil {
+			errs = append(errs, fmt.Errorf("error closing overdue campaign %d: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Service) isAdmin(roles []string) bool {
+	for _, role := range roles {
+		if slices.Contains(s.adminRoles, role) {
+			return true
+		}
+	}
+	return false
+}
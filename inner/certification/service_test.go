@@ -0,0 +1,302 @@
+package certification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"idm/inner/common"
+	"idm/inner/database/dbtest"
+	"idm/inner/validator"
+	"testing"
+	"time"
+)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+func (r *MockRepo) BeginTransaction() (*sqlx.Tx, error) {
+	args := r.Called()
+	return args.Get(0).(*sqlx.Tx), args.Error(1)
+}
+
+func (r *MockRepo) SaveCampaign(tx *sqlx.Tx, e CampaignEntity) (int64, error) {
+	args := r.Called(tx, e)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (r *MockRepo) GenerateItems(tx *sqlx.Tx, campaign CampaignEntity) (int64, error) {
+	args := r.Called(tx, campaign)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (r *MockRepo) FindCampaignById(id int64) (CampaignEntity, error) {
+	args := r.Called(id)
+	return args.Get(0).(CampaignEntity), args.Error(1)
+}
+
+func (r *MockRepo) FindCampaignByIdForUpdate(tx *sqlx.Tx, id int64) (CampaignEntity, error) {
+	args := r.Called(tx, id)
+	return args.Get(0).(CampaignEntity), args.Error(1)
+}
+
+func (r *MockRepo) FindCampaigns() ([]CampaignEntity, error) {
+	args := r.Called()
+	return args.Get(0).([]CampaignEntity), args.Error(1)
+}
+
+func (r *MockRepo) FindOverdueCampaigns(now time.Time) ([]int64, error) {
+	args := r.Called(now)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (r *MockRepo) CloseCampaign(tx *sqlx.Tx, id int64, closedBy string) error {
+	args := r.Called(tx, id, closedBy)
+	return args.Error(0)
+}
+
+func (r *MockRepo) FindItems(campaignId int64, reviewer string) ([]ItemEntity, error) {
+	args := r.Called(campaignId, reviewer)
+	return args.Get(0).([]ItemEntity), args.Error(1)
+}
+
+func (r *MockRepo) FindItemForUpdate(tx *sqlx.Tx, id int64) (ItemEntity, error) {
+	args := r.Called(tx, id)
+	return args.Get(0).(ItemEntity), args.Error(1)
+}
+
+func (r *MockRepo) FindItemsByDecision(tx *sqlx.Tx, campaignId int64, decision string) ([]ItemEntity, error) {
+	args := r.Called(tx, campaignId, decision)
+	return args.Get(0).([]ItemEntity), args.Error(1)
+}
+
+func (r *MockRepo) UpdateItemDecision(tx *sqlx.Tx, id int64, decision string, comment string, decidedBy string) error {
+	args := r.Called(tx, id, decision, comment, decidedBy)
+	return args.Error(0)
+}
+
+func (r *MockRepo) MarkItemApplied(tx *sqlx.Tx, id int64) error {
+	args := r.Called(tx, id)
+	return args.Error(0)
+}
+
+type MockAssignmentRepo struct {
+	mock.Mock
+}
+
+func (r *MockAssignmentRepo) IsPrimary(tx *sqlx.Tx, employeeId int64, roleId int64) (bool, error) {
+	args := r.Called(tx, employeeId, roleId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (r *MockAssignmentRepo) Revoke(tx *sqlx.Tx, employeeId int64, roleId int64) error {
+	args := r.Called(tx, employeeId, roleId)
+	return args.Error(0)
+}
+
+var adminRoles = []string{"IDM_ADMIN"}
+
+func TestCreate(t *testing.T) {
+	var request = CreateRequest{
+		Name:       "Q3 review",
+		ScopeType:  ScopeDepartment,
+		ScopeValue: "Engineering",
+		Reviewer:   "reviewer-1",
+		DueAt:      time.Now().Add(time.Hour),
+		CreatedBy:  "admin-1",
+	}
+	t.Run("should create campaign with generated items", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, true)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockAssignmentRepo), validator.New(), adminRoles)
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("SaveCampaign", tx, request.ToEntity()).Return(int64(3), nil)
+		repo.On("GenerateItems", tx, mock.MatchedBy(func(c CampaignEntity) bool {
+			return c.Id == 3 && c.ScopeValue == "Engineering"
+		})).Return(int64(12), nil)
+		got, err := svc.Create(context.Background(), request)
+		a.Nil(err)
+		a.Equal(int64(3), got.Id)
+		a.Equal(StateOpen, got.State)
+		a.Equal(Progress{Total: 12, Pending: 12}, got.Progress)
+	})
+	t.Run("should return validation error for unknown scope", func(t *testing.T) {
+		a := assert.New(t)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockAssignmentRepo), validator.New(), adminRoles)
+		var invalid = request
+		invalid.ScopeType = "location"
+		_, err := svc.Create(context.Background(), invalid)
+		a.True(errors.As(err, &common.RequestValidationError{}))
+		a.True(repo.AssertNumberOfCalls(t, "BeginTransaction", 0))
+	})
+}
+
+func TestDecide(t *testing.T) {
+	var item = ItemEntity{Id: 7, CampaignId: 3, EmployeeId: 1, RoleId: 2, Reviewer: "reviewer-1", Decision: DecisionPending}
+	t.Run("should save decision of assigned reviewer", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, true)
+		var repo = new(MockRepo)
+		var assignments = new(MockAssignmentRepo)
+		var svc = NewService(repo, assignments, validator.New(), adminRoles)
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("FindItemForUpdate", tx, int64(7)).Return(item, nil)
+		repo.On("FindCampaignByIdForUpdate", tx, int64(3)).Return(CampaignEntity{Id: 3, State: StateOpen}, nil)
+		assignments.On("IsPrimary", tx, int64(1), int64(2)).Return(false, nil)
+		repo.On("UpdateItemDecision", tx, int64(7), DecisionRevoke, "left the team", "reviewer-1").Return(nil)
+		got, err := svc.Decide(context.Background(), DecisionRequest{
+			ItemId:   7,
+			Decision: DecisionRevoke,
+			Comment:  "left the team",
+			Actor:    "reviewer-1",
+		})
+		a.Nil(err)
+		a.Equal(DecisionRevoke, got.Decision)
+		a.False(got.Applied)
+	})
+	t.Run("should reject revoking primary role", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, false)
+		var repo = new(MockRepo)
+		var assignments = new(MockAssignmentRepo)
+		var svc = NewService(repo, assignments, validator.New(), adminRoles)
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("FindItemForUpdate", tx, int64(7)).Return(item, nil)
+		repo.On("FindCampaignByIdForUpdate", tx, int64(3)).Return(CampaignEntity{Id: 3, State: StateOpen}, nil)
+		assignments.On("IsPrimary", tx, int64(1), int64(2)).Return(true, nil)
+		_, err := svc.Decide(context.Background(), DecisionRequest{ItemId: 7, Decision: DecisionRevoke, Actor: "reviewer-1"})
+		a.Equal(common.RequestValidationError{Message: "role 2 is the primary role of employee 1 and can not be revoked"}, err)
+		a.True(repo.AssertNumberOfCalls(t, "UpdateItemDecision", 0))
+	})
+	t.Run("should forbid decision of another reviewer", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, false)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockAssignmentRepo), validator.New(), adminRoles)
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("FindItemForUpdate", tx, int64(7)).Return(item, nil)
+		_, err := svc.Decide(context.Background(), DecisionRequest{ItemId: 7, Decision: DecisionKeep, Actor: "user-2"})
+		a.True(errors.As(err, &common.ForbiddenError{}))
+		a.True(repo.AssertNumberOfCalls(t, "UpdateItemDecision", 0))
+	})
+	t.Run("should reject decision in closed campaign", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, false)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockAssignmentRepo), validator.New(), adminRoles)
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("FindItemForUpdate", tx, int64(7)).Return(item, nil)
+		repo.On("FindCampaignByIdForUpdate", tx, int64(3)).Return(CampaignEntity{Id: 3, State: StateClosed}, nil)
+		_, err := svc.Decide(context.Background(), DecisionRequest{
+			ItemId:     7,
+			Decision:   DecisionKeep,
+			Actor:      "admin-1",
+			ActorRoles: adminRoles,
+		})
+		a.Equal(common.RequestValidationError{Message: "campaign 3 is already closed"}, err)
+	})
+}
+
+func TestClose(t *testing.T) {
+	t.Run("should revoke roles of revoked items and close campaign", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, true)
+		var repo = new(MockRepo)
+		var assignments = new(MockAssignmentRepo)
+		var svc = NewService(repo, assignments, validator.New(), adminRoles)
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("FindCampaignByIdForUpdate", tx, int64(3)).Return(CampaignEntity{Id: 3, State: StateOpen}, nil)
+		repo.On("FindItemsByDecision", tx, int64(3), DecisionRevoke).Return([]ItemEntity{
+			{Id: 7, EmployeeId: 1, RoleId: 2},
+			{Id: 8, EmployeeId: 4, RoleId: 2},
+			{Id: 9, EmployeeId: 5, RoleId: 2},
+		}, nil)
+		assignments.On("IsPrimary", tx, int64(1), int64(2)).Return(false, nil)
+		assignments.On("IsPrimary", tx, int64(4), int64(2)).Return(false, nil)
+		assignments.On("IsPrimary", tx, int64(5), int64(2)).Return(true, nil)
+		assignments.On("Revoke", tx, int64(1), int64(2)).Return(nil)
+		assignments.On("Revoke", tx, int64(4), int64(2)).Return(nil)
+		repo.On("MarkItemApplied", tx, mock.AnythingOfType("int64")).Return(nil)
+		repo.On("CloseCampaign", tx, int64(3), "admin-1").Return(nil)
+		repo.On("FindCampaignById", int64(3)).Return(CampaignEntity{
+			Id:       3,
+			State:    StateClosed,
+			Progress: Progress{Total: 5, Kept: 3, Revoked: 2},
+		}, nil)
+		got, err := svc.Close(context.Background(), CloseRequest{Id: 3, Actor: "admin-1"})
+		a.Nil(err)
+		a.Equal(StateClosed, got.State)
+		a.Equal(int64(2), got.Progress.Revoked)
+		a.True(assignments.AssertNumberOfCalls(t, "Revoke", 2))
+		a.True(repo.AssertNumberOfCalls(t, "MarkItemApplied", 2))
+	})
+	t.Run("should rollback when revoke fails", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, false)
+		var repo = new(MockRepo)
+		var assignments = new(MockAssignmentRepo)
+		var svc = NewService(repo, assignments, validator.New(), adminRoles)
+		var dbErr = errors.New("database error")
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("FindCampaignByIdForUpdate", tx, int64(3)).Return(CampaignEntity{Id: 3, State: StateOpen}, nil)
+		repo.On("FindItemsByDecision", tx, int64(3), DecisionRevoke).Return([]ItemEntity{
+			{Id: 7, EmployeeId: 1, RoleId: 2},
+		}, nil)
+		assignments.On("IsPrimary", tx, int64(1), int64(2)).Return(false, nil)
+		assignments.On("Revoke", tx, int64(1), int64(2)).Return(dbErr)
+		_, err := svc.Close(context.Background(), CloseRequest{Id: 3, Actor: "admin-1"})
+		a.Equal(fmt.Errorf("error revoking role %d from employee %d: %w", 2, 1, dbErr), err)
+		a.True(repo.AssertNumberOfCalls(t, "CloseCampaign", 0))
+	})
+	t.Run("should not close already closed campaign", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, false)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockAssignmentRepo), validator.New(), adminRoles)
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("FindCampaignByIdForUpdate", tx, int64(3)).Return(CampaignEntity{Id: 3, State: StateClosed}, nil)
+		_, err := svc.Close(context.Background(), CloseRequest{Id: 3, Actor: "admin-1"})
+		a.Equal(common.RequestValidationError{Message: "campaign 3 is already closed"}, err)
+	})
+}
+
+func TestCloseOverdue(t *testing.T) {
+	var a = assert.New(t)
+	var failed, closed = dbtest.NewTx(t, false), dbtest.NewTx(t, true)
+	var repo = new(MockRepo)
+	var svc = NewService(repo, new(MockAssignmentRepo), validator.New(), adminRoles)
+	var dbErr = errors.New("database error")
+	repo.On("FindOverdueCampaigns", mock.Anything).Return([]int64{3, 4}, nil)
+	repo.On("BeginTransaction").Return(failed, nil).Once()
+	repo.On("BeginTransaction").Return(closed, nil).Once()
+	repo.On("FindCampaignByIdForUpdate", failed, int64(3)).Return(CampaignEntity{Id: 3, State: StateOpen}, nil)
+	repo.On("FindItemsByDecision", failed, int64(3), DecisionRevoke).Return([]ItemEntity{}, dbErr)
+	repo.On("FindCampaignByIdForUpdate", closed, int64(4)).Return(CampaignEntity{Id: 4, State: StateOpen}, nil)
+	repo.On("FindItemsByDecision", closed, int64(4), DecisionRevoke).Return([]ItemEntity{}, nil)
+	repo.On("CloseCampaign", closed, int64(4), "system").Return(nil)
+	repo.On("FindCampaignById", int64(4)).Return(CampaignEntity{Id: 4, State: StateClosed}, nil)
+	var err = svc.CloseOverdue(context.Background())
+	a.ErrorIs(err, dbErr)
+	a.Contains(err.Error(), "campaign 3")
+	a.True(repo.AssertCalled(t, "CloseCampaign", closed, int64(4), "system"))
+}
+
+func TestFindAll(t *testing.T) {
+	t.Run("should return campaigns with progress", func(t *testing.T) {
+		a := assert.New(t)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockAssignmentRepo), validator.New(), adminRoles)
+		repo.On("FindCampaigns").Return([]CampaignEntity{
+			{Id: 1, Progress: Progress{Total: 2, Pending: 1, Kept: 1}},
+		}, nil)
+		got, err := svc.FindAll()
+		a.Nil(err)
+		a.Len(got, 1)
+		a.Equal(int64(1), got[0].Progress.Pending)
+	})
+}
@@ -8,6 +8,10 @@ import (
 	"github.com/joho/godotenv"
 	"go.uber.org/zap"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -17,26 +21,156 @@ type Config struct {
 	AppVersion     string `validate:"required"`
 	LogLevel       string
 	LogDevelopMode bool
-	SslSert        string `validate:"required"`
-	SslKey         string `validate:"required"`
-	KeycloakJwkUrl string `validate:"required"`
+	// LogRedactFields имена полей, значения которых не пишутся в лог
+	LogRedactFields []string
+	// Production промышленный режим: в нём запрещены небезопасные для разработки настройки
+	Production bool
+	SslSert    string `validate:"required"`
+	SslKey     string `validate:"required"`
+	// TlsClientCa PEM-файл с сертификатами CA, которыми проверяются клиентские сертификаты; если не задан, mTLS выключен
+	TlsClientCa string
+	// TlsClientCertRequired отклонять TLS-соединения без клиентского сертификата
+	TlsClientCertRequired bool `validate:"excluded_without=TlsClientCa"`
+	// TlsClientIdentities JSON-файл с сопоставлением сертификатов сервисным учётным записям и ролям
+	TlsClientIdentities string `validate:"excluded_without=TlsClientCa"`
+	KeycloakJwkUrl      string `validate:"required_if=AuthMode keycloak"`
+	// AuthMode keycloak - токены проверяются ключами Keycloak, dev - локальным ключом DevAuthKey без Keycloak,
+	// introspection - токены (в том числе непрозрачные) проверяются запросом к IntrospectionUrl
+	AuthMode string `validate:"oneof=keycloak dev introspection"`
+	// DevAuthKey ключ подписи токенов в режиме dev
+	DevAuthKey string `validate:"required_if=AuthMode dev,omitempty,min=32"`
+	// IntrospectionUrl адрес token introspection (RFC 7662) сервера авторизации
+	IntrospectionUrl string `validate:"required_if=AuthMode introspection,omitempty,url"`
+	// IntrospectionClientId и IntrospectionClientSecret учётные данные IDM для запросов introspection
+	IntrospectionClientId     string `validate:"required_if=AuthMode introspection"`
+	IntrospectionClientSecret string `validate:"required_if=AuthMode introspection"`
+	// KeycloakIssuers допустимые значения iss в токене; если не заданы, издатель не проверяется
+	KeycloakIssuers []string
+	// KeycloakAudience значение, которое должно быть в aud токена; если не задано, аудитория не проверяется
+	KeycloakAudience string
+	// JwtAlgorithms допустимые алгоритмы подписи токенов
+	JwtAlgorithms []string `validate:"min=1"`
+	// JwtLeeway допустимое расхождение часов при проверке exp, nbf и iat
+	JwtLeeway time.Duration
+	// RoleClaims пути к ролям в токене через точку, например realm_access.roles,resource_access.idm.roles,groups
+	RoleClaims []string `validate:"min=1"`
+	// RolePrefix префикс, который отрезается от ролей из токена
+	RolePrefix string
+	// RoleRenames переименование ролей из токена в роли IDM, например admins=IDM_ADMIN,users=IDM_USER
+	RoleRenames map[string]string
+	// RateLimitStore где хранить лимиты запросов: memory - в памяти реплики, postgres - в базе, общие для всех реплик
+	RateLimitStore string `validate:"oneof=memory postgres"`
+	// RateLimitApiRead, RateLimitApiWrite и RateLimitApiBulk лимиты /api по классам запросов вида "600/1m";
+	// off - без лимита
+	RateLimitApiRead  string
+	RateLimitApiWrite string
+	RateLimitApiBulk  string
+	// RateLimitApiIp лимит /api по IP до аутентификации, чтобы запросы без токена или с неверным токеном
+	// тоже ограничивались; off - без лимита
+	RateLimitApiIp string
+	// RateLimitInternal лимит /internal по IP; если не задан, без лимита
+	RateLimitInternal string
+	// CorsAllowOrigins источники, которым разрешены запросы из браузера; если не заданы, CORS выключен
+	CorsAllowOrigins     []string
+	CorsAllowMethods     []string
+	CorsAllowHeaders     []string
+	CorsAllowCredentials bool
+	// CorsMaxAge сколько браузер может кешировать ответ на preflight-запрос
+	CorsMaxAge time.Duration
+	// HstsMaxAge срок Strict-Transport-Security для HTTPS-ответов; 0 - заголовок не отправляется
+	HstsMaxAge time.Duration
+	// ContentSecurityPolicy значение Content-Security-Policy; по умолчанию разрешает то, что нужно Swagger UI
+	ContentSecurityPolicy string
+	// BodyLimit наибольший размер тела запроса в байтах
+	BodyLimit int `validate:"min=0"`
+	// HeaderLimit наибольший размер заголовков запроса в байтах
+	HeaderLimit int `validate:"min=0"`
+	// ReadTimeout, WriteTimeout и IdleTimeout таймауты чтения запроса, записи ответа и ожидания следующего запроса
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// AccessApproverGroup роль из токена, владельцы которой могут согласовывать заявки на доступ
+	AccessApproverGroup string
+	// AccessRequestTtl время, через которое несогласованная заявка на доступ истекает
+	AccessRequestTtl time.Duration `validate:"gt=0"`
+	// PolicyFile путь к файлу политик доступа; если не задан, действуют только проверки ролей
+	PolicyFile string
+	// PolicyReloadInterval как часто проверять изменения файла политик
+	PolicyReloadInterval time.Duration `validate:"gt=0"`
+	// BreakGlassRole имя привилегированной роли для экстренного доступа; если не задано, экстренный доступ выключен
+	BreakGlassRole string
+	// BreakGlassGroup роль из токена, владельцы которой (дежурные) могут запросить экстренный доступ
+	BreakGlassGroup string
+	// BreakGlassWindow время, через которое экстренный доступ отзывается автоматически
+	BreakGlassWindow time.Duration `validate:"gt=0"`
+	// AccountDormantAfter через сколько времени без входа активная учётная запись в приложении считается неиспользуемой
+	AccountDormantAfter time.Duration
 }
 
+var defaultCorsHeaders = []string{"Authorization", "Content-Type", "X-Api-Key", "X-Request-ID"}
+
+// defaultContentSecurityPolicy Swagger UI использует встроенные скрипты, стили и картинки data:
+const defaultContentSecurityPolicy = "default-src 'self'; script-src 'self' 'unsafe-inline'; " +
+	"style-src 'self' 'unsafe-inline'; img-src 'self' data:"
+
 func GetConfig(envFile string) Config {
 	var err = godotenv.Load(envFile)
 	if err != nil {
 		log.Infof(fmt.Sprintf("Error loading .env file: %v\n", zap.Error(err)))
 	}
 	var cfg = Config{
-		DbDriverName:   os.Getenv("DB_DRIVER_NAME"),
-		Dsn:            os.Getenv("DB_DSN"),
-		AppName:        os.Getenv("APP_NAME"),
-		AppVersion:     os.Getenv("APP_VERSION"),
-		LogLevel:       os.Getenv("LOG_LEVEL"),
-		LogDevelopMode: os.Getenv("LOG_DEVELOP_MODE") == "true",
-		SslSert:        os.Getenv("SSL_SERT"),
-		SslKey:         os.Getenv("SSL_KEY"),
-		KeycloakJwkUrl: os.Getenv("KEYCLOAK_JWK_URL"),
+		DbDriverName:              os.Getenv("DB_DRIVER_NAME"),
+		Dsn:                       os.Getenv("DB_DSN"),
+		AppName:                   os.Getenv("APP_NAME"),
+		AppVersion:                os.Getenv("APP_VERSION"),
+		LogLevel:                  os.Getenv("LOG_LEVEL"),
+		LogDevelopMode:            os.Getenv("LOG_DEVELOP_MODE") == "true",
+		LogRedactFields:           getListOrDefault("LOG_REDACT_FIELDS", DefaultLogRedactFields),
+		Production:                os.Getenv("APP_PRODUCTION") == "true",
+		SslSert:                   os.Getenv("SSL_SERT"),
+		SslKey:                    os.Getenv("SSL_KEY"),
+		TlsClientCa:               os.Getenv("TLS_CLIENT_CA"),
+		TlsClientCertRequired:     os.Getenv("TLS_CLIENT_CERT_REQUIRED") == "true",
+		TlsClientIdentities:       os.Getenv("TLS_CLIENT_IDENTITIES"),
+		KeycloakJwkUrl:            os.Getenv("KEYCLOAK_JWK_URL"),
+		AuthMode:                  getEnvOrDefault("AUTH_MODE", "keycloak"),
+		DevAuthKey:                os.Getenv("DEV_AUTH_KEY"),
+		IntrospectionUrl:          os.Getenv("INTROSPECTION_URL"),
+		IntrospectionClientId:     os.Getenv("INTROSPECTION_CLIENT_ID"),
+		IntrospectionClientSecret: os.Getenv("INTROSPECTION_CLIENT_SECRET"),
+		KeycloakIssuers:           getListOrDefault("KEYCLOAK_ISSUERS", nil),
+		KeycloakAudience:          os.Getenv("KEYCLOAK_AUDIENCE"),
+		JwtAlgorithms:             getListOrDefault("JWT_ALGORITHMS", []string{"RS256"}),
+		JwtLeeway:                 getDurationOrDefault("JWT_LEEWAY", 0),
+		RoleClaims:                getListOrDefault("ROLE_CLAIMS", []string{"realm_access.roles"}),
+		RolePrefix:                os.Getenv("ROLE_PREFIX"),
+		RoleRenames:               getMapOrDefault("ROLE_RENAMES", nil),
+		RateLimitStore:            getEnvOrDefault("RATE_LIMIT_STORE", "memory"),
+		RateLimitApiRead:          getEnvOrDefault("RATE_LIMIT_API_READ", "600/1m"),
+		RateLimitApiWrite:         getEnvOrDefault("RATE_LIMIT_API_WRITE", "120/1m"),
+		RateLimitApiBulk:          getEnvOrDefault("RATE_LIMIT_API_BULK", "10/1m"),
+		RateLimitApiIp:            getEnvOrDefault("RATE_LIMIT_API_IP", "1200/1m"),
+		RateLimitInternal:         os.Getenv("RATE_LIMIT_INTERNAL"),
+		CorsAllowOrigins:          getListOrDefault("CORS_ALLOW_ORIGINS", nil),
+		CorsAllowMethods:          getListOrDefault("CORS_ALLOW_METHODS", []string{"GET", "POST", "PUT", "DELETE"}),
+		CorsAllowHeaders:          getListOrDefault("CORS_ALLOW_HEADERS", defaultCorsHeaders),
+		CorsAllowCredentials:      os.Getenv("CORS_ALLOW_CREDENTIALS") == "true",
+		CorsMaxAge:                getDurationOrDefault("CORS_MAX_AGE", 10*time.Minute),
+		HstsMaxAge:                getDurationOrDefault("HSTS_MAX_AGE", 365*24*time.Hour),
+		ContentSecurityPolicy:     getEnvOrDefault("CONTENT_SECURITY_POLICY", defaultContentSecurityPolicy),
+		BodyLimit:                 getIntOrDefault("BODY_LIMIT", 4*1024*1024),
+		HeaderLimit:               getIntOrDefault("HEADER_LIMIT", 8*1024),
+		ReadTimeout:               getDurationOrDefault("READ_TIMEOUT", 30*time.Second),
+		WriteTimeout:              getDurationOrDefault("WRITE_TIMEOUT", 30*time.Second),
+		IdleTimeout:               getDurationOrDefault("IDLE_TIMEOUT", 2*time.Minute),
+		AccessApproverGroup:       getEnvOrDefault("ACCESS_APPROVER_GROUP", "IDM_APPROVER"),
+		AccessRequestTtl:          getDurationOrDefault("ACCESS_REQUEST_TTL", 7*24*time.Hour),
+		PolicyFile:                os.Getenv("POLICY_FILE"),
+		PolicyReloadInterval:      getDurationOrDefault("POLICY_RELOAD_INTERVAL", 30*time.Second),
+		BreakGlassRole:            os.Getenv("BREAK_GLASS_ROLE"),
+		BreakGlassGroup:           getEnvOrDefault("BREAK_GLASS_GROUP", "IDM_ON_CALL"),
+		BreakGlassWindow:          getDurationOrDefault("BREAK_GLASS_WINDOW", time.Hour),
+		AccountDormantAfter:       getDurationOrDefault("ACCOUNT_DORMANT_AFTER", 90*24*time.Hour),
 	}
 	err = validator.New().Struct(cfg)
 	if err != nil {
@@ -45,5 +179,96 @@ func GetConfig(envFile string) Config {
 			panic(fmt.Sprintf("config validation error: %v", err))
 		}
 	}
+	if cfg.Production && cfg.AuthMode == "dev" {
+		panic("config validation error: dev auth mode is not allowed in production")
+	}
+	if cfg.CorsAllowCredentials && slices.Contains(cfg.CorsAllowOrigins, "*") {
+		panic("config validation error: CORS credentials are not allowed for any origin")
+	}
 	return cfg
 }
+
+// GetTokenConfig настройки, нужные только для выпуска токенов режима разработки: AUTH_MODE, DEV_AUTH_KEY,
+// KEYCLOAK_AUDIENCE и ROLE_CLAIMS. База данных, сертификаты и прочие настройки сервера не требуются
+func GetTokenConfig(envFile string) Config {
+	var err = godotenv.Load(envFile)
+	if err != nil {
+		log.Infof(fmt.Sprintf("Error loading .env file: %v\n", zap.Error(err)))
+	}
+	var cfg = Config{
+		AuthMode:         getEnvOrDefault("AUTH_MODE", "keycloak"),
+		DevAuthKey:       os.Getenv("DEV_AUTH_KEY"),
+		KeycloakAudience: os.Getenv("KEYCLOAK_AUDIENCE"),
+		RoleClaims:       getListOrDefault("ROLE_CLAIMS", []string{"realm_access.roles"}),
+	}
+	err = validator.New().StructPartial(cfg, "AuthMode", "DevAuthKey", "RoleClaims")
+	if err != nil {
+		var validateErrs validator.ValidationErrors
+		if errors.As(err, &validateErrs) {
+			panic(fmt.Sprintf("config validation error: %v", err))
+		}
+	}
+	return cfg
+}
+
+func getEnvOrDefault(key string, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// getListOrDefault значения через запятую
+func getListOrDefault(key string, defaultValue []string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return defaultValue
+	}
+	return values
+}
+
+// getMapOrDefault пары ключ=значение через запятую
+func getMapOrDefault(key string, defaultValue map[string]string) map[string]string {
+	var pairs = getListOrDefault(key, nil)
+	if len(pairs) == 0 {
+		return defaultValue
+	}
+	var values = make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		name, value, found := strings.Cut(pair, "=")
+		if !found || strings.TrimSpace(name) == "" {
+			panic(fmt.Sprintf("config validation error: invalid pair %s=%s: expected name=value", key, pair))
+		}
+		values[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return values
+}
+
+func getIntOrDefault(key string, defaultValue int) int {
+	var value = os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		panic(fmt.Sprintf("config validation error: invalid number %s=%s: %v", key, value, err))
+	}
+	return number
+}
+
+func getDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	var value = os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		panic(fmt.Sprintf("config validation error: invalid duration %s=%s: %v", key, value, err))
+	}
+	return duration
+}
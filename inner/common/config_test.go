@@ -4,6 +4,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"os"
 	"testing"
+	"time"
 )
 
 const dsn = "host=127.0.0.1 port=5432 user=postgres password=postgres dbname=postgres sslmode=disable"
@@ -33,7 +34,7 @@ func TestEnvFileExistHaventVarsThenGetEmptyConfig(t *testing.T) {
 			"'required' tag\nKey: 'Config.AppVersion' Error:Field validation for 'AppVersion' failed on the "+
 			"'required' tag\nKey: 'Config.SslSert' Error:Field validation for 'SslSert' failed on the 'required' tag"+
 			"\nKey: 'Config.SslKey' Error:Field validation for 'SslKey' failed on the 'required' tag\n"+
-			"Key: 'Config.KeycloakJwkUrl' Error:Field validation for 'KeycloakJwkUrl' failed on the 'required' tag", r)
+			"Key: 'Config.KeycloakJwkUrl' Error:Field validation for 'KeycloakJwkUrl' failed on the 'required_if' tag", r)
 	}()
 	t.Setenv("DB_DRIVER_NAME", "")
 	t.Setenv("DB_DSN", "")
@@ -82,6 +83,172 @@ func TestEnvFileExistHaveVarsInEnvFileAndEnvVarsThenGetValidConfig(t *testing.T)
 	assert.Equal(t, dsn, config.Dsn)
 }
 
+func TestTokenValidationSettings(t *testing.T) {
+	var a = assert.New(t)
+	file := createEnvFile(t, "DB_DRIVER_NAME=postgres\nDB_DSN=random_dsn\nAPP_NAME=idm\nAPP_VERSION=1.0.0\n"+
+		"SSL_SERT=certs/ssl.cert\nSSL_KEY=certs/ssl.key\nKEYCLOAK_JWK_URL=http://localhost:9990/realms/")
+	defer os.Remove(file)
+	t.Run("defaults", func(t *testing.T) {
+		config := GetConfig(file)
+		a.Empty(config.KeycloakIssuers)
+		a.Empty(config.KeycloakAudience)
+		a.Equal([]string{"RS256"}, config.JwtAlgorithms)
+		a.Zero(config.JwtLeeway)
+	})
+	t.Run("from env vars", func(t *testing.T) {
+		t.Setenv("KEYCLOAK_ISSUERS", "https://sso.example.com/realms/idm, https://sso2.example.com/realms/idm")
+		t.Setenv("KEYCLOAK_AUDIENCE", "idm")
+		t.Setenv("JWT_ALGORITHMS", "RS256,ES256")
+		t.Setenv("JWT_LEEWAY", "30s")
+		config := GetConfig(file)
+		a.Equal([]string{"https://sso.example.com/realms/idm", "https://sso2.example.com/realms/idm"}, config.KeycloakIssuers)
+		a.Equal("idm", config.KeycloakAudience)
+		a.Equal([]string{"RS256", "ES256"}, config.JwtAlgorithms)
+		a.Equal(30*time.Second, config.JwtLeeway)
+	})
+}
+
+func TestRoleMappingSettings(t *testing.T) {
+	var a = assert.New(t)
+	file := createEnvFile(t, "DB_DRIVER_NAME=postgres\nDB_DSN=random_dsn\nAPP_NAME=idm\nAPP_VERSION=1.0.0\n"+
+		"SSL_SERT=certs/ssl.cert\nSSL_KEY=certs/ssl.key\nKEYCLOAK_JWK_URL=http://localhost/certs")
+	defer os.Remove(file)
+	t.Run("realm roles by default", func(t *testing.T) {
+		config := GetConfig(file)
+		a.Equal([]string{"realm_access.roles"}, config.RoleClaims)
+		a.Nil(config.RoleRenames)
+	})
+	t.Run("claims, prefix and renames", func(t *testing.T) {
+		t.Setenv("ROLE_CLAIMS", "resource_access.idm.roles, groups")
+		t.Setenv("ROLE_PREFIX", "/idm/")
+		t.Setenv("ROLE_RENAMES", "admins=IDM_ADMIN, users=IDM_USER")
+		config := GetConfig(file)
+		a.Equal([]string{"resource_access.idm.roles", "groups"}, config.RoleClaims)
+		a.Equal("/idm/", config.RolePrefix)
+		a.Equal(map[string]string{"admins": "IDM_ADMIN", "users": "IDM_USER"}, config.RoleRenames)
+	})
+	t.Run("malformed rename", func(t *testing.T) {
+		t.Setenv("ROLE_RENAMES", "admins")
+		a.Panics(func() { _ = GetConfig(file) })
+	})
+}
+
+func TestServerSettings(t *testing.T) {
+	var a = assert.New(t)
+	file := createEnvFile(t, "DB_DRIVER_NAME=postgres\nDB_DSN=random_dsn\nAPP_NAME=idm\nAPP_VERSION=1.0.0\n"+
+		"SSL_SERT=certs/ssl.cert\nSSL_KEY=certs/ssl.key\nKEYCLOAK_JWK_URL=http://localhost/certs")
+	defer os.Remove(file)
+	t.Run("defaults", func(t *testing.T) {
+		config := GetConfig(file)
+		a.Empty(config.CorsAllowOrigins)
+		a.Equal(4*1024*1024, config.BodyLimit)
+		a.Equal(30*time.Second, config.ReadTimeout)
+		a.Equal(365*24*time.Hour, config.HstsMaxAge)
+		a.Contains(config.ContentSecurityPolicy, "default-src 'self'")
+		a.Equal(DefaultLogRedactFields, config.LogRedactFields)
+	})
+	t.Run("from env", func(t *testing.T) {
+		t.Setenv("CORS_ALLOW_ORIGINS", "https://portal.example.com,https://admin.example.com")
+		t.Setenv("CORS_ALLOW_CREDENTIALS", "true")
+		t.Setenv("BODY_LIMIT", "1048576")
+		t.Setenv("WRITE_TIMEOUT", "10s")
+		t.Setenv("LOG_REDACT_FIELDS", "email, snils")
+		config := GetConfig(file)
+		a.Equal([]string{"email", "snils"}, config.LogRedactFields)
+		a.Equal([]string{"https://portal.example.com", "https://admin.example.com"}, config.CorsAllowOrigins)
+		a.True(config.CorsAllowCredentials)
+		a.Equal(1048576, config.BodyLimit)
+		a.Equal(10*time.Second, config.WriteTimeout)
+	})
+	t.Run("invalid values", func(t *testing.T) {
+		t.Setenv("BODY_LIMIT", "1mb")
+		a.Panics(func() { _ = GetConfig(file) })
+	})
+	t.Run("intervals must be positive", func(t *testing.T) {
+		for _, key := range []string{"POLICY_RELOAD_INTERVAL", "ACCESS_REQUEST_TTL", "BREAK_GLASS_WINDOW"} {
+			t.Setenv(key, "0s")
+			a.Panics(func() { _ = GetConfig(file) }, key)
+			t.Setenv(key, "1m")
+		}
+	})
+	t.Run("credentials for any origin are refused", func(t *testing.T) {
+		t.Setenv("CORS_ALLOW_ORIGINS", "*")
+		t.Setenv("CORS_ALLOW_CREDENTIALS", "true")
+		a.PanicsWithValue("config validation error: CORS credentials are not allowed for any origin", func() { _ = GetConfig(file) })
+	})
+}
+
+func TestTokenConfig(t *testing.T) {
+	var a = assert.New(t)
+	file := createEnvFile(t, "")
+	defer os.Remove(file)
+	t.Setenv("AUTH_MODE", "dev")
+	t.Setenv("DEV_AUTH_KEY", "0123456789abcdef0123456789abcdef")
+	t.Setenv("KEYCLOAK_AUDIENCE", "idm")
+	t.Setenv("ROLE_CLAIMS", "groups")
+	t.Run("server settings are not required", func(t *testing.T) {
+		t.Setenv("DB_DSN", "")
+		t.Setenv("SSL_SERT", "")
+		config := GetTokenConfig(file)
+		a.Equal("dev", config.AuthMode)
+		a.Equal("0123456789abcdef0123456789abcdef", config.DevAuthKey)
+		a.Equal("idm", config.KeycloakAudience)
+		a.Equal([]string{"groups"}, config.RoleClaims)
+	})
+	t.Run("dev key is validated", func(t *testing.T) {
+		t.Setenv("DEV_AUTH_KEY", "short")
+		a.Panics(func() { _ = GetTokenConfig(file) })
+	})
+}
+
+func TestDevAuthMode(t *testing.T) {
+	var a = assert.New(t)
+	file := createEnvFile(t, "DB_DRIVER_NAME=postgres\nDB_DSN=random_dsn\nAPP_NAME=idm\nAPP_VERSION=1.0.0\n"+
+		"SSL_SERT=certs/ssl.cert\nSSL_KEY=certs/ssl.key")
+	defer os.Remove(file)
+	t.Setenv("KEYCLOAK_JWK_URL", "")
+	t.Run("keycloak mode requires jwk url", func(t *testing.T) {
+		a.Panics(func() { _ = GetConfig(file) })
+	})
+	t.Run("dev mode works without keycloak", func(t *testing.T) {
+		t.Setenv("AUTH_MODE", "dev")
+		t.Setenv("DEV_AUTH_KEY", "0123456789abcdef0123456789abcdef")
+		config := GetConfig(file)
+		a.Equal("dev", config.AuthMode)
+		a.False(config.Production)
+	})
+	t.Run("dev mode requires long enough key", func(t *testing.T) {
+		t.Setenv("AUTH_MODE", "dev")
+		t.Setenv("DEV_AUTH_KEY", "short")
+		a.Panics(func() { _ = GetConfig(file) })
+	})
+	t.Run("dev mode is refused in production", func(t *testing.T) {
+		t.Setenv("AUTH_MODE", "dev")
+		t.Setenv("DEV_AUTH_KEY", "0123456789abcdef0123456789abcdef")
+		t.Setenv("APP_PRODUCTION", "true")
+		a.PanicsWithValue("config validation error: dev auth mode is not allowed in production", func() { _ = GetConfig(file) })
+	})
+}
+
+func TestClientCertificateSettings(t *testing.T) {
+	var a = assert.New(t)
+	file := createEnvFile(t, "DB_DRIVER_NAME=postgres\nDB_DSN=random_dsn\nAPP_NAME=idm\nAPP_VERSION=1.0.0\n"+
+		"SSL_SERT=certs/ssl.cert\nSSL_KEY=certs/ssl.key\nKEYCLOAK_JWK_URL=http://localhost/certs")
+	defer os.Remove(file)
+	t.Run("identities require client CA", func(t *testing.T) {
+		t.Setenv("TLS_CLIENT_IDENTITIES", "config/client_identities.json")
+		a.Panics(func() { _ = GetConfig(file) })
+	})
+	t.Run("client CA with required certificates", func(t *testing.T) {
+		t.Setenv("TLS_CLIENT_CA", "certs/clients.pem")
+		t.Setenv("TLS_CLIENT_CERT_REQUIRED", "true")
+		t.Setenv("TLS_CLIENT_IDENTITIES", "config/client_identities.json")
+		config := GetConfig(file)
+		a.Equal("certs/clients.pem", config.TlsClientCa)
+		a.True(config.TlsClientCertRequired)
+	})
+}
+
 func createEnvFile(t *testing.T, s string) string {
 	f, err := os.CreateTemp(".", ".env")
 	if err != nil {
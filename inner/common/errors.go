@@ -23,3 +23,11 @@ type NotFoundError struct {
 func (err NotFoundError) Error() string {
 	return err.Message
 }
+
+type ForbiddenError struct {
+	Message string
+}
+
+func (err ForbiddenError) Error() string {
+	return err.Message
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/requestid"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"time"
 )
 
 type Logger struct {
@@ -33,18 +34,17 @@ func NewLogger(cfg Config) *Logger {
 		ConsoleSeparator: "  ",
 	}
 	var zapCfg = zap.Config{
-		Level:       zap.NewAtomicLevelAt(parseLogLevel(cfg.LogLevel)),
-		Development: cfg.LogDevelopMode,
-		Sampling: &zap.SamplingConfig{
-			Initial:    100,
-			Thereafter: 100,
-		},
+		Level:            zap.NewAtomicLevelAt(parseLogLevel(cfg.LogLevel)),
+		Development:      cfg.LogDevelopMode,
 		Encoding:         "json",
 		EncoderConfig:    zapEncoderCfg,
 		OutputPaths:      []string{"stdout"},
 		ErrorOutputPaths: []string{"stdout"},
 	}
-	var logger = zap.Must(zapCfg.Build())
+	// маскирование ставится до сэмплирования: сэмплер пишет записи в core, который ему передан
+	var logger = zap.Must(zapCfg.Build(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewSamplerWithOptions(newRedactingCore(core, cfg.LogRedactFields), time.Second, 100, 100)
+	})))
 	logger.Info("logger construction succeeded")
 	var created = &Logger{logger}
 	created.setNewFiberZapLogger()
@@ -82,11 +82,7 @@ func (l *Logger) DebugCtx(
 	msg string,
 	fields ...zap.Field,
 ) {
-	var rid string
-	if v := ctx.Value(ridKey); v != nil {
-		rid = v.(string)
-	}
-	fields = append(fields, zap.String(ridKey, rid))
+	fields = contextFields(ctx, fields)
 	l.Debug(msg, fields...)
 }
 
@@ -95,23 +91,37 @@ func (l *Logger) ErrorCtx(
 	msg string,
 	fields ...zap.Field,
 ) {
-	var rid string
-	if v := ctx.Value(ridKey); v != nil {
-		rid = v.(string)
-	}
-	fields = append(fields, zap.String(ridKey, rid))
+	fields = contextFields(ctx, fields)
 	l.Error(msg, fields...)
 }
 
+func (l *Logger) WarnCtx(
+	ctx context.Context,
+	msg string,
+	fields ...zap.Field,
+) {
+	fields = contextFields(ctx, fields)
+	l.Warn(msg, fields...)
+}
+
 func (l *Logger) InfoCtx(
 	ctx context.Context,
 	msg string,
 	fields ...zap.Field,
 ) {
+	fields = contextFields(ctx, fields)
+	l.Info(msg, fields...)
+}
+
+// contextFields добавить к записи requestId и, если клиент прошёл mTLS, его сервисную учётную запись
+func contextFields(ctx context.Context, fields []zap.Field) []zap.Field {
 	var rid string
 	if v := ctx.Value(ridKey); v != nil {
 		rid = v.(string)
 	}
 	fields = append(fields, zap.String(ridKey, rid))
-	l.Info(msg, fields...)
+	if identity, ok := ctx.Value(ClientIdentityKey).(string); ok && identity != "" {
+		fields = append(fields, zap.String(string(ClientIdentityKey), identity))
+	}
+	return fields
 }
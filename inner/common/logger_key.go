@@ -3,3 +3,6 @@ package common
 type key string
 
 const LoggerKey key = "logger"
+
+// ClientIdentityKey ключ Locals с сервисной учётной записью клиента, прошедшего mTLS
+const ClientIdentityKey key = "client_identity"
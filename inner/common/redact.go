@@ -0,0 +1,224 @@
+package common
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// значения тега log у полей структур, которые попадают в логи
+const (
+	// logRedact значение не пишется в лог
+	logRedact = "redact"
+	// logMask в лог пишется только первый символ значения
+	logMask = "mask"
+)
+
+const redacted = "[REDACTED]"
+
+// DefaultLogRedactFields поля, значения которых не пишутся в лог, даже если у них нет тега log
+var DefaultLogRedactFields = []string{
+	"password", "secret", "client_secret", "token", "access_token", "refresh_token", "api_key",
+	"authorization", "email", "phone", "passport", "birth_date", "address",
+}
+
+// секреты, которые могут оказаться в тексте ошибок: JWT и API-ключи idm_<prefix>_<secret>
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`eyJ[\w-]*\.[\w-]*\.[\w-]*`),
+	regexp.MustCompile(`idm_[0-9a-f]+_[0-9a-f]+`),
+}
+
+// redactor убирает персональные данные и секреты из полей записи лога
+type redactor struct {
+	fields map[string]bool
+}
+
+func newRedactor(fields []string) *redactor {
+	var r = &redactor{fields: make(map[string]bool, len(fields))}
+	for _, field := range fields {
+		r.fields[normalizeField(field)] = true
+	}
+	return r
+}
+
+var separators = strings.NewReplacer("_", "", "-", "")
+
+// normalizeField имена полей сравниваются без учёта регистра, "_" и "-": BirthDate, birth_date и birth-date совпадают
+func normalizeField(name string) string {
+	return separators.Replace(strings.ToLower(name))
+}
+
+func (r *redactor) sensitive(name string) bool {
+	return r.fields[normalizeField(name)]
+}
+
+func (r *redactor) redactFields(fields []zapcore.Field) []zapcore.Field {
+	var result = make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		result[i] = r.redactField(field)
+	}
+	return result
+}
+
+func (r *redactor) redactField(field zapcore.Field) zapcore.Field {
+	if field.Type == zapcore.SkipType || field.Type == zapcore.NamespaceType {
+		return field
+	}
+	if r.sensitive(field.Key) {
+		return zap.String(field.Key, redacted)
+	}
+	switch field.Type {
+	case zapcore.StringType:
+		return zap.String(field.Key, scrub(field.String))
+	case zapcore.ErrorType:
+		if err, ok := field.Interface.(error); ok && err != nil {
+			return zap.String(field.Key, scrub(err.Error()))
+		}
+	case zapcore.ReflectType:
+		return zap.Reflect(field.Key, r.redactValue(reflect.ValueOf(field.Interface), 0))
+	}
+	return field
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// redactValue копия значения для лога: структуры и map становятся map с замаскированными полями
+func (r *redactor) redactValue(value reflect.Value, depth int) any {
+	if !value.IsValid() {
+		return nil
+	}
+	if depth > 10 {
+		return redacted
+	}
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			return nil
+		}
+		return r.redactValue(value.Elem(), depth+1)
+	case reflect.Struct:
+		if value.Type() == timeType || value.Type().Implements(jsonMarshalerType) ||
+			value.Type().Implements(textMarshalerType) {
+			return value.Interface()
+		}
+		var result = make(map[string]any)
+		r.redactStruct(value, depth, result)
+		return result
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			return value.Interface()
+		}
+		var result = make(map[string]any, value.Len())
+		for iter := value.MapRange(); iter.Next(); {
+			var name = iter.Key().String()
+			if r.sensitive(name) {
+				result[name] = redacted
+			} else {
+				result[name] = r.redactValue(iter.Value(), depth+1)
+			}
+		}
+		return result
+	case reflect.Slice, reflect.Array:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			return value.Interface()
+		}
+		var result = make([]any, value.Len())
+		for i := range value.Len() {
+			result[i] = r.redactValue(value.Index(i), depth+1)
+		}
+		return result
+	case reflect.String:
+		return scrub(value.String())
+	}
+	return value.Interface()
+}
+
+// redactStruct поля структуры под их json-именами; поля встроенных структур - на верхнем уровне, как в json
+func (r *redactor) redactStruct(value reflect.Value, depth int, result map[string]any) {
+	for i := range value.NumField() {
+		var field = value.Type().Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			r.redactStruct(value.Field(i), depth+1, result)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		switch {
+		case field.Tag.Get("log") == logRedact, r.sensitive(name), r.sensitive(field.Name):
+			result[name] = redacted
+		case field.Tag.Get("log") == logMask:
+			result[name] = mask(value.Field(i))
+		default:
+			result[name] = r.redactValue(value.Field(i), depth+1)
+		}
+	}
+}
+
+// mask первый символ и звёздочки вместо остального
+func mask(value reflect.Value) string {
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return ""
+		}
+		value = value.Elem()
+	}
+	var text = fmt.Sprint(value.Interface())
+	if text == "" {
+		return ""
+	}
+	first, _ := utf8.DecodeRuneInString(text)
+	return string(first) + "***"
+}
+
+// scrub заменить секреты в тексте
+func scrub(text string) string {
+	for _, pattern := range secretPatterns {
+		text = pattern.ReplaceAllString(text, redacted)
+	}
+	return text
+}
+
+// redactingCore core, который убирает персональные данные и секреты из полей перед записью
+type redactingCore struct {
+	zapcore.Core
+	redactor *redactor
+}
+
+func newRedactingCore(core zapcore.Core, fields []string) zapcore.Core {
+	return &redactingCore{Core: core, redactor: newRedactor(fields)}
+}
+
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{Core: c.Core.With(c.redactor.redactFields(fields)), redactor: c.redactor}
+}
+
+func (c *redactingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *redactingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = scrub(entry.Message)
+	return c.Core.Write(entry, c.redactor.redactFields(fields))
+}
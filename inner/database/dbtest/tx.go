@@ -0,0 +1,27 @@
+// Package dbtest транзакции sqlmock для тестов сервисов
+package dbtest
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"testing"
+)
+
+// NewTx открытая транзакция, которая ожидает коммит при commit = true и откат иначе
+func NewTx(t *testing.T, commit bool) *sqlx.Tx {
+	db, mck, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mck.ExpectBegin()
+	if commit {
+		mck.ExpectCommit()
+	} else {
+		mck.ExpectRollback()
+	}
+	tx, err := sqlx.NewDb(db, "sqlmock").Beginx()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
@@ -0,0 +1,38 @@
+package database
+
+import (
+	"fmt"
+	"github.com/jmoiron/sqlx"
+)
+
+// InTransaction выполнить fn в транзакции: коммит при успехе, откат при ошибке или панике
+func InTransaction(
+	begin func() (*sqlx.Tx, error),
+	operation string,
+	fn func(tx *sqlx.Tx) error,
+) (err error) {
+	tx, err := begin()
+	if err != nil {
+		return fmt.Errorf("error creating transaction: %w", err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s panic: %v", operation, r)
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("%s: rolling back transaction errors: %w, %w", operation, err, errTx)
+			}
+		} else if err != nil {
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("%s: rolling back transaction errors: %w, %w", operation, err, errTx)
+			}
+		} else {
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("%s: commiting transaction error: %w", operation, errTx)
+			}
+		}
+	}()
+	return fn(tx)
+}
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestInTransaction(t *testing.T) {
+	t.Run("should commit when fn succeeds", func(t *testing.T) {
+		a := assert.New(t)
+		db, mck, err := sqlmock.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sqlxDb := sqlx.NewDb(db, "sqlmock")
+		mck.ExpectBegin()
+		mck.ExpectCommit()
+		err = InTransaction(sqlxDb.Beginx, "test", func(tx *sqlx.Tx) error {
+			return nil
+		})
+		a.Nil(err)
+		a.Nil(mck.ExpectationsWereMet())
+	})
+	t.Run("should rollback and return fn error", func(t *testing.T) {
+		a := assert.New(t)
+		db, mck, err := sqlmock.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sqlxDb := sqlx.NewDb(db, "sqlmock")
+		mck.ExpectBegin()
+		mck.ExpectRollback()
+		var want = errors.New("database error")
+		err = InTransaction(sqlxDb.Beginx, "test", func(tx *sqlx.Tx) error {
+			return want
+		})
+		a.Equal(want, err)
+		a.Nil(mck.ExpectationsWereMet())
+	})
+	t.Run("should rollback on panic", func(t *testing.T) {
+		a := assert.New(t)
+		db, mck, err := sqlmock.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sqlxDb := sqlx.NewDb(db, "sqlmock")
+		mck.ExpectBegin()
+		mck.ExpectRollback()
+		err = InTransaction(sqlxDb.Beginx, "test", func(tx *sqlx.Tx) error {
+			panic("boom")
+		})
+		a.Equal(fmt.Errorf("test panic: %v", "boom"), err)
+		a.Nil(mck.ExpectationsWereMet())
+	})
+	t.Run("should return error when begin fails", func(t *testing.T) {
+		a := assert.New(t)
+		var want = errors.New("connection error")
+		err := InTransaction(func() (*sqlx.Tx, error) { return nil, want }, "test", func(tx *sqlx.Tx) error {
+			return nil
+		})
+		a.Equal(fmt.Errorf("error creating transaction: %w", want), err)
+	})
+}
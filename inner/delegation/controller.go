@@ -0,0 +1,159 @@
+package delegation
+
+import (
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"idm/inner/common"
+	"idm/inner/middleware"
+	"idm/inner/web"
+	"slices"
+	"strconv"
+)
+
+type Controller struct {
+	server            *web.Server
+	delegationService Svc
+}
+
+type Svc interface {
+	Create(ctx context.Context, request CreateRequest) (Response, error)
+	FindAll(request FindRequest) ([]Response, error)
+	Revoke(ctx context.Context, request RevokeRequest) error
+}
+
+func NewController(
+	server *web.Server,
+	delegationService Svc,
+) *Controller {
+	return &Controller{
+		server:            server,
+		delegationService: delegationService,
+	}
+}
+
+func (c *Controller) RegisterRoutes() {
+	c.server.GroupApiV1.Post("/delegations", c.CreateDelegation)
+	c.server.GroupApiV1.Get("/delegations", c.FindAll)
+	c.server.GroupApiV1.Post("/delegations/:id/revoke", c.Revoke)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/delegations"
+// @Summary delegate administration
+// @Description Grant a local admin rights over one department or a set of roles until expiry, with roles: admin
+// @Tags delegation
+// @Security OAuth2Password
+// @Accept json
+// @Produce json
+// @Param request body delegation.CreateRequest true "create delegation request"
+// @Success 200 {object} common.Response[int64]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /delegations [post]
+func (c *Controller) CreateDelegation(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	var request CreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		logger.ErrorCtx(ctx.Context(), "body parse error: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	request.GrantedBy = claims.Subject
+	logger.InfoCtx(ctx.Context(), "create delegation: received request", zap.Any("request", request))
+	var response, err = c.delegationService.Create(ctx.Context(), request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			logger.ErrorCtx(ctx.Context(), "error creating delegation: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "error creating delegation: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response.Id)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/delegations"
+// @Summary Get delegations
+// @Description returns delegations, including expired and revoked unless active=true, with roles: admin
+// @Tags delegation
+// @Security OAuth2Password
+// @Produce json
+// @Param active query bool false "Only active delegations"
+// @Success 200 {object} common.Response[[]delegation.Response]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /delegations [get]
+func (c *Controller) FindAll(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	var request = FindRequest{ActiveOnly: ctx.QueryBool("active", false)}
+	logger.InfoCtx(ctx.Context(), "find delegations: received request", zap.Any("request", request))
+	response, err := c.delegationService.FindAll(request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), "find delegations: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "find delegations: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/delegations/:id/revoke"
+// @Summary Revoke delegation
+// @Description Revokes an active delegation before its expiry, with roles: admin
+// @Tags delegation
+// @Security OAuth2Password
+// @Produce json
+// @Param id path int true "Delegation ID"
+// @Success 200 {object} common.Response[int64]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /delegations/{id}/revoke [post]
+func (c *Controller) Revoke(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error parsing id: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request = RevokeRequest{Id: id, RevokedBy: claims.Subject}
+	logger.InfoCtx(ctx.Context(), "revoke delegation: received request", zap.Any("request", request))
+	err = c.delegationService.Revoke(ctx.Context(), request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			logger.ErrorCtx(ctx.Context(), "revoke delegation: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), "revoke delegation: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "revoke delegation: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, id)
+}
@@ -0,0 +1,104 @@
+package delegation
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"idm/inner/common"
+	"idm/inner/web"
+	"idm/inner/web/webtest"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) Create(ctx context.Context, request CreateRequest) (Response, error) {
+	args := svc.Called(ctx, request)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) FindAll(request FindRequest) ([]Response, error) {
+	args := svc.Called(request)
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (svc *MockService) Revoke(ctx context.Context, request RevokeRequest) error {
+	args := svc.Called(ctx, request)
+	return args.Error(0)
+}
+
+func newServer(svc Svc, subject string, roles ...string) *web.Server {
+	server := webtest.NewServer(subject, roles...)
+	NewController(server, svc).RegisterRoutes()
+	return server
+}
+
+func TestCreateDelegation(t *testing.T) {
+	var a = assert.New(t)
+	var expiresAt = time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	var body = "{\"subject\": \"local-admin\", \"department\": \"Sales\", \"expires_at\": \"2030-01-01T00:00:00Z\"}"
+	t.Run("create delegation", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "admin-1", web.IdmAdmin)
+		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/delegations", strings.NewReader(body))
+		request.Header.Add("Content-Type", "application/json")
+		svc.On("Create", mock.Anything, CreateRequest{
+			Subject:    "local-admin",
+			Department: "Sales",
+			ExpiresAt:  expiresAt,
+			GrantedBy:  "admin-1",
+		}).Return(Response{Id: 1}, nil)
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+	})
+	t.Run("create delegation without role admin", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "user-1", web.IdmUser)
+		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/delegations", strings.NewReader(body))
+		request.Header.Add("Content-Type", "application/json")
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		a.True(svc.AssertNumberOfCalls(t, "Create", 0))
+	})
+}
+
+func TestFindDelegations(t *testing.T) {
+	var a = assert.New(t)
+	var svc = new(MockService)
+	server := newServer(svc, "admin-1", web.IdmAdmin)
+	var want = []Response{{Id: 1, Subject: "local-admin", Department: "Sales", Active: true}}
+	svc.On("FindAll", FindRequest{ActiveOnly: true}).Return(want, nil)
+	resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/delegations?active=true", nil))
+	a.Nil(err)
+	a.Equal(http.StatusOK, resp.StatusCode)
+	bytesData, err := io.ReadAll(resp.Body)
+	a.Nil(err)
+	var responseBody common.Response[[]Response]
+	a.Nil(json.Unmarshal(bytesData, &responseBody))
+	a.Equal(want[0].Subject, responseBody.Data[0].Subject)
+	a.True(responseBody.Data[0].Active)
+}
+
+func TestRevokeDelegation(t *testing.T) {
+	var a = assert.New(t)
+	var svc = new(MockService)
+	server := newServer(svc, "admin-1", web.IdmAdmin)
+	svc.On("Revoke", mock.Anything, RevokeRequest{Id: 3, RevokedBy: "admin-1"}).Return(nil)
+	resp, err := server.App.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/delegations/3/revoke", nil))
+	a.Nil(err)
+	a.Equal(http.StatusOK, resp.StatusCode)
+	resp, err = server.App.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/delegations/abc/revoke", nil))
+	a.Nil(err)
+	a.Equal(http.StatusBadRequest, resp.StatusCode)
+}
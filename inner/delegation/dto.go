@@ -0,0 +1,98 @@
+package delegation
+
+import (
+	"github.com/lib/pq"
+	"slices"
+	"time"
+)
+
+type Entity struct {
+	Id         int64         `db:"id"`
+	Subject    string        `db:"subject"`
+	Department string        `db:"department"`
+	RoleIds    pq.Int64Array `db:"role_ids"`
+	GrantedBy  string        `db:"granted_by"`
+	ExpiresAt  time.Time     `db:"expires_at"`
+	RevokedBy  string        `db:"revoked_by"`
+	RevokedAt  *time.Time    `db:"revoked_at"`
+	CreatedAt  time.Time     `db:"created_at"`
+}
+
+type Response struct {
+	Id         int64      `json:"id"`
+	Subject    string     `json:"subject"`
+	Department string     `json:"department,omitempty"`
+	RoleIds    []int64    `json:"role_ids,omitempty"`
+	GrantedBy  string     `json:"granted_by"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedBy  string     `json:"revoked_by,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Active     bool       `json:"active"`
+}
+
+// CreateRequest делегирование ограничено либо подразделением, либо набором ролей
+type CreateRequest struct {
+	Subject    string    `json:"subject" validate:"required,max=255"`
+	Department string    `json:"department" validate:"required_without=RoleIds,excluded_with=RoleIds,max=155"`
+	RoleIds    []int64   `json:"role_ids" validate:"required_without=Department,dive,min=1"`
+	ExpiresAt  time.Time `json:"expires_at" validate:"required"`
+	GrantedBy  string    `json:"-" validate:"required"`
+}
+
+type FindRequest struct {
+	ActiveOnly bool
+}
+
+type RevokeRequest struct {
+	Id        int64  `validate:"required,min=1"`
+	RevokedBy string `validate:"required"`
+}
+
+// Scope полномочия пользователя на изменение сотрудников и ролей
+type Scope struct {
+	Admin       bool
+	Departments []string
+	RoleIds     []int64
+}
+
+// AllowsEmployee можно ли создавать, изменять и удалять сотрудника из подразделения department.
+// Делегирование набора ролей на сотрудников не распространяется, только на назначение этих ролей
+func (s Scope) AllowsEmployee(department string) bool {
+	return s.Admin || (department != "" && slices.Contains(s.Departments, department))
+}
+
+// Empty у пользователя нет ни прав администратора, ни действующих делегирований
+func (s Scope) Empty() bool {
+	return !s.Admin && len(s.Departments) == 0 && len(s.RoleIds) == 0
+}
+
+// AllowsRole можно ли назначать роль roleId сотрудникам и отзывать её
+func (s Scope) AllowsRole(roleId int64) bool {
+	return s.Admin || slices.Contains(s.RoleIds, roleId)
+}
+
+func (e *Entity) toResponse(now time.Time) Response {
+	return Response{
+		Id:         e.Id,
+		Subject:    e.Subject,
+		Department: e.Department,
+		RoleIds:    e.RoleIds,
+		GrantedBy:  e.GrantedBy,
+		ExpiresAt:  e.ExpiresAt,
+		RevokedBy:  e.RevokedBy,
+		RevokedAt:  e.RevokedAt,
+		CreatedAt:  e.CreatedAt,
+		Active:     e.RevokedAt == nil && e.ExpiresAt.After(now),
+	}
+}
+
+func (req *CreateRequest) ToEntity() Entity {
+	return Entity{
+		Subject:    req.Subject,
+		Department: req.Department,
+		RoleIds:    req.RoleIds,
+		GrantedBy:  req.GrantedBy,
+		ExpiresAt:  req.ExpiresAt,
+	}
+}
@@ -0,0 +1,62 @@
+package delegation
+
+import (
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{
+		db: database,
+	}
+}
+
+func (r *Repository) Save(e Entity) (int64, error) {
+	var id int64
+	err := r.db.QueryRow(
+		"INSERT INTO delegation (subject, department, role_ids, granted_by, expires_at) "+
+			"VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		e.Subject, e.Department, e.RoleIds, e.GrantedBy, e.ExpiresAt).Scan(&id)
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
+// FindAll найти делегирования; activeOnly оставляет только не отозванные и не истёкшие на момент now
+func (r *Repository) FindAll(activeOnly bool, now time.Time) ([]Entity, error) {
+	var delegations []Entity
+	err := r.db.Select(
+		&delegations,
+		"SELECT * FROM delegation WHERE NOT $1 OR (revoked_at IS NULL AND expires_at > $2) ORDER BY id",
+		activeOnly, now,
+	)
+	return delegations, err
+}
+
+func (r *Repository) FindActiveBySubject(subject string, now time.Time) ([]Entity, error) {
+	var delegations []Entity
+	err := r.db.Select(
+		&delegations,
+		"SELECT * FROM delegation WHERE subject = $1 AND revoked_at IS NULL AND expires_at > $2",
+		subject, now,
+	)
+	return delegations, err
+}
+
+// Revoke отозвать делегирование; вернуть false, если оно не найдено или уже отозвано
+func (r *Repository) Revoke(id int64, revokedBy string) (bool, error) {
+	result, err := r.db.Exec(
+		"UPDATE delegation SET revoked_by = $1, revoked_at = NOW() WHERE id = $2 AND revoked_at IS NULL",
+		revokedBy, id,
+	)
+	if err != nil {
+		return false, err
+	}
+	revoked, err := result.RowsAffected()
+	return revoked > 0, err
+}
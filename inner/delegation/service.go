@@ -0,0 +1,98 @@
+package delegation
+
+import (
+	"context"
+	"fmt"
+	"idm/inner/common"
+	"slices"
+	"time"
+)
+
+type Service struct {
+	repo       Repo
+	validator  Validator
+	adminRoles []string
+}
+
+type Repo interface {
+	Save(e Entity) (int64, error)
+	FindAll(activeOnly bool, now time.Time) ([]Entity, error)
+	FindActiveBySubject(subject string, now time.Time) ([]Entity, error)
+	Revoke(id int64, revokedBy string) (bool, error)
+}
+
+type Validator interface {
+	Validate(request any) error
+}
+
+// NewService создать сервис делегирования;
+// adminRoles - роли из токена, владельцы которых управляют всеми сотрудниками и ролями без ограничений
+func NewService(repo Repo, validator Validator, adminRoles []string) *Service {
+	return &Service{
+		repo:       repo,
+		validator:  validator,
+		adminRoles: adminRoles,
+	}
+}
+
+func (s *Service) Create(ctx context.Context, request CreateRequest) (Response, error) {
+	if err := s.validator.Validate(request); err != nil {
+		return Response{}, common.RequestValidationError{Message: err.Error()}
+	}
+	if !request.ExpiresAt.After(time.Now()) {
+		return Response{}, common.RequestValidationError{Message: "delegation expiry must be in the future"}
+	}
+	id, err := s.repo.Save(request.ToEntity())
+	if err != nil {
+		return Response{}, fmt.Errorf("error saving delegation: %w", err)
+	}
+	return Response{Id: id}, nil
+}
+
+func (s *Service) FindAll(request FindRequest) ([]Response, error) {
+	var now = time.Now()
+	delegations, err := s.repo.FindAll(request.ActiveOnly, now)
+	if err != nil {
+		return nil, common.NotFoundError{Message: fmt.Sprintf("error finding delegations: %v", err)}
+	}
+	var response []Response
+	for _, entity := range delegations {
+		response = append(response, entity.toResponse(now))
+	}
+	return response, nil
+}
+
+func (s *Service) Revoke(ctx context.Context, request RevokeRequest) error {
+	if err := s.validator.Validate(request); err != nil {
+		return common.RequestValidationError{Message: err.Error()}
+	}
+	revoked, err := s.repo.Revoke(request.Id, request.RevokedBy)
+	if err != nil {
+		return fmt.Errorf("error revoking delegation %d: %w", request.Id, err)
+	}
+	if !revoked {
+		return common.NotFoundError{Message: fmt.Sprintf("active delegation with id %d not found", request.Id)}
+	}
+	return nil
+}
+
+// Scope собрать полномочия пользователя из ролей токена и действующих делегирований
+func (s *Service) Scope(ctx context.Context, subject string, roles []string) (Scope, error) {
+	for _, role := range roles {
+		if slices.Contains(s.adminRoles, role) {
+			return Scope{Admin: true}, nil
+		}
+	}
+	delegations, err := s.repo.FindActiveBySubject(subject, time.Now())
+	if err != nil {
+		return Scope{}, fmt.Errorf("error finding delegations of %s: %w", subject, err)
+	}
+	var scope Scope
+	for _, delegation := range delegations {
+		if delegation.Department != "" {
+			scope.Departments = append(scope.Departments, delegation.Department)
+		}
+		scope.RoleIds = append(scope.RoleIds, delegation.RoleIds...)
+	}
+	return scope, nil
+}
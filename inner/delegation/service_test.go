@@ -0,0 +1,127 @@
+package delegation
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"idm/inner/common"
+	"idm/inner/validator"
+	"idm/inner/web"
+	"testing"
+	"time"
+)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+func (m *MockRepo) Save(e Entity) (int64, error) {
+	args := m.Called(e)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepo) FindAll(activeOnly bool, now time.Time) ([]Entity, error) {
+	args := m.Called(activeOnly, now)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindActiveBySubject(subject string, now time.Time) ([]Entity, error) {
+	args := m.Called(subject, now)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) Revoke(id int64, revokedBy string) (bool, error) {
+	args := m.Called(id, revokedBy)
+	return args.Bool(0), args.Error(1)
+}
+
+func TestCreate(t *testing.T) {
+	var a = assert.New(t)
+	var ctx = context.Background()
+	var expiresAt = time.Now().Add(24 * time.Hour)
+	t.Run("delegate department", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New(), []string{web.IdmAdmin})
+		var request = CreateRequest{Subject: "local-admin", Department: "Sales", ExpiresAt: expiresAt, GrantedBy: "admin"}
+		repo.On("Save", request.ToEntity()).Return(int64(2), nil)
+		got, err := svc.Create(ctx, request)
+		a.Nil(err)
+		a.Equal(Response{Id: 2}, got)
+	})
+	t.Run("department and roles are exclusive", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New(), []string{web.IdmAdmin})
+		_, err := svc.Create(ctx, CreateRequest{
+			Subject:    "local-admin",
+			Department: "Sales",
+			RoleIds:    []int64{1},
+			ExpiresAt:  expiresAt,
+			GrantedBy:  "admin",
+		})
+		a.True(errors.As(err, &common.RequestValidationError{}))
+		_, err = svc.Create(ctx, CreateRequest{Subject: "local-admin", ExpiresAt: expiresAt, GrantedBy: "admin"})
+		a.True(errors.As(err, &common.RequestValidationError{}))
+		a.True(repo.AssertNumberOfCalls(t, "Save", 0))
+	})
+	t.Run("expiry in the past", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New(), []string{web.IdmAdmin})
+		_, err := svc.Create(ctx, CreateRequest{
+			Subject:   "local-admin",
+			RoleIds:   []int64{1},
+			ExpiresAt: time.Now().Add(-time.Minute),
+			GrantedBy: "admin",
+		})
+		a.True(errors.As(err, &common.RequestValidationError{}))
+		a.True(repo.AssertNumberOfCalls(t, "Save", 0))
+	})
+}
+
+func TestRevoke(t *testing.T) {
+	var a = assert.New(t)
+	var repo = new(MockRepo)
+	var svc = NewService(repo, validator.New(), []string{web.IdmAdmin})
+	repo.On("Revoke", int64(1), "admin").Return(true, nil)
+	repo.On("Revoke", int64(2), "admin").Return(false, nil)
+	a.Nil(svc.Revoke(context.Background(), RevokeRequest{Id: 1, RevokedBy: "admin"}))
+	err := svc.Revoke(context.Background(), RevokeRequest{Id: 2, RevokedBy: "admin"})
+	a.True(errors.As(err, &common.NotFoundError{}))
+}
+
+func TestScope(t *testing.T) {
+	var a = assert.New(t)
+	var ctx = context.Background()
+	t.Run("admin role is not restricted", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New(), []string{web.IdmAdmin})
+		scope, err := svc.Scope(ctx, "admin", []string{web.IdmUser, web.IdmAdmin})
+		a.Nil(err)
+		a.True(scope.Admin)
+		a.True(repo.AssertNumberOfCalls(t, "FindActiveBySubject", 0))
+	})
+	t.Run("delegations are merged", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New(), []string{web.IdmAdmin})
+		repo.On("FindActiveBySubject", "local-admin", mock.Anything).Return([]Entity{
+			{Department: "Sales"},
+			{RoleIds: []int64{3, 4}},
+		}, nil)
+		scope, err := svc.Scope(ctx, "local-admin", []string{web.IdmUser})
+		a.Nil(err)
+		a.Equal(Scope{Departments: []string{"Sales"}, RoleIds: []int64{3, 4}}, scope)
+		a.True(scope.AllowsEmployee("Sales"))
+		a.False(scope.AllowsEmployee("HR"))
+		a.True(scope.AllowsRole(3))
+		a.False(scope.AllowsRole(1))
+	})
+	t.Run("no delegations", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New(), []string{web.IdmAdmin})
+		repo.On("FindActiveBySubject", "user-1", mock.Anything).Return([]Entity{}, nil)
+		scope, err := svc.Scope(ctx, "user-1", nil)
+		a.Nil(err)
+		a.True(scope.Empty())
+		a.False(scope.AllowsEmployee(""))
+	})
+}
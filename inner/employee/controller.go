@@ -7,7 +7,10 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"go.uber.org/zap"
 	"idm/inner/common"
+	"idm/inner/delegation"
 	"idm/inner/middleware"
+	"idm/inner/policy"
+	"idm/inner/relation"
 	"idm/inner/web"
 	"slices"
 	"strconv"
@@ -17,6 +20,35 @@ import (
 type Controller struct {
 	server          *web.Server
 	employeeService Svc
+	policy          Policy
+	relations       Relations
+	delegations     Delegations
+	breakGlass      BreakGlass
+}
+
+// действия над сотрудниками, которые проверяются политиками доступа
+const (
+	ActionCreate = "employee:create"
+	ActionRead   = "employee:read"
+	ActionDelete = "employee:delete"
+)
+
+type Policy interface {
+	Evaluate(ctx context.Context, request policy.Request) policy.Decision
+	Check(ctx context.Context, request policy.Request) policy.Decision
+	Restricts(action string) bool
+}
+
+type Delegations interface {
+	Scope(ctx context.Context, subject string, roles []string) (delegation.Scope, error)
+}
+
+type BreakGlass interface {
+	Available(roles []string) bool
+}
+
+type Relations interface {
+	Check(ctx context.Context, subject string, relation string, object string) (bool, error)
 }
 
 type Svc interface {
@@ -24,7 +56,14 @@ type Svc interface {
 	FindById(request IdRequest) (Response, error)
 	FindAll() ([]Response, error)
 	FindByIds(request IdsRequest) ([]Response, error)
-	FindWithOffset(request PageRequest) (PageResponse, error)
+	FindWithOffset(request PageRequest, allowed func(Response) bool) (PageResponse, error)
+	FindRiskiest() ([]Response, error)
+	FindByRole(request RoleMembersRequest, allowed func(Response) bool) (PageResponse, error)
+	FindMe(request MeRequest) (MeResponse, error)
+	LinkSubject(request LinkSubjectRequest) error
+	UpdateDepartment(ctx context.Context, request UpdateDepartmentRequest) error
+	AssignRole(ctx context.Context, request RoleAssignmentRequest) error
+	UnassignRole(ctx context.Context, request RoleAssignmentRequest) error
 	DeleteById(request IdRequest) error
 	DeleteByIds(request IdsRequest) error
 }
@@ -32,10 +71,18 @@ type Svc interface {
 func NewController(
 	server *web.Server,
 	employeeService Svc,
+	policy Policy,
+	relations Relations,
+	delegations Delegations,
+	breakGlass BreakGlass,
 ) *Controller {
 	return &Controller{
 		server:          server,
 		employeeService: employeeService,
+		policy:          policy,
+		relations:       relations,
+		delegations:     delegations,
+		breakGlass:      breakGlass,
 	}
 }
 
@@ -43,15 +90,22 @@ func (c *Controller) RegisterRoutes() {
 	c.server.GroupApiV1.Post("/employees", c.CreateEmployee)
 	c.server.GroupApiV1.Get("/employees/find", c.FindByIds)
 	c.server.GroupApiV1.Get("/employees/page", c.FindWithOffset)
+	c.server.GroupApiV1.Get("/employees/riskiest", c.FindRiskiest)
 	c.server.GroupApiV1.Get("/employees/:id", c.FindById)
 	c.server.GroupApiV1.Get("/employees", c.FindAll)
 	c.server.GroupApiV1.Delete("/employees/delete", c.DeleteByIds)
 	c.server.GroupApiV1.Delete("/employees/:id", c.DeleteById)
+	c.server.GroupApiV1.Get("/roles/:id/employees", c.FindByRole)
+	c.server.GroupApiV1.Put("/employees/:id/subject", c.LinkSubject)
+	c.server.GroupApiV1.Put("/employees/:id/department", c.UpdateDepartment)
+	c.server.GroupApiV1.Post("/employees/:id/roles", c.AssignRole)
+	c.server.GroupApiV1.Delete("/employees/:id/roles/:roleId", c.UnassignRole)
+	c.server.GroupApiV1.Get("/me", c.FindMe)
 }
 
 // Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/employees"
 // @Summary create a new employee
-// @Description Create a new employee with roles: admin
+// @Description Create a new employee with roles: admin, or a delegated admin of its department
 // @Tags employee
 // @Security OAuth2Password
 // @Accept json
@@ -59,13 +113,11 @@ func (c *Controller) RegisterRoutes() {
 // @Param request body employee.CreateRequest true "create employee request"
 // @Success 200 {object} common.Response[int64]
 // @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
 // @Router /employees [post]
 func (c *Controller) CreateEmployee(ctx *fiber.Ctx) error {
 	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
 	claims := token.Claims.(*web.IdmClaims)
-	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
-		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
-	}
 	logger := middleware.GetLogger(ctx)
 	var request CreateRequest
 	if err := ctx.BodyParser(&request); err != nil {
@@ -73,7 +125,22 @@ func (c *Controller) CreateEmployee(ctx *fiber.Ctx) error {
 		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
 	}
 	logger.InfoCtx(ctx.Context(), "create employee: received request", zap.Any("request", request))
-	var response, err = c.employeeService.Save(ctx.Context(), request)
+	scope, err := c.delegations.Scope(ctx.Context(), claims.Subject, claims.RealmAccess.Roles)
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error checking delegated scope: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+	if !scope.AllowsEmployee(request.Department) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	// основная роль назначается при создании, поэтому на неё нужны те же права, что и на назначение роли
+	if !scope.AllowsRole(request.RoleId) && !c.related(ctx, claims, relation.PermissionManage, relation.Role(request.RoleId)) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	if !c.allowed(ctx, claims, ActionCreate, request.attributes()) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	response, err := c.employeeService.Save(ctx.Context(), request)
 	if err != nil {
 		switch {
 		case errors.As(err, &common.RequestValidationError{}) || errors.As(err, &common.AlreadyExistsError{}):
@@ -97,6 +164,8 @@ func (c *Controller) CreateEmployee(ctx *fiber.Ctx) error {
 // @Param pageNumber  query int true "Page number (0 is first page)"
 // @Param pageSize    query int true "Page size (number of employee on the page)"
 // @Param textFilter  query string false "Filter name of employees"
+// @Param sortBy      query string false "Sort field: id, name or risk_score"
+// @Param sortOrder   query string false "Sort order: asc or desc"
 // @Success 200 {object} common.PageResponse[[]employee.Response]
 // @Failure 400 {object} common.Response[string]
 // @Failure 500 {object} common.Response[string]
@@ -124,9 +193,11 @@ func (c *Controller) FindWithOffset(ctx *fiber.Ctx) error {
 		PageSize:   pageSize,
 		PageNumber: pageNumber,
 		TextFilter: textFilter,
+		SortBy:     ctx.Query("sortBy", ""),
+		SortOrder:  ctx.Query("sortOrder", ""),
 	}
 	logger.InfoCtx(ctx.Context(), "find with offset employees: received request", zap.Any("request", request))
-	response, err := c.employeeService.FindWithOffset(request)
+	response, err := c.employeeService.FindWithOffset(request, c.readable(ctx, claims))
 	if err != nil {
 		switch {
 		case errors.As(err, &common.RequestValidationError{}):
@@ -143,6 +214,264 @@ func (c *Controller) FindWithOffset(ctx *fiber.Ctx) error {
 	return common.OkResponse(ctx, response)
 }
 
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/employees/riskiest"
+// @Summary Get riskiest employees
+// @Description returns 50 employees with the highest risk score with roles: admin
+// @Tags employee
+// @Security OAuth2Password
+// @Produce json
+// @Success 200 {object} common.Response[[]employee.Response]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /employees/riskiest [get]
+func (c *Controller) FindRiskiest(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	logger.InfoCtx(ctx.Context(), "find riskiest employees: received request")
+	response, err := c.employeeService.FindRiskiest()
+	if err != nil {
+		switch {
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), "find riskiest employees: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "find riskiest employees: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/roles/:id/employees"
+// @Summary Get employees of a role with name filter(optional) and pagination.
+// @Description returns employees who have the role with roles: admin, user
+// @Tags employee
+// @Security OAuth2Password
+// @Accept json
+// @Produce json
+// @Param id          path int true "Role ID"
+// @Param pageNumber  query int true "Page number (0 is first page)"
+// @Param pageSize    query int true "Page size (number of employee on the page)"
+// @Param textFilter  query string false "Filter name of employees"
+// @Success 200 {object} common.PageResponse[[]employee.Response]
+// @Failure 400 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /roles/{id}/employees [get]
+func (c *Controller) FindByRole(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	logger := middleware.GetLogger(ctx)
+	roleId, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error parsing role id: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	if !(slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) ||
+		slices.Contains(claims.RealmAccess.Roles, web.IdmUser)) &&
+		!c.related(ctx, claims, relation.PermissionManage, relation.Role(roleId)) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	pageSize, err := strconv.Atoi(ctx.Query("pageSize", "100"))
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error parsing page size: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	pageNumber, err := strconv.Atoi(ctx.Query("pageNumber", "0"))
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error parsing page number: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	request := RoleMembersRequest{
+		RoleId:     roleId,
+		PageSize:   pageSize,
+		PageNumber: pageNumber,
+		TextFilter: ctx.Query("textFilter", ""),
+	}
+	logger.InfoCtx(ctx.Context(), "find employees by role: received request", zap.Any("request", request))
+	response, err := c.employeeService.FindByRole(request, c.readable(ctx, claims))
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			logger.ErrorCtx(ctx.Context(), "find employees by role: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), "find employees by role: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "find employees by role: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/me"
+// @Summary Get current user
+// @Description returns the employee linked to the token subject, their roles and effective permissions; any authenticated user
+// @Tags employee
+// @Security OAuth2Password
+// @Produce json
+// @Success 200 {object} common.Response[employee.MeResponse]
+// @Failure 400 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /me [get]
+func (c *Controller) FindMe(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	logger := middleware.GetLogger(ctx)
+	scope, err := c.delegations.Scope(ctx.Context(), claims.Subject, claims.RealmAccess.Roles)
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "find me: checking delegated scope", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+	var request = MeRequest{
+		Subject:    claims.Subject,
+		TokenRoles: claims.RealmAccess.Roles,
+		Granted:    web.DelegatedPermissions(len(scope.Departments) > 0, len(scope.RoleIds) > 0),
+	}
+	if c.breakGlass.Available(claims.RealmAccess.Roles) {
+		request.Granted = append(request.Granted, web.PermissionBreakGlass)
+	}
+	logger.InfoCtx(ctx.Context(), "find me: received request", zap.String("subject", request.Subject))
+	response, err := c.employeeService.FindMe(request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			logger.ErrorCtx(ctx.Context(), "find me: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), "find me: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "find me: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// Функция-хендлер, которая будет вызываться при PUT запросе по маршруту "/api/v1/employees/:id/subject"
+// @Summary Link employee to identity provider subject
+// @Description Links an employee to the Keycloak user (claim sub) with roles: admin, or a delegated admin of its department
+// @Tags employee
+// @Security OAuth2Password
+// @Accept json
+// @Produce json
+// @Param id path int true "Employee ID"
+// @Param request body employee.LinkSubjectRequest true "link subject request"
+// @Success 200 {object} common.Response[int64]
+// @Failure 400 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /employees/{id}/subject [put]
+func (c *Controller) LinkSubject(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	logger := middleware.GetLogger(ctx)
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error parsing id: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request LinkSubjectRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		logger.ErrorCtx(ctx.Context(), "body parse error: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	request.Id = id
+	logger.InfoCtx(ctx.Context(), "link employee subject: received request", zap.Any("request", request))
+	allowed, err := c.managesEmployees(ctx, claims, []int64{id})
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error checking delegated scope: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+	if !allowed {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	err = c.employeeService.LinkSubject(request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			logger.ErrorCtx(ctx.Context(), "link employee subject: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), "link employee subject: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "link employee subject: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, id)
+}
+
+// Функция-хендлер, которая будет вызываться при PUT запросе по маршруту "/api/v1/employees/:id/department"
+// @Summary Move employee to another department
+// @Description Changes the department and re-evaluates birthright roles, with roles: admin, or a delegated admin of both departments
+// @Tags employee
+// @Security OAuth2Password
+// @Accept json
+// @Produce json
+// @Param id path int true "Employee ID"
+// @Param request body employee.UpdateDepartmentRequest true "update department request"
+// @Success 200 {object} common.Response[int64]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /employees/{id}/department [put]
+func (c *Controller) UpdateDepartment(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	logger := middleware.GetLogger(ctx)
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error parsing id: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request UpdateDepartmentRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		logger.ErrorCtx(ctx.Context(), "body parse error: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	request.Id = id
+	logger.InfoCtx(ctx.Context(), "update employee department: received request", zap.Any("request", request))
+	allowed, err := c.managesEmployees(ctx, claims, []int64{id})
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error checking delegated scope: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+	if allowed {
+		// делегированный администратор не может перевести сотрудника в чужое подразделение
+		scope, err := c.delegations.Scope(ctx.Context(), claims.Subject, claims.RealmAccess.Roles)
+		if err != nil {
+			logger.ErrorCtx(ctx.Context(), "error checking delegated scope: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+		allowed = scope.Admin || slices.Contains(scope.Departments, request.Department)
+	}
+	if !allowed {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	err = c.employeeService.UpdateDepartment(ctx.Context(), request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			logger.ErrorCtx(ctx.Context(), "update employee department: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), "update employee department: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "update employee department: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, id)
+}
+
 // Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/employees/:id"
 // @Summary Get employee by ID
 // @Description returns details of a single employee by their unique ID with roles: admin, user
@@ -158,10 +487,6 @@ func (c *Controller) FindWithOffset(ctx *fiber.Ctx) error {
 func (c *Controller) FindById(ctx *fiber.Ctx) error {
 	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
 	claims := token.Claims.(*web.IdmClaims)
-	if !(slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) ||
-		slices.Contains(claims.RealmAccess.Roles, web.IdmUser)) {
-		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
-	}
 	logger := middleware.GetLogger(ctx)
 	var param = ctx.Params("id")
 	id, err := strconv.Atoi(param)
@@ -169,6 +494,11 @@ func (c *Controller) FindById(ctx *fiber.Ctx) error {
 		logger.ErrorCtx(ctx.Context(), "error parsing id: ", zap.Error(err))
 		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
 	}
+	if !(slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) ||
+		slices.Contains(claims.RealmAccess.Roles, web.IdmUser)) &&
+		!c.related(ctx, claims, relation.PermissionView, relation.Employee(int64(id))) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
 	request := IdRequest{Id: int64(id)}
 	logger.InfoCtx(ctx.Context(), "find by id employee: received request", zap.Any("request", request))
 	response, err := c.employeeService.FindById(request)
@@ -185,6 +515,9 @@ func (c *Controller) FindById(ctx *fiber.Ctx) error {
 			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
 		}
 	}
+	if !c.allowed(ctx, claims, ActionRead, response.attributes()) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
 	return common.OkResponse(ctx, response)
 }
 
@@ -213,7 +546,7 @@ func (c *Controller) FindAll(ctx *fiber.Ctx) error {
 		logger.ErrorCtx(ctx.Context(), "find all employees: ", zap.Error(err))
 		return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
 	}
-	return common.OkResponse(ctx, response)
+	return common.OkResponse(ctx, c.filterAllowed(ctx, claims, response))
 }
 
 // Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/employees/find?ids=1,2,3"
@@ -229,6 +562,8 @@ func (c *Controller) FindAll(ctx *fiber.Ctx) error {
 // @Failure 500 {object} common.Response[string]
 // @Router /employees/find [get]
 func (c *Controller) FindByIds(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
 	logger := middleware.GetLogger(ctx)
 	idsParam := ctx.Query("ids")
 	stringIds := strings.Split(idsParam, ",")
@@ -257,12 +592,12 @@ func (c *Controller) FindByIds(ctx *fiber.Ctx) error {
 			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
 		}
 	}
-	return common.OkResponse(ctx, response)
+	return common.OkResponse(ctx, c.filterAllowed(ctx, claims, response))
 }
 
 // Функция-хендлер, которая будет вызываться при DELETE запросе по маршруту "/api/v1/employees/:id"
 // @Summary Delete employee by ID
-// @Description Deletes a single employee by their unique ID with roles: admin
+// @Description Deletes a single employee by their unique ID with roles: admin, or a delegated admin of its department
 // @Tags employee
 // @Security OAuth2Password
 // @Accept json
@@ -275,9 +610,6 @@ func (c *Controller) FindByIds(ctx *fiber.Ctx) error {
 func (c *Controller) DeleteById(ctx *fiber.Ctx) error {
 	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
 	claims := token.Claims.(*web.IdmClaims)
-	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
-		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
-	}
 	logger := middleware.GetLogger(ctx)
 	var param = ctx.Params("id")
 	id, err := strconv.Atoi(param)
@@ -287,6 +619,14 @@ func (c *Controller) DeleteById(ctx *fiber.Ctx) error {
 	}
 	request := IdRequest{Id: int64(id)}
 	logger.InfoCtx(ctx.Context(), "delete by id employee: received request", zap.Any("request", request))
+	allowed, err := c.deletable(ctx, claims, []int64{request.Id})
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error checking delegated scope: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+	if !allowed {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
 	err = c.employeeService.DeleteById(request)
 	if err != nil {
 		switch {
@@ -306,7 +646,7 @@ func (c *Controller) DeleteById(ctx *fiber.Ctx) error {
 
 // Функция-хендлер, которая будет вызываться при Delete запросе по маршруту "/api/v1/employees/delete?ids=1,2,3"
 // @Summary Delete multiple employees by IDs
-// @Description Deletes multiple employees matching the provided IDs with roles: admin
+// @Description Deletes multiple employees matching the provided IDs with roles: admin, or a delegated admin of their departments
 // @Tags employee
 // @Security OAuth2Password
 // @Accept json
@@ -319,9 +659,6 @@ func (c *Controller) DeleteById(ctx *fiber.Ctx) error {
 func (c *Controller) DeleteByIds(ctx *fiber.Ctx) error {
 	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
 	claims := token.Claims.(*web.IdmClaims)
-	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
-		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
-	}
 	logger := middleware.GetLogger(ctx)
 	idsParam := ctx.Query("ids")
 	stringIds := strings.Split(idsParam, ",")
@@ -336,7 +673,15 @@ func (c *Controller) DeleteByIds(ctx *fiber.Ctx) error {
 	}
 	var request = IdsRequest{Ids: ids}
 	logger.InfoCtx(ctx.Context(), "delete by ids: received request", zap.Any("request", request))
-	err := c.employeeService.DeleteByIds(request)
+	allowed, err := c.deletable(ctx, claims, ids)
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error checking delegated scope: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+	if !allowed {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	err = c.employeeService.DeleteByIds(request)
 	if err != nil {
 		switch {
 		case errors.As(err, &common.RequestValidationError{}):
@@ -356,3 +701,219 @@ func (c *Controller) DeleteByIds(ctx *fiber.Ctx) error {
 	}
 	return common.OkResponse(ctx, responses)
 }
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/employees/:id/roles"
+// @Summary Assign role to employee
+// @Description Assigns a role to an employee directly, without an access request, with roles: admin, or a delegated admin or the owner of the role
+// @Tags employee
+// @Security OAuth2Password
+// @Accept json
+// @Produce json
+// @Param id path int true "Employee ID"
+// @Param request body employee.RoleAssignmentRequest true "role assignment request"
+// @Success 200 {object} common.Response[int64]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /employees/{id}/roles [post]
+func (c *Controller) AssignRole(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	logger := middleware.GetLogger(ctx)
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error parsing id: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request RoleAssignmentRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		logger.ErrorCtx(ctx.Context(), "body parse error: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	request.EmployeeId = id
+	request.Actor = claims.Subject
+	logger.InfoCtx(ctx.Context(), "assign role: received request", zap.Any("request", request))
+	return c.changeRole(ctx, claims, request, "assign role: ", c.employeeService.AssignRole)
+}
+
+// Функция-хендлер, которая будет вызываться при DELETE запросе по маршруту "/api/v1/employees/:id/roles/:roleId"
+// @Summary Unassign role from employee
+// @Description Removes a role assigned directly or by an access request, with roles: admin, or a delegated admin or the owner of the role
+// @Tags employee
+// @Security OAuth2Password
+// @Accept json
+// @Produce json
+// @Param id path int true "Employee ID"
+// @Param roleId path int true "Role ID"
+// @Success 200 {object} common.Response[int64]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /employees/{id}/roles/{roleId} [delete]
+func (c *Controller) UnassignRole(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	logger := middleware.GetLogger(ctx)
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error parsing id: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	roleId, err := strconv.ParseInt(ctx.Params("roleId"), 10, 64)
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error parsing role id: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request = RoleAssignmentRequest{EmployeeId: id, RoleId: roleId, Actor: claims.Subject}
+	logger.InfoCtx(ctx.Context(), "unassign role: received request", zap.Any("request", request))
+	return c.changeRole(ctx, claims, request, "unassign role: ", c.employeeService.UnassignRole)
+}
+
+// changeRole проверить, что роль входит в полномочия пользователя или он управляет ролью по отношениям,
+// и назначить или отозвать её
+func (c *Controller) changeRole(
+	ctx *fiber.Ctx,
+	claims *web.IdmClaims,
+	request RoleAssignmentRequest,
+	operation string,
+	change func(ctx context.Context, request RoleAssignmentRequest) error,
+) error {
+	logger := middleware.GetLogger(ctx)
+	scope, err := c.delegations.Scope(ctx.Context(), claims.Subject, claims.RealmAccess.Roles)
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error checking delegated scope: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+	if !scope.AllowsRole(request.RoleId) && !c.related(ctx, claims, relation.PermissionManage, relation.Role(request.RoleId)) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	err = change(ctx.Context(), request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			logger.ErrorCtx(ctx.Context(), operation, zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), operation, zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), operation, zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, request.EmployeeId)
+}
+
+// allowed проверить действие над сотрудником политиками доступа
+func (c *Controller) allowed(ctx *fiber.Ctx, claims *web.IdmClaims, action string, resource map[string]any) bool {
+	var decision = c.policy.Evaluate(ctx.Context(), policy.Request{
+		Subject:  policy.Subject(claims),
+		Action:   action,
+		Resource: resource,
+	})
+	return decision.Allowed
+}
+
+// managesEmployees проверить, что все сотрудники с указанными id входят в полномочия пользователя:
+// IDM_ADMIN управляет всеми, делегированный администратор - своим подразделением.
+// Администратор набора ролей сотрудниками не управляет, он только назначает и отзывает свои роли
+func (c *Controller) managesEmployees(ctx *fiber.Ctx, claims *web.IdmClaims, ids []int64) (bool, error) {
+	scope, err := c.delegations.Scope(ctx.Context(), claims.Subject, claims.RealmAccess.Roles)
+	if err != nil {
+		return false, err
+	}
+	if scope.Admin {
+		return true, nil
+	}
+	if len(scope.Departments) == 0 {
+		return false, nil
+	}
+	employees, err := c.employeeService.FindByIds(IdsRequest{Ids: ids})
+	if err != nil {
+		return false, err
+	}
+	var allowed = make(map[int64]bool, len(employees))
+	for _, employee := range employees {
+		allowed[employee.Id] = scope.AllowsEmployee(employee.Department)
+	}
+	for _, id := range ids {
+		if !allowed[id] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// deletable проверить, что пользователь может удалить сотрудников с указанными id: они входят в его полномочия,
+// а политики разрешают удаление с теми же атрибутами сотрудника, что и создание и чтение.
+// Сотрудников, которых нет, IDM_ADMIN удаляет без проверки политик, остальным они недоступны
+func (c *Controller) deletable(ctx *fiber.Ctx, claims *web.IdmClaims, ids []int64) (bool, error) {
+	scope, err := c.delegations.Scope(ctx.Context(), claims.Subject, claims.RealmAccess.Roles)
+	if err != nil {
+		return false, err
+	}
+	if !scope.Admin && len(scope.Departments) == 0 {
+		return false, nil
+	}
+	employees, err := c.employeeService.FindByIds(IdsRequest{Ids: ids})
+	if err != nil {
+		return false, err
+	}
+	var found = make(map[int64]Response, len(employees))
+	for _, employee := range employees {
+		found[employee.Id] = employee
+	}
+	for _, id := range ids {
+		employee, ok := found[id]
+		switch {
+		case !ok && scope.Admin:
+			continue
+		case !ok, !scope.AllowsEmployee(employee.Department),
+			!c.allowed(ctx, claims, ActionDelete, employee.attributes()):
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// related проверить отношение пользователя из токена к объекту, например руководителя к подчинённому
+func (c *Controller) related(ctx *fiber.Ctx, claims *web.IdmClaims, permission string, object string) bool {
+	allowed, err := c.relations.Check(ctx.Context(), relation.User(claims.Subject), permission, object)
+	if err != nil {
+		middleware.GetLogger(ctx).ErrorCtx(ctx.Context(), "error checking relation: ", zap.Error(err))
+		return false
+	}
+	return allowed
+}
+
+// filterAllowed оставить в ответе только сотрудников, чтение которых разрешено политиками
+func (c *Controller) filterAllowed(ctx *fiber.Ctx, claims *web.IdmClaims, responses []Response) []Response {
+	var readable = c.readable(ctx, claims)
+	if readable == nil {
+		return responses
+	}
+	var allowed = make([]Response, 0, len(responses))
+	for _, response := range responses {
+		if readable(response) {
+			allowed = append(allowed, response)
+		}
+	}
+	return allowed
+}
+
+// readable проверка чтения сотрудника политиками; страницы собираются из сотрудников, которых она разрешает.
+// nil, если политики чтение не ограничивают: тогда страница читается запросом с LIMIT/OFFSET.
+// Решения по каждой записи списка в журнал не пишутся
+func (c *Controller) readable(ctx *fiber.Ctx, claims *web.IdmClaims) func(Response) bool {
+	if !c.policy.Restricts(ActionRead) {
+		return nil
+	}
+	var subject = policy.Subject(claims)
+	return func(response Response) bool {
+		return c.policy.Check(ctx.Context(), policy.Request{
+			Subject:  subject,
+			Action:   ActionRead,
+			Resource: response.attributes(),
+		}).Allowed
+	}
+}
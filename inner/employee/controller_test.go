@@ -2,29 +2,69 @@ package employee
 
 import (
 	"context"
-	"crypto/rand"
-	"crypto/rsa"
-	"encoding/base64"
 	"encoding/json"
-	jwtMiddleware "github.com/gofiber/contrib/jwt"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 	"idm/inner/common"
+	"idm/inner/delegation"
+	"idm/inner/policy"
 	"idm/inner/web"
+	"idm/inner/web/oidctest"
+	"idm/inner/web/webtest"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 )
 
+// движок политик без правил разрешает всё и не влияет на проверки ролей
+var allowAll = policy.NewEngine(&common.Logger{Logger: zap.NewNop()})
+
+// MockRelations отношения пользователей к объектам в виде "subject relation object"
+type MockRelations []string
+
+func (r MockRelations) Check(ctx context.Context, subject string, relation string, object string) (bool, error) {
+	return slices.Contains(r, subject+" "+relation+" "+object), nil
+}
+
+var noRelations = MockRelations{}
+
+// MockDelegations полномочия делегированных администраторов по subject
+type MockDelegations map[string]delegation.Scope
+
+func (d MockDelegations) Scope(ctx context.Context, subject string, roles []string) (delegation.Scope, error) {
+	if slices.Contains(roles, web.IdmAdmin) {
+		return delegation.Scope{Admin: true}, nil
+	}
+	return d[subject], nil
+}
+
+// MockBreakGlass роли, владельцы которых могут запросить экстренный доступ
+type MockBreakGlass []string
+
+func (b MockBreakGlass) Available(roles []string) bool {
+	return slices.ContainsFunc(roles, func(role string) bool { return slices.Contains(b, role) })
+}
+
+var noBreakGlass = MockBreakGlass{}
+
+var delegations = MockDelegations{
+	"sales-admin":     {Departments: []string{"Sales"}, RoleIds: []int64{1}},
+	"developer-admin": {RoleIds: []int64{5}},
+}
+
 type MockService struct {
 	mock.Mock
+	// filtered страница запрошена с проверкой политик
+	filtered bool
 }
 
 func (svc *MockService) Save(ctx context.Context, request CreateRequest) (Response, error) {
@@ -47,7 +87,19 @@ func (svc *MockService) FindByIds(request IdsRequest) ([]Response, error) {
 	return args.Get(0).([]Response), args.Error(1)
 }
 
-func (svc *MockService) FindWithOffset(request PageRequest) (PageResponse, error) {
+func (svc *MockService) FindWithOffset(request PageRequest, allowed func(Response) bool) (PageResponse, error) {
+	svc.filtered = allowed != nil
+	args := svc.Called(request)
+	return args.Get(0).(PageResponse), args.Error(1)
+}
+
+func (svc *MockService) FindRiskiest() ([]Response, error) {
+	args := svc.Called()
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (svc *MockService) FindByRole(request RoleMembersRequest, allowed func(Response) bool) (PageResponse, error) {
+	svc.filtered = allowed != nil
 	args := svc.Called(request)
 	return args.Get(0).(PageResponse), args.Error(1)
 }
@@ -62,6 +114,31 @@ func (svc *MockService) DeleteByIds(request IdsRequest) error {
 	return args.Error(0)
 }
 
+func (svc *MockService) FindMe(request MeRequest) (MeResponse, error) {
+	args := svc.Called(request)
+	return args.Get(0).(MeResponse), args.Error(1)
+}
+
+func (svc *MockService) UpdateDepartment(ctx context.Context, request UpdateDepartmentRequest) error {
+	args := svc.Called(ctx, request)
+	return args.Error(0)
+}
+
+func (svc *MockService) AssignRole(ctx context.Context, request RoleAssignmentRequest) error {
+	args := svc.Called(ctx, request)
+	return args.Error(0)
+}
+
+func (svc *MockService) UnassignRole(ctx context.Context, request RoleAssignmentRequest) error {
+	args := svc.Called(ctx, request)
+	return args.Error(0)
+}
+
+func (svc *MockService) LinkSubject(request LinkSubjectRequest) error {
+	args := svc.Called(request)
+	return args.Error(0)
+}
+
 func TestCreateEmployee(t *testing.T) {
 	var a = assert.New(t)
 	file := createEnvFile(t, "DB_DRIVER_NAME=random_driver\n"+
@@ -81,7 +158,7 @@ func TestCreateEmployee(t *testing.T) {
 		server := web.NewServer()
 		server.GroupApiV1.Use(auth)
 		var svc = new(MockService)
-		var controller = NewController(server, svc)
+		var controller = NewController(server, svc, allowAll, noRelations, delegations, noBreakGlass)
 		controller.RegisterRoutes()
 		var body = strings.NewReader("{\"name\": \"john doe\", \"role_id\": 1}")
 		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/employees", body)
@@ -113,7 +190,7 @@ func TestCreateEmployee(t *testing.T) {
 		server := web.NewServer()
 		server.GroupApiV1.Use(auth)
 		var svc = new(MockService)
-		var controller = NewController(server, svc)
+		var controller = NewController(server, svc, allowAll, noRelations, delegations, noBreakGlass)
 		controller.RegisterRoutes()
 		var body = strings.NewReader("{\"name\": \"\", \"role_id\": 1}")
 		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/employees", body)
@@ -145,7 +222,7 @@ func TestCreateEmployee(t *testing.T) {
 		server := web.NewServer()
 		server.GroupApiV1.Use(auth)
 		var svc = new(MockService)
-		var controller = NewController(server, svc)
+		var controller = NewController(server, svc, allowAll, noRelations, delegations, noBreakGlass)
 		controller.RegisterRoutes()
 		var body = strings.NewReader("{\"name\": \"john doe\", \"role_id\": 1}")
 		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/employees", body)
@@ -177,7 +254,7 @@ func TestCreateEmployee(t *testing.T) {
 		server := web.NewServer()
 		server.GroupApiV1.Use(auth)
 		var svc = new(MockService)
-		var controller = NewController(server, svc)
+		var controller = NewController(server, svc, allowAll, noRelations, delegations, noBreakGlass)
 		controller.RegisterRoutes()
 		var body = strings.NewReader("{\"name\": \"john doe\", \"role_id\": 1}")
 		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/employees", body)
@@ -197,33 +274,12 @@ func TestCreateEmployee(t *testing.T) {
 		a.Equal(message, responseBody.Message)
 	})
 	t.Run("create employee invalid token", func(t *testing.T) {
-		jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			jwks := map[string]interface{}{
-				"keys": []interface{}{
-					map[string]interface{}{
-						"kty": "RSA",
-						"alg": "RS256",
-						"use": "sig",
-						"kid": "test-key",
-					},
-				},
-			}
-			_ = json.NewEncoder(w).Encode(jwks)
-		}))
-		defer jwksServer.Close()
-		web.AuthMiddleware = func(logger *common.Logger) fiber.Handler {
-			config := jwtMiddleware.Config{
-				ContextKey:   web.JwtKey,
-				ErrorHandler: web.CreateJwtErrorHandler(logger),
-				JWKSetURLs:   []string{jwksServer.URL},
-				Claims:       &web.IdmClaims{},
-			}
-			return jwtMiddleware.New(config)
-		}
+		var provider = oidctest.NewProvider()
+		defer provider.Close()
 		server := web.NewServer()
-		server.GroupApiV1.Use(web.AuthMiddleware(logger))
+		server.GroupApiV1.Use(web.AuthMiddleware(logger, provider.Config(common.Config{})))
 		var svc = new(MockService)
-		var controller = NewController(server, svc)
+		var controller = NewController(server, svc, allowAll, noRelations, delegations, noBreakGlass)
 		controller.RegisterRoutes()
 		var body = strings.NewReader("{\"name\": \"john doe\", \"role_id\": 1}")
 		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/employees", body)
@@ -244,46 +300,16 @@ func TestCreateEmployee(t *testing.T) {
 		a.Equal(message, responseBody.Message)
 	})
 	t.Run("create employee expired token", func(t *testing.T) {
-		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
-		require.NoError(t, err)
-		publicKey := &privateKey.PublicKey
-		jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			jwks := map[string]interface{}{
-				"keys": []interface{}{
-					map[string]interface{}{
-						"kty": "RSA",
-						"alg": "RS256",
-						"use": "sig",
-						"kid": "test-key",
-						"n":   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
-						"e":   "AQAB",
-					},
-				},
-			}
-			_ = json.NewEncoder(w).Encode(jwks)
-		}))
-		defer jwksServer.Close()
-		claims := jwt.RegisteredClaims{
+		var provider = oidctest.NewProvider()
+		defer provider.Close()
+		signedToken := provider.Sign(&web.IdmClaims{RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-1 * time.Hour)),
 			IssuedAt:  jwt.NewNumericDate(time.Now().Add(-2 * time.Hour)),
-		}
-		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-		token.Header["kid"] = "test-key"
-		signedToken, err := token.SignedString(privateKey)
-		require.NoError(t, err)
-		web.AuthMiddleware = func(logger *common.Logger) fiber.Handler {
-			config := jwtMiddleware.Config{
-				ContextKey:   web.JwtKey,
-				ErrorHandler: web.CreateJwtErrorHandler(logger),
-				JWKSetURLs:   []string{jwksServer.URL},
-				Claims:       &web.IdmClaims{},
-			}
-			return jwtMiddleware.New(config)
-		}
+		}})
 		server := web.NewServer()
-		server.GroupApiV1.Use(web.AuthMiddleware(logger))
+		server.GroupApiV1.Use(web.AuthMiddleware(logger, provider.Config(common.Config{})))
 		var svc = new(MockService)
-		var controller = NewController(server, svc)
+		var controller = NewController(server, svc, allowAll, noRelations, delegations, noBreakGlass)
 		controller.RegisterRoutes()
 		var body = strings.NewReader("{\"name\": \"john doe\", \"role_id\": 1}")
 		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/employees", body)
@@ -304,33 +330,12 @@ func TestCreateEmployee(t *testing.T) {
 		a.Equal(message, responseBody.Message)
 	})
 	t.Run("create employee without token", func(t *testing.T) {
-		jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			jwks := map[string]interface{}{
-				"keys": []interface{}{
-					map[string]interface{}{
-						"kty": "RSA",
-						"alg": "RS256",
-						"use": "sig",
-						"kid": "test-key",
-					},
-				},
-			}
-			_ = json.NewEncoder(w).Encode(jwks)
-		}))
-		defer jwksServer.Close()
-		web.AuthMiddleware = func(logger *common.Logger) fiber.Handler {
-			config := jwtMiddleware.Config{
-				ContextKey:   web.JwtKey,
-				ErrorHandler: web.CreateJwtErrorHandler(logger),
-				JWKSetURLs:   []string{jwksServer.URL},
-				Claims:       &web.IdmClaims{},
-			}
-			return jwtMiddleware.New(config)
-		}
+		var provider = oidctest.NewProvider()
+		defer provider.Close()
 		server := web.NewServer()
-		server.GroupApiV1.Use(web.AuthMiddleware(logger))
+		server.GroupApiV1.Use(web.AuthMiddleware(logger, provider.Config(common.Config{})))
 		var svc = new(MockService)
-		var controller = NewController(server, svc)
+		var controller = NewController(server, svc, allowAll, noRelations, delegations, noBreakGlass)
 		controller.RegisterRoutes()
 		var body = strings.NewReader("{\"name\": \"john doe\", \"role_id\": 1}")
 		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/employees", body)
@@ -364,7 +369,7 @@ func TestFindEmployeeById(t *testing.T) {
 		server := web.NewServer()
 		server.GroupApiV1.Use(auth)
 		var svc = new(MockService)
-		var controller = NewController(server, svc)
+		var controller = NewController(server, svc, allowAll, noRelations, delegations, noBreakGlass)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/123", nil)
 		request.Header.Add("Content-Type", "application/json")
@@ -391,7 +396,7 @@ func TestFindEmployeeById(t *testing.T) {
 		server := web.NewServer()
 		server.GroupApiV1.Use(auth)
 		var svc = new(MockService)
-		var controller = NewController(server, svc)
+		var controller = NewController(server, svc, allowAll, noRelations, delegations, noBreakGlass)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/ffff", nil)
 		request.Header.Add("Content-Type", "application/json")
@@ -411,7 +416,7 @@ func TestFindEmployeeById(t *testing.T) {
 		server := web.NewServer()
 		server.GroupApiV1.Use(auth)
 		var svc = new(MockService)
-		var controller = NewController(server, svc)
+		var controller = NewController(server, svc, allowAll, noRelations, delegations, noBreakGlass)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/0", nil)
 		request.Header.Add("Content-Type", "application/json")
@@ -441,7 +446,7 @@ func TestFindEmployeeById(t *testing.T) {
 		server := web.NewServer()
 		server.GroupApiV1.Use(auth)
 		var svc = new(MockService)
-		var controller = NewController(server, svc)
+		var controller = NewController(server, svc, allowAll, noRelations, delegations, noBreakGlass)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/123", nil)
 		request.Header.Add("Content-Type", "application/json")
@@ -475,7 +480,7 @@ func TestFindAllEmployees(t *testing.T) {
 		server := web.NewServer()
 		server.GroupApiV1.Use(auth)
 		var svc = new(MockService)
-		var controller = NewController(server, svc)
+		var controller = NewController(server, svc, allowAll, noRelations, delegations, noBreakGlass)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodGet, "/api/v1/employees", nil)
 		request.Header.Add("Content-Type", "application/json")
@@ -508,7 +513,7 @@ func TestFindAllEmployees(t *testing.T) {
 		server := web.NewServer()
 		server.GroupApiV1.Use(auth)
 		var svc = new(MockService)
-		var controller = NewController(server, svc)
+		var controller = NewController(server, svc, allowAll, noRelations, delegations, noBreakGlass)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodGet, "/api/v1/employees", nil)
 		request.Header.Add("Content-Type", "application/json")
@@ -542,7 +547,7 @@ func TestFindEmployeesByIds(t *testing.T) {
 		server := web.NewServer()
 		server.GroupApiV1.Use(auth)
 		var svc = new(MockService)
-		var controller = NewController(server, svc)
+		var controller = NewController(server, svc, allowAll, noRelations, delegations, noBreakGlass)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/find?ids=123,124,125", nil)
 		request.Header.Add("Content-Type", "application/json")
@@ -574,7 +579,7 @@ func TestFindEmployeesByIds(t *testing.T) {
 		server := web.NewServer()
 		server.GroupApiV1.Use(auth)
 		var svc = new(MockService)
-		var controller = NewController(server, svc)
+		var controller = NewController(server, svc, allowAll, noRelations, delegations, noBreakGlass)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/find?ids=fff,124,125", nil)
 		request.Header.Add("Content-Type", "application/json")
@@ -607,7 +612,7 @@ func TestFindEmployeesByIds(t *testing.T) {
 		server := web.NewServer()
 		server.GroupApiV1.Use(auth)
 		var svc = new(MockService)
-		var controller = NewController(server, svc)
+		var controller = NewController(server, svc, allowAll, noRelations, delegations, noBreakGlass)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/find?ids=123,124,125", nil)
 		request.Header.Add("Content-Type", "application/json")
@@ -637,7 +642,7 @@ func TestFindEmployeesByIds(t *testing.T) {
 		server := web.NewServer()
 		server.GroupApiV1.Use(auth)
 		var svc = new(MockService)
-		var controller = NewController(server, svc)
+		var controller = NewController(server, svc, allowAll, noRelations, delegations, noBreakGlass)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/find?ids=123,124,125", nil)
 		request.Header.Add("Content-Type", "application/json")
@@ -671,10 +676,11 @@ func TestDeleteEmployeeById(t *testing.T) {
 		server := web.NewServer()
 		server.GroupApiV1.Use(auth)
 		var svc = new(MockService)
-		var controller = NewController(server, svc)
+		var controller = NewController(server, svc, allowAll, noRelations, delegations, noBreakGlass)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodDelete, "/api/v1/employees/123", nil)
 		request.Header.Add("Content-Type", "application/json")
+		svc.On("FindByIds", mock.AnythingOfType("IdsRequest")).Return([]Response{}, nil)
 		svc.On("DeleteById", mock.AnythingOfType("IdRequest")).Return(nil)
 		resp, err := server.App.Test(request)
 		a.Nil(err)
@@ -698,7 +704,7 @@ func TestDeleteEmployeeById(t *testing.T) {
 		server := web.NewServer()
 		server.GroupApiV1.Use(auth)
 		var svc = new(MockService)
-		var controller = NewController(server, svc)
+		var controller = NewController(server, svc, allowAll, noRelations, delegations, noBreakGlass)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodDelete, "/api/v1/employees/ffff", nil)
 		request.Header.Add("Content-Type", "application/json")
@@ -718,11 +724,12 @@ func TestDeleteEmployeeById(t *testing.T) {
 		server := web.NewServer()
 		server.GroupApiV1.Use(auth)
 		var svc = new(MockService)
-		var controller = NewController(server, svc)
+		var controller = NewController(server, svc, allowAll, noRelations, delegations, noBreakGlass)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodDelete, "/api/v1/employees/0", nil)
 		request.Header.Add("Content-Type", "application/json")
 		message := "Field validation for 'Name' failed on the 'min' tag"
+		svc.On("FindByIds", mock.AnythingOfType("IdsRequest")).Return([]Response{}, nil)
 		svc.On("DeleteById", mock.AnythingOfType("IdRequest")).Return(common.RequestValidationError{
 			Message: message,
 		})
@@ -748,11 +755,12 @@ func TestDeleteEmployeeById(t *testing.T) {
 		server := web.NewServer()
 		server.GroupApiV1.Use(auth)
 		var svc = new(MockService)
-		var controller = NewController(server, svc)
+		var controller = NewController(server, svc, allowAll, noRelations, delegations, noBreakGlass)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodDelete, "/api/v1/employees/123", nil)
 		request.Header.Add("Content-Type", "application/json")
 		message := "error finding employee with id 123"
+		svc.On("FindByIds", mock.AnythingOfType("IdsRequest")).Return([]Response{}, nil)
 		svc.On("DeleteById", mock.AnythingOfType("IdRequest")).Return(common.NotFoundError{
 			Message: message,
 		})
@@ -782,7 +790,7 @@ func TestDeleteEmployeesByIds(t *testing.T) {
 		server := web.NewServer()
 		server.GroupApiV1.Use(auth)
 		var svc = new(MockService)
-		var controller = NewController(server, svc)
+		var controller = NewController(server, svc, allowAll, noRelations, delegations, noBreakGlass)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodDelete, "/api/v1/employees/delete?ids=123,124,125", nil)
 		request.Header.Add("Content-Type", "application/json")
@@ -791,6 +799,7 @@ func TestDeleteEmployeesByIds(t *testing.T) {
 			{Id: int64(124)},
 			{Id: int64(125)},
 		}
+		svc.On("FindByIds", mock.AnythingOfType("IdsRequest")).Return([]Response{}, nil)
 		svc.On("DeleteByIds", mock.AnythingOfType("IdsRequest")).Return(nil)
 		resp, err := server.App.Test(request)
 		a.Nil(err)
@@ -814,11 +823,12 @@ func TestDeleteEmployeesByIds(t *testing.T) {
 		server := web.NewServer()
 		server.GroupApiV1.Use(auth)
 		var svc = new(MockService)
-		var controller = NewController(server, svc)
+		var controller = NewController(server, svc, allowAll, noRelations, delegations, noBreakGlass)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodDelete, "/api/v1/employees/delete?ids=fff,124,125", nil)
 		request.Header.Add("Content-Type", "application/json")
 		message := "strconv.ParseInt: parsing \"fff\": invalid syntax"
+		svc.On("FindByIds", mock.AnythingOfType("IdsRequest")).Return([]Response{}, nil)
 		svc.On("DeleteByIds", mock.AnythingOfType("IdsRequest")).Return(nil)
 		resp, err := server.App.Test(request)
 		a.Nil(err)
@@ -842,11 +852,12 @@ func TestDeleteEmployeesByIds(t *testing.T) {
 		server := web.NewServer()
 		server.GroupApiV1.Use(auth)
 		var svc = new(MockService)
-		var controller = NewController(server, svc)
+		var controller = NewController(server, svc, allowAll, noRelations, delegations, noBreakGlass)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodDelete, "/api/v1/employees/delete?ids=123,124,125", nil)
 		request.Header.Add("Content-Type", "application/json")
 		message := "employee not found"
+		svc.On("FindByIds", mock.AnythingOfType("IdsRequest")).Return([]Response{}, nil)
 		svc.On("DeleteByIds", mock.AnythingOfType("IdsRequest")).Return(common.RequestValidationError{
 			Message: message,
 		})
@@ -872,11 +883,12 @@ func TestDeleteEmployeesByIds(t *testing.T) {
 		server := web.NewServer()
 		server.GroupApiV1.Use(auth)
 		var svc = new(MockService)
-		var controller = NewController(server, svc)
+		var controller = NewController(server, svc, allowAll, noRelations, delegations, noBreakGlass)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodDelete, "/api/v1/employees/delete?ids=123,124,125", nil)
 		request.Header.Add("Content-Type", "application/json")
 		message := "error finding employees by ids"
+		svc.On("FindByIds", mock.AnythingOfType("IdsRequest")).Return([]Response{}, nil)
 		svc.On("DeleteByIds", mock.AnythingOfType("IdsRequest")).Return(common.NotFoundError{
 			Message: message,
 		})
@@ -893,6 +905,84 @@ func TestDeleteEmployeesByIds(t *testing.T) {
 	})
 }
 
+func TestFindEmployeesByRole(t *testing.T) {
+	var a = assert.New(t)
+	t.Run("find employees by role", func(t *testing.T) {
+		var claims = &web.IdmClaims{
+			RealmAccess: web.RealmAccessClaims{Roles: []string{web.IdmUser}},
+		}
+		var auth = func(c *fiber.Ctx) error {
+			c.Locals(web.JwtKey, &jwt.Token{Claims: claims})
+			return c.Next()
+		}
+		server := web.NewServer()
+		server.GroupApiV1.Use(auth)
+		var svc = new(MockService)
+		var controller = NewController(server, svc, allowAll, noRelations, delegations, noBreakGlass)
+		controller.RegisterRoutes()
+		var request = httptest.NewRequest(fiber.MethodGet,
+			"/api/v1/roles/7/employees?pageNumber=0&pageSize=2&textFilter=john", nil)
+		var want = PageResponse{
+			Result:     []Response{{Id: 1, RoleId: 7}, {Id: 2, RoleId: 3}},
+			PageSize:   2,
+			PageNumber: 0,
+			Total:      5,
+		}
+		svc.On("FindByRole", RoleMembersRequest{
+			RoleId:     7,
+			PageSize:   2,
+			PageNumber: 0,
+			TextFilter: "john",
+		}).Return(want, nil)
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		bytesData, err := io.ReadAll(resp.Body)
+		a.Nil(err)
+		var responseBody common.Response[PageResponse]
+		err = json.Unmarshal(bytesData, &responseBody)
+		a.Nil(err)
+		a.Equal(want, responseBody.Data)
+	})
+	t.Run("find employees by role - incorrect role id", func(t *testing.T) {
+		var claims = &web.IdmClaims{
+			RealmAccess: web.RealmAccessClaims{Roles: []string{web.IdmUser}},
+		}
+		var auth = func(c *fiber.Ctx) error {
+			c.Locals(web.JwtKey, &jwt.Token{Claims: claims})
+			return c.Next()
+		}
+		server := web.NewServer()
+		server.GroupApiV1.Use(auth)
+		var svc = new(MockService)
+		var controller = NewController(server, svc, allowAll, noRelations, delegations, noBreakGlass)
+		controller.RegisterRoutes()
+		var request = httptest.NewRequest(fiber.MethodGet, "/api/v1/roles/abc/employees", nil)
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusBadRequest, resp.StatusCode)
+		a.True(svc.AssertNumberOfCalls(t, "FindByRole", 0))
+	})
+	t.Run("find employees by role - permission denied", func(t *testing.T) {
+		var claims = &web.IdmClaims{
+			RealmAccess: web.RealmAccessClaims{Roles: []string{}},
+		}
+		var auth = func(c *fiber.Ctx) error {
+			c.Locals(web.JwtKey, &jwt.Token{Claims: claims})
+			return c.Next()
+		}
+		server := web.NewServer()
+		server.GroupApiV1.Use(auth)
+		var svc = new(MockService)
+		var controller = NewController(server, svc, allowAll, noRelations, delegations, noBreakGlass)
+		controller.RegisterRoutes()
+		var request = httptest.NewRequest(fiber.MethodGet, "/api/v1/roles/7/employees", nil)
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+	})
+}
+
 func createEnvFile(t *testing.T, s string) string {
 	f, err := os.CreateTemp(".", ".env")
 	if err != nil {
@@ -906,3 +996,409 @@ func createEnvFile(t *testing.T, s string) string {
 	}
 	return f.Name()
 }
+
+func TestFindRiskiestEmployees(t *testing.T) {
+	var a = assert.New(t)
+	var newServer = func(svc Svc, roles ...string) *web.Server {
+		server := webtest.NewServer("", roles...)
+		NewController(server, svc, allowAll, noRelations, delegations, noBreakGlass).RegisterRoutes()
+		return server
+	}
+	t.Run("admin gets riskiest employees", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, web.IdmAdmin)
+		var want = []Response{{Id: 3, RiskScore: 60}, {Id: 1, RiskScore: 25}}
+		svc.On("FindRiskiest").Return(want, nil)
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/riskiest", nil))
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		bytesData, err := io.ReadAll(resp.Body)
+		a.Nil(err)
+		var responseBody common.Response[[]Response]
+		err = json.Unmarshal(bytesData, &responseBody)
+		a.Nil(err)
+		a.Equal(want, responseBody.Data)
+	})
+	t.Run("user can not get riskiest employees", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, web.IdmUser)
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/riskiest", nil))
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		a.True(svc.AssertNumberOfCalls(t, "FindRiskiest", 0))
+	})
+	t.Run("page sorted by risk score", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, web.IdmUser)
+		svc.On("FindWithOffset", PageRequest{
+			PageSize:   10,
+			PageNumber: 0,
+			SortBy:     "risk_score",
+			SortOrder:  "desc",
+		}).Return(PageResponse{PageSize: 10}, nil)
+		var request = httptest.NewRequest(fiber.MethodGet,
+			"/api/v1/employees/page?pageSize=10&pageNumber=0&sortBy=risk_score&sortOrder=desc", nil)
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+	})
+}
+
+func TestEmployeePolicies(t *testing.T) {
+	var a = assert.New(t)
+	var engine = policy.NewEngine(&common.Logger{Logger: zap.NewNop()})
+	err := engine.Apply([]byte(`{"rules": [
+		{"name": "user-reads-own-department", "effect": "deny", "actions": ["employee:read"],
+		 "condition": "!('IDM_ADMIN' in subject.roles) && resource.department != subject.department"},
+		{"name": "no-finance-deletes", "effect": "deny", "actions": ["employee:delete"],
+		 "condition": "resource.department == 'Finance'"}
+	]}`))
+	require.Nil(t, err)
+	var newServer = func(svc Svc, department string, roles ...string) *web.Server {
+		var claims = &web.IdmClaims{
+			RealmAccess: web.RealmAccessClaims{Roles: roles},
+			Department:  department,
+		}
+		var auth = func(c *fiber.Ctx) error {
+			c.Locals(web.JwtKey, &jwt.Token{Claims: claims})
+			return c.Next()
+		}
+		server := web.NewServer()
+		server.GroupApiV1.Use(auth)
+		NewController(server, svc, engine, noRelations, delegations, noBreakGlass).RegisterRoutes()
+		return server
+	}
+	t.Run("user reads employee of own department", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "Sales", web.IdmUser)
+		svc.On("FindById", IdRequest{Id: 1}).Return(Response{Id: 1, Department: "Sales"}, nil)
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/1", nil))
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+	})
+	t.Run("user can not read employee of other department", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "Sales", web.IdmUser)
+		svc.On("FindById", IdRequest{Id: 1}).Return(Response{Id: 1, Department: "Engineering"}, nil)
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/1", nil))
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+	})
+	t.Run("list is filtered by department", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "Sales", web.IdmUser)
+		svc.On("FindAll").Return([]Response{
+			{Id: 1, Department: "Sales"},
+			{Id: 2, Department: "Engineering"},
+		}, nil)
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees", nil))
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		bytesData, err := io.ReadAll(resp.Body)
+		a.Nil(err)
+		var responseBody common.Response[[]Response]
+		err = json.Unmarshal(bytesData, &responseBody)
+		a.Nil(err)
+		a.Equal([]Response{{Id: 1, Department: "Sales"}}, responseBody.Data)
+	})
+	t.Run("page is filtered only when read policies apply", func(t *testing.T) {
+		var request = PageRequest{PageSize: 10, PageNumber: 0}
+		var svc = new(MockService)
+		svc.On("FindWithOffset", request).Return(PageResponse{PageSize: 10}, nil)
+		resp, err := newServer(svc, "Sales", web.IdmUser).App.Test(
+			httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/page?pageSize=10", nil))
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		a.True(svc.filtered)
+		svc = new(MockService)
+		svc.On("FindWithOffset", request).Return(PageResponse{PageSize: 10}, nil)
+		server := webtest.NewServer("", web.IdmUser)
+		NewController(server, svc, allowAll, noRelations, delegations, noBreakGlass).RegisterRoutes()
+		resp, err = server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/page?pageSize=10", nil))
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		a.False(svc.filtered)
+	})
+	t.Run("admin reads any department", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "Sales", web.IdmAdmin)
+		svc.On("FindById", IdRequest{Id: 1}).Return(Response{Id: 1, Department: "Engineering"}, nil)
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/1", nil))
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+	})
+	t.Run("delete policy sees employee attributes", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "Sales", web.IdmAdmin)
+		svc.On("FindByIds", IdsRequest{Ids: []int64{1}}).Return([]Response{{Id: 1, Department: "Finance"}}, nil)
+		svc.On("FindByIds", IdsRequest{Ids: []int64{2}}).Return([]Response{{Id: 2, Department: "Sales"}}, nil)
+		svc.On("DeleteById", IdRequest{Id: 2}).Return(nil)
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodDelete, "/api/v1/employees/1", nil))
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		resp, err = server.App.Test(httptest.NewRequest(fiber.MethodDelete, "/api/v1/employees/2", nil))
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		a.True(svc.AssertNumberOfCalls(t, "DeleteById", 1))
+	})
+}
+
+func TestEmployeeRelations(t *testing.T) {
+	var a = assert.New(t)
+	var relations = MockRelations{
+		"user:manager-1 view employee:7",
+		"user:owner-1 manage role:3",
+	}
+	var newServer = func(svc Svc, subject string) *web.Server {
+		server := webtest.NewServer(subject)
+		NewController(server, svc, allowAll, relations, delegations, noBreakGlass).RegisterRoutes()
+		return server
+	}
+	t.Run("manager views report without IDM roles", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "manager-1")
+		svc.On("FindById", IdRequest{Id: 7}).Return(Response{Id: 7}, nil)
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/7", nil))
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+	})
+	t.Run("manager can not view other employees", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "manager-1")
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/8", nil))
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		a.True(svc.AssertNumberOfCalls(t, "FindById", 0))
+	})
+	t.Run("role owner lists role members", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "owner-1")
+		var request = RoleMembersRequest{RoleId: 3, PageSize: 100, PageNumber: 0}
+		svc.On("FindByRole", request).Return(PageResponse{PageSize: 100}, nil)
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/roles/3/employees", nil))
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		resp, err = server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/roles/4/employees", nil))
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+	})
+}
+
+func TestFindCurrentEmployee(t *testing.T) {
+	var a = assert.New(t)
+	var newServer = func(svc Svc, subject string, roles ...string) *web.Server {
+		server := webtest.NewServer(subject, roles...)
+		NewController(server, svc, allowAll, noRelations, delegations, noBreakGlass).RegisterRoutes()
+		return server
+	}
+	t.Run("user without IDM roles sees own record", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "kc-1")
+		var want = MeResponse{
+			Employee:    Response{Id: 3, Name: "john doe", Subject: "kc-1"},
+			Roles:       []RoleEntity{{RoleId: 2, Name: "Developer", Source: "primary"}},
+			Permissions: []string{"access-request:create", "me:read", "role:read"},
+		}
+		svc.On("FindMe", MeRequest{Subject: "kc-1"}).Return(want, nil)
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/me", nil))
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		bytesData, err := io.ReadAll(resp.Body)
+		a.Nil(err)
+		var responseBody common.Response[MeResponse]
+		a.Nil(json.Unmarshal(bytesData, &responseBody))
+		a.Equal(want, responseBody.Data)
+	})
+	t.Run("permissions include delegations and break-glass", func(t *testing.T) {
+		var svc = new(MockService)
+		server := webtest.NewServer("developer-admin", "ON_CALL")
+		NewController(server, svc, allowAll, noRelations, delegations, MockBreakGlass{"ON_CALL"}).RegisterRoutes()
+		svc.On("FindMe", MeRequest{
+			Subject:    "developer-admin",
+			TokenRoles: []string{"ON_CALL"},
+			Granted:    []string{"employee:assign-role", web.PermissionBreakGlass},
+		}).Return(MeResponse{}, nil)
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/me", nil))
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		svc.AssertExpectations(t)
+	})
+	t.Run("subject not linked", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "kc-2", web.IdmUser)
+		svc.On("FindMe", MeRequest{Subject: "kc-2", TokenRoles: []string{web.IdmUser}}).
+			Return(MeResponse{}, common.NotFoundError{Message: "no employee linked to subject kc-2"})
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/me", nil))
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		bytesData, err := io.ReadAll(resp.Body)
+		a.Nil(err)
+		var responseBody common.Response[MeResponse]
+		a.Nil(json.Unmarshal(bytesData, &responseBody))
+		a.False(responseBody.Success)
+	})
+	t.Run("admin links subject", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "admin-1", web.IdmAdmin)
+		svc.On("LinkSubject", LinkSubjectRequest{Id: 3, Subject: "kc-1"}).Return(nil)
+		var request = httptest.NewRequest(fiber.MethodPut, "/api/v1/employees/3/subject",
+			strings.NewReader("{\"subject\": \"kc-1\"}"))
+		request.Header.Add("Content-Type", "application/json")
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+	})
+	t.Run("link subject without role admin", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "kc-1", web.IdmUser)
+		var request = httptest.NewRequest(fiber.MethodPut, "/api/v1/employees/3/subject",
+			strings.NewReader("{\"subject\": \"kc-1\"}"))
+		request.Header.Add("Content-Type", "application/json")
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		a.True(svc.AssertNumberOfCalls(t, "LinkSubject", 0))
+	})
+}
+
+func TestDelegatedEmployeeAdministration(t *testing.T) {
+	var a = assert.New(t)
+	var newServer = func(svc Svc, subject string) *web.Server {
+		server := webtest.NewServer(subject)
+		NewController(server, svc, allowAll, noRelations, delegations, noBreakGlass).RegisterRoutes()
+		return server
+	}
+	var create = func(server *web.Server, body string) *http.Response {
+		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/employees", strings.NewReader(body))
+		request.Header.Add("Content-Type", "application/json")
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		return resp
+	}
+	t.Run("department admin creates employee of own department", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "sales-admin")
+		svc.On("Save", mock.Anything, CreateRequest{Name: "john doe", RoleId: 1, Department: "Sales"}).
+			Return(Response{Id: 10}, nil)
+		resp := create(server, "{\"name\": \"john doe\", \"role_id\": 1, \"department\": \"Sales\"}")
+		a.Equal(http.StatusOK, resp.StatusCode)
+	})
+	t.Run("department admin can not create employee of other department", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "sales-admin")
+		resp := create(server, "{\"name\": \"john doe\", \"role_id\": 1, \"department\": \"HR\"}")
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		a.True(svc.AssertNumberOfCalls(t, "Save", 0))
+	})
+	t.Run("department admin can not create employee with role outside scope", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "sales-admin")
+		resp := create(server, "{\"name\": \"john doe\", \"role_id\": 9, \"department\": \"Sales\"}")
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		a.True(svc.AssertNumberOfCalls(t, "Save", 0))
+	})
+	t.Run("role admin can not create employees, even with own role", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "developer-admin")
+		resp := create(server, "{\"name\": \"john doe\", \"role_id\": 5}")
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		a.True(svc.AssertNumberOfCalls(t, "Save", 0))
+	})
+	t.Run("role admin can not delete employees holding own role", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "developer-admin")
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodDelete, "/api/v1/employees/1", nil))
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		a.True(svc.AssertNumberOfCalls(t, "FindByIds", 0))
+		a.True(svc.AssertNumberOfCalls(t, "DeleteById", 0))
+	})
+	t.Run("role admin assigns and unassigns only own roles", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "developer-admin")
+		var assign = func(roleId string) *http.Response {
+			var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/employees/1/roles",
+				strings.NewReader("{\"role_id\": "+roleId+"}"))
+			request.Header.Add("Content-Type", "application/json")
+			resp, err := server.App.Test(request)
+			a.Nil(err)
+			return resp
+		}
+		var request = RoleAssignmentRequest{EmployeeId: 1, RoleId: 5, Actor: "developer-admin"}
+		svc.On("AssignRole", mock.Anything, request).Return(nil)
+		svc.On("UnassignRole", mock.Anything, request).Return(nil)
+		a.Equal(http.StatusOK, assign("5").StatusCode)
+		a.Equal(http.StatusForbidden, assign("1").StatusCode)
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodDelete, "/api/v1/employees/1/roles/5", nil))
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		resp, err = server.App.Test(httptest.NewRequest(fiber.MethodDelete, "/api/v1/employees/1/roles/1", nil))
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		a.True(svc.AssertNumberOfCalls(t, "AssignRole", 1))
+		a.True(svc.AssertNumberOfCalls(t, "UnassignRole", 1))
+	})
+	t.Run("role owner assigns own role by relation", func(t *testing.T) {
+		var svc = new(MockService)
+		server := webtest.NewServer("owner-1")
+		var relations = MockRelations{"user:owner-1 manage role:7"}
+		NewController(server, svc, allowAll, relations, delegations, noBreakGlass).RegisterRoutes()
+		svc.On("UnassignRole", mock.Anything, RoleAssignmentRequest{EmployeeId: 1, RoleId: 7, Actor: "owner-1"}).Return(nil)
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodDelete, "/api/v1/employees/1/roles/7", nil))
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		resp, err = server.App.Test(httptest.NewRequest(fiber.MethodDelete, "/api/v1/employees/1/roles/8", nil))
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		a.True(svc.AssertNumberOfCalls(t, "UnassignRole", 1))
+	})
+	t.Run("department admin can not assign roles", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "sales-admin")
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodDelete, "/api/v1/employees/1/roles/5", nil))
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+	})
+	t.Run("department admin deletes only employees of own department", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "sales-admin")
+		svc.On("FindByIds", IdsRequest{Ids: []int64{1, 2}}).Return([]Response{
+			{Id: 1, Department: "Sales"},
+			{Id: 2, Department: "HR"},
+		}, nil)
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodDelete, "/api/v1/employees/delete?ids=1,2", nil))
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		a.True(svc.AssertNumberOfCalls(t, "DeleteByIds", 0))
+		svc.On("FindByIds", IdsRequest{Ids: []int64{1}}).Return([]Response{{Id: 1, Department: "Sales"}}, nil)
+		svc.On("DeleteById", IdRequest{Id: 1}).Return(nil)
+		resp, err = server.App.Test(httptest.NewRequest(fiber.MethodDelete, "/api/v1/employees/1", nil))
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+	})
+	t.Run("department admin can not move employee to other department", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "sales-admin")
+		svc.On("FindByIds", IdsRequest{Ids: []int64{1}}).Return([]Response{{Id: 1, Department: "Sales"}}, nil)
+		var move = func(department string) *http.Response {
+			var request = httptest.NewRequest(fiber.MethodPut, "/api/v1/employees/1/department",
+				strings.NewReader("{\"department\": \""+department+"\"}"))
+			request.Header.Add("Content-Type", "application/json")
+			resp, err := server.App.Test(request)
+			a.Nil(err)
+			return resp
+		}
+		a.Equal(http.StatusForbidden, move("HR").StatusCode)
+		a.True(svc.AssertNumberOfCalls(t, "UpdateDepartment", 0))
+		svc.On("UpdateDepartment", mock.Anything, UpdateDepartmentRequest{Id: 1, Department: "Sales"}).Return(nil)
+		a.Equal(http.StatusOK, move("Sales").StatusCode)
+	})
+	t.Run("missing employee is not in scope", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "sales-admin")
+		svc.On("FindByIds", IdsRequest{Ids: []int64{9}}).Return([]Response{}, nil)
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodDelete, "/api/v1/employees/9", nil))
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+	})
+}
@@ -3,23 +3,51 @@ package employee
 import "time"
 
 type Entity struct {
-	Id        int64     `db:"id"`
-	Name      string    `db:"name"`
-	CreatedAt time.Time `db:"created_at"`
-	UpdatedAt time.Time `db:"updated_at"`
-	RoleId    int64     `db:"role_id"`
+	Id         int64     `db:"id"`
+	Name       string    `db:"name"`
+	CreatedAt  time.Time `db:"created_at"`
+	UpdatedAt  time.Time `db:"updated_at"`
+	RoleId     int64     `db:"role_id"`
+	Department string    `db:"department"`
+	Subject    string    `db:"subject"`
+	RiskScore  int64     `db:"risk_score"`
 }
 
 type Response struct {
-	Id        int64     `db:"id"`
-	Name      string    `db:"name"`
-	CreatedAt time.Time `db:"created_at"`
-	UpdatedAt time.Time `db:"updated_at"`
+	Id         int64     `db:"id"`
+	Name       string    `db:"name"`
+	RoleId     int64     `db:"role_id"`
+	Department string    `db:"department"`
+	Subject    string    `db:"subject"`
+	RiskScore  int64     `db:"risk_score"`
+	CreatedAt  time.Time `db:"created_at"`
+	UpdatedAt  time.Time `db:"updated_at"`
 }
 
 type CreateRequest struct {
-	Name   string `json:"name" validate:"required,min=2,max=155"`
-	RoleId int64  `json:"role_id" validate:"required,min=1"`
+	Name       string `json:"name" validate:"required,min=2,max=155" log:"mask"`
+	RoleId     int64  `json:"role_id" validate:"required,min=1"`
+	Department string `json:"department" validate:"max=155"`
+	// Subject идентификатор пользователя в Keycloak (claim sub), необязательный
+	Subject string `json:"subject" validate:"max=255"`
+}
+
+// attributes атрибуты сотрудника для проверки политиками доступа
+func (r Response) attributes() map[string]any {
+	return map[string]any{
+		"id":         r.Id,
+		"name":       r.Name,
+		"role_id":    r.RoleId,
+		"department": r.Department,
+	}
+}
+
+func (r CreateRequest) attributes() map[string]any {
+	return map[string]any{
+		"name":       r.Name,
+		"role_id":    r.RoleId,
+		"department": r.Department,
+	}
 }
 
 type IdRequest struct {
@@ -34,6 +62,60 @@ type PageRequest struct {
 	PageSize   int    `validate:"min=1,max=100"`
 	PageNumber int    `validate:"min=0"`
 	TextFilter string `validate:"omitempty,minnows3"`
+	SortBy     string `validate:"omitempty,oneof=id name risk_score"`
+	SortOrder  string `validate:"omitempty,oneof=asc desc"`
+}
+
+type RoleMembersRequest struct {
+	RoleId     int64  `validate:"required,min=1"`
+	PageSize   int    `validate:"min=1,max=100"`
+	PageNumber int    `validate:"min=0"`
+	TextFilter string `validate:"omitempty,minnows3"`
+}
+
+// LinkSubjectRequest привязать сотрудника к пользователю Keycloak
+type LinkSubjectRequest struct {
+	Id      int64  `json:"-" validate:"required,min=1"`
+	Subject string `json:"subject" validate:"required,max=255"`
+}
+
+// UpdateDepartmentRequest перевести сотрудника в другое подразделение
+type UpdateDepartmentRequest struct {
+	Id         int64  `json:"-" validate:"required,min=1"`
+	Department string `json:"department" validate:"max=155"`
+}
+
+// RoleAssignmentRequest назначить роль сотруднику или отозвать её
+type RoleAssignmentRequest struct {
+	EmployeeId int64  `json:"-" validate:"required,min=1"`
+	RoleId     int64  `json:"role_id" validate:"required,min=1"`
+	Actor      string `json:"-" validate:"required"`
+}
+
+// MeRequest запрос сведений о текущем пользователе;
+// Granted - права, полученные через делегирования и экстренный доступ, а не через роли из токена
+type MeRequest struct {
+	Subject    string `validate:"required"`
+	TokenRoles []string
+	Granted    []string
+}
+
+// RoleEntity роль, назначенная сотруднику, с источником назначения
+type RoleEntity struct {
+	RoleId    int64     `db:"role_id"`
+	Name      string    `db:"name"`
+	Source    string    `db:"source"`
+	CreatedBy string    `db:"created_by"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// MeResponse сведения о текущем пользователе: запись сотрудника, назначенные роли,
+// роли из токена и все права пользователя
+type MeResponse struct {
+	Employee    Response
+	Roles       []RoleEntity
+	TokenRoles  []string
+	Permissions []string
 }
 
 type PageResponse struct {
@@ -45,16 +127,22 @@ type PageResponse struct {
 
 func (e *Entity) toResponse() Response {
 	return Response{
-		Id:        e.Id,
-		Name:      e.Name,
-		CreatedAt: e.CreatedAt,
-		UpdatedAt: e.UpdatedAt,
+		Id:         e.Id,
+		Name:       e.Name,
+		RoleId:     e.RoleId,
+		Department: e.Department,
+		Subject:    e.Subject,
+		RiskScore:  e.RiskScore,
+		CreatedAt:  e.CreatedAt,
+		UpdatedAt:  e.UpdatedAt,
 	}
 }
 
 func (req *CreateRequest) ToEntity() Entity {
 	return Entity{
-		Name:   req.Name,
-		RoleId: req.RoleId,
+		Name:       req.Name,
+		RoleId:     req.RoleId,
+		Department: req.Department,
+		Subject:    req.Subject,
 	}
 }
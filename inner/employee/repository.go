@@ -5,6 +5,36 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
+// веса составляющих оценки риска сотрудника
+const (
+	riskPrivilegedRole = 10
+	riskCriticalRole   = 25
+	riskSodConflict    = 20
+	riskStaleReview    = 5
+)
+
+// selectWithRisk выборка сотрудников с оценкой риска: веса привилегированных и критичных ролей,
+// совмещённых конфликтующих ролей (SoD) и чувствительных ролей, не подтверждённых пересмотром за 90 дней
+var selectWithRisk = fmt.Sprintf("SELECT e.*, ("+
+	"SELECT COALESCE(SUM(CASE r.sensitivity WHEN 'critical' THEN %d WHEN 'privileged' THEN %d ELSE 0 END), 0) + "+
+	"%d * COUNT(*) FILTER (WHERE r.sensitivity <> 'normal' AND NOT EXISTS (SELECT 1 FROM certification_item ci "+
+	"WHERE ci.employee_id = e.id AND ci.role_id = r.id AND ci.decision = 'keep' "+
+	"AND ci.decided_at > NOW() - INTERVAL '90 days')) "+
+	"FROM role r WHERE r.id IN (SELECT er.role_id FROM employee_role er WHERE er.employee_id = e.id)"+
+	") + %d * ("+
+	"SELECT COUNT(*) FROM role_conflict rc "+
+	"WHERE rc.role_id IN (SELECT er.role_id FROM employee_role er WHERE er.employee_id = e.id) "+
+	"AND rc.conflicting_role_id IN (SELECT er.role_id FROM employee_role er WHERE er.employee_id = e.id)"+
+	") AS risk_score FROM employee e",
+	riskCriticalRole, riskPrivilegedRole, riskStaleReview, riskSodConflict)
+
+// sortColumns допустимые поля сортировки страницы сотрудников
+var sortColumns = map[string]string{
+	"id":         "e.id",
+	"name":       "e.name",
+	"risk_score": "risk_score",
+}
+
 type Repository struct {
 	db *sqlx.DB
 }
@@ -19,11 +49,15 @@ func (r *Repository) BeginTransaction() (*sqlx.Tx, error) {
 	return r.db.Beginx()
 }
 
+// Save сохранить сотрудника и записать его основную роль в назначения
 func (r *Repository) Save(tx *sqlx.Tx, e Entity) (int64, error) {
 	var id int64
 	err := tx.QueryRow(
-		"INSERT INTO employee (name, role_id) VALUES ($1, $2) RETURNING id",
-		e.Name, e.RoleId).Scan(&id)
+		"WITH created AS (INSERT INTO employee (name, role_id, department, subject) VALUES ($1, $2, $3, $4) "+
+			"RETURNING id, role_id) "+
+			"INSERT INTO employee_role (employee_id, role_id, source, created_by) "+
+			"SELECT id, role_id, 'primary', 'system' FROM created RETURNING employee_id",
+		e.Name, e.RoleId, e.Department, e.Subject).Scan(&id)
 	if err != nil {
 		return -1, err
 	}
@@ -31,7 +65,7 @@ func (r *Repository) Save(tx *sqlx.Tx, e Entity) (int64, error) {
 }
 
 func (r *Repository) FindById(id int64) (res Entity, err error) {
-	err = r.db.Get(&res, "SELECT * FROM employee WHERE id = $1", id)
+	err = r.db.Get(&res, selectWithRisk+" WHERE e.id = $1", id)
 	return res, err
 }
 
@@ -47,9 +81,52 @@ func (r *Repository) FindByName(tx *sqlx.Tx, name string) (isExist bool, err err
 	return isExist, nil
 }
 
+func (r *Repository) ExistsBySubject(tx *sqlx.Tx, subject string) (isExist bool, err error) {
+	err = tx.Get(&isExist, "SELECT EXISTS(SELECT 1 FROM employee WHERE subject = $1)", subject)
+	return isExist, err
+}
+
+func (r *Repository) FindBySubject(subject string) (res Entity, err error) {
+	err = r.db.Get(&res, selectWithRisk+" WHERE e.subject = $1", subject)
+	return res, err
+}
+
+// UpdateSubject привязать сотрудника к пользователю; вернуть false, если сотрудник не найден
+func (r *Repository) UpdateSubject(id int64, subject string) (bool, error) {
+	result, err := r.db.Exec("UPDATE employee SET subject = $1, updated_at = NOW() WHERE id = $2", subject, id)
+	if err != nil {
+		return false, err
+	}
+	updated, err := result.RowsAffected()
+	return updated > 0, err
+}
+
+// UpdateDepartment изменить подразделение сотрудника; вернуть false, если сотрудник не найден
+func (r *Repository) UpdateDepartment(tx *sqlx.Tx, id int64, department string) (bool, error) {
+	result, err := tx.Exec("UPDATE employee SET department = $1, updated_at = NOW() WHERE id = $2", department, id)
+	if err != nil {
+		return false, err
+	}
+	updated, err := result.RowsAffected()
+	return updated > 0, err
+}
+
+// FindRoles найти все роли сотрудника из назначений вместе с источником
+func (r *Repository) FindRoles(employeeId int64) ([]RoleEntity, error) {
+	var roles []RoleEntity
+	err := r.db.Select(
+		&roles,
+		"SELECT er.role_id, r.name, er.source, er.created_by, er.created_at "+
+			"FROM employee_role er JOIN role r ON r.id = er.role_id "+
+			"WHERE er.employee_id = $1 ORDER BY er.role_id, er.source",
+		employeeId,
+	)
+	return roles, err
+}
+
 func (r *Repository) FindAll() ([]Entity, error) {
 	var employees []Entity
-	rows, err := r.db.Queryx("SELECT * FROM employee")
+	rows, err := r.db.Queryx(selectWithRisk)
 	if err != nil {
 		return employees, err
 	}
@@ -65,7 +142,7 @@ func (r *Repository) FindAll() ([]Entity, error) {
 
 func (r *Repository) FindByIds(ids []int64) ([]Entity, error) {
 	var employees []Entity
-	query, args, err := sqlx.In("SELECT * FROM employee WHERE id IN (?)", ids)
+	query, args, err := sqlx.In(selectWithRisk+" WHERE e.id IN (?)", ids)
 	if err != nil {
 		return employees, err
 	}
@@ -84,17 +161,31 @@ func (r *Repository) FindByIds(ids []int64) ([]Entity, error) {
 	return employees, nil
 }
 
-func (r *Repository) FindWithOffset(offset int, limit int, filter string) ([]Entity, error) {
+func (r *Repository) FindWithOffset(
+	offset int,
+	limit int,
+	filter string,
+	sortBy string,
+	sortOrder string,
+) ([]Entity, error) {
 	var employees []Entity
-	query := "SELECT * FROM employee WHERE 1 = 1"
+	query := selectWithRisk + " WHERE 1 = 1"
 	var args []interface{}
 	paramIdx := 1
 	if filter != "" {
-		query += fmt.Sprintf(" AND name ILIKE $%d", paramIdx)
+		query += fmt.Sprintf(" AND e.name ILIKE $%d", paramIdx)
 		args = append(args, "%"+filter+"%")
 		paramIdx++
 	}
-	query += fmt.Sprintf(" ORDER BY id OFFSET $%d LIMIT $%d", paramIdx, paramIdx+1)
+	column, ok := sortColumns[sortBy]
+	if !ok {
+		column = sortColumns["id"]
+	}
+	direction := "ASC"
+	if sortOrder == "desc" {
+		direction = "DESC"
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, e.id OFFSET $%d LIMIT $%d", column, direction, paramIdx, paramIdx+1)
 	args = append(args, offset, limit)
 	err := r.db.Select(&employees, query, args...)
 	if err != nil {
@@ -103,6 +194,46 @@ func (r *Repository) FindWithOffset(offset int, limit int, filter string) ([]Ent
 	return employees, nil
 }
 
+// FindByRoleWithOffset найти страницу сотрудников, которым назначена роль
+func (r *Repository) FindByRoleWithOffset(roleId int64, offset int, limit int, filter string) ([]Entity, error) {
+	var employees []Entity
+	query := selectWithRisk +
+		" WHERE EXISTS(SELECT 1 FROM employee_role er WHERE er.role_id = $1 AND er.employee_id = e.id)"
+	var args = []interface{}{roleId}
+	paramIdx := 2
+	if filter != "" {
+		query += fmt.Sprintf(" AND e.name ILIKE $%d", paramIdx)
+		args = append(args, "%"+filter+"%")
+		paramIdx++
+	}
+	query += fmt.Sprintf(" ORDER BY e.id OFFSET $%d LIMIT $%d", paramIdx, paramIdx+1)
+	args = append(args, offset, limit)
+	err := r.db.Select(&employees, query, args...)
+	if err != nil {
+		return employees, err
+	}
+	return employees, nil
+}
+
+// FindRiskiest найти limit сотрудников с наибольшей оценкой риска
+func (r *Repository) FindRiskiest(limit int) ([]Entity, error) {
+	var employees []Entity
+	err := r.db.Select(&employees, selectWithRisk+" ORDER BY risk_score DESC, e.id LIMIT $1", limit)
+	return employees, err
+}
+
+func (r *Repository) CountByRole(roleId int64, filter string) (total int64, err error) {
+	query := "SELECT COUNT(*) FROM employee e " +
+		"WHERE EXISTS(SELECT 1 FROM employee_role er WHERE er.role_id = $1 AND er.employee_id = e.id)"
+	var args = []interface{}{roleId}
+	if filter != "" {
+		query += " AND e.name ILIKE $2"
+		args = append(args, "%"+filter+"%")
+	}
+	err = r.db.Get(&total, query, args...)
+	return total, err
+}
+
 func (r *Repository) DeleteById(id int64) error {
 	_, err := r.db.Exec("DELETE FROM employee WHERE id = $1", id)
 	if err != nil {
@@ -4,12 +4,26 @@ import (
 	"context"
 	"fmt"
 	"github.com/jmoiron/sqlx"
+	"idm/inner/assignment"
 	"idm/inner/common"
+	"idm/inner/database"
+	"idm/inner/web"
 )
 
+// riskiestLimit сколько сотрудников возвращает отчёт о самых рискованных учётных записях
+const riskiestLimit = 50
+
+// pageBatch по сколько сотрудников читать, когда страница собирается из разрешённых политиками записей
+const pageBatch = 500
+
+// pageScanLimit сколько сотрудников просматривается самое большее, когда страница собирается из разрешённых записей
+const pageScanLimit = 20 * pageBatch
+
 type Service struct {
-	repo      Repo
-	validator Validator
+	repo        Repo
+	rules       RuleRepo
+	assignments AssignmentRepo
+	validator   Validator
 }
 
 type Repo interface {
@@ -17,21 +31,42 @@ type Repo interface {
 	Save(tx *sqlx.Tx, e Entity) (int64, error)
 	FindById(id int64) (Entity, error)
 	FindByName(tx *sqlx.Tx, name string) (bool, error)
+	ExistsBySubject(tx *sqlx.Tx, subject string) (bool, error)
+	FindBySubject(subject string) (Entity, error)
+	UpdateSubject(id int64, subject string) (bool, error)
+	UpdateDepartment(tx *sqlx.Tx, id int64, department string) (bool, error)
+	FindRoles(employeeId int64) ([]RoleEntity, error)
 	FindAll() ([]Entity, error)
 	FindByIds(ids []int64) ([]Entity, error)
-	FindWithOffset(offset int, limit int, filter string) ([]Entity, error)
+	FindWithOffset(offset int, limit int, filter string, sortBy string, sortOrder string) ([]Entity, error)
+	FindRiskiest(limit int) ([]Entity, error)
+	FindByRoleWithOffset(roleId int64, offset int, limit int, filter string) ([]Entity, error)
+	CountByRole(roleId int64, filter string) (int64, error)
 	DeleteById(id int64) error
 	DeleteByIds(ids []int64) error
 }
 
+// RuleRepo пересчёт ролей, выдаваемых правилами по атрибутам сотрудника
+type RuleRepo interface {
+	Apply(tx *sqlx.Tx, employeeIds []int64) error
+}
+
+// AssignmentRepo назначения ролей сотрудникам в employee_role
+type AssignmentRepo interface {
+	Save(tx *sqlx.Tx, e assignment.Entity) error
+	Delete(tx *sqlx.Tx, employeeId int64, roleId int64, source string) error
+}
+
 type Validator interface {
 	Validate(request any) error
 }
 
-func NewService(repo Repo, validator Validator) *Service {
+func NewService(repo Repo, rules RuleRepo, assignments AssignmentRepo, validator Validator) *Service {
 	return &Service{
-		repo:      repo,
-		validator: validator,
+		repo:        repo,
+		rules:       rules,
+		assignments: assignments,
+		validator:   validator,
 	}
 }
 
@@ -42,40 +77,36 @@ func (s *Service) Save(ctx context.Context, request CreateRequest) (Response, er
 			Message: err.Error(),
 		}
 	}
-	tx, err := s.repo.BeginTransaction()
-	if err != nil {
-		return Response{}, fmt.Errorf("error creating transaction: %w", err)
-	}
-	defer func() {
-		if r := recover(); r != nil {
-			err = fmt.Errorf("creating employee panic: %v", r)
-			errTx := tx.Rollback()
-			if errTx != nil {
-				err = fmt.Errorf("creating employee: rolling back transaction errors: %w, %w", err, errTx)
-			}
-		} else if err != nil {
-			errTx := tx.Rollback()
-			if errTx != nil {
-				err = fmt.Errorf("creating employee: rolling back transaction errors: %w, %w", err, errTx)
+	var id int64
+	err = database.InTransaction(s.repo.BeginTransaction, "creating employee", func(tx *sqlx.Tx) error {
+		isExist, err := s.repo.FindByName(tx, request.Name)
+		if err != nil {
+			return fmt.Errorf("error finding employee: %w", err)
+		}
+		if isExist {
+			return common.AlreadyExistsError{Message: fmt.Sprintf("employee already exists: %v", request.Name)}
+		}
+		if request.Subject != "" {
+			isExist, err = s.repo.ExistsBySubject(tx, request.Subject)
+			if err != nil {
+				return fmt.Errorf("error finding employee by subject: %w", err)
 			}
-		} else {
-			errTx := tx.Commit()
-			if errTx != nil {
-				err = fmt.Errorf("creating employee: commiting transaction error: %w", errTx)
+			if isExist {
+				return common.AlreadyExistsError{Message: fmt.Sprintf("employee with subject already exists: %v", request.Subject)}
 			}
 		}
-	}()
-	isExist, err := s.repo.FindByName(tx, request.Name)
-	if err != nil {
-		return Response{}, fmt.Errorf("error finding employee: %w", err)
-	}
-	if isExist {
-		return Response{}, common.AlreadyExistsError{Message: fmt.Sprintf("employee already exists: %v", request.Name)}
-	}
-	var id int64
-	id, err = s.repo.Save(tx, request.ToEntity())
+		id, err = s.repo.Save(tx, request.ToEntity())
+		if err != nil {
+			return fmt.Errorf("error saving employee with: %w", err)
+		}
+		err = s.rules.Apply(tx, []int64{id})
+		if err != nil {
+			return fmt.Errorf("error assigning birthright roles: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return Response{}, fmt.Errorf("error saving employee with: %w", err)
+		return Response{}, err
 	}
 	return Response{
 		Id: id,
@@ -94,6 +125,99 @@ func (s *Service) FindById(request IdRequest) (Response, error) {
 	return entity.toResponse(), nil
 }
 
+// FindMe найти сотрудника, привязанного к пользователю из токена, и его роли
+func (s *Service) FindMe(request MeRequest) (MeResponse, error) {
+	if err := s.validator.Validate(request); err != nil {
+		return MeResponse{}, common.RequestValidationError{Message: err.Error()}
+	}
+	entity, err := s.repo.FindBySubject(request.Subject)
+	if err != nil {
+		return MeResponse{}, common.NotFoundError{Message: fmt.Sprintf("no employee linked to subject %s: %v", request.Subject, err)}
+	}
+	roles, err := s.repo.FindRoles(entity.Id)
+	if err != nil {
+		return MeResponse{}, fmt.Errorf("error finding roles of employee %d: %w", entity.Id, err)
+	}
+	return MeResponse{
+		Employee:    entity.toResponse(),
+		Roles:       roles,
+		TokenRoles:  request.TokenRoles,
+		Permissions: web.Permissions(request.TokenRoles, request.Granted...),
+	}, nil
+}
+
+func (s *Service) LinkSubject(request LinkSubjectRequest) error {
+	if err := s.validator.Validate(request); err != nil {
+		return common.RequestValidationError{Message: err.Error()}
+	}
+	updated, err := s.repo.UpdateSubject(request.Id, request.Subject)
+	if err != nil {
+		return fmt.Errorf("error linking employee %d to subject: %w", request.Id, err)
+	}
+	if !updated {
+		return common.NotFoundError{Message: fmt.Sprintf("employee with id %d not found", request.Id)}
+	}
+	return nil
+}
+
+// UpdateDepartment изменить подразделение и пересчитать роли, выданные правилами, в той же транзакции
+func (s *Service) UpdateDepartment(ctx context.Context, request UpdateDepartmentRequest) error {
+	if err := s.validator.Validate(request); err != nil {
+		return common.RequestValidationError{Message: err.Error()}
+	}
+	return database.InTransaction(s.repo.BeginTransaction, "updating employee department", func(tx *sqlx.Tx) error {
+		updated, err := s.repo.UpdateDepartment(tx, request.Id, request.Department)
+		if err != nil {
+			return fmt.Errorf("error updating department of employee %d: %w", request.Id, err)
+		}
+		if !updated {
+			return common.NotFoundError{Message: fmt.Sprintf("employee with id %d not found", request.Id)}
+		}
+		if err = s.rules.Apply(tx, []int64{request.Id}); err != nil {
+			return fmt.Errorf("error reassigning birthright roles: %w", err)
+		}
+		return nil
+	})
+}
+
+// AssignRole назначить сотруднику роль напрямую, без заявки на доступ
+func (s *Service) AssignRole(ctx context.Context, request RoleAssignmentRequest) error {
+	if err := s.validator.Validate(request); err != nil {
+		return common.RequestValidationError{Message: err.Error()}
+	}
+	if _, err := s.repo.FindById(request.EmployeeId); err != nil {
+		return common.NotFoundError{Message: fmt.Sprintf("error finding employee with id %d: %v", request.EmployeeId, err)}
+	}
+	return database.InTransaction(s.repo.BeginTransaction, "assigning role", func(tx *sqlx.Tx) error {
+		err := s.assignments.Save(tx, assignment.Entity{
+			EmployeeId: request.EmployeeId,
+			RoleId:     request.RoleId,
+			Source:     assignment.SourceDirect,
+			CreatedBy:  request.Actor,
+		})
+		if err != nil {
+			return fmt.Errorf("error assigning role %d to employee %d: %w", request.RoleId, request.EmployeeId, err)
+		}
+		return nil
+	})
+}
+
+// UnassignRole отозвать роль, назначенную напрямую или по заявке. Основная роль сотрудника
+// и роли, выданные правилами или экстренным доступом, так не отзываются
+func (s *Service) UnassignRole(ctx context.Context, request RoleAssignmentRequest) error {
+	if err := s.validator.Validate(request); err != nil {
+		return common.RequestValidationError{Message: err.Error()}
+	}
+	return database.InTransaction(s.repo.BeginTransaction, "unassigning role", func(tx *sqlx.Tx) error {
+		for _, source := range []string{assignment.SourceDirect, assignment.SourceRequest} {
+			if err := s.assignments.Delete(tx, request.EmployeeId, request.RoleId, source); err != nil {
+				return fmt.Errorf("error unassigning role %d from employee %d: %w", request.RoleId, request.EmployeeId, err)
+			}
+		}
+		return nil
+	})
+}
+
 func (s *Service) FindAll() ([]Response, error) {
 	var employees, err = s.repo.FindAll()
 	if err != nil {
@@ -121,11 +245,28 @@ func (s *Service) FindByIds(request IdsRequest) ([]Response, error) {
 	return response, nil
 }
 
-func (s *Service) FindWithOffset(request PageRequest) (PageResponse, error) {
+// FindWithOffset найти страницу сотрудников. Если задан allowed, страница собирается только из сотрудников,
+// которых он разрешает, и Total считает только их
+func (s *Service) FindWithOffset(request PageRequest, allowed func(Response) bool) (PageResponse, error) {
 	if err := s.validator.Validate(request); err != nil {
 		return PageResponse{}, common.RequestValidationError{Message: err.Error()}
 	}
-	employees, err := s.repo.FindWithOffset(request.PageSize*request.PageNumber, request.PageSize, request.TextFilter)
+	var fetch = func(offset int, limit int) ([]Entity, error) {
+		return s.repo.FindWithOffset(offset, limit, request.TextFilter, request.SortBy, request.SortOrder)
+	}
+	if allowed != nil {
+		response, total, err := allowedPage(request.PageSize, request.PageNumber, allowed, fetch)
+		if err != nil {
+			return PageResponse{}, common.NotFoundError{Message: fmt.Sprintf("error finding employees with offset: %v", err)}
+		}
+		return PageResponse{
+			Result:     response,
+			PageSize:   request.PageSize,
+			PageNumber: request.PageNumber,
+			Total:      total,
+		}, nil
+	}
+	employees, err := fetch(request.PageSize*request.PageNumber, request.PageSize)
 	if err != nil {
 		return PageResponse{}, common.NotFoundError{Message: fmt.Sprintf("error finding employees with offset: %v", err)}
 	}
@@ -141,6 +282,93 @@ func (s *Service) FindWithOffset(request PageRequest) (PageResponse, error) {
 	}, nil
 }
 
+// FindRiskiest найти сотрудников с наибольшей оценкой риска
+func (s *Service) FindRiskiest() ([]Response, error) {
+	employees, err := s.repo.FindRiskiest(riskiestLimit)
+	if err != nil {
+		return nil, common.NotFoundError{Message: fmt.Sprintf("error finding riskiest employees: %v", err)}
+	}
+	var response []Response
+	for _, employee := range employees {
+		response = append(response, employee.toResponse())
+	}
+	return response, nil
+}
+
+// FindByRole найти страницу сотрудников, которым назначена роль; allowed - как в FindWithOffset
+func (s *Service) FindByRole(request RoleMembersRequest, allowed func(Response) bool) (PageResponse, error) {
+	if err := s.validator.Validate(request); err != nil {
+		return PageResponse{}, common.RequestValidationError{Message: err.Error()}
+	}
+	var fetch = func(offset int, limit int) ([]Entity, error) {
+		return s.repo.FindByRoleWithOffset(request.RoleId, offset, limit, request.TextFilter)
+	}
+	if allowed != nil {
+		response, total, err := allowedPage(request.PageSize, request.PageNumber, allowed, fetch)
+		if err != nil {
+			return PageResponse{}, common.NotFoundError{Message: fmt.Sprintf("error finding employees of role %d: %v", request.RoleId, err)}
+		}
+		return PageResponse{
+			Result:     response,
+			PageSize:   request.PageSize,
+			PageNumber: request.PageNumber,
+			Total:      total,
+		}, nil
+	}
+	employees, err := fetch(request.PageSize*request.PageNumber, request.PageSize)
+	if err != nil {
+		return PageResponse{}, common.NotFoundError{Message: fmt.Sprintf("error finding employees of role %d: %v", request.RoleId, err)}
+	}
+	total, err := s.repo.CountByRole(request.RoleId, request.TextFilter)
+	if err != nil {
+		return PageResponse{}, common.NotFoundError{Message: fmt.Sprintf("error counting employees of role %d: %v", request.RoleId, err)}
+	}
+	var response []Response
+	for _, employee := range employees {
+		response = append(response, employee.toResponse())
+	}
+	return PageResponse{
+		Result:     response,
+		PageSize:   request.PageSize,
+		PageNumber: request.PageNumber,
+		Total:      total,
+	}, nil
+}
+
+// allowedPage собрать страницу pageNumber из сотрудников, которых разрешает allowed, и посчитать всех таких сотрудников.
+// Проверка идёт до разбиения на страницы, поэтому страницы не короче pageSize, а скрытые сотрудники не попадают в Total.
+// Просматриваются только первые pageScanLimit сотрудников, Total считается по ним
+func allowedPage(
+	pageSize int,
+	pageNumber int,
+	allowed func(Response) bool,
+	fetch func(offset int, limit int) ([]Entity, error),
+) ([]Response, int64, error) {
+	var response []Response
+	var total int64
+	var first = int64(pageSize * pageNumber)
+	for offset := 0; offset < pageScanLimit; offset += pageBatch {
+		employees, err := fetch(offset, pageBatch)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, employee := range employees {
+			var item = employee.toResponse()
+			if !allowed(item) {
+				continue
+			}
+			if total >= first && total < first+int64(pageSize) {
+				response = append(response, item)
+			}
+			total++
+		}
+		if len(employees) < pageBatch {
+			break
+		}
+	}
+	return response, total, nil
+}
+
 func (s *Service) DeleteById(request IdRequest) error {
 	var err = s.validator.Validate(request)
 	if err != nil {
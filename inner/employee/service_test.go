@@ -8,7 +8,9 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"idm/inner/assignment"
 	"idm/inner/common"
+	"idm/inner/database/dbtest"
 	"idm/inner/validator"
 	"strings"
 	"testing"
@@ -39,6 +41,31 @@ func (r *MockRepo) FindByName(tx *sqlx.Tx, name string) (bool, error) {
 	return args.Bool(0), args.Error(1)
 }
 
+func (r *MockRepo) ExistsBySubject(tx *sqlx.Tx, subject string) (bool, error) {
+	args := r.Called(tx, subject)
+	return args.Bool(0), args.Error(1)
+}
+
+func (r *MockRepo) FindBySubject(subject string) (Entity, error) {
+	args := r.Called(subject)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (r *MockRepo) UpdateSubject(id int64, subject string) (bool, error) {
+	args := r.Called(id, subject)
+	return args.Bool(0), args.Error(1)
+}
+
+func (r *MockRepo) UpdateDepartment(tx *sqlx.Tx, id int64, department string) (bool, error) {
+	args := r.Called(tx, id, department)
+	return args.Bool(0), args.Error(1)
+}
+
+func (r *MockRepo) FindRoles(employeeId int64) ([]RoleEntity, error) {
+	args := r.Called(employeeId)
+	return args.Get(0).([]RoleEntity), args.Error(1)
+}
+
 func (r *MockRepo) FindAll() ([]Entity, error) {
 	args := r.Called()
 	return args.Get(0).([]Entity), args.Error(1)
@@ -49,11 +76,26 @@ func (r *MockRepo) FindByIds(ids []int64) ([]Entity, error) {
 	return args.Get(0).([]Entity), args.Error(1)
 }
 
-func (r *MockRepo) FindWithOffset(offset int, limit int, filter string) ([]Entity, error) {
-	args := r.Called(offset, limit, filter)
+func (r *MockRepo) FindWithOffset(offset int, limit int, filter string, sortBy string, sortOrder string) ([]Entity, error) {
+	args := r.Called(offset, limit, filter, sortBy, sortOrder)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (r *MockRepo) FindRiskiest(limit int) ([]Entity, error) {
+	args := r.Called(limit)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (r *MockRepo) FindByRoleWithOffset(roleId int64, offset int, limit int, filter string) ([]Entity, error) {
+	args := r.Called(roleId, offset, limit, filter)
 	return args.Get(0).([]Entity), args.Error(1)
 }
 
+func (r *MockRepo) CountByRole(roleId int64, filter string) (int64, error) {
+	args := r.Called(roleId, filter)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 func (r *MockRepo) DeleteById(id int64) error {
 	args := r.Called(id)
 	return args.Error(0)
@@ -64,6 +106,29 @@ func (r *MockRepo) DeleteByIds(ids []int64) error {
 	return args.Error(0)
 }
 
+type MockRules struct {
+	mock.Mock
+}
+
+func (r *MockRules) Apply(tx *sqlx.Tx, employeeIds []int64) error {
+	args := r.Called(tx, employeeIds)
+	return args.Error(0)
+}
+
+type MockAssignmentRepo struct {
+	mock.Mock
+}
+
+func (r *MockAssignmentRepo) Save(tx *sqlx.Tx, e assignment.Entity) error {
+	args := r.Called(tx, e)
+	return args.Error(0)
+}
+
+func (r *MockAssignmentRepo) Delete(tx *sqlx.Tx, employeeId int64, roleId int64, source string) error {
+	args := r.Called(tx, employeeId, roleId, source)
+	return args.Error(0)
+}
+
 func TestSave(t *testing.T) {
 	t.Run("should return wrapped error because begin transaction was failed", func(t *testing.T) {
 		a := assert.New(t)
@@ -83,12 +148,13 @@ func TestSave(t *testing.T) {
 		}
 		sqlxDb := sqlx.NewDb(db, "sqlmock")
 		mck.ExpectBegin()
+		mck.ExpectRollback()
 		tx, err := sqlxDb.Beginx()
 		if err != nil {
 			t.Fatal(err)
 		}
 		var repo = new(MockRepo)
-		var svc = NewService(repo, validator.New())
+		var svc = NewService(repo, new(MockRules), new(MockAssignmentRepo), validator.New())
 		repo.On("BeginTransaction").Return(tx, nil)
 		var entity = Entity{
 			Id:        1,
@@ -121,12 +187,13 @@ func TestSave(t *testing.T) {
 		}
 		sqlxDb := sqlx.NewDb(db, "sqlmock")
 		mck.ExpectBegin()
+		mck.ExpectRollback()
 		tx, err := sqlxDb.Beginx()
 		if err != nil {
 			t.Fatal(err)
 		}
 		var repo = new(MockRepo)
-		var svc = NewService(repo, validator.New())
+		var svc = NewService(repo, new(MockRules), new(MockAssignmentRepo), validator.New())
 		repo.On("BeginTransaction").Return(tx, nil)
 		var entity = Entity{
 			Id:        1,
@@ -158,12 +225,13 @@ func TestSave(t *testing.T) {
 		}
 		sqlxDb := sqlx.NewDb(db, "sqlmock")
 		mck.ExpectBegin()
+		mck.ExpectRollback()
 		tx, err := sqlxDb.Beginx()
 		if err != nil {
 			t.Fatal(err)
 		}
 		var repo = new(MockRepo)
-		var svc = NewService(repo, validator.New())
+		var svc = NewService(repo, new(MockRules), new(MockAssignmentRepo), validator.New())
 		repo.On("BeginTransaction").Return(tx, nil)
 		var entity = Entity{
 			Name:      "test",
@@ -199,13 +267,16 @@ func TestSave(t *testing.T) {
 		}
 		sqlxDb := sqlx.NewDb(db, "sqlmock")
 		mck.ExpectBegin()
+		mck.ExpectCommit()
 		tx, err := sqlxDb.Beginx()
 		if err != nil {
 			t.Fatal(err)
 		}
 		var repo = new(MockRepo)
-		var svc = NewService(repo, validator.New())
+		var rules = new(MockRules)
+		var svc = NewService(repo, rules, new(MockAssignmentRepo), validator.New())
 		repo.On("BeginTransaction").Return(tx, nil)
+		rules.On("Apply", tx, []int64{1}).Return(nil)
 		var entity = Entity{
 			Id:        1,
 			Name:      "test",
@@ -232,6 +303,58 @@ func TestSave(t *testing.T) {
 		a.True(repo.AssertNumberOfCalls(t, "BeginTransaction", 1))
 		a.True(repo.AssertNumberOfCalls(t, "FindByName", 1))
 		a.True(repo.AssertNumberOfCalls(t, "Save", 1))
+		a.True(rules.AssertNumberOfCalls(t, "Apply", 1))
+	})
+	t.Run("should roll back employee when birthright roles fail", func(t *testing.T) {
+		a := assert.New(t)
+		db, mck, err := sqlmock.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		mck.ExpectBegin()
+		mck.ExpectRollback()
+		tx, err := sqlx.NewDb(db, "sqlmock").Beginx()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var repo = new(MockRepo)
+		var rules = new(MockRules)
+		var svc = NewService(repo, rules, new(MockAssignmentRepo), validator.New())
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("FindByName", tx, "test").Return(false, nil)
+		repo.On("Save", tx, mock.Anything).Return(int64(2), nil)
+		rules.On("Apply", tx, []int64{2}).Return(errors.New("database error"))
+		_, err = svc.Save(context.Background(), CreateRequest{Name: "test", RoleId: 1})
+		a.NotNil(err)
+		a.Nil(mck.ExpectationsWereMet())
+	})
+	t.Run("should return already exists error because subject is linked to other employee", func(t *testing.T) {
+		a := assert.New(t)
+		db, mck, err := sqlmock.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sqlxDb := sqlx.NewDb(db, "sqlmock")
+		mck.ExpectBegin()
+		mck.ExpectRollback()
+		tx, err := sqlxDb.Beginx()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockRules), new(MockAssignmentRepo), validator.New())
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("FindByName", tx, "test").Return(false, nil)
+		repo.On("ExistsBySubject", tx, "kc-1").Return(true, nil)
+		_, err = svc.Save(
+			context.Background(),
+			CreateRequest{
+				Name:    "test",
+				RoleId:  1,
+				Subject: "kc-1",
+			})
+		a.True(errors.As(err, &common.AlreadyExistsError{}))
+		a.True(repo.AssertNumberOfCalls(t, "Save", 0))
 	})
 }
 
@@ -239,7 +362,7 @@ func TestFindById(t *testing.T) {
 	var a = assert.New(t)
 	t.Run("should return found employee", func(t *testing.T) {
 		var repo = new(MockRepo)
-		var svc = NewService(repo, validator.New())
+		var svc = NewService(repo, new(MockRules), new(MockAssignmentRepo), validator.New())
 		var entity = Entity{
 			Id:        1,
 			Name:      "test",
@@ -255,7 +378,7 @@ func TestFindById(t *testing.T) {
 	})
 	t.Run("should return wrapped error", func(t *testing.T) {
 		var repo = new(MockRepo)
-		var svc = NewService(repo, validator.New())
+		var svc = NewService(repo, new(MockRules), new(MockAssignmentRepo), validator.New())
 		var entity = Entity{}
 		var err = errors.New("database error")
 		var id = int64(1)
@@ -273,7 +396,7 @@ func TestFindAll(t *testing.T) {
 	var a = assert.New(t)
 	t.Run("should return all employees", func(t *testing.T) {
 		var repo = new(MockRepo)
-		var svc = NewService(repo, validator.New())
+		var svc = NewService(repo, new(MockRules), new(MockAssignmentRepo), validator.New())
 		var entities = []Entity{
 			{Id: 1, Name: "test1", CreatedAt: time.Now(), UpdatedAt: time.Now(), RoleId: 1},
 			{Id: 1, Name: "test2", CreatedAt: time.Now(), UpdatedAt: time.Now(), RoleId: 1},
@@ -292,7 +415,7 @@ func TestFindAll(t *testing.T) {
 	})
 	t.Run("should return wrapped error", func(t *testing.T) {
 		var repo = new(MockRepo)
-		var svc = NewService(repo, validator.New())
+		var svc = NewService(repo, new(MockRules), new(MockAssignmentRepo), validator.New())
 		var entities []Entity
 		var err = errors.New("database error")
 		var want = common.NotFoundError{Message: fmt.Sprintf("error finding all employees: %v", err)}
@@ -309,7 +432,7 @@ func TestFindByIds(t *testing.T) {
 	var a = assert.New(t)
 	t.Run("should return employees by ids", func(t *testing.T) {
 		var repo = new(MockRepo)
-		var svc = NewService(repo, validator.New())
+		var svc = NewService(repo, new(MockRules), new(MockAssignmentRepo), validator.New())
 		var entities = []Entity{
 			{Id: 2, Name: "test2", CreatedAt: time.Now(), UpdatedAt: time.Now(), RoleId: 1},
 			{Id: 4, Name: "test4", CreatedAt: time.Now(), UpdatedAt: time.Now(), RoleId: 1},
@@ -330,7 +453,7 @@ func TestFindByIds(t *testing.T) {
 	})
 	t.Run("should return wrapped error", func(t *testing.T) {
 		var repo = new(MockRepo)
-		var svc = NewService(repo, validator.New())
+		var svc = NewService(repo, new(MockRules), new(MockAssignmentRepo), validator.New())
 		var entities []Entity
 		var err = errors.New("database error")
 		var want = common.NotFoundError{Message: fmt.Sprintf("error finding employees by ids: %v", err)}
@@ -344,11 +467,192 @@ func TestFindByIds(t *testing.T) {
 	})
 }
 
+func TestFindByRole(t *testing.T) {
+	var a = assert.New(t)
+	t.Run("should return page of role members with total", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockRules), new(MockAssignmentRepo), validator.New())
+		var entities = []Entity{
+			{Id: 1, Name: "test1", RoleId: 7},
+			{Id: 2, Name: "test2", RoleId: 3},
+		}
+		repo.On("FindByRoleWithOffset", int64(7), 4, 2, "test").Return(entities, nil)
+		repo.On("CountByRole", int64(7), "test").Return(int64(6), nil)
+		got, err := svc.FindByRole(RoleMembersRequest{RoleId: 7, PageSize: 2, PageNumber: 2, TextFilter: "test"}, nil)
+		a.Nil(err)
+		a.Equal(int64(6), got.Total)
+		a.Len(got.Result, 2)
+		a.Equal(int64(7), got.Result[0].RoleId)
+	})
+	t.Run("should return validation error without role id", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockRules), new(MockAssignmentRepo), validator.New())
+		_, err := svc.FindByRole(RoleMembersRequest{PageSize: 2}, nil)
+		a.NotNil(err)
+		a.True(errors.As(err, &common.RequestValidationError{}))
+		a.True(repo.AssertNumberOfCalls(t, "FindByRoleWithOffset", 0))
+	})
+	t.Run("should return wrapped error", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockRules), new(MockAssignmentRepo), validator.New())
+		var err = errors.New("database error")
+		repo.On("FindByRoleWithOffset", int64(7), 0, 10, "").Return([]Entity{}, err)
+		_, got := svc.FindByRole(RoleMembersRequest{RoleId: 7, PageSize: 10}, nil)
+		a.Equal(common.NotFoundError{Message: fmt.Sprintf("error finding employees of role %d: %v", 7, err)}, got)
+	})
+}
+
+func TestFindWithOffset(t *testing.T) {
+	var a = assert.New(t)
+	t.Run("should pass sorting to repository", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockRules), new(MockAssignmentRepo), validator.New())
+		var entities = []Entity{{Id: 2, Name: "test2", RiskScore: 35}, {Id: 1, Name: "test1", RiskScore: 10}}
+		repo.On("FindWithOffset", 0, 2, "", "risk_score", "desc").Return(entities, nil)
+		got, err := svc.FindWithOffset(PageRequest{PageSize: 2, SortBy: "risk_score", SortOrder: "desc"}, nil)
+		a.Nil(err)
+		a.Len(got.Result, 2)
+		a.Equal(int64(35), got.Result[0].RiskScore)
+	})
+	t.Run("should return validation error for unknown sort field", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockRules), new(MockAssignmentRepo), validator.New())
+		_, err := svc.FindWithOffset(PageRequest{PageSize: 2, SortBy: "department"}, nil)
+		a.NotNil(err)
+		a.True(errors.As(err, &common.RequestValidationError{}))
+		a.True(repo.AssertNumberOfCalls(t, "FindWithOffset", 0))
+	})
+}
+
+func TestAllowedPage(t *testing.T) {
+	var a = assert.New(t)
+	var sales = func(response Response) bool { return response.Department == "Sales" }
+	var members = make([]Entity, 0, pageBatch+10)
+	for i := range pageBatch + 10 {
+		var department = "Engineering"
+		if i%2 == 0 {
+			department = "Sales"
+		}
+		members = append(members, Entity{Id: int64(i + 1), Department: department})
+	}
+	t.Run("page is filled with allowed employees only", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockRules), new(MockAssignmentRepo), validator.New())
+		repo.On("FindWithOffset", 0, pageBatch, "", "", "").Return(members[:pageBatch], nil)
+		repo.On("FindWithOffset", pageBatch, pageBatch, "", "", "").Return(members[pageBatch:], nil)
+		got, err := svc.FindWithOffset(PageRequest{PageSize: 3, PageNumber: 1}, sales)
+		a.Nil(err)
+		a.Equal([]int64{7, 9, 11}, []int64{got.Result[0].Id, got.Result[1].Id, got.Result[2].Id})
+		a.Equal(int64((pageBatch+10)/2), got.Total)
+	})
+	t.Run("scan stops at limit", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockRules), new(MockAssignmentRepo), validator.New())
+		var batch = make([]Entity, pageBatch)
+		repo.On("FindWithOffset", mock.Anything, pageBatch, "", "", "").Return(batch, nil)
+		got, err := svc.FindWithOffset(PageRequest{PageSize: 3}, sales)
+		a.Nil(err)
+		a.Empty(got.Result)
+		a.True(repo.AssertNumberOfCalls(t, "FindWithOffset", pageScanLimit/pageBatch))
+	})
+	t.Run("role members total counts only allowed employees", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockRules), new(MockAssignmentRepo), validator.New())
+		repo.On("FindByRoleWithOffset", int64(7), 0, pageBatch, "").Return(members[:4], nil)
+		got, err := svc.FindByRole(RoleMembersRequest{RoleId: 7, PageSize: 10}, sales)
+		a.Nil(err)
+		a.Len(got.Result, 2)
+		a.Equal(int64(2), got.Total)
+		a.True(repo.AssertNumberOfCalls(t, "CountByRole", 0))
+	})
+}
+
+func TestFindRiskiest(t *testing.T) {
+	var a = assert.New(t)
+	t.Run("should return riskiest employees", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockRules), new(MockAssignmentRepo), validator.New())
+		repo.On("FindRiskiest", 50).Return([]Entity{{Id: 3, Name: "test3", RiskScore: 60}}, nil)
+		got, err := svc.FindRiskiest()
+		a.Nil(err)
+		a.Len(got, 1)
+		a.Equal(int64(60), got[0].RiskScore)
+	})
+	t.Run("should return wrapped error", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockRules), new(MockAssignmentRepo), validator.New())
+		var err = errors.New("database error")
+		repo.On("FindRiskiest", 50).Return([]Entity{}, err)
+		_, got := svc.FindRiskiest()
+		a.Equal(common.NotFoundError{Message: fmt.Sprintf("error finding riskiest employees: %v", err)}, got)
+	})
+}
+
+func TestFindMe(t *testing.T) {
+	var a = assert.New(t)
+	t.Run("should return employee linked to subject with roles and permissions", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockRules), new(MockAssignmentRepo), validator.New())
+		var entity = Entity{Id: 3, Name: "john doe", RoleId: 2, Subject: "kc-1"}
+		var roles = []RoleEntity{{RoleId: 2, Name: "Developer", Source: "primary"}}
+		repo.On("FindBySubject", "kc-1").Return(entity, nil)
+		repo.On("FindRoles", int64(3)).Return(roles, nil)
+		got, err := svc.FindMe(MeRequest{Subject: "kc-1", TokenRoles: []string{"IDM_USER"}, Granted: []string{"employee:delete"}})
+		a.Nil(err)
+		a.Equal(entity.toResponse(), got.Employee)
+		a.Equal(roles, got.Roles)
+		a.Equal([]string{"IDM_USER"}, got.TokenRoles)
+		a.Equal([]string{
+			"access-request:create",
+			"access-request:read",
+			"access:explain",
+			"account:read",
+			"application:read",
+			"certification:review",
+			"employee:delete",
+			"employee:read",
+			"me:read",
+			"role:read",
+		}, got.Permissions)
+	})
+	t.Run("should return not found error when subject is not linked", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockRules), new(MockAssignmentRepo), validator.New())
+		repo.On("FindBySubject", "kc-2").Return(Entity{}, errors.New("sql: no rows in result set"))
+		_, err := svc.FindMe(MeRequest{Subject: "kc-2"})
+		a.True(errors.As(err, &common.NotFoundError{}))
+		a.True(repo.AssertNumberOfCalls(t, "FindRoles", 0))
+	})
+	t.Run("should return validation error without subject", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockRules), new(MockAssignmentRepo), validator.New())
+		_, err := svc.FindMe(MeRequest{})
+		a.True(errors.As(err, &common.RequestValidationError{}))
+	})
+}
+
+func TestLinkSubject(t *testing.T) {
+	var a = assert.New(t)
+	t.Run("should link subject", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockRules), new(MockAssignmentRepo), validator.New())
+		repo.On("UpdateSubject", int64(3), "kc-1").Return(true, nil)
+		a.Nil(svc.LinkSubject(LinkSubjectRequest{Id: 3, Subject: "kc-1"}))
+	})
+	t.Run("should return not found error", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, new(MockRules), new(MockAssignmentRepo), validator.New())
+		repo.On("UpdateSubject", int64(4), "kc-1").Return(false, nil)
+		err := svc.LinkSubject(LinkSubjectRequest{Id: 4, Subject: "kc-1"})
+		a.True(errors.As(err, &common.NotFoundError{}))
+	})
+}
+
 func TestDeleteById(t *testing.T) {
 	var a = assert.New(t)
 	t.Run("should delete employee by id", func(t *testing.T) {
 		var repo = new(MockRepo)
-		var svc = NewService(repo, validator.New())
+		var svc = NewService(repo, new(MockRules), new(MockAssignmentRepo), validator.New())
 		repo.On("DeleteById", int64(1)).Return(nil)
 		var got = svc.DeleteById(IdRequest{Id: int64(1)})
 		a.Nil(got)
@@ -356,7 +660,7 @@ func TestDeleteById(t *testing.T) {
 	})
 	t.Run("should return wrapped error", func(t *testing.T) {
 		var repo = new(MockRepo)
-		var svc = NewService(repo, validator.New())
+		var svc = NewService(repo, new(MockRules), new(MockAssignmentRepo), validator.New())
 		var err = errors.New("database error")
 		var id = int64(1)
 		var want = common.NotFoundError{Message: fmt.Sprintf("error deleting employee with id %d: %v", id, err)}
@@ -372,7 +676,7 @@ func TestDeleteByIds(t *testing.T) {
 	var a = assert.New(t)
 	t.Run("should delete employee by ids", func(t *testing.T) {
 		var repo = new(MockRepo)
-		var svc = NewService(repo, validator.New())
+		var svc = NewService(repo, new(MockRules), new(MockAssignmentRepo), validator.New())
 		repo.On("DeleteByIds", []int64{2, 4}).Return(nil)
 		var got = svc.DeleteByIds(IdsRequest{Ids: []int64{2, 4}})
 		a.Nil(got)
@@ -380,7 +684,7 @@ func TestDeleteByIds(t *testing.T) {
 	})
 	t.Run("should return wrapped error", func(t *testing.T) {
 		var repo = new(MockRepo)
-		var svc = NewService(repo, validator.New())
+		var svc = NewService(repo, new(MockRules), new(MockAssignmentRepo), validator.New())
 		var err = errors.New("database error")
 		var ids = []int64{2, 4}
 		var want = common.NotFoundError{Message: fmt.Sprintf("error deleting employee with ids %d: %v", ids, err)}
@@ -579,3 +883,70 @@ func TestPageRequest(t *testing.T) {
 		a.NoError(err)
 	})
 }
+
+func TestUpdateDepartment(t *testing.T) {
+	t.Run("should re-evaluate birthright roles", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, true)
+		var repo = new(MockRepo)
+		var rules = new(MockRules)
+		var svc = NewService(repo, rules, new(MockAssignmentRepo), validator.New())
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("UpdateDepartment", tx, int64(3), "Engineering").Return(true, nil)
+		rules.On("Apply", tx, []int64{3}).Return(nil)
+		err := svc.UpdateDepartment(context.Background(), UpdateDepartmentRequest{Id: 3, Department: "Engineering"})
+		a.Nil(err)
+		a.True(rules.AssertNumberOfCalls(t, "Apply", 1))
+	})
+	t.Run("should return not found error", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, false)
+		var repo = new(MockRepo)
+		var rules = new(MockRules)
+		var svc = NewService(repo, rules, new(MockAssignmentRepo), validator.New())
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("UpdateDepartment", tx, int64(3), "Engineering").Return(false, nil)
+		err := svc.UpdateDepartment(context.Background(), UpdateDepartmentRequest{Id: 3, Department: "Engineering"})
+		a.True(errors.As(err, &common.NotFoundError{}))
+		a.True(rules.AssertNumberOfCalls(t, "Apply", 0))
+	})
+}
+
+func TestRoleAssignment(t *testing.T) {
+	var request = RoleAssignmentRequest{EmployeeId: 3, RoleId: 5, Actor: "developer-admin"}
+	t.Run("should assign role directly", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, true)
+		var repo = new(MockRepo)
+		var assignments = new(MockAssignmentRepo)
+		var svc = NewService(repo, new(MockRules), assignments, validator.New())
+		repo.On("FindById", int64(3)).Return(Entity{Id: 3}, nil)
+		repo.On("BeginTransaction").Return(tx, nil)
+		assignments.On("Save", tx, assignment.Entity{
+			EmployeeId: 3, RoleId: 5, Source: assignment.SourceDirect, CreatedBy: "developer-admin",
+		}).Return(nil)
+		a.Nil(svc.AssignRole(context.Background(), request))
+		a.True(assignments.AssertNumberOfCalls(t, "Save", 1))
+	})
+	t.Run("should return not found error for missing employee", func(t *testing.T) {
+		a := assert.New(t)
+		var repo = new(MockRepo)
+		var assignments = new(MockAssignmentRepo)
+		var svc = NewService(repo, new(MockRules), assignments, validator.New())
+		repo.On("FindById", int64(3)).Return(Entity{}, errors.New("sql: no rows in result set"))
+		a.True(errors.As(svc.AssignRole(context.Background(), request), &common.NotFoundError{}))
+		a.True(assignments.AssertNumberOfCalls(t, "Save", 0))
+	})
+	t.Run("should unassign only direct and requested roles", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, true)
+		var repo = new(MockRepo)
+		var assignments = new(MockAssignmentRepo)
+		var svc = NewService(repo, new(MockRules), assignments, validator.New())
+		repo.On("BeginTransaction").Return(tx, nil)
+		assignments.On("Delete", tx, int64(3), int64(5), assignment.SourceDirect).Return(nil)
+		assignments.On("Delete", tx, int64(3), int64(5), assignment.SourceRequest).Return(nil)
+		a.Nil(svc.UnassignRole(context.Background(), request))
+		a.True(assignments.AssertNumberOfCalls(t, "Delete", 2))
+	})
+}
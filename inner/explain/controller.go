@@ -0,0 +1,82 @@
+package explain
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"idm/inner/common"
+	"idm/inner/middleware"
+	"idm/inner/web"
+	"slices"
+	"strconv"
+)
+
+type Controller struct {
+	server         *web.Server
+	explainService Svc
+}
+
+type Svc interface {
+	Explain(request Request) (Response, error)
+}
+
+func NewController(
+	server *web.Server,
+	explainService Svc,
+) *Controller {
+	return &Controller{
+		server:         server,
+		explainService: explainService,
+	}
+}
+
+func (c *Controller) RegisterRoutes() {
+	c.server.GroupApiV1.Get("/employees/:id/access/explain", c.Explain)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/employees/:id/access/explain"
+// @Summary Explain access of employee
+// @Description returns every path granting the permission: direct or birthright role, or delegation,
+// @Description with roles: admin, user
+// @Tags explain
+// @Security OAuth2Password
+// @Produce json
+// @Param id path int true "Employee ID"
+// @Param permission query string true "application entitlement as application:entitlement or IDM permission"
+// @Success 200 {object} common.Response[explain.Response]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /employees/{id}/access/explain [get]
+func (c *Controller) Explain(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !(slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) ||
+		slices.Contains(claims.RealmAccess.Roles, web.IdmUser)) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error parsing id: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request = Request{EmployeeId: id, Permission: ctx.Query("permission")}
+	logger.InfoCtx(ctx.Context(), "explain access: received request", zap.Any("request", request))
+	response, err := c.explainService.Explain(request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			logger.ErrorCtx(ctx.Context(), "explain access: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), "explain access: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "explain access: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response)
+}
@@ -0,0 +1,69 @@
+package explain
+
+import (
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"idm/inner/common"
+	"idm/inner/web"
+	"idm/inner/web/webtest"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) Explain(request Request) (Response, error) {
+	args := svc.Called(request)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func newServer(svc Svc, subject string, roles ...string) *web.Server {
+	server := webtest.NewServer(subject, roles...)
+	NewController(server, svc).RegisterRoutes()
+	return server
+}
+
+func TestExplainAccess(t *testing.T) {
+	var a = assert.New(t)
+	t.Run("user gets paths granting permission", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "user-1", web.IdmUser)
+		var want = Response{EmployeeId: 7, Permission: "GitLab:push", Granted: true, Paths: []Path{{
+			Type:     PathDirect,
+			RoleId:   2,
+			RoleName: "dev",
+			Steps:    []Step{{Kind: StepAssignment, Description: "role assigned with source request", Actor: "boss"}},
+		}}}
+		svc.On("Explain", Request{EmployeeId: 7, Permission: "GitLab:push"}).Return(want, nil)
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/7/access/explain?permission=GitLab:push", nil))
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		bytesData, err := io.ReadAll(resp.Body)
+		a.Nil(err)
+		var responseBody common.Response[Response]
+		err = json.Unmarshal(bytesData, &responseBody)
+		a.Nil(err)
+		a.Equal(want, responseBody.Data)
+	})
+	t.Run("invalid request", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "user-1", web.IdmUser)
+		svc.On("Explain", Request{EmployeeId: 7}).Return(Response{}, common.RequestValidationError{Message: "permission is required"})
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/7/access/explain", nil))
+		a.Nil(err)
+		a.Equal(http.StatusBadRequest, resp.StatusCode)
+	})
+	t.Run("caller without idm roles is forbidden", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "someone")
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/7/access/explain?permission=x", nil))
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+	})
+}
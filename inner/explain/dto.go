@@ -0,0 +1,78 @@
+package explain
+
+import (
+	"github.com/lib/pq"
+	"time"
+)
+
+// виды путей, которыми сотрудник получает право
+const (
+	// PathDirect роль назначена сотруднику: основная, по заявке или экстренный доступ
+	PathDirect = "direct"
+	// PathBirthright роль выдана правилом по атрибутам сотрудника
+	PathBirthright = "birthright"
+	// PathDelegation право дано делегированием администрирования
+	PathDelegation = "delegation"
+)
+
+// звенья пути
+const (
+	StepAssignment  = "assignment"
+	StepRule        = "rule"
+	StepEntitlement = "entitlement"
+	StepIdmRole     = "idm-role"
+	StepDelegation  = "delegation"
+)
+
+// RoleGrantEntity роль, через которую сотрудник получает право, и то, как она у него оказалась
+type RoleGrantEntity struct {
+	Path          string     `db:"path"`
+	Source        string     `db:"source"`
+	RoleId        int64      `db:"role_id"`
+	RoleName      string     `db:"role_name"`
+	LinkBy        string     `db:"link_by"`
+	LinkAt        time.Time  `db:"link_at"`
+	Via           string     `db:"via"`
+	ViaBy         string     `db:"via_by"`
+	ViaAt         time.Time  `db:"via_at"`
+	RuleId        *int64     `db:"rule_id"`
+	RuleCreatedBy *string    `db:"rule_created_by"`
+	RuleCreatedAt *time.Time `db:"rule_created_at"`
+}
+
+type DelegationEntity struct {
+	Id         int64         `db:"id"`
+	Department string        `db:"department"`
+	RoleIds    pq.Int64Array `db:"role_ids"`
+	GrantedBy  string        `db:"granted_by"`
+	ExpiresAt  time.Time     `db:"expires_at"`
+	CreatedAt  time.Time     `db:"created_at"`
+}
+
+// Step звено пути: кто и когда создал связь
+type Step struct {
+	Kind        string     `json:"kind"`
+	Description string     `json:"description"`
+	Actor       string     `json:"actor,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+type Path struct {
+	Type     string `json:"type"`
+	RoleId   int64  `json:"role_id,omitempty"`
+	RoleName string `json:"role_name,omitempty"`
+	Steps    []Step `json:"steps"`
+}
+
+type Request struct {
+	EmployeeId int64  `validate:"required,min=1"`
+	Permission string `validate:"required,max=255"`
+}
+
+type Response struct {
+	EmployeeId int64  `json:"employee_id"`
+	Permission string `json:"permission"`
+	Granted    bool   `json:"granted"`
+	Paths      []Path `json:"paths"`
+}
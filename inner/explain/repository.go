@@ -0,0 +1,64 @@
+package explain
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"idm/inner/assignment"
+	"time"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{
+		db: database,
+	}
+}
+
+func (r *Repository) FindSubject(employeeId int64) (subject string, err error) {
+	err = r.db.Get(&subject, "SELECT subject FROM employee WHERE id = $1", employeeId)
+	return subject, err
+}
+
+// FindRoleGrants роли сотрудника, которые дают право: включают право приложения вида "приложение:право"
+// или совпадают по имени с ролями IDM idmRoles. Учитываются только назначения из employee_role,
+// в том числе правилами: отношение member в графе отношений роль не выдаёт
+func (r *Repository) FindRoleGrants(employeeId int64, permission string, idmRoles []string) ([]RoleGrantEntity, error) {
+	var grants []RoleGrantEntity
+	err := r.db.Select(&grants, `
+		WITH granting AS (
+			SELECT r.id AS role_id, r.name AS role_name, 'entitlement' AS via, re.created_by AS via_by, re.created_at AS via_at
+			FROM role r
+			JOIN role_entitlement re ON re.role_id = r.id
+			JOIN entitlement en ON en.id = re.entitlement_id
+			JOIN application a ON a.id = en.application_id
+			WHERE a.name || ':' || en.name = $2
+			UNION ALL
+			SELECT r.id, r.name, 'idm-role', '', r.created_at FROM role r WHERE r.name = ANY($3)
+		)
+		SELECT CASE er.source WHEN $4 THEN 'birthright' ELSE 'direct' END AS path, er.source,
+			g.role_id, g.role_name, er.created_by AS link_by, er.created_at AS link_at, g.via, g.via_by, g.via_at,
+			br.id AS rule_id, br.created_by AS rule_created_by, br.created_at AS rule_created_at
+		FROM employee_role er
+		JOIN granting g ON g.role_id = er.role_id
+		LEFT JOIN birthright_rule br ON er.source = $4 AND er.created_by = 'rule:' || br.id
+		WHERE er.employee_id = $1
+		ORDER BY link_at, role_id`,
+		employeeId, permission, pq.StringArray(idmRoles), assignment.SourceBirthright,
+	)
+	return grants, err
+}
+
+// FindDelegations действующие делегирования, выданные пользователю с указанным subject
+func (r *Repository) FindDelegations(subject string, now time.Time) ([]DelegationEntity, error) {
+	var delegations []DelegationEntity
+	err := r.db.Select(
+		&delegations,
+		"SELECT id, department, role_ids, granted_by, expires_at, created_at FROM delegation "+
+			"WHERE subject = $1 AND revoked_at IS NULL AND expires_at > $2 ORDER BY id",
+		subject, now,
+	)
+	return delegations, err
+}
@@ -0,0 +1,128 @@
+package explain
+
+import (
+	"fmt"
+	"idm/inner/common"
+	"idm/inner/web"
+	"strconv"
+	"time"
+)
+
+type Service struct {
+	repo      Repo
+	validator Validator
+}
+
+type Repo interface {
+	FindSubject(employeeId int64) (string, error)
+	FindRoleGrants(employeeId int64, permission string, idmRoles []string) ([]RoleGrantEntity, error)
+	FindDelegations(subject string, now time.Time) ([]DelegationEntity, error)
+}
+
+type Validator interface {
+	Validate(request any) error
+}
+
+func NewService(repo Repo, validator Validator) *Service {
+	return &Service{
+		repo:      repo,
+		validator: validator,
+	}
+}
+
+// Explain все пути, которыми сотрудник получает право: право приложения "приложение:право"
+// или право IDM, например employee:create
+func (s *Service) Explain(request Request) (Response, error) {
+	if err := s.validator.Validate(request); err != nil {
+		return Response{}, common.RequestValidationError{Message: err.Error()}
+	}
+	subject, err := s.repo.FindSubject(request.EmployeeId)
+	if err != nil {
+		return Response{}, common.NotFoundError{Message: fmt.Sprintf("error finding employee with id %d: %v", request.EmployeeId, err)}
+	}
+	grants, err := s.repo.FindRoleGrants(request.EmployeeId, request.Permission, web.RolesWithPermission(request.Permission))
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding roles of employee %d: %w", request.EmployeeId, err)
+	}
+	var response = Response{EmployeeId: request.EmployeeId, Permission: request.Permission, Paths: []Path{}}
+	for _, grant := range grants {
+		response.Paths = append(response.Paths, grant.toPath())
+	}
+	var byDepartment, byRoleSet = web.DepartmentDelegated(request.Permission), web.RoleSetDelegated(request.Permission)
+	if subject != "" && (byDepartment || byRoleSet) {
+		delegations, err := s.repo.FindDelegations(subject, time.Now())
+		if err != nil {
+			return Response{}, fmt.Errorf("error finding delegations of employee %d: %w", request.EmployeeId, err)
+		}
+		for _, delegation := range delegations {
+			// делегирование на подразделение и делегирование набора ролей дают разные права
+			if delegation.Department != "" && byDepartment || len(delegation.RoleIds) > 0 && byRoleSet {
+				response.Paths = append(response.Paths, delegation.toPath())
+			}
+		}
+	}
+	response.Granted = len(response.Paths) > 0
+	return response, nil
+}
+
+// toPath путь от сотрудника к праву: как роль попала к сотруднику и почему роль даёт право
+func (g *RoleGrantEntity) toPath() Path {
+	var steps []Step
+	switch g.Path {
+	case PathBirthright:
+		if g.RuleId != nil {
+			steps = append(steps, Step{
+				Kind:        StepRule,
+				Description: "birthright rule " + strconv.FormatInt(*g.RuleId, 10),
+				Actor:       *g.RuleCreatedBy,
+				CreatedAt:   *g.RuleCreatedAt,
+			})
+		}
+		fallthrough
+	default:
+		steps = append(steps, Step{
+			Kind:        StepAssignment,
+			Description: "role assigned with source " + g.Source,
+			Actor:       g.LinkBy,
+			CreatedAt:   g.LinkAt,
+		})
+	}
+	if g.Via == StepEntitlement {
+		steps = append(steps, Step{
+			Kind:        StepEntitlement,
+			Description: "entitlement bundled into role",
+			Actor:       g.ViaBy,
+			CreatedAt:   g.ViaAt,
+		})
+	} else {
+		steps = append(steps, Step{
+			Kind:        StepIdmRole,
+			Description: "role " + g.RoleName + " grants IDM permission",
+			CreatedAt:   g.ViaAt,
+		})
+	}
+	return Path{
+		Type:     g.Path,
+		RoleId:   g.RoleId,
+		RoleName: g.RoleName,
+		Steps:    steps,
+	}
+}
+
+func (d *DelegationEntity) toPath() Path {
+	var scope = "department " + d.Department
+	if d.Department == "" {
+		scope = fmt.Sprintf("roles %v", []int64(d.RoleIds))
+	}
+	var expiresAt = d.ExpiresAt
+	return Path{
+		Type: PathDelegation,
+		Steps: []Step{{
+			Kind:        StepDelegation,
+			Description: "delegation " + strconv.FormatInt(d.Id, 10) + " over " + scope,
+			Actor:       d.GrantedBy,
+			CreatedAt:   d.CreatedAt,
+			ExpiresAt:   &expiresAt,
+		}},
+	}
+}
@@ -0,0 +1,110 @@
+package explain
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"idm/inner/common"
+	"idm/inner/validator"
+	"idm/inner/web"
+	"testing"
+	"time"
+)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+func (r *MockRepo) FindSubject(employeeId int64) (string, error) {
+	args := r.Called(employeeId)
+	return args.String(0), args.Error(1)
+}
+
+func (r *MockRepo) FindRoleGrants(employeeId int64, permission string, idmRoles []string) ([]RoleGrantEntity, error) {
+	args := r.Called(employeeId, permission, idmRoles)
+	return args.Get(0).([]RoleGrantEntity), args.Error(1)
+}
+
+func (r *MockRepo) FindDelegations(subject string, now time.Time) ([]DelegationEntity, error) {
+	args := r.Called(subject, now)
+	return args.Get(0).([]DelegationEntity), args.Error(1)
+}
+
+func TestExplain(t *testing.T) {
+	var a = assert.New(t)
+	var linkAt = time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	var bundleAt = time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	t.Run("application entitlement through direct and birthright roles", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		var ruleId = int64(3)
+		var ruleBy = "admin"
+		var ruleAt = time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+		repo.On("FindSubject", int64(7)).Return("", nil)
+		repo.On("FindRoleGrants", int64(7), "GitLab:push", []string(nil)).Return([]RoleGrantEntity{
+			{Path: PathDirect, Source: "request", RoleId: 2, RoleName: "dev", LinkBy: "boss", LinkAt: linkAt,
+				Via: StepEntitlement, ViaBy: "owner", ViaAt: bundleAt},
+			{Path: PathBirthright, Source: "birthright", RoleId: 4, RoleName: "eng", LinkBy: "rule:3", LinkAt: linkAt,
+				Via: StepEntitlement, ViaBy: "owner", ViaAt: bundleAt,
+				RuleId: &ruleId, RuleCreatedBy: &ruleBy, RuleCreatedAt: &ruleAt},
+		}, nil)
+		got, err := svc.Explain(Request{EmployeeId: 7, Permission: "GitLab:push"})
+		a.Nil(err)
+		a.True(got.Granted)
+		a.Len(got.Paths, 2)
+		var bundled = Step{Kind: StepEntitlement, Description: "entitlement bundled into role", Actor: "owner", CreatedAt: bundleAt}
+		a.Equal(Path{Type: PathDirect, RoleId: 2, RoleName: "dev", Steps: []Step{
+			{Kind: StepAssignment, Description: "role assigned with source request", Actor: "boss", CreatedAt: linkAt},
+			bundled,
+		}}, got.Paths[0])
+		a.Equal([]Step{
+			{Kind: StepRule, Description: "birthright rule 3", Actor: "admin", CreatedAt: ruleAt},
+			{Kind: StepAssignment, Description: "role assigned with source birthright", Actor: "rule:3", CreatedAt: linkAt},
+			bundled,
+		}, got.Paths[1].Steps)
+		repo.AssertNotCalled(t, "FindDelegations", mock.Anything, mock.Anything)
+	})
+	t.Run("idm permission through role and delegation", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		var expiresAt = time.Date(2026, 2, 1, 9, 0, 0, 0, time.UTC)
+		repo.On("FindSubject", int64(7)).Return("alice", nil)
+		repo.On("FindRoleGrants", int64(7), "employee:create", web.RolesWithPermission("employee:create")).Return([]RoleGrantEntity{}, nil)
+		repo.On("FindDelegations", "alice", mock.Anything).Return([]DelegationEntity{
+			{Id: 8, Department: "IT", GrantedBy: "admin", ExpiresAt: expiresAt, CreatedAt: linkAt},
+			{Id: 9, RoleIds: []int64{5}, GrantedBy: "admin", ExpiresAt: expiresAt, CreatedAt: linkAt},
+		}, nil)
+		got, err := svc.Explain(Request{EmployeeId: 7, Permission: "employee:create"})
+		a.Nil(err)
+		a.True(got.Granted)
+		a.Equal([]Path{{Type: PathDelegation, Steps: []Step{{
+			Kind:        StepDelegation,
+			Description: "delegation 8 over department IT",
+			Actor:       "admin",
+			CreatedAt:   linkAt,
+			ExpiresAt:   &expiresAt,
+		}}}}, got.Paths)
+	})
+	t.Run("no paths means permission is not granted", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		repo.On("FindSubject", int64(7)).Return("alice", nil)
+		repo.On("FindRoleGrants", int64(7), "GitLab:push", []string(nil)).Return([]RoleGrantEntity{}, nil)
+		got, err := svc.Explain(Request{EmployeeId: 7, Permission: "GitLab:push"})
+		a.Nil(err)
+		a.False(got.Granted)
+		a.Empty(got.Paths)
+	})
+	t.Run("unknown employee", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		repo.On("FindSubject", int64(7)).Return("", errors.New("sql: no rows in result set"))
+		_, err := svc.Explain(Request{EmployeeId: 7, Permission: "GitLab:push"})
+		a.True(errors.As(err, &common.NotFoundError{}))
+	})
+	t.Run("permission is required", func(t *testing.T) {
+		var svc = NewService(new(MockRepo), validator.New())
+		_, err := svc.Explain(Request{EmployeeId: 7})
+		a.True(errors.As(err, &common.RequestValidationError{}))
+	})
+}
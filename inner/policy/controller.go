@@ -0,0 +1,59 @@
+package policy
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"idm/inner/common"
+	"idm/inner/middleware"
+	"idm/inner/web"
+	"slices"
+)
+
+type Controller struct {
+	server *web.Server
+	engine Reloader
+}
+
+type Reloader interface {
+	Reload() error
+}
+
+func NewController(
+	server *web.Server,
+	engine Reloader,
+) *Controller {
+	return &Controller{
+		server: server,
+		engine: engine,
+	}
+}
+
+func (c *Controller) RegisterRoutes() {
+	c.server.GroupApiV1.Post("/policies/reload", c.Reload)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/policies/reload"
+// @Summary reload access policies
+// @Description Re-read the policy file; on error the current policies stay active. Roles: admin
+// @Tags policy
+// @Security OAuth2Password
+// @Produce json
+// @Success 200 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /policies/reload [post]
+func (c *Controller) Reload(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	logger.InfoCtx(ctx.Context(), "reload policies: received request", zap.String("subject", claims.Subject))
+	if err := c.engine.Reload(); err != nil {
+		logger.ErrorCtx(ctx.Context(), "error reloading policies: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+	return common.OkResponse(ctx, "policies reloaded")
+}
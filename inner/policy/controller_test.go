@@ -0,0 +1,57 @@
+package policy
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"idm/inner/web"
+	"idm/inner/web/webtest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type MockReloader struct {
+	mock.Mock
+}
+
+func (r *MockReloader) Reload() error {
+	args := r.Called()
+	return args.Error(0)
+}
+
+func newServer(reloader Reloader, roles ...string) *web.Server {
+	server := webtest.NewServer("", roles...)
+	NewController(server, reloader).RegisterRoutes()
+	return server
+}
+
+func TestReload(t *testing.T) {
+	var a = assert.New(t)
+	t.Run("reload policies", func(t *testing.T) {
+		var reloader = new(MockReloader)
+		reloader.On("Reload").Return(nil)
+		server := newServer(reloader, web.IdmAdmin)
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/policies/reload", nil))
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		a.True(reloader.AssertNumberOfCalls(t, "Reload", 1))
+	})
+	t.Run("reload error", func(t *testing.T) {
+		var reloader = new(MockReloader)
+		reloader.On("Reload").Return(errors.New("broken file"))
+		server := newServer(reloader, web.IdmAdmin)
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/policies/reload", nil))
+		a.Nil(err)
+		a.Equal(http.StatusInternalServerError, resp.StatusCode)
+	})
+	t.Run("permission denied", func(t *testing.T) {
+		var reloader = new(MockReloader)
+		server := newServer(reloader, web.IdmUser)
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/policies/reload", nil))
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		a.True(reloader.AssertNumberOfCalls(t, "Reload", 0))
+	})
+}
@@ -0,0 +1,268 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"idm/inner/common"
+	"idm/inner/web"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+	// DefaultRule имя правила в решении, если ни одно правило не подошло
+	DefaultRule = "default"
+)
+
+// Document файл политик: решение по умолчанию и список правил
+type Document struct {
+	Default string `json:"default"`
+	Rules   []Rule `json:"rules"`
+}
+
+// Rule правило политики; действия поддерживают шаблон "employee:*" и "*"
+type Rule struct {
+	Name      string   `json:"name"`
+	Effect    string   `json:"effect"`
+	Actions   []string `json:"actions"`
+	Condition string   `json:"condition"`
+	condition node
+}
+
+// Request запрос на проверку доступа: кто, что делает и с каким ресурсом
+type Request struct {
+	Subject  map[string]any
+	Action   string
+	Resource map[string]any
+}
+
+// Decision результат проверки с именем сработавшего правила
+type Decision struct {
+	Allowed bool
+	Rule    string
+}
+
+type Engine struct {
+	logger   *common.Logger
+	now      func() time.Time
+	mu       sync.RWMutex
+	document Document
+	path     string
+	modified time.Time
+}
+
+// NewEngine создать движок без правил, который разрешает всё до загрузки политик
+func NewEngine(logger *common.Logger) *Engine {
+	return &Engine{
+		logger:   logger,
+		now:      time.Now,
+		document: Document{Default: EffectAllow},
+	}
+}
+
+// Load загрузить политики из файла и запомнить путь для перезагрузки
+func (e *Engine) Load(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("error reading policy file: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading policy file: %w", err)
+	}
+	if err = e.Apply(data); err != nil {
+		return fmt.Errorf("error loading policy file %s: %w", path, err)
+	}
+	e.mu.Lock()
+	e.path = path
+	e.modified = info.ModTime()
+	e.mu.Unlock()
+	return nil
+}
+
+// Reload перечитать ранее загруженный файл политик
+func (e *Engine) Reload() error {
+	e.mu.RLock()
+	var path = e.path
+	e.mu.RUnlock()
+	if path == "" {
+		return nil
+	}
+	if err := e.Load(path); err != nil {
+		return err
+	}
+	e.logger.Info("policies reloaded", zap.String("path", path), zap.Int("rules", e.rulesCount()))
+	return nil
+}
+
+// ReloadIfChanged перечитать файл политик, если он изменился; используется фоновой задачей
+func (e *Engine) ReloadIfChanged(ctx context.Context) error {
+	e.mu.RLock()
+	var path, modified = e.path, e.modified
+	e.mu.RUnlock()
+	if path == "" {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("error reading policy file: %w", err)
+	}
+	if !info.ModTime().After(modified) {
+		return nil
+	}
+	return e.Reload()
+}
+
+// Apply разобрать и применить политики; при ошибке действующие политики не меняются
+func (e *Engine) Apply(data []byte) error {
+	var document Document
+	if err := json.Unmarshal(data, &document); err != nil {
+		return err
+	}
+	if document.Default == "" {
+		document.Default = EffectAllow
+	}
+	if document.Default != EffectAllow && document.Default != EffectDeny {
+		return fmt.Errorf("invalid default effect %q", document.Default)
+	}
+	for i := range document.Rules {
+		var rule = &document.Rules[i]
+		if rule.Name == "" {
+			return fmt.Errorf("rule #%d has no name", i)
+		}
+		if rule.Effect != EffectAllow && rule.Effect != EffectDeny {
+			return fmt.Errorf("rule %s: invalid effect %q", rule.Name, rule.Effect)
+		}
+		if rule.Condition == "" {
+			rule.condition = literalNode{value: true}
+			continue
+		}
+		condition, err := compile(rule.Condition)
+		if err != nil {
+			return fmt.Errorf("rule %s: %w", rule.Name, err)
+		}
+		rule.condition = condition
+	}
+	e.mu.Lock()
+	e.document = document
+	e.mu.Unlock()
+	return nil
+}
+
+// Evaluate проверить запрос и записать решение в журнал
+func (e *Engine) Evaluate(ctx context.Context, request Request) Decision {
+	var decision = e.Check(ctx, request)
+	e.logger.InfoCtx(
+		ctx,
+		"policy decision",
+		zap.String("action", request.Action),
+		zap.Any("subject", request.Subject["sub"]),
+		zap.Any("resource", request.Resource["id"]),
+		zap.Bool("allowed", decision.Allowed),
+		zap.String("rule", decision.Rule),
+	)
+	return decision
+}
+
+// Check проверить запрос без записи решения в журнал; используется при фильтрации списков.
+// Запрещающее правило важнее разрешающего, если ни одно правило не подошло — действует решение по умолчанию
+func (e *Engine) Check(ctx context.Context, request Request) Decision {
+	e.mu.RLock()
+	var document = e.document
+	e.mu.RUnlock()
+	var attrs = map[string]any{
+		"subject":  request.Subject,
+		"action":   request.Action,
+		"resource": request.Resource,
+		"env":      environment(e.now()),
+	}
+	var decision = Decision{Allowed: document.Default == EffectAllow, Rule: DefaultRule}
+	var allowRule string
+	for _, rule := range document.Rules {
+		if !matchesAction(rule.Actions, request.Action) {
+			continue
+		}
+		value, err := rule.condition.eval(attrs)
+		if err != nil {
+			e.logger.ErrorCtx(ctx, "policy rule evaluation error", zap.String("rule", rule.Name), zap.Error(err))
+			continue
+		}
+		if !truthy(value) {
+			continue
+		}
+		if rule.Effect == EffectDeny {
+			decision = Decision{Allowed: false, Rule: rule.Name}
+			allowRule = ""
+			break
+		}
+		if allowRule == "" {
+			allowRule = rule.Name
+		}
+	}
+	if allowRule != "" {
+		decision = Decision{Allowed: true, Rule: allowRule}
+	}
+	return decision
+}
+
+// Restricts может ли политика запретить действие: решение по умолчанию deny или для действия есть правила.
+// Если нет, проверять каждый ресурс не нужно
+func (e *Engine) Restricts(action string) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.document.Default == EffectDeny {
+		return true
+	}
+	for _, rule := range e.document.Rules {
+		if matchesAction(rule.Actions, action) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Engine) rulesCount() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return len(e.document.Rules)
+}
+
+func matchesAction(actions []string, action string) bool {
+	if len(actions) == 0 {
+		return true
+	}
+	for _, pattern := range actions {
+		if pattern == "*" || pattern == action {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasPrefix(action, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// environment атрибуты окружения: текущее время для правил вроде "только в рабочие часы"
+func environment(now time.Time) map[string]any {
+	return map[string]any{
+		"hour":    float64(now.Hour()),
+		"minute":  float64(now.Minute()),
+		"weekday": float64(now.Weekday()),
+		"date":    now.Format(time.DateOnly),
+	}
+}
+
+// Subject атрибуты субъекта из claims токена
+func Subject(claims *web.IdmClaims) map[string]any {
+	return map[string]any{
+		"sub":        claims.Subject,
+		"roles":      normalize(claims.RealmAccess.Roles),
+		"department": claims.Department,
+	}
+}
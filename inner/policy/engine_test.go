@@ -0,0 +1,176 @@
+package policy
+
+import (
+	"context"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"idm/inner/common"
+	"idm/inner/web"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const policies = `{
+	"default": "allow",
+	"rules": [
+		{"name": "user-reads-own-department", "effect": "deny", "actions": ["employee:read"],
+		 "condition": "!('IDM_ADMIN' in subject.roles) && resource.department != subject.department"},
+		{"name": "deletes-in-business-hours", "effect": "deny", "actions": ["employee:delete"],
+		 "condition": "env.hour < 9 || env.hour >= 18"},
+		{"name": "admins", "effect": "allow", "actions": ["employee:*"], "condition": "'IDM_ADMIN' in subject.roles"}
+	]
+}`
+
+func newEngine(now time.Time) (*Engine, *observer.ObservedLogs) {
+	core, logs := observer.New(zap.InfoLevel)
+	var engine = NewEngine(&common.Logger{Logger: zap.New(core)})
+	engine.now = func() time.Time { return now }
+	return engine, logs
+}
+
+func subject(department string, roles ...string) map[string]any {
+	return Subject(&web.IdmClaims{
+		RealmAccess:      web.RealmAccessClaims{Roles: roles},
+		Department:       department,
+		RegisteredClaims: jwt.RegisteredClaims{Subject: "user-1"},
+	})
+}
+
+func TestEvaluate(t *testing.T) {
+	var a = assert.New(t)
+	var ctx = context.Background()
+	var workday = time.Date(2025, 11, 3, 11, 0, 0, 0, time.UTC)
+	t.Run("no policies allow everything", func(t *testing.T) {
+		var engine, _ = newEngine(workday)
+		var decision = engine.Evaluate(ctx, Request{Subject: subject("Sales"), Action: "employee:delete"})
+		a.Equal(Decision{Allowed: true, Rule: DefaultRule}, decision)
+	})
+	t.Run("deny rule wins over allow rule", func(t *testing.T) {
+		var engine, _ = newEngine(time.Date(2025, 11, 3, 20, 0, 0, 0, time.UTC))
+		a.Nil(engine.Apply([]byte(policies)))
+		var decision = engine.Evaluate(ctx, Request{
+			Subject:  subject("Sales", web.IdmAdmin),
+			Action:   "employee:delete",
+			Resource: map[string]any{"id": int64(1)},
+		})
+		a.Equal(Decision{Allowed: false, Rule: "deletes-in-business-hours"}, decision)
+	})
+	t.Run("first matching allow rule is reported", func(t *testing.T) {
+		var engine, _ = newEngine(workday)
+		a.Nil(engine.Apply([]byte(policies)))
+		var decision = engine.Evaluate(ctx, Request{
+			Subject:  subject("Sales", web.IdmAdmin),
+			Action:   "employee:delete",
+			Resource: map[string]any{"id": int64(1)},
+		})
+		a.Equal(Decision{Allowed: true, Rule: "admins"}, decision)
+	})
+	t.Run("user reads only own department", func(t *testing.T) {
+		var engine, _ = newEngine(workday)
+		a.Nil(engine.Apply([]byte(policies)))
+		var own = engine.Evaluate(ctx, Request{
+			Subject:  subject("Sales", web.IdmUser),
+			Action:   "employee:read",
+			Resource: map[string]any{"department": "Sales"},
+		})
+		var other = engine.Evaluate(ctx, Request{
+			Subject:  subject("Sales", web.IdmUser),
+			Action:   "employee:read",
+			Resource: map[string]any{"department": "Engineering"},
+		})
+		a.Equal(Decision{Allowed: true, Rule: DefaultRule}, own)
+		a.Equal(Decision{Allowed: false, Rule: "user-reads-own-department"}, other)
+	})
+	t.Run("default deny", func(t *testing.T) {
+		var engine, _ = newEngine(workday)
+		a.Nil(engine.Apply([]byte(`{"default": "deny"}`)))
+		var decision = engine.Evaluate(ctx, Request{Subject: subject("Sales"), Action: "employee:read"})
+		a.Equal(Decision{Allowed: false, Rule: DefaultRule}, decision)
+	})
+	t.Run("decision is logged with matched rule", func(t *testing.T) {
+		var engine, logs = newEngine(workday)
+		a.Nil(engine.Apply([]byte(policies)))
+		engine.Evaluate(ctx, Request{
+			Subject:  subject("Sales", web.IdmUser),
+			Action:   "employee:read",
+			Resource: map[string]any{"id": int64(5), "department": "HR"},
+		})
+		var entries = logs.FilterMessage("policy decision").All()
+		a.Len(entries, 1)
+		var fields = entries[0].ContextMap()
+		a.Equal("user-reads-own-department", fields["rule"])
+		a.Equal(false, fields["allowed"])
+		a.Equal("user-1", fields["subject"])
+	})
+}
+
+func TestCheck(t *testing.T) {
+	var a = assert.New(t)
+	var engine, logs = newEngine(time.Date(2025, 3, 5, 11, 0, 0, 0, time.UTC))
+	a.Nil(engine.Apply([]byte(policies)))
+	var decision = engine.Check(context.Background(), Request{
+		Subject:  subject("Sales", web.IdmUser),
+		Action:   "employee:read",
+		Resource: map[string]any{"id": int64(5), "department": "HR"},
+	})
+	a.Equal(Decision{Allowed: false, Rule: "user-reads-own-department"}, decision)
+	a.Empty(logs.FilterMessage("policy decision").All())
+}
+
+func TestRestricts(t *testing.T) {
+	var a = assert.New(t)
+	var engine, _ = newEngine(time.Now())
+	a.False(engine.Restricts("employee:read"))
+	a.Nil(engine.Apply([]byte(`{"rules": [{"name": "r", "effect": "deny", "actions": ["employee:delete"]}]}`)))
+	a.False(engine.Restricts("employee:read"))
+	a.True(engine.Restricts("employee:delete"))
+	a.Nil(engine.Apply([]byte(policies)))
+	a.True(engine.Restricts("employee:read"))
+	a.Nil(engine.Apply([]byte(`{"default": "deny"}`)))
+	a.True(engine.Restricts("employee:read"))
+}
+
+func TestApplyErrors(t *testing.T) {
+	var a = assert.New(t)
+	var engine, _ = newEngine(time.Now())
+	a.Nil(engine.Apply([]byte(policies)))
+	a.NotNil(engine.Apply([]byte(`{"default": "maybe"}`)))
+	a.NotNil(engine.Apply([]byte(`{"rules": [{"effect": "deny"}]}`)))
+	a.NotNil(engine.Apply([]byte(`{"rules": [{"name": "r", "effect": "skip"}]}`)))
+	a.NotNil(engine.Apply([]byte(`{"rules": [{"name": "r", "effect": "deny", "condition": "a =="}]}`)))
+	a.NotNil(engine.Apply([]byte(`not json`)))
+	// после ошибок действуют ранее загруженные политики
+	a.Equal(3, engine.rulesCount())
+}
+
+func TestLoadAndReload(t *testing.T) {
+	var a = assert.New(t)
+	var path = filepath.Join(t.TempDir(), "policies.json")
+	a.Nil(os.WriteFile(path, []byte(policies), 0o600))
+	var engine, _ = newEngine(time.Now())
+	a.Nil(engine.Load(path))
+	a.Equal(3, engine.rulesCount())
+	t.Run("file not changed", func(t *testing.T) {
+		a.Nil(engine.ReloadIfChanged(context.Background()))
+		a.Equal(3, engine.rulesCount())
+	})
+	t.Run("changed file is reloaded", func(t *testing.T) {
+		a.Nil(os.WriteFile(path, []byte(`{"rules": [{"name": "r", "effect": "deny"}]}`), 0o600))
+		var later = time.Now().Add(time.Minute)
+		a.Nil(os.Chtimes(path, later, later))
+		a.Nil(engine.ReloadIfChanged(context.Background()))
+		a.Equal(1, engine.rulesCount())
+	})
+	t.Run("broken file keeps current policies", func(t *testing.T) {
+		a.Nil(os.WriteFile(path, []byte(`{"rules": [`), 0o600))
+		a.NotNil(engine.Reload())
+		a.Equal(1, engine.rulesCount())
+	})
+	t.Run("missing file", func(t *testing.T) {
+		a.NotNil(NewEngine(&common.Logger{Logger: zap.NewNop()}).Load(filepath.Join(t.TempDir(), "absent.json")))
+	})
+}
@@ -0,0 +1,445 @@
+package policy
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Выражения условий политик.
+// Поддерживаются: строки в одинарных или двойных кавычках, числа, true/false, списки [a, b],
+// пути к атрибутам (subject.roles, resource.department, action, env.hour),
+// операторы == != < <= > >= in contains, логические && || ! и скобки.
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+}
+
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	var runes = []rune(input)
+	for i := 0; i < len(runes); {
+		var r = runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '\'' || r == '"':
+			var end = i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			tokens = append(tokens, token{kind: tokenString, value: string(runes[i+1 : end])})
+			i = end + 1
+		case unicode.IsDigit(r):
+			var end = i
+			for end < len(runes) && (unicode.IsDigit(runes[end]) || runes[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, value: string(runes[i:end])})
+			i = end
+		case unicode.IsLetter(r) || r == '_':
+			var end = i
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) ||
+				runes[end] == '_' || runes[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, value: string(runes[i:end])})
+			i = end
+		default:
+			var op string
+			if i+1 < len(runes) {
+				op = string(runes[i : i+2])
+			}
+			switch op {
+			case "==", "!=", "<=", ">=", "&&", "||":
+				tokens = append(tokens, token{kind: tokenOperator, value: op})
+				i += 2
+				continue
+			}
+			switch r {
+			case '<', '>', '!', '(', ')', '[', ']', ',':
+				tokens = append(tokens, token{kind: tokenOperator, value: string(r)})
+				i++
+			default:
+				return nil, fmt.Errorf("unexpected character %q at position %d", r, i)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF}), nil
+}
+
+// node узел разобранного выражения
+type node interface {
+	eval(attrs map[string]any) (any, error)
+}
+
+type literalNode struct {
+	value any
+}
+
+type pathNode struct {
+	path []string
+}
+
+type listNode struct {
+	items []node
+}
+
+type notNode struct {
+	operand node
+}
+
+type binaryNode struct {
+	op    string
+	left  node
+	right node
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// compile разобрать выражение условия
+func compile(expression string) (node, error) {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, err
+	}
+	var p = &parser{tokens: tokens}
+	result, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected token %q", p.peek().value)
+	}
+	return result, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	var t = p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isOperator(values ...string) bool {
+	var t = p.peek()
+	return (t.kind == tokenOperator || t.kind == tokenIdent) && slices.Contains(values, t.value)
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("||") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("&&") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.isOperator("!") {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if p.isOperator("==", "!=", "<", "<=", ">", ">=", "in", "contains") {
+		var op = p.next().value
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return binaryNode{op: op, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	var t = p.next()
+	switch t.kind {
+	case tokenString:
+		return literalNode{value: t.value}, nil
+	case tokenNumber:
+		number, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", t.value)
+		}
+		return literalNode{value: number}, nil
+	case tokenIdent:
+		switch t.value {
+		case "true":
+			return literalNode{value: true}, nil
+		case "false":
+			return literalNode{value: false}, nil
+		case "null":
+			return literalNode{value: nil}, nil
+		}
+		return pathNode{path: strings.Split(t.value, ".")}, nil
+	case tokenOperator:
+		switch t.value {
+		case "(":
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if !p.isOperator(")") {
+				return nil, fmt.Errorf("expected )")
+			}
+			p.next()
+			return inner, nil
+		case "[":
+			var list listNode
+			for !p.isOperator("]") {
+				item, err := p.parsePrimary()
+				if err != nil {
+					return nil, err
+				}
+				list.items = append(list.items, item)
+				if p.isOperator(",") {
+					p.next()
+				} else if !p.isOperator("]") {
+					return nil, fmt.Errorf("expected , or ] in list")
+				}
+			}
+			p.next()
+			return list, nil
+		}
+	}
+	if t.kind == tokenEOF {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected token %q", t.value)
+}
+
+func (n literalNode) eval(attrs map[string]any) (any, error) {
+	return n.value, nil
+}
+
+// eval найти значение атрибута по пути; отсутствующий атрибут равен null
+func (n pathNode) eval(attrs map[string]any) (any, error) {
+	var current any = attrs
+	for _, key := range n.path {
+		values, ok := current.(map[string]any)
+		if !ok {
+			return nil, nil
+		}
+		current = values[key]
+	}
+	return normalize(current), nil
+}
+
+func (n listNode) eval(attrs map[string]any) (any, error) {
+	var values []any
+	for _, item := range n.items {
+		value, err := item.eval(attrs)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func (n notNode) eval(attrs map[string]any) (any, error) {
+	value, err := n.operand.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+	return !truthy(value), nil
+}
+
+func (n binaryNode) eval(attrs map[string]any) (any, error) {
+	left, err := n.left.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "&&":
+		if !truthy(left) {
+			return false, nil
+		}
+		right, err := n.right.eval(attrs)
+		return truthy(right), err
+	case "||":
+		if truthy(left) {
+			return true, nil
+		}
+		right, err := n.right.eval(attrs)
+		return truthy(right), err
+	}
+	right, err := n.right.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "in":
+		return contains(right, left), nil
+	case "contains":
+		return contains(left, right), nil
+	}
+	return compare(n.op, left, right)
+}
+
+func truthy(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case float64:
+		return v != 0
+	case []any:
+		return len(v) > 0
+	}
+	return true
+}
+
+func equal(left any, right any) bool {
+	return fmt.Sprint(left) == fmt.Sprint(right) && sameKind(left, right)
+}
+
+func sameKind(left any, right any) bool {
+	switch left.(type) {
+	case float64:
+		_, ok := right.(float64)
+		return ok
+	case string:
+		_, ok := right.(string)
+		return ok
+	case bool:
+		_, ok := right.(bool)
+		return ok
+	case nil:
+		return right == nil
+	}
+	return false
+}
+
+func contains(list any, value any) bool {
+	switch v := list.(type) {
+	case []any:
+		for _, item := range v {
+			if equal(item, value) {
+				return true
+			}
+		}
+	case string:
+		if s, ok := value.(string); ok {
+			return strings.Contains(v, s)
+		}
+	}
+	return false
+}
+
+func compare(op string, left any, right any) (any, error) {
+	l, lok := left.(float64)
+	r, rok := right.(float64)
+	if !lok || !rok {
+		ls, lsok := left.(string)
+		rs, rsok := right.(string)
+		if !lsok || !rsok {
+			return false, nil
+		}
+		l, r = float64(strings.Compare(ls, rs)), 0
+	}
+	switch op {
+	case "<":
+		return l < r, nil
+	case "<=":
+		return l <= r, nil
+	case ">":
+		return l > r, nil
+	case ">=":
+		return l >= r, nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", op)
+}
+
+// normalize привести значения атрибутов к типам выражений: числа к float64, срезы к []any
+func normalize(value any) any {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case []string:
+		var values = make([]any, 0, len(v))
+		for _, item := range v {
+			values = append(values, item)
+		}
+		return values
+	case []int64:
+		var values = make([]any, 0, len(v))
+		for _, item := range v {
+			values = append(values, float64(item))
+		}
+		return values
+	}
+	return value
+}
@@ -0,0 +1,59 @@
+package policy
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestExpression(t *testing.T) {
+	var a = assert.New(t)
+	var attrs = map[string]any{
+		"subject": map[string]any{
+			"sub":        "user-1",
+			"roles":      normalize([]string{"IDM_USER"}),
+			"department": "Sales",
+		},
+		"action":   "employee:read",
+		"resource": map[string]any{"id": int64(7), "department": "Sales"},
+		"env":      map[string]any{"hour": float64(10)},
+	}
+	var cases = []struct {
+		expression string
+		want       bool
+	}{
+		{"resource.department == subject.department", true},
+		{"resource.department != subject.department", false},
+		{"'IDM_USER' in subject.roles", true},
+		{"subject.roles contains 'IDM_ADMIN'", false},
+		{"!('IDM_ADMIN' in subject.roles) && action == \"employee:read\"", true},
+		{"env.hour < 9 || env.hour >= 18", false},
+		{"env.hour >= 9 && env.hour < 18", true},
+		{"resource.id == 7", true},
+		{"resource.id in [1, 2, 7]", true},
+		{"resource.missing == null", true},
+		{"subject.department in ['HR', 'Finance']", false},
+		{"resource.id == '7'", false},
+	}
+	for _, c := range cases {
+		condition, err := compile(c.expression)
+		a.Nil(err, c.expression)
+		value, err := condition.eval(attrs)
+		a.Nil(err, c.expression)
+		a.Equal(c.want, truthy(value), c.expression)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	var a = assert.New(t)
+	for _, expression := range []string{
+		"resource.department ==",
+		"(env.hour > 1",
+		"'unterminated",
+		"env.hour # 1",
+		"[1, 2",
+		"a b",
+	} {
+		_, err := compile(expression)
+		a.NotNil(err, expression)
+	}
+}
@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule лимит token bucket: не больше Requests запросов за Period, пополнение равномерное
+type Rule struct {
+	Requests int
+	Period   time.Duration
+}
+
+// ParseRule разобрать лимит вида "100/1m"; пустая строка или off - без лимита
+func ParseRule(spec string) (*Rule, error) {
+	if spec == "" || spec == "off" {
+		return nil, nil
+	}
+	requests, period, found := strings.Cut(spec, "/")
+	if !found {
+		return nil, fmt.Errorf("invalid rate limit %q: expected requests/period", spec)
+	}
+	var rule Rule
+	var err error
+	if rule.Requests, err = strconv.Atoi(strings.TrimSpace(requests)); err != nil || rule.Requests <= 0 {
+		return nil, fmt.Errorf("invalid rate limit %q: requests must be a positive number", spec)
+	}
+	if rule.Period, err = time.ParseDuration(strings.TrimSpace(period)); err != nil || rule.Period <= 0 {
+		return nil, fmt.Errorf("invalid rate limit %q: period must be a positive duration", spec)
+	}
+	return &rule, nil
+}
+
+// rate токенов в секунду
+func (r Rule) rate() float64 {
+	return float64(r.Requests) / r.Period.Seconds()
+}
+
+// Bucket состояние корзины токенов клиента
+type Bucket struct {
+	Tokens    float64   `db:"tokens"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// Decision результат попытки взять токен
+type Decision struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset через сколько корзина наполнится полностью
+	Reset time.Duration
+	// RetryAfter через сколько появится следующий токен; 0, если запрос пропущен
+	RetryAfter time.Duration
+}
+
+// take пополнить корзину за прошедшее время и взять из неё токен, если он есть.
+// Новая корзина (нулевое UpdatedAt) полная
+func (b *Bucket) take(rule Rule, now time.Time) Decision {
+	var capacity = float64(rule.Requests)
+	if b.UpdatedAt.IsZero() {
+		b.Tokens = capacity
+	} else if elapsed := now.Sub(b.UpdatedAt).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(capacity, b.Tokens+elapsed*rule.rate())
+	}
+	b.UpdatedAt = now
+	var decision = Decision{Limit: rule.Requests}
+	if b.Tokens >= 1 {
+		b.Tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = seconds((1 - b.Tokens) / rule.rate())
+	}
+	decision.Remaining = int(b.Tokens)
+	decision.Reset = seconds((capacity - b.Tokens) / rule.rate())
+	return decision
+}
+
+// seconds округлить вверх до целых секунд, как их передают заголовки
+func seconds(value float64) time.Duration {
+	return time.Duration(math.Ceil(value)) * time.Second
+}
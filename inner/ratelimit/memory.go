@@ -0,0 +1,42 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore корзины в памяти процесса; у каждой реплики свои лимиты
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*Bucket
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*Bucket),
+	}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, rule Rule, now time.Time) (Decision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bucket, found := s.buckets[key]
+	if !found {
+		bucket = &Bucket{}
+		s.buckets[key] = bucket
+	}
+	return bucket.take(rule, now), nil
+}
+
+// Purge удалить корзины, которые не менялись с before: к этому времени они уже полные
+func (s *MemoryStore) Purge(ctx context.Context, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, bucket := range s.buckets {
+		if bucket.UpdatedAt.Before(before) {
+			delete(s.buckets, key)
+		}
+	}
+	return nil
+}
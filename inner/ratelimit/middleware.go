@@ -0,0 +1,132 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"idm/inner/common"
+	"idm/inner/middleware"
+	"idm/inner/web"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// классы запросов, у каждого свой лимит
+const (
+	ClassRead  = "read"
+	ClassWrite = "write"
+	ClassBulk  = "bulk"
+)
+
+// заголовки лимитов (draft-ietf-httpapi-ratelimit-headers)
+const (
+	HeaderLimit     = "RateLimit-Limit"
+	HeaderRemaining = "RateLimit-Remaining"
+	HeaderReset     = "RateLimit-Reset"
+)
+
+type Store interface {
+	Take(ctx context.Context, key string, rule Rule, now time.Time) (Decision, error)
+	Purge(ctx context.Context, before time.Time) error
+}
+
+// Rules лимиты группы маршрутов по классам запросов; nil - класс не ограничен
+type Rules struct {
+	Group string
+	Read  *Rule
+	Write *Rule
+	Bulk  *Rule
+	// BulkRoutes маршруты массовых операций вида "DELETE /api/v1/employees/delete" или "POST /api/v1/applications/:id/reconcile"
+	BulkRoutes []string
+}
+
+// Longest наибольший период среди лимитов: корзины, которые не менялись дольше, уже полные
+func (r Rules) Longest() time.Duration {
+	var longest time.Duration
+	for _, rule := range []*Rule{r.Read, r.Write, r.Bulk} {
+		if rule != nil && rule.Period > longest {
+			longest = rule.Period
+		}
+	}
+	return longest
+}
+
+// class bulk для маршрутов из BulkRoutes, read для GET и HEAD, иначе write
+func (r Rules) class(method string, path string) string {
+	for _, route := range r.BulkRoutes {
+		routeMethod, routePath, _ := strings.Cut(route, " ")
+		if routeMethod == method && matches(routePath, path) {
+			return ClassBulk
+		}
+	}
+	if method == fiber.MethodGet || method == fiber.MethodHead {
+		return ClassRead
+	}
+	return ClassWrite
+}
+
+func (r Rules) rule(class string) *Rule {
+	switch class {
+	case ClassRead:
+		return r.Read
+	case ClassBulk:
+		return r.Bulk
+	default:
+		return r.Write
+	}
+}
+
+// matches путь соответствует шаблону маршрута, сегменты с двоеточием - параметры
+func matches(pattern string, path string) bool {
+	var patternParts = strings.Split(strings.Trim(pattern, "/"), "/")
+	var pathParts = strings.Split(strings.Trim(path, "/"), "/")
+	if len(patternParts) != len(pathParts) {
+		return false
+	}
+	for i, part := range patternParts {
+		if !strings.HasPrefix(part, ":") && part != pathParts[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Middleware ограничить запросы группы маршрутов по rules. Клиент определяется по subject токена,
+// API-ключа или клиентского сертификата, поэтому middleware ставится после аутентификации; без неё - по IP.
+// Если хранилище недоступно, запрос пропускается
+func Middleware(store Store, rules Rules) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		var class = rules.class(ctx.Method(), ctx.Path())
+		var rule = rules.rule(class)
+		if rule == nil {
+			return ctx.Next()
+		}
+		var key = strings.Join([]string{rules.Group, class, client(ctx)}, ":")
+		decision, err := store.Take(ctx.Context(), key, *rule, time.Now())
+		if err != nil {
+			middleware.GetLogger(ctx).ErrorCtx(ctx.Context(), "rate limit: ", zap.String("key", key), zap.Error(err))
+			return ctx.Next()
+		}
+		ctx.Set(HeaderLimit, strconv.Itoa(decision.Limit))
+		ctx.Set(HeaderRemaining, strconv.Itoa(decision.Remaining))
+		ctx.Set(HeaderReset, strconv.Itoa(int(decision.Reset.Seconds())))
+		if !decision.Allowed {
+			ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(decision.RetryAfter.Seconds())))
+			middleware.GetLogger(ctx).WarnCtx(ctx.Context(), "rate limit exceeded", zap.String("key", key))
+			return common.ErrResponse(ctx, fiber.StatusTooManyRequests, "Too many requests")
+		}
+		return ctx.Next()
+	}
+}
+
+// client subject аутентифицированного клиента или IP
+func client(ctx *fiber.Ctx) string {
+	if token, ok := ctx.Locals(web.JwtKey).(*jwt.Token); ok {
+		if claims, ok := token.Claims.(*web.IdmClaims); ok && claims.Subject != "" {
+			return "sub:" + claims.Subject
+		}
+	}
+	return "ip:" + ctx.IP()
+}
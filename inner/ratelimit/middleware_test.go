@@ -0,0 +1,132 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"idm/inner/web"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, key string, rule Rule, now time.Time) (Decision, error) {
+	return Decision{}, errors.New("connection refused")
+}
+
+func (failingStore) Purge(ctx context.Context, before time.Time) error {
+	return nil
+}
+
+func newServer(store Store, rules Rules) *fiber.App {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		if subject := c.Get("X-Test-Subject"); subject != "" {
+			c.Locals(web.JwtKey, &jwt.Token{Claims: &web.IdmClaims{
+				RegisteredClaims: jwt.RegisteredClaims{Subject: subject},
+			}})
+		}
+		return c.Next()
+	})
+	app.Use(Middleware(store, rules))
+	var ok = func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) }
+	app.Get("/api/v1/employees", ok)
+	app.Delete("/api/v1/employees/delete", ok)
+	app.Delete("/api/v1/employees/:id", ok)
+	app.Post("/api/v1/applications/:id/reconcile", ok)
+	return app
+}
+
+func TestMiddleware(t *testing.T) {
+	var a = assert.New(t)
+	var rules = Rules{
+		Group: "api",
+		Read:  &Rule{Requests: 3, Period: time.Minute},
+		Write: &Rule{Requests: 2, Period: time.Minute},
+		Bulk:  &Rule{Requests: 1, Period: time.Minute},
+		BulkRoutes: []string{
+			"DELETE /api/v1/employees/delete",
+			"POST /api/v1/applications/:id/reconcile",
+		},
+	}
+	var call = func(app *fiber.App, method string, path string, subject string) *http.Response {
+		request := httptest.NewRequest(method, path, nil)
+		if subject != "" {
+			request.Header.Set("X-Test-Subject", subject)
+		}
+		resp, err := app.Test(request)
+		require.NoError(t, err)
+		return resp
+	}
+	t.Run("subject is limited with headers and retry after", func(t *testing.T) {
+		var app = newServer(NewMemoryStore(), rules)
+		for i := 2; i >= 0; i-- {
+			resp := call(app, fiber.MethodGet, "/api/v1/employees", "alice")
+			a.Equal(http.StatusOK, resp.StatusCode)
+			a.Equal("3", resp.Header.Get(HeaderLimit))
+			a.Equal(strconv.Itoa(i), resp.Header.Get(HeaderRemaining))
+		}
+		resp := call(app, fiber.MethodGet, "/api/v1/employees", "alice")
+		a.Equal(http.StatusTooManyRequests, resp.StatusCode)
+		a.Equal("20", resp.Header.Get(fiber.HeaderRetryAfter))
+		a.Equal("60", resp.Header.Get(HeaderReset))
+		a.Equal(http.StatusOK, call(app, fiber.MethodGet, "/api/v1/employees", "bob").StatusCode)
+	})
+	t.Run("bulk endpoints have their own limit", func(t *testing.T) {
+		var app = newServer(NewMemoryStore(), rules)
+		a.Equal(http.StatusOK, call(app, fiber.MethodDelete, "/api/v1/employees/delete", "alice").StatusCode)
+		a.Equal(http.StatusTooManyRequests, call(app, fiber.MethodDelete, "/api/v1/employees/delete", "alice").StatusCode)
+		a.Equal(http.StatusOK, call(app, fiber.MethodDelete, "/api/v1/employees/7", "alice").StatusCode)
+		resp := call(app, fiber.MethodPost, "/api/v1/applications/3/reconcile", "alice")
+		a.Equal(http.StatusTooManyRequests, resp.StatusCode)
+		a.Equal("1", resp.Header.Get(HeaderLimit))
+		a.Equal(http.StatusOK, call(app, fiber.MethodPost, "/api/v1/applications/3/reconcile", "bob").StatusCode)
+	})
+	t.Run("anonymous clients are limited by IP", func(t *testing.T) {
+		var app = newServer(NewMemoryStore(), Rules{Group: "internal", Read: &Rule{Requests: 1, Period: time.Minute}})
+		a.Equal(http.StatusOK, call(app, fiber.MethodGet, "/api/v1/employees", "").StatusCode)
+		a.Equal(http.StatusTooManyRequests, call(app, fiber.MethodGet, "/api/v1/employees", "").StatusCode)
+		a.Equal(http.StatusOK, call(app, fiber.MethodDelete, "/api/v1/employees/7", "").StatusCode)
+	})
+	t.Run("unavailable store lets requests through", func(t *testing.T) {
+		var app = newServer(failingStore{}, rules)
+		resp := call(app, fiber.MethodGet, "/api/v1/employees", "alice")
+		a.Equal(http.StatusOK, resp.StatusCode)
+		a.Empty(resp.Header.Get(HeaderLimit))
+	})
+}
+
+func TestBucketRefill(t *testing.T) {
+	var a = assert.New(t)
+	var rule = Rule{Requests: 2, Period: 10 * time.Second}
+	var store = NewMemoryStore()
+	var now = time.Now()
+	var take = func(at time.Time) Decision {
+		decision, err := store.Take(context.Background(), "alice", rule, at)
+		require.NoError(t, err)
+		return decision
+	}
+	a.True(take(now).Allowed)
+	a.True(take(now).Allowed)
+	a.False(take(now).Allowed)
+	a.True(take(now.Add(5 * time.Second)).Allowed)
+	a.False(take(now.Add(5 * time.Second)).Allowed)
+	a.Nil(store.Purge(context.Background(), now.Add(time.Minute)))
+	a.Empty(store.buckets)
+
+	_, err := ParseRule("10 per minute")
+	a.Error(err)
+	parsed, err := ParseRule("10/1m")
+	a.Nil(err)
+	a.Equal(&Rule{Requests: 10, Period: time.Minute}, parsed)
+	parsed, err = ParseRule("off")
+	a.Nil(err)
+	a.Nil(parsed)
+}
@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"idm/inner/database"
+	"time"
+)
+
+// Repository корзины в Postgres, общие для всех реплик
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{
+		db: database,
+	}
+}
+
+func (r *Repository) Take(ctx context.Context, key string, rule Rule, now time.Time) (decision Decision, err error) {
+	err = database.InTransaction(r.db.Beginx, "take rate limit token", func(tx *sqlx.Tx) error {
+		// новая корзина полная
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO rate_limit_bucket (key, tokens, updated_at) VALUES ($1, $2, $3) ON CONFLICT (key) DO NOTHING",
+			key, rule.Requests, now)
+		if err != nil {
+			return err
+		}
+		var bucket Bucket
+		err = tx.GetContext(ctx, &bucket, "SELECT tokens, updated_at FROM rate_limit_bucket WHERE key = $1 FOR UPDATE", key)
+		if err != nil {
+			return err
+		}
+		decision = bucket.take(rule, now)
+		_, err = tx.ExecContext(ctx, "UPDATE rate_limit_bucket SET tokens = $1, updated_at = $2 WHERE key = $3",
+			bucket.Tokens, bucket.UpdatedAt, key)
+		return err
+	})
+	return decision, err
+}
+
+func (r *Repository) Purge(ctx context.Context, before time.Time) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM rate_limit_bucket WHERE updated_at < $1", before)
+	return err
+}
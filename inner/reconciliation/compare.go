@@ -0,0 +1,76 @@
+package reconciliation
+
+import (
+	"idm/inner/account"
+)
+
+// Compare сравнить выгрузку целевой системы с правами, которые положены по ролям.
+// desired должен быть упорядочен по сотруднику и праву, тогда и результат детерминирован
+func Compare(desired []DesiredEntity, rows []account.ImportRow, owners []Owner) []Finding {
+	var byId = make(map[int64]bool, len(owners))
+	var bySubject = make(map[string]int64, len(owners))
+	for _, owner := range owners {
+		byId[owner.Id] = true
+		if owner.Subject != "" {
+			bySubject[owner.Subject] = owner.Id
+		}
+	}
+	var ownerOf = func(row account.ImportRow) *int64 {
+		if row.EmployeeId > 0 {
+			if byId[row.EmployeeId] {
+				return &row.EmployeeId
+			}
+			return nil
+		}
+		if id, ok := bySubject[row.Subject]; ok && row.Subject != "" {
+			return &id
+		}
+		return nil
+	}
+	var entitled = make(map[int64]map[string]bool)
+	for _, item := range desired {
+		if entitled[item.EmployeeId] == nil {
+			entitled[item.EmployeeId] = make(map[string]bool)
+		}
+		entitled[item.EmployeeId][item.Entitlement] = true
+	}
+	var findings []Finding
+	var held = make(map[int64]map[string]bool)
+	var logins = make(map[int64]string)
+	for _, row := range rows {
+		var owner = ownerOf(row)
+		var disabledWithAccess bool
+		for _, name := range row.Entitlements {
+			if owner == nil || !entitled[*owner][name] {
+				findings = append(findings, Finding{Type: FindingExtra, EmployeeId: owner, Login: row.Login, Entitlement: name})
+				continue
+			}
+			if held[*owner] == nil {
+				held[*owner] = make(map[string]bool)
+			}
+			held[*owner][name] = true
+			disabledWithAccess = disabledWithAccess || row.Status == account.StatusDisabled
+		}
+		if owner != nil {
+			if _, ok := logins[*owner]; !ok {
+				logins[*owner] = row.Login
+			}
+		}
+		if disabledWithAccess {
+			findings = append(findings, Finding{Type: FindingMismatched, EmployeeId: owner, Login: row.Login})
+		}
+	}
+	for _, item := range desired {
+		if held[item.EmployeeId][item.Entitlement] {
+			continue
+		}
+		var employeeId = item.EmployeeId
+		findings = append(findings, Finding{
+			Type:        FindingMissing,
+			EmployeeId:  &employeeId,
+			Login:       logins[employeeId],
+			Entitlement: item.Entitlement,
+		})
+	}
+	return findings
+}
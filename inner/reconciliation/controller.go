@@ -0,0 +1,141 @@
+package reconciliation
+
+import (
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"idm/inner/account"
+	"idm/inner/common"
+	"idm/inner/middleware"
+	"idm/inner/web"
+	"slices"
+	"strconv"
+)
+
+type Controller struct {
+	server                *web.Server
+	reconciliationService Svc
+}
+
+type Svc interface {
+	Reconcile(ctx context.Context, request ReconcileRequest) (ReconcileResponse, error)
+	FindTasks(request IdRequest) ([]TaskResponse, error)
+}
+
+func NewController(
+	server *web.Server,
+	reconciliationService Svc,
+) *Controller {
+	return &Controller{
+		server:                server,
+		reconciliationService: reconciliationService,
+	}
+}
+
+func (c *Controller) RegisterRoutes() {
+	c.server.GroupApiV1.Post("/applications/:id/reconcile", c.Reconcile)
+	c.server.GroupApiV1.Get("/applications/:id/provisioning-tasks", c.FindTasks)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/applications/:id/reconcile"
+// @Summary reconcile application snapshot
+// @Description Compare an account/entitlement snapshot (JSON array or CSV with header) with entitlements granted by roles and report missing, extra and mismatched access, with roles: admin
+// @Tags reconciliation
+// @Security OAuth2Password
+// @Accept json
+// @Accept text/csv
+// @Produce json
+// @Param id path int true "Application ID"
+// @Param tasks query bool false "Create provisioning tasks for every finding"
+// @Param request body []account.ImportRow true "target system snapshot"
+// @Success 200 {object} common.Response[reconciliation.ReconcileResponse]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /applications/{id}/reconcile [post]
+func (c *Controller) Reconcile(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error parsing id: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	rows, err := account.ParseRows(ctx.Get(fiber.HeaderContentType), ctx.Body())
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "body parse error: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request = ReconcileRequest{
+		ApplicationId: id,
+		Rows:          rows,
+		CreateTasks:   ctx.QueryBool("tasks", false),
+		CreatedBy:     claims.Subject,
+	}
+	logger.InfoCtx(ctx.Context(), "reconcile application: received request",
+		zap.Int64("application_id", id), zap.Int("rows", len(rows)), zap.Bool("tasks", request.CreateTasks))
+	response, err := c.reconciliationService.Reconcile(ctx.Context(), request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			logger.ErrorCtx(ctx.Context(), "error reconciling application: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), "error reconciling application: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "error reconciling application: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/applications/:id/provisioning-tasks"
+// @Summary Get provisioning tasks of application
+// @Description returns corrective tasks created by reconciliation, with roles: admin
+// @Tags reconciliation
+// @Security OAuth2Password
+// @Produce json
+// @Param id path int true "Application ID"
+// @Success 200 {object} common.Response[[]reconciliation.TaskResponse]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /applications/{id}/provisioning-tasks [get]
+func (c *Controller) FindTasks(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error parsing id: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request = IdRequest{Id: id}
+	logger.InfoCtx(ctx.Context(), "find provisioning tasks: received request", zap.Any("request", request))
+	response, err := c.reconciliationService.FindTasks(request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			logger.ErrorCtx(ctx.Context(), "find provisioning tasks: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), "find provisioning tasks: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "find provisioning tasks: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response)
+}
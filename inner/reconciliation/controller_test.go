@@ -0,0 +1,80 @@
+package reconciliation
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"idm/inner/account"
+	"idm/inner/web"
+	"idm/inner/web/webtest"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) Reconcile(ctx context.Context, request ReconcileRequest) (ReconcileResponse, error) {
+	args := svc.Called(ctx, request)
+	return args.Get(0).(ReconcileResponse), args.Error(1)
+}
+
+func (svc *MockService) FindTasks(request IdRequest) ([]TaskResponse, error) {
+	args := svc.Called(request)
+	return args.Get(0).([]TaskResponse), args.Error(1)
+}
+
+func newServer(svc Svc, subject string, roles ...string) *web.Server {
+	server := webtest.NewServer(subject, roles...)
+	NewController(server, svc).RegisterRoutes()
+	return server
+}
+
+func TestReconcileApplication(t *testing.T) {
+	var a = assert.New(t)
+	var body = "login,employee_id,entitlements\njdoe,1,push;admins\n"
+	t.Run("reconcile csv snapshot with tasks", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "admin-1", web.IdmAdmin)
+		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/applications/3/reconcile?tasks=true",
+			strings.NewReader(body))
+		request.Header.Add("Content-Type", "text/csv")
+		svc.On("Reconcile", mock.Anything, ReconcileRequest{
+			ApplicationId: 3,
+			Rows: []account.ImportRow{{
+				Login:        "jdoe",
+				EmployeeId:   1,
+				Status:       account.StatusActive,
+				Entitlements: []string{"push", "admins"},
+			}},
+			CreateTasks: true,
+			CreatedBy:   "admin-1",
+		}).Return(ReconcileResponse{ApplicationId: 3, Extra: 1}, nil)
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+	})
+	t.Run("reconcile without role admin", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "user-1", web.IdmUser)
+		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/applications/3/reconcile",
+			strings.NewReader(body))
+		request.Header.Add("Content-Type", "text/csv")
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		a.True(svc.AssertNumberOfCalls(t, "Reconcile", 0))
+	})
+	t.Run("list provisioning tasks", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "admin-1", web.IdmAdmin)
+		svc.On("FindTasks", IdRequest{Id: 3}).Return([]TaskResponse{{Id: 10, Action: ActionRevoke}}, nil)
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/applications/3/provisioning-tasks", nil))
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+	})
+}
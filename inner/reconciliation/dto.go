@@ -0,0 +1,123 @@
+package reconciliation
+
+import (
+	"idm/inner/account"
+	"time"
+)
+
+// виды расхождений между выгрузкой целевой системы и ролями IDM
+const (
+	// FindingMissing сотрудник должен иметь право по ролям, но в выгрузке его нет
+	FindingMissing = "missing"
+	// FindingExtra право есть в выгрузке, но ролями не положено или у учётной записи нет владельца
+	FindingExtra = "extra"
+	// FindingMismatched право положено и есть в выгрузке, но учётная запись отключена
+	FindingMismatched = "mismatched"
+)
+
+// действия задач на исправление расхождений
+const (
+	ActionGrant  = "grant"
+	ActionRevoke = "revoke"
+	ActionEnable = "enable"
+)
+
+// TaskOpen состояние задачи, которая ещё не выполнена
+const TaskOpen = "open"
+
+// DesiredEntity право приложения, которое сотрудник должен иметь через свои роли
+type DesiredEntity struct {
+	EmployeeId  int64  `db:"employee_id"`
+	Entitlement string `db:"entitlement"`
+}
+
+// Owner сотрудник, на которого может ссылаться строка выгрузки
+type Owner struct {
+	Id      int64  `db:"id"`
+	Subject string `db:"subject"`
+}
+
+type Finding struct {
+	Type        string `json:"type"`
+	EmployeeId  *int64 `json:"employee_id,omitempty"`
+	Login       string `json:"login,omitempty"`
+	Entitlement string `json:"entitlement,omitempty"`
+}
+
+// TaskEntity задача на приведение целевой системы к состоянию, которое требуют роли
+type TaskEntity struct {
+	Id            int64     `db:"id"`
+	ApplicationId int64     `db:"application_id"`
+	EmployeeId    *int64    `db:"employee_id"`
+	Login         string    `db:"login"`
+	Entitlement   string    `db:"entitlement"`
+	Action        string    `db:"action"`
+	State         string    `db:"state"`
+	CreatedBy     string    `db:"created_by"`
+	CreatedAt     time.Time `db:"created_at"`
+}
+
+type TaskResponse struct {
+	Id            int64     `json:"id"`
+	ApplicationId int64     `json:"application_id"`
+	EmployeeId    *int64    `json:"employee_id,omitempty"`
+	Login         string    `json:"login,omitempty"`
+	Entitlement   string    `json:"entitlement,omitempty"`
+	Action        string    `json:"action"`
+	State         string    `json:"state"`
+	CreatedBy     string    `json:"created_by"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type ReconcileRequest struct {
+	ApplicationId int64               `validate:"required,min=1"`
+	Rows          []account.ImportRow `validate:"dive"`
+	CreateTasks   bool
+	CreatedBy     string `validate:"required"`
+}
+
+type IdRequest struct {
+	Id int64 `json:"id" validate:"required,min=1"`
+}
+
+type ReconcileResponse struct {
+	ApplicationId int64     `json:"application_id"`
+	Missing       int       `json:"missing"`
+	Extra         int       `json:"extra"`
+	Mismatched    int       `json:"mismatched"`
+	Findings      []Finding `json:"findings"`
+	TaskIds       []int64   `json:"task_ids,omitempty"`
+}
+
+func (e *TaskEntity) toResponse() TaskResponse {
+	return TaskResponse{
+		Id:            e.Id,
+		ApplicationId: e.ApplicationId,
+		EmployeeId:    e.EmployeeId,
+		Login:         e.Login,
+		Entitlement:   e.Entitlement,
+		Action:        e.Action,
+		State:         e.State,
+		CreatedBy:     e.CreatedBy,
+		CreatedAt:     e.CreatedAt,
+	}
+}
+
+// toTask задача, исправляющая расхождение
+func (f *Finding) toTask(applicationId int64, createdBy string) TaskEntity {
+	var action = ActionGrant
+	switch f.Type {
+	case FindingExtra:
+		action = ActionRevoke
+	case FindingMismatched:
+		action = ActionEnable
+	}
+	return TaskEntity{
+		ApplicationId: applicationId,
+		EmployeeId:    f.EmployeeId,
+		Login:         f.Login,
+		Entitlement:   f.Entitlement,
+		Action:        action,
+		CreatedBy:     createdBy,
+	}
+}
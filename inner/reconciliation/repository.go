@@ -0,0 +1,87 @@
+package reconciliation
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{
+		db: database,
+	}
+}
+
+func (r *Repository) BeginTransaction() (*sqlx.Tx, error) {
+	return r.db.Beginx()
+}
+
+func (r *Repository) ApplicationExists(tx *sqlx.Tx, applicationId int64) (isExist bool, err error) {
+	err = tx.Get(&isExist, "SELECT EXISTS(SELECT 1 FROM application WHERE id = $1)", applicationId)
+	return isExist, err
+}
+
+// FindDesired права приложения, положенные сотрудникам по всем их назначениям ролей
+func (r *Repository) FindDesired(tx *sqlx.Tx, applicationId int64) ([]DesiredEntity, error) {
+	var desired []DesiredEntity
+	err := tx.Select(
+		&desired,
+		"SELECT DISTINCT er.employee_id, en.name AS entitlement "+
+			"FROM employee_role er "+
+			"JOIN role_entitlement re ON re.role_id = er.role_id "+
+			"JOIN entitlement en ON en.id = re.entitlement_id "+
+			"WHERE en.application_id = $1 "+
+			"ORDER BY er.employee_id, en.name",
+		applicationId,
+	)
+	return desired, err
+}
+
+// FindOwners сотрудники, на которых ссылаются строки выгрузки по id или subject
+func (r *Repository) FindOwners(tx *sqlx.Tx, ids []int64, subjects []string) ([]Owner, error) {
+	var owners []Owner
+	err := tx.Select(
+		&owners,
+		"SELECT id, subject FROM employee WHERE id = ANY($1) OR (subject <> '' AND subject = ANY($2))",
+		pq.Int64Array(ids), pq.StringArray(subjects),
+	)
+	return owners, err
+}
+
+// ExistsOpenTask есть ли незакрытая задача с тем же приложением, учётной записью, сотрудником, правом и действием
+func (r *Repository) ExistsOpenTask(tx *sqlx.Tx, task TaskEntity) (isExist bool, err error) {
+	err = tx.Get(
+		&isExist,
+		"SELECT EXISTS(SELECT 1 FROM provisioning_task "+
+			"WHERE application_id = $1 AND login = $2 AND employee_id IS NOT DISTINCT FROM $3 "+
+			"AND entitlement = $4 AND action = $5 AND state = $6)",
+		task.ApplicationId, task.Login, task.EmployeeId, task.Entitlement, task.Action, TaskOpen,
+	)
+	return isExist, err
+}
+
+func (r *Repository) SaveTask(tx *sqlx.Tx, task TaskEntity) (int64, error) {
+	var id int64
+	err := tx.QueryRow(
+		"INSERT INTO provisioning_task (application_id, employee_id, login, entitlement, action, created_by) "+
+			"VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
+		task.ApplicationId, task.EmployeeId, task.Login, task.Entitlement, task.Action, task.CreatedBy,
+	).Scan(&id)
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
+func (r *Repository) FindTasks(applicationId int64) ([]TaskEntity, error) {
+	var tasks []TaskEntity
+	err := r.db.Select(
+		&tasks,
+		"SELECT * FROM provisioning_task WHERE application_id = $1 ORDER BY id",
+		applicationId,
+	)
+	return tasks, err
+}
@@ -0,0 +1,118 @@
+package reconciliation
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"idm/inner/common"
+	"idm/inner/database"
+)
+
+type Service struct {
+	repo      Repo
+	validator Validator
+}
+
+type Repo interface {
+	BeginTransaction() (*sqlx.Tx, error)
+	ApplicationExists(tx *sqlx.Tx, applicationId int64) (bool, error)
+	FindDesired(tx *sqlx.Tx, applicationId int64) ([]DesiredEntity, error)
+	FindOwners(tx *sqlx.Tx, ids []int64, subjects []string) ([]Owner, error)
+	ExistsOpenTask(tx *sqlx.Tx, task TaskEntity) (bool, error)
+	SaveTask(tx *sqlx.Tx, task TaskEntity) (int64, error)
+	FindTasks(applicationId int64) ([]TaskEntity, error)
+}
+
+type Validator interface {
+	Validate(request any) error
+}
+
+func NewService(repo Repo, validator Validator) *Service {
+	return &Service{
+		repo:      repo,
+		validator: validator,
+	}
+}
+
+// Reconcile сверить выгрузку приложения с ролями и, если нужно, завести задачи на исправление
+// в той же транзакции, в которой прочитано желаемое состояние.
+// Для расхождения, по которому уже есть открытая задача, новая задача не заводится
+func (s *Service) Reconcile(ctx context.Context, request ReconcileRequest) (ReconcileResponse, error) {
+	if err := s.validator.Validate(request); err != nil {
+		return ReconcileResponse{}, common.RequestValidationError{Message: err.Error()}
+	}
+	var response = ReconcileResponse{ApplicationId: request.ApplicationId}
+	err := database.InTransaction(s.repo.BeginTransaction, "reconciling application", func(tx *sqlx.Tx) error {
+		isExist, err := s.repo.ApplicationExists(tx, request.ApplicationId)
+		if err != nil {
+			return fmt.Errorf("error finding application %d: %w", request.ApplicationId, err)
+		}
+		if !isExist {
+			return common.NotFoundError{Message: fmt.Sprintf("application with id %d not found", request.ApplicationId)}
+		}
+		desired, err := s.repo.FindDesired(tx, request.ApplicationId)
+		if err != nil {
+			return fmt.Errorf("error finding desired entitlements: %w", err)
+		}
+		var ids []int64
+		var subjects []string
+		for _, row := range request.Rows {
+			if row.EmployeeId > 0 {
+				ids = append(ids, row.EmployeeId)
+			} else if row.Subject != "" {
+				subjects = append(subjects, row.Subject)
+			}
+		}
+		owners, err := s.repo.FindOwners(tx, ids, subjects)
+		if err != nil {
+			return fmt.Errorf("error finding account owners: %w", err)
+		}
+		response.Findings = Compare(desired, request.Rows, owners)
+		for _, finding := range response.Findings {
+			switch finding.Type {
+			case FindingMissing:
+				response.Missing++
+			case FindingExtra:
+				response.Extra++
+			case FindingMismatched:
+				response.Mismatched++
+			}
+			if !request.CreateTasks {
+				continue
+			}
+			var task = finding.toTask(request.ApplicationId, request.CreatedBy)
+			isExist, err := s.repo.ExistsOpenTask(tx, task)
+			if err != nil {
+				return fmt.Errorf("error finding open provisioning task: %w", err)
+			}
+			if isExist {
+				continue
+			}
+			id, err := s.repo.SaveTask(tx, task)
+			if err != nil {
+				return fmt.Errorf("error saving provisioning task: %w", err)
+			}
+			response.TaskIds = append(response.TaskIds, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return ReconcileResponse{}, err
+	}
+	return response, nil
+}
+
+func (s *Service) FindTasks(request IdRequest) ([]TaskResponse, error) {
+	if err := s.validator.Validate(request); err != nil {
+		return nil, common.RequestValidationError{Message: err.Error()}
+	}
+	tasks, err := s.repo.FindTasks(request.Id)
+	if err != nil {
+		return nil, common.NotFoundError{Message: fmt.Sprintf("error finding provisioning tasks of application %d: %v", request.Id, err)}
+	}
+	var response []TaskResponse
+	for _, entity := range tasks {
+		response = append(response, entity.toResponse())
+	}
+	return response, nil
+}
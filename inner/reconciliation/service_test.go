@@ -0,0 +1,188 @@
+package reconciliation
+
+import (
+	"context"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"idm/inner/account"
+	"idm/inner/common"
+	"idm/inner/database/dbtest"
+	"idm/inner/validator"
+	"testing"
+)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+func (r *MockRepo) BeginTransaction() (*sqlx.Tx, error) {
+	args := r.Called()
+	return args.Get(0).(*sqlx.Tx), args.Error(1)
+}
+
+func (r *MockRepo) ApplicationExists(tx *sqlx.Tx, applicationId int64) (bool, error) {
+	args := r.Called(tx, applicationId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (r *MockRepo) FindDesired(tx *sqlx.Tx, applicationId int64) ([]DesiredEntity, error) {
+	args := r.Called(tx, applicationId)
+	return args.Get(0).([]DesiredEntity), args.Error(1)
+}
+
+func (r *MockRepo) FindOwners(tx *sqlx.Tx, ids []int64, subjects []string) ([]Owner, error) {
+	args := r.Called(tx, ids, subjects)
+	return args.Get(0).([]Owner), args.Error(1)
+}
+
+func (r *MockRepo) ExistsOpenTask(tx *sqlx.Tx, task TaskEntity) (bool, error) {
+	args := r.Called(tx, task)
+	return args.Bool(0), args.Error(1)
+}
+
+func (r *MockRepo) SaveTask(tx *sqlx.Tx, task TaskEntity) (int64, error) {
+	args := r.Called(tx, task)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (r *MockRepo) FindTasks(applicationId int64) ([]TaskEntity, error) {
+	args := r.Called(applicationId)
+	return args.Get(0).([]TaskEntity), args.Error(1)
+}
+
+func id(value int64) *int64 {
+	return &value
+}
+
+func TestCompare(t *testing.T) {
+	var desired = []DesiredEntity{
+		{EmployeeId: 1, Entitlement: "push"},
+		{EmployeeId: 1, Entitlement: "reviewers"},
+		{EmployeeId: 2, Entitlement: "push"},
+		{EmployeeId: 3, Entitlement: "push"},
+	}
+	var owners = []Owner{{Id: 1, Subject: "kc-1"}, {Id: 2}, {Id: 3}}
+	t.Run("should find missing, extra and mismatched access", func(t *testing.T) {
+		a := assert.New(t)
+		var rows = []account.ImportRow{
+			{Login: "jdoe", Subject: "kc-1", Status: account.StatusActive, Entitlements: []string{"push", "admins"}},
+			{Login: "asmith", EmployeeId: 2, Status: account.StatusDisabled, Entitlements: []string{"push"}},
+			{Login: "svc-backup", Status: account.StatusActive, Entitlements: []string{"push"}},
+		}
+		got := Compare(desired, rows, owners)
+		a.Equal([]Finding{
+			{Type: FindingExtra, EmployeeId: id(1), Login: "jdoe", Entitlement: "admins"},
+			{Type: FindingMismatched, EmployeeId: id(2), Login: "asmith"},
+			{Type: FindingExtra, Login: "svc-backup", Entitlement: "push"},
+			{Type: FindingMissing, EmployeeId: id(1), Login: "jdoe", Entitlement: "reviewers"},
+			{Type: FindingMissing, EmployeeId: id(3), Entitlement: "push"},
+		}, got)
+	})
+	t.Run("should treat unknown employee id as account without owner", func(t *testing.T) {
+		a := assert.New(t)
+		var rows = []account.ImportRow{
+			{Login: "ghost", EmployeeId: 99, Status: account.StatusActive, Entitlements: []string{"push"}},
+		}
+		got := Compare(nil, rows, owners)
+		a.Equal([]Finding{{Type: FindingExtra, Login: "ghost", Entitlement: "push"}}, got)
+	})
+	t.Run("should find nothing when snapshot matches roles", func(t *testing.T) {
+		a := assert.New(t)
+		var rows = []account.ImportRow{
+			{Login: "jdoe", EmployeeId: 1, Status: account.StatusActive, Entitlements: []string{"push", "reviewers"}},
+		}
+		a.Empty(Compare(desired[:2], rows, owners))
+	})
+}
+
+func TestReconcile(t *testing.T) {
+	var rows = []account.ImportRow{
+		{Login: "jdoe", EmployeeId: 1, Status: account.StatusActive, Entitlements: []string{"admins"}},
+	}
+	var desired = []DesiredEntity{{EmployeeId: 1, Entitlement: "push"}}
+	var owners = []Owner{{Id: 1}}
+	t.Run("should report findings and create tasks", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, true)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("ApplicationExists", tx, int64(3)).Return(true, nil)
+		repo.On("FindDesired", tx, int64(3)).Return(desired, nil)
+		repo.On("FindOwners", tx, []int64{1}, []string(nil)).Return(owners, nil)
+		repo.On("ExistsOpenTask", tx, mock.Anything).Return(false, nil)
+		repo.On("SaveTask", tx, TaskEntity{
+			ApplicationId: 3, EmployeeId: id(1), Login: "jdoe", Entitlement: "admins", Action: ActionRevoke, CreatedBy: "admin",
+		}).Return(int64(10), nil)
+		repo.On("SaveTask", tx, TaskEntity{
+			ApplicationId: 3, EmployeeId: id(1), Login: "jdoe", Entitlement: "push", Action: ActionGrant, CreatedBy: "admin",
+		}).Return(int64(11), nil)
+		got, err := svc.Reconcile(context.Background(), ReconcileRequest{
+			ApplicationId: 3,
+			Rows:          rows,
+			CreateTasks:   true,
+			CreatedBy:     "admin",
+		})
+		a.Nil(err)
+		a.Equal(1, got.Missing)
+		a.Equal(1, got.Extra)
+		a.Equal(0, got.Mismatched)
+		a.Equal([]int64{10, 11}, got.TaskIds)
+	})
+	t.Run("should skip findings with open tasks", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, true)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		var revoke = TaskEntity{
+			ApplicationId: 3, EmployeeId: id(1), Login: "jdoe", Entitlement: "admins", Action: ActionRevoke, CreatedBy: "admin",
+		}
+		var grant = TaskEntity{
+			ApplicationId: 3, EmployeeId: id(1), Login: "jdoe", Entitlement: "push", Action: ActionGrant, CreatedBy: "admin",
+		}
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("ApplicationExists", tx, int64(3)).Return(true, nil)
+		repo.On("FindDesired", tx, int64(3)).Return(desired, nil)
+		repo.On("FindOwners", tx, []int64{1}, []string(nil)).Return(owners, nil)
+		repo.On("ExistsOpenTask", tx, revoke).Return(true, nil)
+		repo.On("ExistsOpenTask", tx, grant).Return(false, nil)
+		repo.On("SaveTask", tx, grant).Return(int64(12), nil)
+		got, err := svc.Reconcile(context.Background(), ReconcileRequest{
+			ApplicationId: 3,
+			Rows:          rows,
+			CreateTasks:   true,
+			CreatedBy:     "admin",
+		})
+		a.Nil(err)
+		a.Equal(1, got.Extra)
+		a.Equal([]int64{12}, got.TaskIds)
+		a.True(repo.AssertNumberOfCalls(t, "SaveTask", 1))
+	})
+	t.Run("should only report without tasks", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, true)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("ApplicationExists", tx, int64(3)).Return(true, nil)
+		repo.On("FindDesired", tx, int64(3)).Return(desired, nil)
+		repo.On("FindOwners", tx, []int64{1}, []string(nil)).Return(owners, nil)
+		got, err := svc.Reconcile(context.Background(), ReconcileRequest{ApplicationId: 3, Rows: rows, CreatedBy: "admin"})
+		a.Nil(err)
+		a.Len(got.Findings, 2)
+		a.Empty(got.TaskIds)
+		a.True(repo.AssertNumberOfCalls(t, "SaveTask", 0))
+	})
+	t.Run("should return not found error for unknown application", func(t *testing.T) {
+		a := assert.New(t)
+		var tx = dbtest.NewTx(t, false)
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		repo.On("BeginTransaction").Return(tx, nil)
+		repo.On("ApplicationExists", tx, int64(3)).Return(false, nil)
+		_, err := svc.Reconcile(context.Background(), ReconcileRequest{ApplicationId: 3, Rows: rows, CreatedBy: "admin"})
+		a.True(errors.As(err, &common.NotFoundError{}))
+	})
+}
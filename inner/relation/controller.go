@@ -0,0 +1,205 @@
+package relation
+
+import (
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"idm/inner/common"
+	"idm/inner/middleware"
+	"idm/inner/web"
+	"slices"
+	"strconv"
+)
+
+type Controller struct {
+	server          *web.Server
+	relationService Svc
+}
+
+type Svc interface {
+	Create(ctx context.Context, request CreateRequest) (Response, error)
+	FindAll(request FindRequest) ([]Response, error)
+	DeleteById(request IdRequest) error
+	Check(ctx context.Context, subject string, relation string, object string) (bool, error)
+}
+
+func NewController(
+	server *web.Server,
+	relationService Svc,
+) *Controller {
+	return &Controller{
+		server:          server,
+		relationService: relationService,
+	}
+}
+
+func (c *Controller) RegisterRoutes() {
+	c.server.GroupApiV1.Post("/relations", c.CreateRelation)
+	c.server.GroupApiV1.Get("/relations/check", c.Check)
+	c.server.GroupApiV1.Get("/relations", c.FindAll)
+	c.server.GroupApiV1.Delete("/relations/:id", c.DeleteById)
+}
+
+// Функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/relations"
+// @Summary create a relation tuple
+// @Description Create a relation (subject, relation, object), e.g. (user:alice, owner, role:42), with roles: admin
+// @Tags relation
+// @Security OAuth2Password
+// @Accept json
+// @Produce json
+// @Param request body relation.CreateRequest true "create relation request"
+// @Success 200 {object} common.Response[int64]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /relations [post]
+func (c *Controller) CreateRelation(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	var request CreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		logger.ErrorCtx(ctx.Context(), "body parse error: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	request.CreatedBy = claims.Subject
+	logger.InfoCtx(ctx.Context(), "create relation: received request", zap.Any("request", request))
+	var response, err = c.relationService.Create(ctx.Context(), request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}) || errors.As(err, &common.AlreadyExistsError{}):
+			logger.ErrorCtx(ctx.Context(), "error creating relation: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "error creating relation: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response.Id)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/relations"
+// @Summary Get relation tuples
+// @Description returns relations filtered by subject and/or object with roles: admin
+// @Tags relation
+// @Security OAuth2Password
+// @Produce json
+// @Param subject query string false "Subject, e.g. user:alice"
+// @Param object  query string false "Object, e.g. role:42"
+// @Success 200 {object} common.Response[[]relation.Response]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Router /relations [get]
+func (c *Controller) FindAll(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	var request = FindRequest{Subject: ctx.Query("subject"), Object: ctx.Query("object")}
+	logger.InfoCtx(ctx.Context(), "find relations: received request", zap.Any("request", request))
+	response, err := c.relationService.FindAll(request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			logger.ErrorCtx(ctx.Context(), "find relations: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.As(err, &common.NotFoundError{}):
+			logger.ErrorCtx(ctx.Context(), "find relations: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "find relations: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response)
+}
+
+// Функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/relations/check"
+// @Summary Check a relation
+// @Description resolves the relation transitively; admins may check any subject, other users only themselves
+// @Tags relation
+// @Security OAuth2Password
+// @Produce json
+// @Param subject  query string true "Subject, e.g. user:alice"
+// @Param relation query string true "Relation or permission, e.g. manage"
+// @Param object   query string true "Object, e.g. role:42"
+// @Success 200 {object} common.Response[relation.CheckResponse]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /relations/check [get]
+func (c *Controller) Check(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	var subject = ctx.Query("subject")
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) && subject != User(claims.Subject) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	var relation, object = ctx.Query("relation"), ctx.Query("object")
+	allowed, err := c.relationService.Check(ctx.Context(), subject, relation, object)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			logger.ErrorCtx(ctx.Context(), "check relation: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "check relation: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	logger.InfoCtx(ctx.Context(), "check relation",
+		zap.String("subject", subject),
+		zap.String("relation", relation),
+		zap.String("object", object),
+		zap.Bool("allowed", allowed),
+	)
+	return common.OkResponse(ctx, CheckResponse{Allowed: allowed})
+}
+
+// Функция-хендлер, которая будет вызываться при DELETE запросе по маршруту "/api/v1/relations/:id"
+// @Summary Delete relation by ID
+// @Description Deletes a relation tuple with roles: admin
+// @Tags relation
+// @Security OAuth2Password
+// @Produce json
+// @Param id path int true "Relation ID"
+// @Success 200 {object} common.Response[int64]
+// @Failure 400 {object} common.Response[string]
+// @Failure 403 {object} common.Response[string]
+// @Failure 500 {object} common.Response[string]
+// @Router /relations/{id} [delete]
+func (c *Controller) DeleteById(ctx *fiber.Ctx) error {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	logger := middleware.GetLogger(ctx)
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		logger.ErrorCtx(ctx.Context(), "error parsing id: ", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request = IdRequest{Id: id}
+	logger.InfoCtx(ctx.Context(), "delete relation: received request", zap.Any("request", request))
+	err = c.relationService.DeleteById(request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			logger.ErrorCtx(ctx.Context(), "delete relation: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		default:
+			logger.ErrorCtx(ctx.Context(), "delete relation: ", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, id)
+}
@@ -0,0 +1,131 @@
+package relation
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"idm/inner/common"
+	"idm/inner/web"
+	"idm/inner/web/webtest"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) Create(ctx context.Context, request CreateRequest) (Response, error) {
+	args := svc.Called(ctx, request)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) FindAll(request FindRequest) ([]Response, error) {
+	args := svc.Called(request)
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (svc *MockService) DeleteById(request IdRequest) error {
+	args := svc.Called(request)
+	return args.Error(0)
+}
+
+func (svc *MockService) Check(ctx context.Context, subject string, relation string, object string) (bool, error) {
+	args := svc.Called(ctx, subject, relation, object)
+	return args.Bool(0), args.Error(1)
+}
+
+func newServer(svc Svc, subject string, roles ...string) *web.Server {
+	server := webtest.NewServer(subject, roles...)
+	NewController(server, svc).RegisterRoutes()
+	return server
+}
+
+func TestCreateRelation(t *testing.T) {
+	var a = assert.New(t)
+	t.Run("create relation", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "admin-1", web.IdmAdmin)
+		var body = strings.NewReader("{\"subject\": \"user:alice\", \"relation\": \"owner\", \"object\": \"role:42\"}")
+		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/relations", body)
+		request.Header.Add("Content-Type", "application/json")
+		svc.On("Create", mock.Anything, CreateRequest{
+			Subject:   "user:alice",
+			Relation:  RelationOwner,
+			Object:    "role:42",
+			CreatedBy: "admin-1",
+		}).Return(Response{Id: 1}, nil)
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+	})
+	t.Run("create relation without role admin", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "user-1", web.IdmUser)
+		var body = strings.NewReader("{\"subject\": \"user:user-1\", \"relation\": \"owner\", \"object\": \"role:42\"}")
+		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/relations", body)
+		request.Header.Add("Content-Type", "application/json")
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		a.True(svc.AssertNumberOfCalls(t, "Create", 0))
+	})
+}
+
+func TestCheckRelation(t *testing.T) {
+	var a = assert.New(t)
+	t.Run("user checks own relation", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "alice")
+		svc.On("Check", mock.Anything, "user:alice", PermissionManage, "role:42").Return(true, nil)
+		var request = httptest.NewRequest(fiber.MethodGet,
+			"/api/v1/relations/check?subject=user:alice&relation=manage&object=role:42", nil)
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		bytesData, err := io.ReadAll(resp.Body)
+		a.Nil(err)
+		var responseBody common.Response[CheckResponse]
+		a.Nil(json.Unmarshal(bytesData, &responseBody))
+		a.True(responseBody.Data.Allowed)
+	})
+	t.Run("user can not check other subjects", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "alice")
+		var request = httptest.NewRequest(fiber.MethodGet,
+			"/api/v1/relations/check?subject=user:bob&relation=manage&object=role:42", nil)
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		a.True(svc.AssertNumberOfCalls(t, "Check", 0))
+	})
+	t.Run("unknown relation", func(t *testing.T) {
+		var svc = new(MockService)
+		server := newServer(svc, "admin-1", web.IdmAdmin)
+		svc.On("Check", mock.Anything, "user:bob", "fly", "role:42").
+			Return(false, common.RequestValidationError{Message: "unknown relation fly"})
+		var request = httptest.NewRequest(fiber.MethodGet,
+			"/api/v1/relations/check?subject=user:bob&relation=fly&object=role:42", nil)
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestDeleteRelation(t *testing.T) {
+	var a = assert.New(t)
+	var svc = new(MockService)
+	server := newServer(svc, "admin-1", web.IdmAdmin)
+	svc.On("DeleteById", IdRequest{Id: 4}).Return(nil)
+	resp, err := server.App.Test(httptest.NewRequest(fiber.MethodDelete, "/api/v1/relations/4", nil))
+	a.Nil(err)
+	a.Equal(http.StatusOK, resp.StatusCode)
+	resp, err = server.App.Test(httptest.NewRequest(fiber.MethodDelete, "/api/v1/relations/abc", nil))
+	a.Nil(err)
+	a.Equal(http.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,134 @@
+package relation
+
+import (
+	"slices"
+	"strconv"
+	"time"
+)
+
+// хранимые отношения между субъектом и объектом
+const (
+	RelationOwner   = "owner"
+	RelationMember  = "member"
+	RelationManager = "manager"
+)
+
+// вычисляемые права, которые следуют из хранимых отношений
+const (
+	PermissionManage = "manage"
+	PermissionView   = "view"
+)
+
+// maxDepth максимальная длина цепочки при транзитивном разрешении отношений
+const maxDepth = 10
+
+// definition описывает, какими отношениями подразумевается отношение и транзитивно ли оно
+type definition struct {
+	impliedBy  []string
+	transitive bool
+}
+
+// schema модель отношений: владелец роли управляет ей, управляющий и участник видят объект,
+// руководитель руководителя тоже руководитель
+var schema = map[string]definition{
+	RelationOwner:    {},
+	RelationMember:   {},
+	RelationManager:  {transitive: true},
+	PermissionManage: {impliedBy: []string{RelationOwner}},
+	PermissionView:   {impliedBy: []string{PermissionManage, RelationMember, RelationManager}},
+}
+
+// expand вернуть все отношения, из которых следует relation, и транзитивные среди них
+func expand(relation string) (relations []string, transitive []string) {
+	var queue = []string{relation}
+	for len(queue) > 0 {
+		var current = queue[0]
+		queue = queue[1:]
+		if slices.Contains(relations, current) {
+			continue
+		}
+		relations = append(relations, current)
+		if schema[current].transitive {
+			transitive = append(transitive, current)
+		}
+		queue = append(queue, schema[current].impliedBy...)
+	}
+	return relations, transitive
+}
+
+// User субъект для пользователя из токена
+func User(subject string) string {
+	return "user:" + subject
+}
+
+func Role(id int64) string {
+	return "role:" + strconv.FormatInt(id, 10)
+}
+
+func Employee(id int64) string {
+	return "employee:" + strconv.FormatInt(id, 10)
+}
+
+type Entity struct {
+	Id        int64     `db:"id"`
+	Subject   string    `db:"subject"`
+	Relation  string    `db:"relation"`
+	Object    string    `db:"object"`
+	CreatedBy string    `db:"created_by"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type Response struct {
+	Id        int64     `json:"id"`
+	Subject   string    `json:"subject"`
+	Relation  string    `json:"relation"`
+	Object    string    `json:"object"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateRequest struct {
+	Subject   string `json:"subject" validate:"required,max=255"`
+	Relation  string `json:"relation" validate:"required,oneof=owner member manager"`
+	Object    string `json:"object" validate:"required,max=255"`
+	CreatedBy string `json:"-" validate:"required"`
+}
+
+type CheckRequest struct {
+	Subject  string `validate:"required,max=255"`
+	Relation string `validate:"required"`
+	Object   string `validate:"required,max=255"`
+}
+
+type CheckResponse struct {
+	Allowed bool `json:"allowed"`
+}
+
+type FindRequest struct {
+	Subject string `validate:"max=255"`
+	Object  string `validate:"max=255"`
+}
+
+type IdRequest struct {
+	Id int64 `json:"id" validate:"required,min=1"`
+}
+
+func (e *Entity) toResponse() Response {
+	return Response{
+		Id:        e.Id,
+		Subject:   e.Subject,
+		Relation:  e.Relation,
+		Object:    e.Object,
+		CreatedBy: e.CreatedBy,
+		CreatedAt: e.CreatedAt,
+	}
+}
+
+func (req *CreateRequest) ToEntity() Entity {
+	return Entity{
+		Subject:   req.Subject,
+		Relation:  req.Relation,
+		Object:    req.Object,
+		CreatedBy: req.CreatedBy,
+	}
+}
@@ -0,0 +1,91 @@
+package relation
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewRepository(database *sqlx.DB) *Repository {
+	return &Repository{
+		db: database,
+	}
+}
+
+func (r *Repository) Save(e Entity) (int64, error) {
+	var id int64
+	err := r.db.QueryRow(
+		"INSERT INTO relation_tuple (subject, relation, object, created_by) VALUES ($1, $2, $3, $4) RETURNING id",
+		e.Subject, e.Relation, e.Object, e.CreatedBy).Scan(&id)
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
+func (r *Repository) Exists(subject string, relation string, object string) (isExist bool, err error) {
+	err = r.db.Get(
+		&isExist,
+		"SELECT EXISTS(SELECT 1 FROM relation_tuple WHERE subject = $1 AND relation = $2 AND object = $3)",
+		subject, relation, object,
+	)
+	return isExist, err
+}
+
+// FindAll найти отношения; пустые subject и object не фильтруют выборку
+func (r *Repository) FindAll(subject string, object string) ([]Entity, error) {
+	var tuples []Entity
+	err := r.db.Select(
+		&tuples,
+		"SELECT * FROM relation_tuple WHERE ($1 = '' OR subject = $1) AND ($2 = '' OR object = $2) ORDER BY id",
+		subject, object,
+	)
+	return tuples, err
+}
+
+func (r *Repository) DeleteById(id int64) error {
+	_, err := r.db.Exec("DELETE FROM relation_tuple WHERE id = $1", id)
+	return err
+}
+
+// Check проверить, связан ли субъект с объектом одним из relations.
+// Путь строится от объекта к субъекту: на каждом шаге продолжаются только цепочки
+// транзитивных отношений (руководитель руководителя). Владелец роли из колонки role.owner
+// учитывается как отношение owner, чтобы не дублировать его в relation_tuple.
+// Люди в отношениях - это employee:N: user:sub привязанного сотрудника заменяется на employee:N
+// и в кортежах, и в аргументах, поэтому цепочки руководителей не рвутся на смене именования
+func (r *Repository) Check(
+	subject string,
+	relations []string,
+	transitive []string,
+	object string,
+	depth int,
+) (isExist bool, err error) {
+	err = r.db.Get(&isExist, `
+		WITH RECURSIVE person AS (
+			SELECT 'user:' || subject AS alias, 'employee:' || id AS node FROM employee WHERE subject <> ''
+		), stored AS (
+			SELECT subject, relation, object FROM relation_tuple
+			UNION ALL
+			SELECT 'user:' || owner, 'owner', 'role:' || id FROM role WHERE owner <> ''
+		), tuple AS (
+			SELECT coalesce(s.node, t.subject) AS subject, t.relation, coalesce(o.node, t.object) AS object
+			FROM stored t
+			LEFT JOIN person s ON s.alias = t.subject
+			LEFT JOIN person o ON o.alias = t.object
+		), path (subject, relation, depth) AS (
+			SELECT subject, relation, 1 FROM tuple
+			WHERE object = coalesce((SELECT node FROM person WHERE alias = $1), $1) AND relation = ANY($2)
+			UNION
+			SELECT t.subject, t.relation, p.depth + 1
+			FROM tuple t JOIN path p ON t.object = p.subject AND t.relation = p.relation
+			WHERE p.relation = ANY($3) AND p.depth < $4
+		)
+		SELECT EXISTS(SELECT 1 FROM path WHERE subject = coalesce((SELECT node FROM person WHERE alias = $5), $5))`,
+		object, pq.Array(relations), pq.Array(transitive), depth, subject,
+	)
+	return isExist, err
+}
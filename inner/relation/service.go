@@ -0,0 +1,97 @@
+package relation
+
+import (
+	"context"
+	"fmt"
+	"idm/inner/common"
+)
+
+type Service struct {
+	repo      Repo
+	validator Validator
+}
+
+type Repo interface {
+	Save(e Entity) (int64, error)
+	Exists(subject string, relation string, object string) (bool, error)
+	FindAll(subject string, object string) ([]Entity, error)
+	DeleteById(id int64) error
+	Check(subject string, relations []string, transitive []string, object string, depth int) (bool, error)
+}
+
+type Validator interface {
+	Validate(request any) error
+}
+
+func NewService(
+	repo Repo,
+	validator Validator,
+) *Service {
+	return &Service{
+		repo:      repo,
+		validator: validator,
+	}
+}
+
+func (s *Service) Create(ctx context.Context, request CreateRequest) (Response, error) {
+	err := s.validator.Validate(request)
+	if err != nil {
+		return Response{}, common.RequestValidationError{Message: err.Error()}
+	}
+	isExist, err := s.repo.Exists(request.Subject, request.Relation, request.Object)
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding relation: %w", err)
+	}
+	if isExist {
+		return Response{}, common.AlreadyExistsError{Message: fmt.Sprintf(
+			"relation already exists: %s %s %s", request.Subject, request.Relation, request.Object)}
+	}
+	id, err := s.repo.Save(request.ToEntity())
+	if err != nil {
+		return Response{}, fmt.Errorf("error saving relation: %w", err)
+	}
+	return Response{Id: id}, nil
+}
+
+func (s *Service) FindAll(request FindRequest) ([]Response, error) {
+	if err := s.validator.Validate(request); err != nil {
+		return nil, common.RequestValidationError{Message: err.Error()}
+	}
+	tuples, err := s.repo.FindAll(request.Subject, request.Object)
+	if err != nil {
+		return nil, common.NotFoundError{Message: fmt.Sprintf("error finding relations: %v", err)}
+	}
+	var response []Response
+	for _, entity := range tuples {
+		response = append(response, entity.toResponse())
+	}
+	return response, nil
+}
+
+func (s *Service) DeleteById(request IdRequest) error {
+	if err := s.validator.Validate(request); err != nil {
+		return common.RequestValidationError{Message: err.Error()}
+	}
+	if err := s.repo.DeleteById(request.Id); err != nil {
+		return fmt.Errorf("error deleting relation with id %d: %w", request.Id, err)
+	}
+	return nil
+}
+
+// Check проверить отношение субъекта к объекту с учётом подразумеваемых и транзитивных отношений,
+// например Check(ctx, relation.User(sub), relation.PermissionManage, relation.Role(42))
+func (s *Service) Check(ctx context.Context, subject string, relation string, object string) (bool, error) {
+	var request = CheckRequest{Subject: subject, Relation: relation, Object: object}
+	if err := s.validator.Validate(request); err != nil {
+		return false, common.RequestValidationError{Message: err.Error()}
+	}
+	if _, ok := schema[relation]; !ok {
+		return false, common.RequestValidationError{Message: fmt.Sprintf("unknown relation %s", relation)}
+	}
+	var relations, transitive = expand(relation)
+	allowed, err := s.repo.Check(subject, relations, transitive, object, maxDepth)
+	if err != nil {
+		return false, fmt.Errorf("error checking relation %s %s %s: %w", subject, relation, object, err)
+	}
+	return allowed, nil
+}
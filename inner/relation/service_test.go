@@ -0,0 +1,127 @@
+package relation
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"idm/inner/common"
+	"idm/inner/validator"
+	"testing"
+)
+
+type MockRepo struct {
+	mock.Mock
+}
+
+func (m *MockRepo) Save(e Entity) (int64, error) {
+	args := m.Called(e)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepo) Exists(subject string, relation string, object string) (bool, error) {
+	args := m.Called(subject, relation, object)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) FindAll(subject string, object string) ([]Entity, error) {
+	args := m.Called(subject, object)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) DeleteById(id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockRepo) Check(
+	subject string,
+	relations []string,
+	transitive []string,
+	object string,
+	depth int,
+) (bool, error) {
+	args := m.Called(subject, relations, transitive, object, depth)
+	return args.Bool(0), args.Error(1)
+}
+
+func TestExpand(t *testing.T) {
+	var a = assert.New(t)
+	relations, transitive := expand(PermissionManage)
+	a.Equal([]string{PermissionManage, RelationOwner}, relations)
+	a.Empty(transitive)
+	relations, transitive = expand(PermissionView)
+	a.Equal([]string{PermissionView, PermissionManage, RelationMember, RelationManager, RelationOwner}, relations)
+	a.Equal([]string{RelationManager}, transitive)
+}
+
+func TestCreate(t *testing.T) {
+	var a = assert.New(t)
+	var ctx = context.Background()
+	var request = CreateRequest{Subject: "user:alice", Relation: RelationOwner, Object: "role:42", CreatedBy: "admin"}
+	t.Run("create relation", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		repo.On("Exists", "user:alice", RelationOwner, "role:42").Return(false, nil)
+		repo.On("Save", request.ToEntity()).Return(int64(3), nil)
+		got, err := svc.Create(ctx, request)
+		a.Nil(err)
+		a.Equal(Response{Id: 3}, got)
+	})
+	t.Run("relation already exists", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		repo.On("Exists", "user:alice", RelationOwner, "role:42").Return(true, nil)
+		_, err := svc.Create(ctx, request)
+		a.True(errors.As(err, &common.AlreadyExistsError{}))
+		a.True(repo.AssertNumberOfCalls(t, "Save", 0))
+	})
+	t.Run("computed permissions can not be stored", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		_, err := svc.Create(ctx, CreateRequest{Subject: "user:alice", Relation: PermissionManage, Object: "role:42", CreatedBy: "admin"})
+		a.True(errors.As(err, &common.RequestValidationError{}))
+		a.True(repo.AssertNumberOfCalls(t, "Exists", 0))
+	})
+}
+
+func TestCheck(t *testing.T) {
+	var a = assert.New(t)
+	var ctx = context.Background()
+	t.Run("check expands relation", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		repo.On("Check", "user:alice",
+			[]string{PermissionView, PermissionManage, RelationMember, RelationManager, RelationOwner},
+			[]string{RelationManager}, "employee:7", maxDepth).Return(true, nil)
+		got, err := svc.Check(ctx, User("alice"), PermissionView, Employee(7))
+		a.Nil(err)
+		a.True(got)
+	})
+	t.Run("unknown relation", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		_, err := svc.Check(ctx, User("alice"), "delete", Role(42))
+		a.True(errors.As(err, &common.RequestValidationError{}))
+		a.True(repo.AssertNumberOfCalls(t, "Check", 0))
+	})
+	t.Run("repository error", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		repo.On("Check", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(false, errors.New("database error"))
+		got, err := svc.Check(ctx, User("alice"), PermissionManage, Role(42))
+		a.NotNil(err)
+		a.False(got)
+	})
+}
+
+func TestDeleteById(t *testing.T) {
+	var a = assert.New(t)
+	var repo = new(MockRepo)
+	var svc = NewService(repo, validator.New())
+	repo.On("DeleteById", int64(5)).Return(nil)
+	a.Nil(svc.DeleteById(IdRequest{Id: 5}))
+	err := svc.DeleteById(IdRequest{})
+	a.True(errors.As(err, &common.RequestValidationError{}))
+}
@@ -1,10 +1,13 @@
 package role
 
 import (
+	"context"
 	"errors"
 	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
 	"go.uber.org/zap"
 	"idm/inner/common"
+	"idm/inner/delegation"
 	"idm/inner/web"
 	"strconv"
 	"strings"
@@ -14,13 +17,21 @@ type Controller struct {
 	server      *web.Server
 	roleService Svc
 	logger      *common.Logger
+	delegations Delegations
+}
+
+type Delegations interface {
+	Scope(ctx context.Context, subject string, roles []string) (delegation.Scope, error)
 }
 
 type Svc interface {
 	Save(request CreateRequest) (Response, error)
 	FindById(request IdRequest) (Response, error)
+	UpdateSensitivity(request SensitivityRequest) error
+	AddConflict(request ConflictRequest) error
 	FindAll() ([]Response, error)
 	FindByIds(request IdsRequest) ([]Response, error)
+	FindWithOffset(request PageRequest) (PageResponse, error)
 	DeleteById(request IdRequest) error
 	DeleteByIds(request IdsRequest) error
 }
@@ -29,21 +40,26 @@ func NewController(
 	server *web.Server,
 	roleService Svc,
 	logger *common.Logger,
+	delegations Delegations,
 ) *Controller {
 	return &Controller{
 		server:      server,
 		roleService: roleService,
 		logger:      logger,
+		delegations: delegations,
 	}
 }
 
 func (c *Controller) RegisterRoutes() {
 	c.server.GroupApiV1.Post("/roles", c.CreateRole)
 	c.server.GroupApiV1.Get("/roles/find", c.FindByIds)
+	c.server.GroupApiV1.Get("/roles/page", c.FindWithOffset)
 	c.server.GroupApiV1.Get("/roles/:id", c.FindById)
 	c.server.GroupApiV1.Get("/roles", c.FindAll)
 	c.server.GroupApiV1.Delete("/roles/delete", c.DeleteByIds)
 	c.server.GroupApiV1.Delete("/roles/:id", c.DeleteById)
+	c.server.GroupApiV1.Put("/roles/:id/sensitivity", c.UpdateSensitivity)
+	c.server.GroupApiV1.Post("/roles/:id/conflicts", c.AddConflict)
 }
 
 func (c *Controller) CreateRole(ctx *fiber.Ctx) error {
@@ -53,7 +69,15 @@ func (c *Controller) CreateRole(ctx *fiber.Ctx) error {
 		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
 	}
 	c.logger.Info("create role: received request", zap.Any("request", request))
-	var response, err = c.roleService.Save(request)
+	allowed, err := c.manages(ctx)
+	if err != nil {
+		c.logger.Error("create role: checking delegated scope", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+	if !allowed {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	response, err := c.roleService.Save(request)
 	if err != nil {
 		switch {
 		case errors.As(err, &common.RequestValidationError{}):
@@ -67,6 +91,82 @@ func (c *Controller) CreateRole(ctx *fiber.Ctx) error {
 	return common.OkResponse(ctx, response.Id)
 }
 
+func (c *Controller) UpdateSensitivity(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.Error("update role sensitivity", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request SensitivityRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("update role sensitivity", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	request.Id = id
+	c.logger.Info("update role sensitivity: received request", zap.Any("request", request))
+	allowed, err := c.manages(ctx)
+	if err != nil {
+		c.logger.Error("update role sensitivity: checking delegated scope", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+	if !allowed {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	err = c.roleService.UpdateSensitivity(request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			c.logger.Error("update role sensitivity", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.As(err, &common.NotFoundError{}):
+			c.logger.Error("update role sensitivity", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			c.logger.Error("update role sensitivity", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, id)
+}
+
+func (c *Controller) AddConflict(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.Error("add role conflict", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	var request ConflictRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("add role conflict", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	request.RoleId = id
+	c.logger.Info("add role conflict: received request", zap.Any("request", request))
+	allowed, err := c.manages(ctx)
+	if err != nil {
+		c.logger.Error("add role conflict: checking delegated scope", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+	if !allowed {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	err = c.roleService.AddConflict(request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			c.logger.Error("add role conflict", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.As(err, &common.NotFoundError{}):
+			c.logger.Error("add role conflict", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			c.logger.Error("add role conflict", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, id)
+}
+
 func (c *Controller) FindById(ctx *fiber.Ctx) error {
 	var param = ctx.Params("id")
 	id, err := strconv.Atoi(param)
@@ -133,6 +233,42 @@ func (c *Controller) FindByIds(ctx *fiber.Ctx) error {
 	return common.OkResponse(ctx, response)
 }
 
+func (c *Controller) FindWithOffset(ctx *fiber.Ctx) error {
+	pageSize, err := strconv.Atoi(ctx.Query("pageSize", "100"))
+	if err != nil {
+		c.logger.Error("find roles with offset", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	pageNumber, err := strconv.Atoi(ctx.Query("pageNumber", "0"))
+	if err != nil {
+		c.logger.Error("find roles with offset", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	request := PageRequest{
+		PageSize:   pageSize,
+		PageNumber: pageNumber,
+		TextFilter: ctx.Query("textFilter", ""),
+		SortBy:     ctx.Query("sortBy", ""),
+		SortOrder:  ctx.Query("sortOrder", ""),
+	}
+	c.logger.Info("find roles with offset: received request", zap.Any("request", request))
+	response, err := c.roleService.FindWithOffset(request)
+	if err != nil {
+		switch {
+		case errors.As(err, &common.RequestValidationError{}):
+			c.logger.Error("find roles with offset", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.As(err, &common.NotFoundError{}):
+			c.logger.Error("find roles with offset", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusOK, err.Error())
+		default:
+			c.logger.Error("find roles with offset", zap.Error(err))
+			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+	return common.OkResponse(ctx, response)
+}
+
 func (c *Controller) DeleteById(ctx *fiber.Ctx) error {
 	var param = ctx.Params("id")
 	id, err := strconv.Atoi(param)
@@ -142,6 +278,14 @@ func (c *Controller) DeleteById(ctx *fiber.Ctx) error {
 	}
 	request := IdRequest{Id: int64(id)}
 	c.logger.Info("delete role by id: received request", zap.Any("request", request))
+	allowed, err := c.manages(ctx)
+	if err != nil {
+		c.logger.Error("delete role by id: checking delegated scope", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+	if !allowed {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
 	err = c.roleService.DeleteById(request)
 	if err != nil {
 		switch {
@@ -173,7 +317,15 @@ func (c *Controller) DeleteByIds(ctx *fiber.Ctx) error {
 	}
 	var request = IdsRequest{Ids: ids}
 	c.logger.Info("delete roles by ids: received request", zap.Any("request", request))
-	err := c.roleService.DeleteByIds(request)
+	allowed, err := c.manages(ctx)
+	if err != nil {
+		c.logger.Error("delete roles by ids: checking delegated scope", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+	if !allowed {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+	err = c.roleService.DeleteByIds(request)
 	if err != nil {
 		switch {
 		case errors.As(err, &common.RequestValidationError{}):
@@ -193,3 +345,15 @@ func (c *Controller) DeleteByIds(ctx *fiber.Ctx) error {
 	}
 	return common.OkResponse(ctx, responses)
 }
+
+// manages проверить полномочия пользователя на изменение определений ролей: создавать, менять и удалять роли
+// может только IDM_ADMIN. Делегирование по набору ролей даёт право только на назначения этих ролей сотрудникам
+func (c *Controller) manages(ctx *fiber.Ctx) (bool, error) {
+	var token = ctx.Locals(web.JwtKey).(*jwt.Token)
+	claims := token.Claims.(*web.IdmClaims)
+	scope, err := c.delegations.Scope(ctx.Context(), claims.Subject, claims.RealmAccess.Roles)
+	if err != nil {
+		return false, err
+	}
+	return scope.Admin, nil
+}
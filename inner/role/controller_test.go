@@ -1,16 +1,20 @@
 package role
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/gofiber/fiber/v3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"go.uber.org/zap"
 	"idm/inner/common"
+	"idm/inner/delegation"
 	"idm/inner/web"
+	"idm/inner/web/webtest"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -39,6 +43,21 @@ func (svc *MockService) FindByIds(request IdsRequest) ([]Response, error) {
 	return args.Get(0).([]Response), args.Error(1)
 }
 
+func (svc *MockService) FindWithOffset(request PageRequest) (PageResponse, error) {
+	args := svc.Called(request)
+	return args.Get(0).(PageResponse), args.Error(1)
+}
+
+func (svc *MockService) UpdateSensitivity(request SensitivityRequest) error {
+	args := svc.Called(request)
+	return args.Error(0)
+}
+
+func (svc *MockService) AddConflict(request ConflictRequest) error {
+	args := svc.Called(request)
+	return args.Error(0)
+}
+
 func (svc *MockService) DeleteById(request IdRequest) error {
 	args := svc.Called(request)
 	return args.Error(0)
@@ -51,12 +70,24 @@ func (svc *MockService) DeleteByIds(request IdsRequest) error {
 
 var logger = &common.Logger{Logger: zap.NewNop()}
 
+// MockDelegations полномочия делегированных администраторов по subject
+type MockDelegations map[string]delegation.Scope
+
+func (d MockDelegations) Scope(ctx context.Context, subject string, roles []string) (delegation.Scope, error) {
+	if slices.Contains(roles, web.IdmAdmin) {
+		return delegation.Scope{Admin: true}, nil
+	}
+	return d[subject], nil
+}
+
+var delegations = MockDelegations{"local-admin": {RoleIds: []int64{5, 6}}}
+
 func TestCreateRole(t *testing.T) {
 	var a = assert.New(t)
 	t.Run("create role without error", func(t *testing.T) {
-		server := web.NewServer()
+		server := webtest.NewServer("admin-1", web.IdmAdmin)
 		var svc = new(MockService)
-		var controller = NewController(server, svc, logger)
+		var controller = NewController(server, svc, logger, delegations)
 		controller.RegisterRoutes()
 		var body = strings.NewReader("{\"name\": \"john doe\"}")
 		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/roles", body)
@@ -76,9 +107,9 @@ func TestCreateRole(t *testing.T) {
 		a.Empty(responseBody.Message)
 	})
 	t.Run("create role validation error - name required", func(t *testing.T) {
-		server := web.NewServer()
+		server := webtest.NewServer("admin-1", web.IdmAdmin)
 		var svc = new(MockService)
-		var controller = NewController(server, svc, logger)
+		var controller = NewController(server, svc, logger, delegations)
 		controller.RegisterRoutes()
 		var body = strings.NewReader("{\"name\": \"\"}")
 		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/roles", body)
@@ -103,9 +134,9 @@ func TestCreateRole(t *testing.T) {
 func TestFindRoleById(t *testing.T) {
 	var a = assert.New(t)
 	t.Run("find role by id", func(t *testing.T) {
-		server := web.NewServer()
+		server := webtest.NewServer("admin-1", web.IdmAdmin)
 		var svc = new(MockService)
-		var controller = NewController(server, svc, logger)
+		var controller = NewController(server, svc, logger, delegations)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodGet, "/api/v1/roles/123", nil)
 		request.Header.Add("Content-Type", "application/json")
@@ -122,9 +153,9 @@ func TestFindRoleById(t *testing.T) {
 		a.Equal(int64(123), responseBody.Data.Id)
 	})
 	t.Run("find role - incorrect id", func(t *testing.T) {
-		server := web.NewServer()
+		server := webtest.NewServer("admin-1", web.IdmAdmin)
 		var svc = new(MockService)
-		var controller = NewController(server, svc, logger)
+		var controller = NewController(server, svc, logger, delegations)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodGet, "/api/v1/roles/ffff", nil)
 		request.Header.Add("Content-Type", "application/json")
@@ -134,9 +165,9 @@ func TestFindRoleById(t *testing.T) {
 		a.Equal(http.StatusBadRequest, resp.StatusCode)
 	})
 	t.Run("find role - validation error", func(t *testing.T) {
-		server := web.NewServer()
+		server := webtest.NewServer("admin-1", web.IdmAdmin)
 		var svc = new(MockService)
-		var controller = NewController(server, svc, logger)
+		var controller = NewController(server, svc, logger, delegations)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodGet, "/api/v1/roles/0", nil)
 		request.Header.Add("Content-Type", "application/json")
@@ -156,9 +187,9 @@ func TestFindRoleById(t *testing.T) {
 		a.Equal(message, responseBody.Message)
 	})
 	t.Run("find role - not found error", func(t *testing.T) {
-		server := web.NewServer()
+		server := webtest.NewServer("admin-1", web.IdmAdmin)
 		var svc = new(MockService)
-		var controller = NewController(server, svc, logger)
+		var controller = NewController(server, svc, logger, delegations)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodGet, "/api/v1/roles/123", nil)
 		request.Header.Add("Content-Type", "application/json")
@@ -182,9 +213,9 @@ func TestFindRoleById(t *testing.T) {
 func TestFindAllRoles(t *testing.T) {
 	var a = assert.New(t)
 	t.Run("find all roles", func(t *testing.T) {
-		server := web.NewServer()
+		server := webtest.NewServer("admin-1", web.IdmAdmin)
 		var svc = new(MockService)
-		var controller = NewController(server, svc, logger)
+		var controller = NewController(server, svc, logger, delegations)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodGet, "/api/v1/roles", nil)
 		request.Header.Add("Content-Type", "application/json")
@@ -207,9 +238,9 @@ func TestFindAllRoles(t *testing.T) {
 		a.Equal(responses, responseBody.Data)
 	})
 	t.Run("find all with error", func(t *testing.T) {
-		server := web.NewServer()
+		server := webtest.NewServer("admin-1", web.IdmAdmin)
 		var svc = new(MockService)
-		var controller = NewController(server, svc, logger)
+		var controller = NewController(server, svc, logger, delegations)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodGet, "/api/v1/roles", nil)
 		request.Header.Add("Content-Type", "application/json")
@@ -233,9 +264,9 @@ func TestFindAllRoles(t *testing.T) {
 func TestFindRolesByIds(t *testing.T) {
 	var a = assert.New(t)
 	t.Run("find roles by ids", func(t *testing.T) {
-		server := web.NewServer()
+		server := webtest.NewServer("admin-1", web.IdmAdmin)
 		var svc = new(MockService)
-		var controller = NewController(server, svc, logger)
+		var controller = NewController(server, svc, logger, delegations)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodGet, "/api/v1/roles/find?ids=123,124,125", nil)
 		request.Header.Add("Content-Type", "application/json")
@@ -257,9 +288,9 @@ func TestFindRolesByIds(t *testing.T) {
 		a.Equal(responses, responseBody.Data)
 	})
 	t.Run("find roles by ids - error parsing", func(t *testing.T) {
-		server := web.NewServer()
+		server := webtest.NewServer("admin-1", web.IdmAdmin)
 		var svc = new(MockService)
-		var controller = NewController(server, svc, logger)
+		var controller = NewController(server, svc, logger, delegations)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodGet, "/api/v1/roles/find?ids=fff,124,125", nil)
 		request.Header.Add("Content-Type", "application/json")
@@ -282,9 +313,9 @@ func TestFindRolesByIds(t *testing.T) {
 		a.Equal(message, responseBody.Message)
 	})
 	t.Run("find roles by ids - validation error", func(t *testing.T) {
-		server := web.NewServer()
+		server := webtest.NewServer("admin-1", web.IdmAdmin)
 		var svc = new(MockService)
-		var controller = NewController(server, svc, logger)
+		var controller = NewController(server, svc, logger, delegations)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodGet, "/api/v1/roles/find?ids=123,124,125", nil)
 		request.Header.Add("Content-Type", "application/json")
@@ -304,9 +335,9 @@ func TestFindRolesByIds(t *testing.T) {
 		a.Equal(message, responseBody.Message)
 	})
 	t.Run("find roles by ids - not found error", func(t *testing.T) {
-		server := web.NewServer()
+		server := webtest.NewServer("admin-1", web.IdmAdmin)
 		var svc = new(MockService)
-		var controller = NewController(server, svc, logger)
+		var controller = NewController(server, svc, logger, delegations)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodGet, "/api/v1/roles/find?ids=123,124,125", nil)
 		request.Header.Add("Content-Type", "application/json")
@@ -330,9 +361,9 @@ func TestFindRolesByIds(t *testing.T) {
 func TestDeleteRoleById(t *testing.T) {
 	var a = assert.New(t)
 	t.Run("find role by id", func(t *testing.T) {
-		server := web.NewServer()
+		server := webtest.NewServer("admin-1", web.IdmAdmin)
 		var svc = new(MockService)
-		var controller = NewController(server, svc, logger)
+		var controller = NewController(server, svc, logger, delegations)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodDelete, "/api/v1/roles/123", nil)
 		request.Header.Add("Content-Type", "application/json")
@@ -349,9 +380,9 @@ func TestDeleteRoleById(t *testing.T) {
 		a.Equal(int64(123), responseBody.Data.Id)
 	})
 	t.Run("find role - incorrect id", func(t *testing.T) {
-		server := web.NewServer()
+		server := webtest.NewServer("admin-1", web.IdmAdmin)
 		var svc = new(MockService)
-		var controller = NewController(server, svc, logger)
+		var controller = NewController(server, svc, logger, delegations)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodDelete, "/api/v1/roles/ffff", nil)
 		request.Header.Add("Content-Type", "application/json")
@@ -361,9 +392,9 @@ func TestDeleteRoleById(t *testing.T) {
 		a.Equal(http.StatusBadRequest, resp.StatusCode)
 	})
 	t.Run("find role - validation error", func(t *testing.T) {
-		server := web.NewServer()
+		server := webtest.NewServer("admin-1", web.IdmAdmin)
 		var svc = new(MockService)
-		var controller = NewController(server, svc, logger)
+		var controller = NewController(server, svc, logger, delegations)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodDelete, "/api/v1/roles/0", nil)
 		request.Header.Add("Content-Type", "application/json")
@@ -383,9 +414,9 @@ func TestDeleteRoleById(t *testing.T) {
 		a.Equal(message, responseBody.Message)
 	})
 	t.Run("find role - not found error", func(t *testing.T) {
-		server := web.NewServer()
+		server := webtest.NewServer("admin-1", web.IdmAdmin)
 		var svc = new(MockService)
-		var controller = NewController(server, svc, logger)
+		var controller = NewController(server, svc, logger, delegations)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodDelete, "/api/v1/roles/123", nil)
 		request.Header.Add("Content-Type", "application/json")
@@ -409,9 +440,9 @@ func TestDeleteRoleById(t *testing.T) {
 func TestDeleteRolesByIds(t *testing.T) {
 	var a = assert.New(t)
 	t.Run("delete roles by ids", func(t *testing.T) {
-		server := web.NewServer()
+		server := webtest.NewServer("admin-1", web.IdmAdmin)
 		var svc = new(MockService)
-		var controller = NewController(server, svc, logger)
+		var controller = NewController(server, svc, logger, delegations)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodDelete, "/api/v1/roles/delete?ids=123,124,125", nil)
 		request.Header.Add("Content-Type", "application/json")
@@ -433,9 +464,9 @@ func TestDeleteRolesByIds(t *testing.T) {
 		a.Equal(responses, responseBody.Data)
 	})
 	t.Run("delete roles by ids - error parsing", func(t *testing.T) {
-		server := web.NewServer()
+		server := webtest.NewServer("admin-1", web.IdmAdmin)
 		var svc = new(MockService)
-		var controller = NewController(server, svc, logger)
+		var controller = NewController(server, svc, logger, delegations)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodDelete, "/api/v1/roles/delete?ids=fff,124,125", nil)
 		request.Header.Add("Content-Type", "application/json")
@@ -453,9 +484,9 @@ func TestDeleteRolesByIds(t *testing.T) {
 		a.Equal(message, responseBody.Message)
 	})
 	t.Run("delete roles by ids - validation error", func(t *testing.T) {
-		server := web.NewServer()
+		server := webtest.NewServer("admin-1", web.IdmAdmin)
 		var svc = new(MockService)
-		var controller = NewController(server, svc, logger)
+		var controller = NewController(server, svc, logger, delegations)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodDelete, "/api/v1/roles/delete?ids=123,124,125", nil)
 		request.Header.Add("Content-Type", "application/json")
@@ -475,9 +506,9 @@ func TestDeleteRolesByIds(t *testing.T) {
 		a.Equal(message, responseBody.Message)
 	})
 	t.Run("delete roles by ids - not found error", func(t *testing.T) {
-		server := web.NewServer()
+		server := webtest.NewServer("admin-1", web.IdmAdmin)
 		var svc = new(MockService)
-		var controller = NewController(server, svc, logger)
+		var controller = NewController(server, svc, logger, delegations)
 		controller.RegisterRoutes()
 		var request = httptest.NewRequest(fiber.MethodDelete, "/api/v1/roles/delete?ids=123,124,125", nil)
 		request.Header.Add("Content-Type", "application/json")
@@ -497,3 +528,136 @@ func TestDeleteRolesByIds(t *testing.T) {
 		a.Equal(message, responseBody.Message)
 	})
 }
+
+func TestFindRolesWithOffset(t *testing.T) {
+	var a = assert.New(t)
+	t.Run("find roles with offset", func(t *testing.T) {
+		server := webtest.NewServer("admin-1", web.IdmAdmin)
+		var svc = new(MockService)
+		var controller = NewController(server, svc, logger, delegations)
+		controller.RegisterRoutes()
+		var request = httptest.NewRequest(fiber.MethodGet,
+			"/api/v1/roles/page?pageNumber=1&pageSize=2&textFilter=adm&sortBy=name&sortOrder=desc", nil)
+		var want = PageResponse{
+			Result:     []Response{{Id: 1, MemberCount: 3}, {Id: 2}},
+			PageSize:   2,
+			PageNumber: 1,
+			Total:      4,
+		}
+		svc.On("FindWithOffset", PageRequest{
+			PageSize:   2,
+			PageNumber: 1,
+			TextFilter: "adm",
+			SortBy:     "name",
+			SortOrder:  "desc",
+		}).Return(want, nil)
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		bytesData, err := io.ReadAll(resp.Body)
+		a.Nil(err)
+		var responseBody common.Response[PageResponse]
+		err = json.Unmarshal(bytesData, &responseBody)
+		a.Nil(err)
+		a.Equal(want, responseBody.Data)
+	})
+	t.Run("find roles with offset - incorrect page size", func(t *testing.T) {
+		server := webtest.NewServer("admin-1", web.IdmAdmin)
+		var svc = new(MockService)
+		var controller = NewController(server, svc, logger, delegations)
+		controller.RegisterRoutes()
+		var request = httptest.NewRequest(fiber.MethodGet, "/api/v1/roles/page?pageSize=abc", nil)
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusBadRequest, resp.StatusCode)
+		a.True(svc.AssertNumberOfCalls(t, "FindWithOffset", 0))
+	})
+	t.Run("find roles with offset - validation error", func(t *testing.T) {
+		server := webtest.NewServer("admin-1", web.IdmAdmin)
+		var svc = new(MockService)
+		var controller = NewController(server, svc, logger, delegations)
+		controller.RegisterRoutes()
+		var request = httptest.NewRequest(fiber.MethodGet, "/api/v1/roles/page?pageSize=0", nil)
+		svc.On("FindWithOffset", mock.AnythingOfType("PageRequest")).Return(PageResponse{},
+			common.RequestValidationError{Message: "Field validation for 'PageSize' failed on the 'min' tag"})
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestRoleSensitivity(t *testing.T) {
+	var a = assert.New(t)
+	t.Run("admin sets sensitivity", func(t *testing.T) {
+		server := webtest.NewServer("admin-1", web.IdmAdmin)
+		var svc = new(MockService)
+		NewController(server, svc, logger, delegations).RegisterRoutes()
+		svc.On("UpdateSensitivity", SensitivityRequest{Id: 5, Sensitivity: SensitivityCritical}).Return(nil)
+		var request = httptest.NewRequest(fiber.MethodPut, "/api/v1/roles/5/sensitivity",
+			strings.NewReader("{\"sensitivity\": \"critical\"}"))
+		request.Header.Add("Content-Type", "application/json")
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+	})
+	t.Run("delegated admin can not set sensitivity", func(t *testing.T) {
+		server := webtest.NewServer("local-admin")
+		var svc = new(MockService)
+		NewController(server, svc, logger, delegations).RegisterRoutes()
+		var request = httptest.NewRequest(fiber.MethodPut, "/api/v1/roles/5/sensitivity",
+			strings.NewReader("{\"sensitivity\": \"normal\"}"))
+		request.Header.Add("Content-Type", "application/json")
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		a.True(svc.AssertNumberOfCalls(t, "UpdateSensitivity", 0))
+	})
+	t.Run("admin adds conflicting role", func(t *testing.T) {
+		server := webtest.NewServer("admin-1", web.IdmAdmin)
+		var svc = new(MockService)
+		NewController(server, svc, logger, delegations).RegisterRoutes()
+		svc.On("AddConflict", ConflictRequest{RoleId: 5, ConflictingRoleId: 6}).Return(nil)
+		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/roles/5/conflicts",
+			strings.NewReader("{\"conflicting_role_id\": 6}"))
+		request.Header.Add("Content-Type", "application/json")
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+	})
+}
+
+func TestDelegatedRoleAdministration(t *testing.T) {
+	var a = assert.New(t)
+	t.Run("delegated admin can not create roles", func(t *testing.T) {
+		server := webtest.NewServer("local-admin")
+		var svc = new(MockService)
+		NewController(server, svc, logger, delegations).RegisterRoutes()
+		var request = httptest.NewRequest(fiber.MethodPost, "/api/v1/roles", strings.NewReader("{\"name\": \"new role\"}"))
+		request.Header.Add("Content-Type", "application/json")
+		resp, err := server.App.Test(request)
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		a.True(svc.AssertNumberOfCalls(t, "Save", 0))
+	})
+	t.Run("delegated admin can not delete roles, even of own set", func(t *testing.T) {
+		server := webtest.NewServer("local-admin")
+		var svc = new(MockService)
+		NewController(server, svc, logger, delegations).RegisterRoutes()
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodDelete, "/api/v1/roles/5", nil))
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		resp, err = server.App.Test(httptest.NewRequest(fiber.MethodDelete, "/api/v1/roles/delete?ids=5,6", nil))
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		a.True(svc.AssertNumberOfCalls(t, "DeleteById", 0))
+		a.True(svc.AssertNumberOfCalls(t, "DeleteByIds", 0))
+	})
+	t.Run("user without delegation can not delete roles", func(t *testing.T) {
+		server := webtest.NewServer("user-1", web.IdmUser)
+		var svc = new(MockService)
+		NewController(server, svc, logger, delegations).RegisterRoutes()
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodDelete, "/api/v1/roles/5", nil))
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+	})
+}
@@ -2,39 +2,80 @@ package role
 
 import "time"
 
+// уровни чувствительности роли, влияют на оценку риска сотрудника
+const (
+	SensitivityNormal     = "normal"
+	SensitivityPrivileged = "privileged"
+	SensitivityCritical   = "critical"
+)
+
 type Entity struct {
-	Id        int64     `db:"id"`
-	Name      string    `db:"name"`
-	CreatedAt time.Time `db:"created_at"`
-	UpdatedAt time.Time `db:"updated_at"`
+	Id          int64     `db:"id"`
+	Name        string    `db:"name"`
+	Owner       string    `db:"owner"`
+	Sensitivity string    `db:"sensitivity"`
+	CreatedAt   time.Time `db:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at"`
+}
+
+// PageEntity роль вместе с количеством сотрудников, которым она назначена
+type PageEntity struct {
+	Entity
+	MemberCount int64 `db:"member_count"`
 }
 
 type Response struct {
-	Id        int64     `db:"id"`
-	Name      string    `db:"name"`
-	CreatedAt time.Time `db:"created_at"`
-	UpdatedAt time.Time `db:"updated_at"`
+	Id          int64     `db:"id"`
+	Name        string    `db:"name"`
+	Owner       string    `db:"owner"`
+	Sensitivity string    `db:"sensitivity"`
+	CreatedAt   time.Time `db:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at"`
+	MemberCount int64     `db:"member_count"`
 }
 
 func (e *Entity) toResponse() Response {
 	return Response{
-		Id:        e.Id,
-		Name:      e.Name,
-		CreatedAt: e.CreatedAt,
-		UpdatedAt: e.UpdatedAt,
+		Id:          e.Id,
+		Name:        e.Name,
+		Owner:       e.Owner,
+		Sensitivity: e.Sensitivity,
+		CreatedAt:   e.CreatedAt,
+		UpdatedAt:   e.UpdatedAt,
 	}
 }
 
+func (e *PageEntity) toResponse() Response {
+	var response = e.Entity.toResponse()
+	response.MemberCount = e.MemberCount
+	return response
+}
+
 type CreateRequest struct {
-	Name string `json:"name" validate:"required,min=2,max=155"`
+	Name        string `json:"name" validate:"required,min=2,max=155"`
+	Owner       string `json:"owner" validate:"max=255"`
+	Sensitivity string `json:"sensitivity" validate:"omitempty,oneof=normal privileged critical"`
 }
 
 func (req *CreateRequest) ToEntity() Entity {
 	return Entity{
-		Name: req.Name,
+		Name:        req.Name,
+		Owner:       req.Owner,
+		Sensitivity: req.Sensitivity,
 	}
 }
 
+type SensitivityRequest struct {
+	Id          int64  `json:"-" validate:"required,min=1"`
+	Sensitivity string `json:"sensitivity" validate:"required,oneof=normal privileged critical"`
+}
+
+// ConflictRequest пара ролей, которые нельзя совмещать одному сотруднику (SoD)
+type ConflictRequest struct {
+	RoleId            int64 `json:"-" validate:"required,min=1"`
+	ConflictingRoleId int64 `json:"conflicting_role_id" validate:"required,min=1,nefield=RoleId"`
+}
+
 type IdRequest struct {
 	Id int64 `json:"id" validate:"required,min=1"`
 }
@@ -42,3 +83,18 @@ type IdRequest struct {
 type IdsRequest struct {
 	Ids []int64 `json:"ids" validate:"required,min=1,dive"`
 }
+
+type PageRequest struct {
+	PageSize   int    `validate:"min=1,max=100"`
+	PageNumber int    `validate:"min=0"`
+	TextFilter string `validate:"omitempty,minnows3"`
+	SortBy     string `validate:"omitempty,oneof=id name created_at sensitivity member_count"`
+	SortOrder  string `validate:"omitempty,oneof=asc desc"`
+}
+
+type PageResponse struct {
+	Result     []Response
+	PageSize   int
+	PageNumber int
+	Total      int64
+}
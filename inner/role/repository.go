@@ -1,6 +1,7 @@
 package role
 
 import (
+	"fmt"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -17,14 +18,33 @@ func NewRepository(database *sqlx.DB) *Repository {
 func (r *Repository) Save(e Entity) (int64, error) {
 	var id int64
 	err := r.db.QueryRow(
-		"INSERT INTO role (name) VALUES ($1) RETURNING id",
-		e.Name).Scan(&id)
+		"INSERT INTO role (name, owner, sensitivity) VALUES ($1, $2, COALESCE(NULLIF($3, ''), 'normal')) RETURNING id",
+		e.Name, e.Owner, e.Sensitivity).Scan(&id)
 	if err != nil {
 		return -1, err
 	}
 	return id, nil
 }
 
+// UpdateSensitivity изменить чувствительность роли; вернуть false, если роль не найдена
+func (r *Repository) UpdateSensitivity(id int64, sensitivity string) (bool, error) {
+	result, err := r.db.Exec("UPDATE role SET sensitivity = $1, updated_at = NOW() WHERE id = $2", sensitivity, id)
+	if err != nil {
+		return false, err
+	}
+	updated, err := result.RowsAffected()
+	return updated > 0, err
+}
+
+// SaveConflict запретить совмещение двух ролей; пара хранится упорядоченной, повтор игнорируется
+func (r *Repository) SaveConflict(roleId int64, conflictingRoleId int64) error {
+	_, err := r.db.Exec(
+		"INSERT INTO role_conflict (role_id, conflicting_role_id) VALUES (LEAST($1, $2), GREATEST($1, $2)) "+
+			"ON CONFLICT (role_id, conflicting_role_id) DO NOTHING",
+		roleId, conflictingRoleId)
+	return err
+}
+
 func (r *Repository) FindById(id int64) (res Entity, err error) {
 	err = r.db.Get(&res, "SELECT * FROM role WHERE id = $1", id)
 	return res, err
@@ -67,6 +87,61 @@ func (r *Repository) FindByIds(ids []int64) ([]Entity, error) {
 	return roles, nil
 }
 
+// sortColumns допустимые поля сортировки страницы ролей
+var sortColumns = map[string]string{
+	"id":           "r.id",
+	"name":         "r.name",
+	"created_at":   "r.created_at",
+	"sensitivity":  "r.sensitivity",
+	"member_count": "member_count",
+}
+
+// FindWithOffset найти страницу ролей вместе с количеством сотрудников каждой роли одним запросом
+func (r *Repository) FindWithOffset(
+	offset int,
+	limit int,
+	filter string,
+	sortBy string,
+	sortOrder string,
+) ([]PageEntity, error) {
+	var roles []PageEntity
+	query := "SELECT r.*, COUNT(DISTINCT er.employee_id) AS member_count " +
+		"FROM role r LEFT JOIN employee_role er ON er.role_id = r.id WHERE 1 = 1"
+	var args []interface{}
+	paramIdx := 1
+	if filter != "" {
+		query += fmt.Sprintf(" AND r.name ILIKE $%d", paramIdx)
+		args = append(args, "%"+filter+"%")
+		paramIdx++
+	}
+	column, ok := sortColumns[sortBy]
+	if !ok {
+		column = sortColumns["id"]
+	}
+	direction := "ASC"
+	if sortOrder == "desc" {
+		direction = "DESC"
+	}
+	query += fmt.Sprintf(" GROUP BY r.id ORDER BY %s %s, r.id OFFSET $%d LIMIT $%d", column, direction, paramIdx, paramIdx+1)
+	args = append(args, offset, limit)
+	err := r.db.Select(&roles, query, args...)
+	if err != nil {
+		return roles, err
+	}
+	return roles, nil
+}
+
+func (r *Repository) CountWithFilter(filter string) (total int64, err error) {
+	query := "SELECT COUNT(*) FROM role"
+	var args []interface{}
+	if filter != "" {
+		query += " WHERE name ILIKE $1"
+		args = append(args, "%"+filter+"%")
+	}
+	err = r.db.Get(&total, query, args...)
+	return total, err
+}
+
 func (r *Repository) DeleteById(id int64) error {
 	_, err := r.db.Exec("DELETE FROM role WHERE id = $1", id)
 	if err != nil {
@@ -13,8 +13,12 @@ type Service struct {
 type Repo interface {
 	Save(entity Entity) (int64, error)
 	FindById(id int64) (entity Entity, err error)
+	UpdateSensitivity(id int64, sensitivity string) (bool, error)
+	SaveConflict(roleId int64, conflictingRoleId int64) error
 	FindAll() ([]Entity, error)
 	FindByIds(ids []int64) ([]Entity, error)
+	FindWithOffset(offset int, limit int, filter string, sortBy string, sortOrder string) ([]PageEntity, error)
+	CountWithFilter(filter string) (int64, error)
 	DeleteById(id int64) error
 	DeleteByIds(ids []int64) error
 }
@@ -47,6 +51,40 @@ func (s *Service) Save(request CreateRequest) (Response, error) {
 	}, nil
 }
 
+func (s *Service) UpdateSensitivity(request SensitivityRequest) error {
+	if err := s.validator.Validate(request); err != nil {
+		return common.RequestValidationError{Message: err.Error()}
+	}
+	updated, err := s.repo.UpdateSensitivity(request.Id, request.Sensitivity)
+	if err != nil {
+		return fmt.Errorf("error updating sensitivity of role %d: %w", request.Id, err)
+	}
+	if !updated {
+		return common.NotFoundError{Message: fmt.Sprintf("role with id %d not found", request.Id)}
+	}
+	return nil
+}
+
+// AddConflict запретить совмещение двух ролей; обе роли должны существовать
+func (s *Service) AddConflict(request ConflictRequest) error {
+	if err := s.validator.Validate(request); err != nil {
+		return common.RequestValidationError{Message: err.Error()}
+	}
+	roles, err := s.repo.FindByIds([]int64{request.RoleId, request.ConflictingRoleId})
+	if err != nil {
+		return fmt.Errorf("error finding roles %d and %d: %w", request.RoleId, request.ConflictingRoleId, err)
+	}
+	if len(roles) < 2 {
+		return common.NotFoundError{Message: fmt.Sprintf(
+			"roles %d and %d must both exist to conflict", request.RoleId, request.ConflictingRoleId)}
+	}
+	err = s.repo.SaveConflict(request.RoleId, request.ConflictingRoleId)
+	if err != nil {
+		return fmt.Errorf("error saving conflict of roles %d and %d: %w", request.RoleId, request.ConflictingRoleId, err)
+	}
+	return nil
+}
+
 func (s *Service) FindById(request IdRequest) (Response, error) {
 	var err = s.validator.Validate(request)
 	if err != nil {
@@ -86,6 +124,36 @@ func (s *Service) FindByIds(request IdsRequest) ([]Response, error) {
 	return response, nil
 }
 
+func (s *Service) FindWithOffset(request PageRequest) (PageResponse, error) {
+	if err := s.validator.Validate(request); err != nil {
+		return PageResponse{}, common.RequestValidationError{Message: err.Error()}
+	}
+	roles, err := s.repo.FindWithOffset(
+		request.PageSize*request.PageNumber,
+		request.PageSize,
+		request.TextFilter,
+		request.SortBy,
+		request.SortOrder,
+	)
+	if err != nil {
+		return PageResponse{}, common.NotFoundError{Message: fmt.Sprintf("error finding roles with offset: %v", err)}
+	}
+	total, err := s.repo.CountWithFilter(request.TextFilter)
+	if err != nil {
+		return PageResponse{}, common.NotFoundError{Message: fmt.Sprintf("error counting roles: %v", err)}
+	}
+	var response []Response
+	for _, role := range roles {
+		response = append(response, role.toResponse())
+	}
+	return PageResponse{
+		Result:     response,
+		PageSize:   request.PageSize,
+		PageNumber: request.PageNumber,
+		Total:      total,
+	}, nil
+}
+
 func (s *Service) DeleteById(request IdRequest) error {
 	var err = s.validator.Validate(request)
 	if err != nil {
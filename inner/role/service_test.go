@@ -35,6 +35,32 @@ func (r *MockRepo) FindByIds(ids []int64) ([]Entity, error) {
 	return args.Get(0).([]Entity), args.Error(1)
 }
 
+func (r *MockRepo) FindWithOffset(
+	offset int,
+	limit int,
+	filter string,
+	sortBy string,
+	sortOrder string,
+) ([]PageEntity, error) {
+	args := r.Called(offset, limit, filter, sortBy, sortOrder)
+	return args.Get(0).([]PageEntity), args.Error(1)
+}
+
+func (r *MockRepo) CountWithFilter(filter string) (int64, error) {
+	args := r.Called(filter)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (r *MockRepo) UpdateSensitivity(id int64, sensitivity string) (bool, error) {
+	args := r.Called(id, sensitivity)
+	return args.Bool(0), args.Error(1)
+}
+
+func (r *MockRepo) SaveConflict(roleId int64, conflictingRoleId int64) error {
+	args := r.Called(roleId, conflictingRoleId)
+	return args.Error(0)
+}
+
 func (r *MockRepo) DeleteById(id int64) error {
 	args := r.Called(id)
 	return args.Error(0)
@@ -235,3 +261,99 @@ func TestDeleteByIds(t *testing.T) {
 		a.True(repo.AssertNumberOfCalls(t, "DeleteByIds", 1))
 	})
 }
+
+func TestFindWithOffset(t *testing.T) {
+	var a = assert.New(t)
+	t.Run("should return page with member counts and true total", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		var entities = []PageEntity{
+			{Entity: Entity{Id: 3, Name: "admin"}, MemberCount: 5},
+			{Entity: Entity{Id: 4, Name: "administrator"}, MemberCount: 0},
+		}
+		repo.On("FindWithOffset", 2, 2, "adm", "member_count", "desc").Return(entities, nil)
+		repo.On("CountWithFilter", "adm").Return(int64(7), nil)
+		got, err := svc.FindWithOffset(PageRequest{
+			PageSize:   2,
+			PageNumber: 1,
+			TextFilter: "adm",
+			SortBy:     "member_count",
+			SortOrder:  "desc",
+		})
+		a.Nil(err)
+		a.Equal(int64(7), got.Total)
+		a.Equal(2, got.PageSize)
+		a.Equal(1, got.PageNumber)
+		a.Len(got.Result, 2)
+		a.Equal(int64(5), got.Result[0].MemberCount)
+	})
+	t.Run("should return validation error for unknown sort field", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		_, err := svc.FindWithOffset(PageRequest{PageSize: 2, SortBy: "owner"})
+		a.NotNil(err)
+		a.True(errors.As(err, &common.RequestValidationError{}))
+		a.True(repo.AssertNumberOfCalls(t, "FindWithOffset", 0))
+	})
+	t.Run("should return wrapped error", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		var err = errors.New("database error")
+		repo.On("FindWithOffset", 0, 10, "", "", "").Return([]PageEntity{}, err)
+		_, got := svc.FindWithOffset(PageRequest{PageSize: 10})
+		a.Equal(common.NotFoundError{Message: fmt.Sprintf("error finding roles with offset: %v", err)}, got)
+		a.True(repo.AssertNumberOfCalls(t, "CountWithFilter", 0))
+	})
+}
+
+func TestUpdateSensitivity(t *testing.T) {
+	var a = assert.New(t)
+	t.Run("should update sensitivity", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		repo.On("UpdateSensitivity", int64(3), SensitivityCritical).Return(true, nil)
+		err := svc.UpdateSensitivity(SensitivityRequest{Id: 3, Sensitivity: SensitivityCritical})
+		a.Nil(err)
+	})
+	t.Run("should return not found error", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		repo.On("UpdateSensitivity", int64(3), SensitivityPrivileged).Return(false, nil)
+		err := svc.UpdateSensitivity(SensitivityRequest{Id: 3, Sensitivity: SensitivityPrivileged})
+		a.True(errors.As(err, &common.NotFoundError{}))
+	})
+	t.Run("should return validation error for unknown level", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		err := svc.UpdateSensitivity(SensitivityRequest{Id: 3, Sensitivity: "secret"})
+		a.True(errors.As(err, &common.RequestValidationError{}))
+		a.True(repo.AssertNumberOfCalls(t, "UpdateSensitivity", 0))
+	})
+}
+
+func TestAddConflict(t *testing.T) {
+	var a = assert.New(t)
+	t.Run("should save conflict", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		repo.On("FindByIds", []int64{3, 4}).Return([]Entity{{Id: 3}, {Id: 4}}, nil)
+		repo.On("SaveConflict", int64(3), int64(4)).Return(nil)
+		err := svc.AddConflict(ConflictRequest{RoleId: 3, ConflictingRoleId: 4})
+		a.Nil(err)
+	})
+	t.Run("should return not found error for unknown role", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		repo.On("FindByIds", []int64{3, 9}).Return([]Entity{{Id: 3}}, nil)
+		err := svc.AddConflict(ConflictRequest{RoleId: 3, ConflictingRoleId: 9})
+		a.True(errors.As(err, &common.NotFoundError{}))
+		a.True(repo.AssertNumberOfCalls(t, "SaveConflict", 0))
+	})
+	t.Run("should return validation error for conflict with itself", func(t *testing.T) {
+		var repo = new(MockRepo)
+		var svc = NewService(repo, validator.New())
+		err := svc.AddConflict(ConflictRequest{RoleId: 3, ConflictingRoleId: 3})
+		a.True(errors.As(err, &common.RequestValidationError{}))
+		a.True(repo.AssertNumberOfCalls(t, "SaveConflict", 0))
+	})
+}
@@ -0,0 +1,61 @@
+package scheduler
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"idm/inner/common"
+	"sync"
+	"time"
+)
+
+// Job фоновая задача, которая выполняется с заданным интервалом
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+type Scheduler struct {
+	logger *common.Logger
+	jobs   []Job
+	wg     sync.WaitGroup
+}
+
+func New(logger *common.Logger) *Scheduler {
+	return &Scheduler{
+		logger: logger,
+	}
+}
+
+func (s *Scheduler) Add(job Job) {
+	s.jobs = append(s.jobs, job)
+}
+
+// Start запустить все задачи; задачи останавливаются при отмене контекста
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go s.run(ctx, job)
+	}
+}
+
+// Wait дождаться завершения всех задач после отмены контекста
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Scheduler) run(ctx context.Context, job Job) {
+	defer s.wg.Done()
+	var ticker = time.NewTicker(job.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := job.Run(ctx); err != nil {
+				s.logger.Error("background job failed", zap.String("job", job.Name), zap.Error(err))
+			}
+		}
+	}
+}
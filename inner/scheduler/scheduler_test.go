@@ -0,0 +1,54 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"idm/inner/common"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var logger = &common.Logger{Logger: zap.NewNop()}
+
+func TestScheduler(t *testing.T) {
+	var a = assert.New(t)
+	t.Run("should run job until context is cancelled", func(t *testing.T) {
+		var calls atomic.Int32
+		var sched = New(logger)
+		sched.Add(Job{
+			Name:     "counter",
+			Interval: 5 * time.Millisecond,
+			Run: func(ctx context.Context) error {
+				calls.Add(1)
+				return nil
+			},
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		sched.Start(ctx)
+		a.Eventually(func() bool { return calls.Load() >= 2 }, time.Second, time.Millisecond)
+		cancel()
+		sched.Wait()
+		var stopped = calls.Load()
+		time.Sleep(20 * time.Millisecond)
+		a.Equal(stopped, calls.Load())
+	})
+	t.Run("should keep running after job error", func(t *testing.T) {
+		var calls atomic.Int32
+		var sched = New(logger)
+		sched.Add(Job{
+			Name:     "failing",
+			Interval: 5 * time.Millisecond,
+			Run: func(ctx context.Context) error {
+				calls.Add(1)
+				return errors.New("job error")
+			},
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		sched.Start(ctx)
+		a.Eventually(func() bool { return calls.Load() >= 3 }, time.Second, time.Millisecond)
+	})
+}
@@ -0,0 +1,33 @@
+package web
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"idm/inner/common"
+)
+
+// ApiKeyHeader заголовок, в котором сервисные клиенты передают API-ключ
+const ApiKeyHeader = "X-Api-Key"
+
+type ApiKeyAuthenticator interface {
+	Authenticate(ctx context.Context, key string) (*IdmClaims, error)
+}
+
+// WithApiKeys запросы с API-ключом проверить через keys, остальные - через jwtAuth.
+// Claims ключа кладутся в ctx.Locals(JwtKey) так же, как claims токена, поэтому проверки ролей общие
+func WithApiKeys(logger *common.Logger, jwtAuth fiber.Handler, keys ApiKeyAuthenticator) fiber.Handler {
+	var errorHandler = CreateJwtErrorHandler(logger)
+	return func(ctx *fiber.Ctx) error {
+		var key = ctx.Get(ApiKeyHeader)
+		if key == "" {
+			return jwtAuth(ctx)
+		}
+		claims, err := keys.Authenticate(ctx.Context(), key)
+		if err != nil {
+			return errorHandler(ctx, err)
+		}
+		ctx.Locals(JwtKey, &jwt.Token{Claims: claims, Valid: true})
+		return ctx.Next()
+	}
+}
@@ -0,0 +1,57 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"idm/inner/common"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+type staticKeys map[string]*IdmClaims
+
+func (k staticKeys) Authenticate(ctx context.Context, key string) (*IdmClaims, error) {
+	if claims, found := k[key]; found {
+		return claims, nil
+	}
+	return nil, errors.New("invalid API key")
+}
+
+func TestWithApiKeys(t *testing.T) {
+	var a = assert.New(t)
+	var keys = staticKeys{"idm_abc_secret": {
+		RealmAccess:      RealmAccessClaims{Roles: []string{IdmAdmin}},
+		RegisteredClaims: jwt.RegisteredClaims{Subject: "api-key:3"},
+	}}
+	var jwtAuth = func(c *fiber.Ctx) error {
+		return common.ErrResponse(c, fiber.StatusUnauthorized, "jwt checked")
+	}
+	app := fiber.New()
+	app.Use(WithApiKeys(&common.Logger{Logger: zap.NewNop()}, jwtAuth, keys))
+	app.Get("/admin", func(c *fiber.Ctx) error {
+		var claims = c.Locals(JwtKey).(*jwt.Token).Claims.(*IdmClaims)
+		if !slices.Contains(claims.RealmAccess.Roles, IdmAdmin) {
+			return c.SendStatus(http.StatusForbidden)
+		}
+		return c.SendString(claims.Subject)
+	})
+	var call = func(key string) int {
+		request := httptest.NewRequest(fiber.MethodGet, "/admin", nil)
+		if key != "" {
+			request.Header.Set(ApiKeyHeader, key)
+		}
+		resp, err := app.Test(request)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+	a.Equal(http.StatusOK, call("idm_abc_secret"))
+	a.Equal(http.StatusUnauthorized, call("idm_abc_forged"))
+	a.Equal(http.StatusUnauthorized, call(""))
+}
@@ -1,11 +1,16 @@
 package web
 
 import (
-	jwtMiddleware "github.com/gofiber/contrib/jwt"
+	"errors"
+	"fmt"
+	"github.com/MicahParks/keyfunc/v2"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 	"go.uber.org/zap"
 	"idm/inner/common"
+	"slices"
+	"strings"
+	"time"
 )
 
 const (
@@ -14,23 +19,127 @@ const (
 	IdmUser  = "IDM_USER"
 )
 
+const bearer = "Bearer "
+
+var ErrJwtMissingOrMalformed = errors.New("missing or malformed JWT")
+
 type IdmClaims struct {
+	// RealmAccess после проверки токена содержит роли IDM, полученные по RoleMapping
 	RealmAccess RealmAccessClaims `json:"realm_access"`
+	// Department подразделение сотрудника, добавляется маппером Keycloak; используется в политиках доступа
+	Department string `json:"department,omitempty"`
 	jwt.RegisteredClaims
+	// raw все claims токена
+	raw map[string]any
 }
 
 type RealmAccessClaims struct {
 	Roles []string `json:"roles"`
 }
 
-var AuthMiddleware = func(logger *common.Logger) fiber.Handler {
-	config := jwtMiddleware.Config{
-		ContextKey:   JwtKey,
-		ErrorHandler: CreateJwtErrorHandler(logger),
-		JWKSetURLs:   []string{"http://localhost:9990/realms/idm/protocol/openid-connect/certs"},
-		Claims:       &IdmClaims{},
+// TokenRules требования к токену помимо подписи
+type TokenRules struct {
+	// Issuers допустимые издатели; пустой список - издатель не проверяется
+	Issuers []string
+	// Audience значение, которое должно быть в aud; пустое - аудитория не проверяется
+	Audience   string
+	Algorithms []string
+	Leeway     time.Duration
+	Roles      RoleMapping
+}
+
+func NewTokenRules(cfg common.Config) TokenRules {
+	return TokenRules{
+		Issuers:    cfg.KeycloakIssuers,
+		Audience:   cfg.KeycloakAudience,
+		Algorithms: cfg.JwtAlgorithms,
+		Leeway:     cfg.JwtLeeway,
+		Roles: RoleMapping{
+			Claims:      cfg.RoleClaims,
+			StripPrefix: cfg.RolePrefix,
+			Rename:      cfg.RoleRenames,
+		},
+	}
+}
+
+var AuthMiddleware = func(logger *common.Logger, cfg common.Config) fiber.Handler {
+	if cfg.AuthMode == AuthModeDev {
+		logger.Warn("dev auth mode: tokens are checked with local key, Keycloak is not used")
+		return devAuthMiddleware(logger, cfg)
+	}
+	if cfg.AuthMode == AuthModeIntrospection {
+		return IntrospectionMiddleware(logger, NewIntrospector(cfg))
+	}
+	jwks, err := keyfunc.Get(cfg.KeycloakJwkUrl, keyfunc.Options{
+		RefreshErrorHandler: func(err error) {
+			logger.Error("failed JWKS refreshing", zap.String("url", cfg.KeycloakJwkUrl), zap.Error(err))
+		},
+		RefreshInterval:   time.Hour,
+		RefreshRateLimit:  5 * time.Minute,
+		RefreshTimeout:    10 * time.Second,
+		RefreshUnknownKID: true,
+	})
+	if err != nil {
+		logger.Panic("failed JWKS loading", zap.String("url", cfg.KeycloakJwkUrl), zap.Error(err))
+	}
+	return JwtMiddleware(logger, jwks.Keyfunc, NewTokenRules(cfg))
+}
+
+// JwtMiddleware проверить bearer-токен ключами из keyFunc и правилами rules,
+// а разобранный токен положить в ctx.Locals(JwtKey)
+func JwtMiddleware(logger *common.Logger, keyFunc jwt.Keyfunc, rules TokenRules) fiber.Handler {
+	var errorHandler = CreateJwtErrorHandler(logger)
+	return func(ctx *fiber.Ctx) error {
+		var raw, found = bearerToken(ctx)
+		if !found {
+			return errorHandler(ctx, ErrJwtMissingOrMalformed)
+		}
+		token, err := rules.Parse(raw, keyFunc)
+		if err != nil {
+			return errorHandler(ctx, err)
+		}
+		ctx.Locals(JwtKey, token)
+		return ctx.Next()
+	}
+}
+
+// bearerToken токен из заголовка Authorization
+func bearerToken(ctx *fiber.Ctx) (string, bool) {
+	var header = ctx.Get(fiber.HeaderAuthorization)
+	if len(header) <= len(bearer) || !strings.EqualFold(header[:len(bearer)], bearer) {
+		return "", false
+	}
+	return strings.TrimSpace(header[len(bearer):]), true
+}
+
+// Parse разобрать токен, проверить подпись, алгоритм, сроки с учётом Leeway, издателя и аудиторию
+// и заменить роли на роли IDM
+func (r TokenRules) Parse(raw string, keyFunc jwt.Keyfunc) (*jwt.Token, error) {
+	var options = []jwt.ParserOption{jwt.WithLeeway(r.Leeway)}
+	if len(r.Algorithms) > 0 {
+		options = append(options, jwt.WithValidMethods(r.Algorithms))
+	}
+	token, err := jwt.ParseWithClaims(raw, &IdmClaims{}, keyFunc, options...)
+	if err != nil {
+		return nil, err
+	}
+	var claims = token.Claims.(*IdmClaims)
+	if err = r.Check(claims); err != nil {
+		return nil, err
+	}
+	claims.RealmAccess.Roles = r.Roles.Map(claims)
+	return token, nil
+}
+
+// Check проверить издателя и аудиторию
+func (r TokenRules) Check(claims *IdmClaims) error {
+	if len(r.Issuers) > 0 && !slices.Contains(r.Issuers, claims.Issuer) {
+		return fmt.Errorf("%w: %q is not trusted", jwt.ErrTokenInvalidIssuer, claims.Issuer)
+	}
+	if r.Audience != "" && !slices.Contains(claims.Audience, r.Audience) {
+		return fmt.Errorf("%w: %q is expected", jwt.ErrTokenInvalidAudience, r.Audience)
 	}
-	return jwtMiddleware.New(config)
+	return nil
 }
 
 func CreateJwtErrorHandler(logger *common.Logger) fiber.ErrorHandler {
@@ -0,0 +1,112 @@
+package web
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"idm/inner/common"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestJwtMiddleware(t *testing.T) {
+	var a = assert.New(t)
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	var keyFunc = func(token *jwt.Token) (interface{}, error) {
+		return &privateKey.PublicKey, nil
+	}
+	var rules = TokenRules{
+		Issuers:    []string{"https://sso.example.com/realms/idm", "https://sso2.example.com/realms/idm"},
+		Audience:   "idm",
+		Algorithms: []string{"RS256"},
+		Leeway:     time.Minute,
+	}
+	var claims = func() *IdmClaims {
+		return &IdmClaims{
+			RealmAccess: RealmAccessClaims{Roles: []string{IdmUser}},
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "alice",
+				Issuer:    "https://sso2.example.com/realms/idm",
+				Audience:  jwt.ClaimStrings{"account", "idm"},
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+		}
+	}
+	var sign = func(method jwt.SigningMethod, key any, claims *IdmClaims) string {
+		signed, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		require.NoError(t, err)
+		return signed
+	}
+	var call = func(token string) (int, string) {
+		app := fiber.New()
+		app.Use(JwtMiddleware(&common.Logger{Logger: zap.NewNop()}, keyFunc, rules))
+		app.Get("/", func(c *fiber.Ctx) error {
+			return c.SendString(c.Locals(JwtKey).(*jwt.Token).Claims.(*IdmClaims).Subject)
+		})
+		request := httptest.NewRequest(fiber.MethodGet, "/", nil)
+		if token != "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := app.Test(request)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		if resp.StatusCode != http.StatusOK {
+			var response common.Response[string]
+			require.NoError(t, json.Unmarshal(body, &response))
+			return resp.StatusCode, response.Message
+		}
+		return resp.StatusCode, string(body)
+	}
+	t.Run("valid token", func(t *testing.T) {
+		status, body := call(sign(jwt.SigningMethodRS256, privateKey, claims()))
+		a.Equal(http.StatusOK, status)
+		a.Equal("alice", body)
+	})
+	t.Run("expired token within leeway", func(t *testing.T) {
+		var expired = claims()
+		expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-30 * time.Second))
+		status, _ := call(sign(jwt.SigningMethodRS256, privateKey, expired))
+		a.Equal(http.StatusOK, status)
+	})
+	t.Run("expired token beyond leeway", func(t *testing.T) {
+		var expired = claims()
+		expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-2 * time.Minute))
+		status, message := call(sign(jwt.SigningMethodRS256, privateKey, expired))
+		a.Equal(http.StatusUnauthorized, status)
+		a.Equal("token has invalid claims: token is expired", message)
+	})
+	t.Run("untrusted issuer", func(t *testing.T) {
+		var foreign = claims()
+		foreign.Issuer = "https://evil.example.com/realms/idm"
+		status, message := call(sign(jwt.SigningMethodRS256, privateKey, foreign))
+		a.Equal(http.StatusUnauthorized, status)
+		a.Equal("token has invalid issuer: \"https://evil.example.com/realms/idm\" is not trusted", message)
+	})
+	t.Run("wrong audience", func(t *testing.T) {
+		var foreign = claims()
+		foreign.Audience = jwt.ClaimStrings{"account"}
+		status, message := call(sign(jwt.SigningMethodRS256, privateKey, foreign))
+		a.Equal(http.StatusUnauthorized, status)
+		a.Equal("token has invalid audience: \"idm\" is expected", message)
+	})
+	t.Run("algorithm not allowed", func(t *testing.T) {
+		status, message := call(sign(jwt.SigningMethodHS256, []byte("secret"), claims()))
+		a.Equal(http.StatusUnauthorized, status)
+		a.Equal("token signature is invalid: signing method HS256 is invalid", message)
+	})
+	t.Run("missing token", func(t *testing.T) {
+		status, message := call("")
+		a.Equal(http.StatusUnauthorized, status)
+		a.Equal(ErrJwtMissingOrMalformed.Error(), message)
+	})
+}
@@ -0,0 +1,75 @@
+package web
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"idm/inner/common"
+	"strings"
+	"time"
+)
+
+// режимы проверки токенов
+const (
+	AuthModeKeycloak = "keycloak"
+	// AuthModeDev токены подписываются и проверяются локальным ключом, Keycloak не нужен
+	AuthModeDev = "dev"
+	// AuthModeIntrospection токены проверяются запросом к серверу авторизации (RFC 7662)
+	AuthModeIntrospection = "introspection"
+)
+
+// DevIssuer издатель токенов, выпущенных в режиме разработки
+const DevIssuer = "idm-dev"
+
+// devTokenRules в режиме разработки принимаются только токены, подписанные локальным ключом;
+// сопоставление ролей то же, что и для токенов Keycloak
+func devTokenRules(cfg common.Config) TokenRules {
+	var rules = NewTokenRules(cfg)
+	rules.Issuers = []string{DevIssuer}
+	rules.Algorithms = []string{jwt.SigningMethodHS256.Alg()}
+	return rules
+}
+
+func devAuthMiddleware(logger *common.Logger, cfg common.Config) fiber.Handler {
+	var key = []byte(cfg.DevAuthKey)
+	return JwtMiddleware(logger, func(token *jwt.Token) (interface{}, error) {
+		return key, nil
+	}, devTokenRules(cfg))
+}
+
+// MintDevToken выпустить токен для режима разработки с subject и ролями roles.
+// Роли записываются по первому пути из RoleClaims, откуда их затем прочитает RoleMapping
+func MintDevToken(cfg common.Config, subject string, roles []string, ttl time.Duration) (string, error) {
+	if cfg.AuthMode != AuthModeDev {
+		return "", fmt.Errorf("tokens can be minted only in %s auth mode", AuthModeDev)
+	}
+	var now = time.Now()
+	var claims = jwt.MapClaims{
+		"iss": DevIssuer,
+		"sub": subject,
+		"iat": jwt.NewNumericDate(now),
+		"exp": jwt.NewNumericDate(now.Add(ttl)),
+	}
+	if cfg.KeycloakAudience != "" {
+		claims["aud"] = jwt.ClaimStrings{cfg.KeycloakAudience}
+	}
+	var path = DefaultRoleClaim
+	if len(cfg.RoleClaims) > 0 {
+		path = cfg.RoleClaims[0]
+	}
+	setClaim(claims, strings.Split(path, "."), roles)
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(cfg.DevAuthKey))
+}
+
+// setClaim записать value по пути names, создавая недостающие вложенные объекты
+func setClaim(claims map[string]any, names []string, value any) {
+	for _, name := range names[:len(names)-1] {
+		nested, ok := claims[name].(map[string]any)
+		if !ok {
+			nested = map[string]any{}
+			claims[name] = nested
+		}
+		claims = nested
+	}
+	claims[names[len(names)-1]] = value
+}
@@ -0,0 +1,70 @@
+package web
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"idm/inner/common"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDevAuth(t *testing.T) {
+	var a = assert.New(t)
+	var cfg = common.Config{AuthMode: AuthModeDev, DevAuthKey: strings.Repeat("k", 32)}
+	var call = func(cfg common.Config, token string) int {
+		app := fiber.New()
+		app.Use(AuthMiddleware(&common.Logger{Logger: zap.NewNop()}, cfg))
+		app.Get("/", func(c *fiber.Ctx) error {
+			var claims = c.Locals(JwtKey).(*jwt.Token).Claims.(*IdmClaims)
+			a.Equal("alice", claims.Subject)
+			a.Equal([]string{IdmAdmin}, claims.RealmAccess.Roles)
+			return c.SendStatus(http.StatusOK)
+		})
+		request := httptest.NewRequest(fiber.MethodGet, "/", nil)
+		request.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(request)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+	t.Run("minted token is accepted", func(t *testing.T) {
+		token, err := MintDevToken(cfg, "alice", []string{IdmAdmin}, time.Hour)
+		a.Nil(err)
+		a.Equal(http.StatusOK, call(cfg, token))
+	})
+	t.Run("token signed with other key is rejected", func(t *testing.T) {
+		var other = cfg
+		other.DevAuthKey = strings.Repeat("x", 32)
+		token, err := MintDevToken(other, "alice", []string{IdmAdmin}, time.Hour)
+		a.Nil(err)
+		a.Equal(http.StatusUnauthorized, call(cfg, token))
+	})
+	t.Run("expired token is rejected", func(t *testing.T) {
+		token, err := MintDevToken(cfg, "alice", []string{IdmAdmin}, -time.Minute)
+		a.Nil(err)
+		a.Equal(http.StatusUnauthorized, call(cfg, token))
+	})
+	t.Run("role mapping is applied", func(t *testing.T) {
+		var mapped = cfg
+		mapped.RoleRenames = map[string]string{"idm-admin": IdmAdmin}
+		token, err := MintDevToken(mapped, "alice", []string{"idm-admin"}, time.Hour)
+		a.Nil(err)
+		a.Equal(http.StatusOK, call(mapped, token))
+	})
+	t.Run("roles are written to configured claim", func(t *testing.T) {
+		var mapped = cfg
+		mapped.RoleClaims = []string{"resource_access.idm.roles", "groups"}
+		token, err := MintDevToken(mapped, "alice", []string{IdmAdmin}, time.Hour)
+		a.Nil(err)
+		a.Equal(http.StatusOK, call(mapped, token))
+	})
+	t.Run("tokens are not minted outside dev mode", func(t *testing.T) {
+		_, err := MintDevToken(common.Config{AuthMode: AuthModeKeycloak, DevAuthKey: cfg.DevAuthKey}, "alice", nil, time.Hour)
+		a.NotNil(err)
+	})
+}
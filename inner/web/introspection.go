@@ -0,0 +1,143 @@
+package web
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"idm/inner/common"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrTokenInactive = errors.New("token is not active")
+
+// introspectionResponse ответ сервера авторизации (RFC 7662); помимо active Keycloak кладёт в него
+// те же claims, что и в JWT
+type introspectionResponse struct {
+	Active bool `json:"active"`
+}
+
+type introspected struct {
+	claims    *IdmClaims
+	expiresAt time.Time
+}
+
+// Introspector проверяет токены запросом к серверу авторизации.
+// Активные токены кешируются до истечения их срока, в кеше хранится хеш токена, а не сам токен
+type Introspector struct {
+	url          string
+	clientId     string
+	clientSecret string
+	rules        TokenRules
+	client       *http.Client
+	now          func() time.Time
+	mu           sync.Mutex
+	cache        map[[sha256.Size]byte]introspected
+}
+
+func NewIntrospector(cfg common.Config) *Introspector {
+	return &Introspector{
+		url:          cfg.IntrospectionUrl,
+		clientId:     cfg.IntrospectionClientId,
+		clientSecret: cfg.IntrospectionClientSecret,
+		rules:        NewTokenRules(cfg),
+		client:       &http.Client{Timeout: 10 * time.Second},
+		now:          time.Now,
+		cache:        make(map[[sha256.Size]byte]introspected),
+	}
+}
+
+// IntrospectionMiddleware проверить bearer-токен через introspector и положить claims в ctx.Locals(JwtKey)
+// так же, как JwtMiddleware, поэтому проверки ролей не зависят от режима
+func IntrospectionMiddleware(logger *common.Logger, introspector *Introspector) fiber.Handler {
+	var errorHandler = CreateJwtErrorHandler(logger)
+	return func(ctx *fiber.Ctx) error {
+		var raw, found = bearerToken(ctx)
+		if !found {
+			return errorHandler(ctx, ErrJwtMissingOrMalformed)
+		}
+		claims, err := introspector.Introspect(ctx.Context(), raw)
+		if err != nil {
+			return errorHandler(ctx, err)
+		}
+		ctx.Locals(JwtKey, &jwt.Token{Raw: raw, Claims: claims, Valid: true})
+		return ctx.Next()
+	}
+}
+
+// Introspect claims активного токена: из кеша или от сервера авторизации
+func (i *Introspector) Introspect(ctx context.Context, token string) (*IdmClaims, error) {
+	var key = sha256.Sum256([]byte(token))
+	var now = i.now()
+	i.mu.Lock()
+	cached, found := i.cache[key]
+	i.mu.Unlock()
+	if found && now.Before(cached.expiresAt) {
+		return cached.claims, nil
+	}
+	claims, err := i.request(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if err = i.rules.Check(claims); err != nil {
+		return nil, err
+	}
+	claims.RealmAccess.Roles = i.rules.Roles.Map(claims)
+	if claims.ExpiresAt != nil {
+		i.mu.Lock()
+		for k, v := range i.cache {
+			if !now.Before(v.expiresAt) {
+				delete(i.cache, k)
+			}
+		}
+		i.cache[key] = introspected{claims: claims, expiresAt: claims.ExpiresAt.Time}
+		i.mu.Unlock()
+	}
+	return claims, nil
+}
+
+func (i *Introspector) request(ctx context.Context, token string) (*IdmClaims, error) {
+	var form = url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, i.url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationForm)
+	request.Header.Set(fiber.HeaderAccept, fiber.MIMEApplicationJSON)
+	request.SetBasicAuth(url.QueryEscape(i.clientId), url.QueryEscape(i.clientSecret))
+	response, err := i.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("error introspecting token: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error introspecting token: unexpected status %d", response.StatusCode)
+	}
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error introspecting token: %w", err)
+	}
+	var body introspectionResponse
+	if err = json.Unmarshal(data, &body); err != nil {
+		return nil, fmt.Errorf("error introspecting token: %w", err)
+	}
+	if !body.Active {
+		return nil, ErrTokenInactive
+	}
+	var claims IdmClaims
+	if err = json.Unmarshal(data, &claims); err != nil {
+		return nil, fmt.Errorf("error introspecting token: %w", err)
+	}
+	if claims.ExpiresAt != nil && !i.now().Before(claims.ExpiresAt.Time) {
+		return nil, jwt.ErrTokenExpired
+	}
+	return &claims, nil
+}
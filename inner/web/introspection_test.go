@@ -0,0 +1,96 @@
+package web
+
+import (
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"idm/inner/common"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIntrospectionMiddleware(t *testing.T) {
+	var a = assert.New(t)
+	var expiresAt = time.Now().Add(time.Minute)
+	var calls atomic.Int32
+	authServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if id, secret, ok := r.BasicAuth(); !ok || id != "idm" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var response = map[string]any{"active": false}
+		if r.PostFormValue("token") == "opaque-admin" {
+			response = map[string]any{
+				"active":       true,
+				"sub":          "hr-sync",
+				"iss":          "https://sso.example.com/realms/idm",
+				"exp":          expiresAt.Unix(),
+				"realm_access": map[string]any{"roles": []string{IdmAdmin}},
+			}
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer authServer.Close()
+	var introspector = NewIntrospector(common.Config{
+		IntrospectionUrl:          authServer.URL,
+		IntrospectionClientId:     "idm",
+		IntrospectionClientSecret: "s3cret",
+		KeycloakIssuers:           []string{"https://sso.example.com/realms/idm"},
+	})
+	app := fiber.New()
+	app.Use(IntrospectionMiddleware(&common.Logger{Logger: zap.NewNop()}, introspector))
+	app.Get("/", func(c *fiber.Ctx) error {
+		var claims = c.Locals(JwtKey).(*jwt.Token).Claims.(*IdmClaims)
+		return c.SendString(claims.Subject + " " + claims.RealmAccess.Roles[0])
+	})
+	var call = func(token string) int {
+		request := httptest.NewRequest(fiber.MethodGet, "/", nil)
+		if token != "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := app.Test(request)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+	t.Run("active token gives claims and is cached for its lifetime", func(t *testing.T) {
+		calls.Store(0)
+		a.Equal(http.StatusOK, call("opaque-admin"))
+		a.Equal(http.StatusOK, call("opaque-admin"))
+		a.Equal(int32(1), calls.Load())
+		introspector.now = func() time.Time { return expiresAt.Add(-time.Second) }
+		a.Equal(http.StatusOK, call("opaque-admin"))
+		a.Equal(int32(1), calls.Load())
+		introspector.now = func() time.Time { return expiresAt.Add(time.Second) }
+		a.Equal(http.StatusUnauthorized, call("opaque-admin"))
+		a.Equal(int32(2), calls.Load())
+		introspector.now = time.Now
+	})
+	t.Run("inactive token is not cached", func(t *testing.T) {
+		calls.Store(0)
+		a.Equal(http.StatusUnauthorized, call("revoked"))
+		a.Equal(http.StatusUnauthorized, call("revoked"))
+		a.Equal(int32(2), calls.Load())
+	})
+	t.Run("missing token", func(t *testing.T) {
+		calls.Store(0)
+		a.Equal(http.StatusUnauthorized, call(""))
+		a.Equal(int32(0), calls.Load())
+	})
+	t.Run("untrusted issuer", func(t *testing.T) {
+		var strict = NewIntrospector(common.Config{
+			IntrospectionUrl:          authServer.URL,
+			IntrospectionClientId:     "idm",
+			IntrospectionClientSecret: "s3cret",
+			KeycloakIssuers:           []string{"https://other.example.com"},
+		})
+		_, err := strict.Introspect(t.Context(), "opaque-admin")
+		a.ErrorIs(err, jwt.ErrTokenInvalidIssuer)
+	})
+}
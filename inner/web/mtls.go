@@ -0,0 +1,106 @@
+package web
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"idm/inner/common"
+	"os"
+	"slices"
+)
+
+// ClientIdentity сервисная учётная запись клиента с сертификатом;
+// сертификат сопоставляется по CN (Subject) либо по DNS, URI или email из SAN (San)
+type ClientIdentity struct {
+	Subject  string   `json:"subject,omitempty"`
+	San      string   `json:"san,omitempty"`
+	Identity string   `json:"identity"`
+	Roles    []string `json:"roles"`
+}
+
+type ClientIdentities []ClientIdentity
+
+// LoadClientIdentities прочитать сопоставления из JSON-файла вида
+// [{"san": "spiffe://corp/hr-sync", "identity": "hr-sync", "roles": ["IDM_USER"]}]
+func LoadClientIdentities(path string) (ClientIdentities, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var identities ClientIdentities
+	if err = json.Unmarshal(data, &identities); err != nil {
+		return nil, fmt.Errorf("error parsing client identities %s: %w", path, err)
+	}
+	for i, identity := range identities {
+		if (identity.Subject == "") == (identity.San == "") {
+			return nil, fmt.Errorf("client identity %d: exactly one of subject or san must be set", i)
+		}
+		if identity.Identity == "" {
+			return nil, fmt.Errorf("client identity %d: identity is required", i)
+		}
+	}
+	return identities, nil
+}
+
+// Match первая учётная запись, которой соответствует сертификат
+func (ids ClientIdentities) Match(cert *x509.Certificate) (ClientIdentity, bool) {
+	var sans = slices.Concat(cert.DNSNames, cert.EmailAddresses)
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+	for _, identity := range ids {
+		if identity.Subject != "" && identity.Subject == cert.Subject.CommonName ||
+			identity.San != "" && slices.Contains(sans, identity.San) {
+			return identity, true
+		}
+	}
+	return ClientIdentity{}, false
+}
+
+// ConfigureClientAuth включить проверку клиентских сертификатов по CA из cfg.TlsClientCa
+func ConfigureClientAuth(tlsConfig *tls.Config, cfg common.Config) error {
+	if cfg.TlsClientCa == "" {
+		return nil
+	}
+	data, err := os.ReadFile(cfg.TlsClientCa)
+	if err != nil {
+		return err
+	}
+	var pool = x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return fmt.Errorf("no certificates found in %s", cfg.TlsClientCa)
+	}
+	tlsConfig.ClientCAs = pool
+	tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	if cfg.TlsClientCertRequired {
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return nil
+}
+
+// WithClientCertificates клиент с проверенным сертификатом, сопоставленным учётной записи, проходит
+// проверки ролей с её ролями; остальные запросы проверяет next. Учётная запись пишется в логи запроса
+func WithClientCertificates(identities ClientIdentities, next fiber.Handler) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		var state = ctx.Context().TLSConnectionState()
+		if state == nil || len(state.VerifiedChains) == 0 {
+			return next(ctx)
+		}
+		identity, found := identities.Match(state.VerifiedChains[0][0])
+		if !found {
+			return next(ctx)
+		}
+		ctx.Locals(common.ClientIdentityKey, identity.Identity)
+		ctx.Locals(JwtKey, &jwt.Token{
+			Claims: &IdmClaims{
+				RealmAccess:      RealmAccessClaims{Roles: identity.Roles},
+				RegisteredClaims: jwt.RegisteredClaims{Subject: identity.Identity},
+			},
+			Valid: true,
+		})
+		return ctx.Next()
+	}
+}
@@ -0,0 +1,145 @@
+package web
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"idm/inner/common"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+type testCa struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCa(t *testing.T) testCa {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+	var template = &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "idm test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.Nil(t, err)
+	return testCa{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+func (ca testCa) issue(t *testing.T, template *x509.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.Nil(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestClientIdentitiesMatch(t *testing.T) {
+	var a = assert.New(t)
+	var path = filepath.Join(t.TempDir(), "identities.json")
+	require.Nil(t, os.WriteFile(path, []byte(`[
+		{"san": "spiffe://corp/hr-sync", "identity": "hr-sync", "roles": ["IDM_USER"]},
+		{"subject": "ci.corp", "identity": "ci", "roles": ["IDM_ADMIN"]}
+	]`), 0600))
+	identities, err := LoadClientIdentities(path)
+	require.Nil(t, err)
+	spiffe, _ := url.Parse("spiffe://corp/hr-sync")
+	identity, found := identities.Match(&x509.Certificate{URIs: []*url.URL{spiffe}})
+	a.True(found)
+	a.Equal("hr-sync", identity.Identity)
+	identity, found = identities.Match(&x509.Certificate{Subject: pkix.Name{CommonName: "ci.corp"}})
+	a.True(found)
+	a.Equal([]string{IdmAdmin}, identity.Roles)
+	_, found = identities.Match(&x509.Certificate{DNSNames: []string{"ci.corp"}})
+	a.False(found)
+
+	require.Nil(t, os.WriteFile(path, []byte(`[{"subject": "ci", "san": "ci", "identity": "ci"}]`), 0600))
+	_, err = LoadClientIdentities(path)
+	a.ErrorContains(err, "exactly one of subject or san")
+}
+
+func TestWithClientCertificates(t *testing.T) {
+	var a = assert.New(t)
+	var ca = newTestCa(t)
+	var caFile = filepath.Join(t.TempDir(), "ca.pem")
+	require.Nil(t, os.WriteFile(caFile, ca.pem, 0600))
+	var serverCert = ca.issue(t, &x509.Certificate{
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	var clientCert = func(commonName string) tls.Certificate {
+		return ca.issue(t, &x509.Certificate{
+			Subject:     pkix.Name{CommonName: commonName},
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		})
+	}
+	var identities = ClientIdentities{{Subject: "ci.corp", Identity: "ci", Roles: []string{IdmAdmin}}}
+	var jwtAuth = func(c *fiber.Ctx) error {
+		return common.ErrResponse(c, fiber.StatusUnauthorized, "jwt checked")
+	}
+
+	var tlsConfig = &tls.Config{Certificates: []tls.Certificate{serverCert}}
+	require.Nil(t, ConfigureClientAuth(tlsConfig, common.Config{TlsClientCa: caFile}))
+	a.Equal(tls.VerifyClientCertIfGiven, tlsConfig.ClientAuth)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	require.Nil(t, err)
+	app := fiber.New()
+	app.Use(WithClientCertificates(identities, jwtAuth))
+	app.Get("/admin", func(c *fiber.Ctx) error {
+		var claims = c.Locals(JwtKey).(*jwt.Token).Claims.(*IdmClaims)
+		if !slices.Contains(claims.RealmAccess.Roles, IdmAdmin) {
+			return common.ErrResponse(c, fiber.StatusForbidden, "Permission denied")
+		}
+		return common.OkResponse(c, c.Locals(common.ClientIdentityKey))
+	})
+	go func() { _ = app.Listener(listener) }()
+	t.Cleanup(func() { _ = app.Shutdown() })
+
+	var roots = x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	var get = func(certificates ...tls.Certificate) int {
+		var client = &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      roots,
+			Certificates: certificates,
+		}}}
+		resp, err := client.Get("https://" + listener.Addr().String() + "/admin")
+		require.Nil(t, err)
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+	t.Run("mapped certificate passes with identity roles", func(t *testing.T) {
+		a.Equal(http.StatusOK, get(clientCert("ci.corp")))
+	})
+	t.Run("unmapped certificate falls back to token", func(t *testing.T) {
+		a.Equal(http.StatusUnauthorized, get(clientCert("unknown.corp")))
+	})
+	t.Run("no certificate falls back to token", func(t *testing.T) {
+		a.Equal(http.StatusUnauthorized, get())
+	})
+}
@@ -0,0 +1,238 @@
+// Package oidctest поддельный OIDC-провайдер в духе Keycloak для тестов: JWKS, выдача токенов
+// по паролю и ротация ключей подписи
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"idm/inner/common"
+	"idm/inner/web"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const realmPath = "/realms/idm"
+
+// User пользователь, который может получить токен по паролю
+type User struct {
+	Password   string
+	Subject    string
+	Roles      []string
+	Department string
+}
+
+type signingKey struct {
+	id      string
+	private *rsa.PrivateKey
+}
+
+type Provider struct {
+	// TokenTtl время жизни выдаваемых токенов
+	TokenTtl time.Duration
+	server   *httptest.Server
+	mu       sync.RWMutex
+	// keys опубликованные ключи, первый - текущий ключ подписи
+	keys    []signingKey
+	users   map[string]User
+	counter int
+}
+
+// NewProvider запустить провайдер; остановить его нужно через Close
+func NewProvider() *Provider {
+	var p = &Provider{
+		TokenTtl: 5 * time.Minute,
+		users:    map[string]User{},
+	}
+	p.keys = []signingKey{p.newKey()}
+	var mux = http.NewServeMux()
+	mux.HandleFunc(realmPath+"/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc(realmPath+"/protocol/openid-connect/certs", p.jwks)
+	mux.HandleFunc(realmPath+"/protocol/openid-connect/token", p.token)
+	p.server = httptest.NewServer(mux)
+	return p
+}
+
+func (p *Provider) Close() {
+	p.server.Close()
+}
+
+func (p *Provider) Issuer() string {
+	return p.server.URL + realmPath
+}
+
+func (p *Provider) JwksUrl() string {
+	return p.Issuer() + "/protocol/openid-connect/certs"
+}
+
+func (p *Provider) TokenUrl() string {
+	return p.Issuer() + "/protocol/openid-connect/token"
+}
+
+// Config настройки проверки токенов этого провайдера поверх cfg
+func (p *Provider) Config(cfg common.Config) common.Config {
+	cfg.AuthMode = web.AuthModeKeycloak
+	cfg.KeycloakJwkUrl = p.JwksUrl()
+	cfg.KeycloakIssuers = []string{p.Issuer()}
+	if len(cfg.JwtAlgorithms) == 0 {
+		cfg.JwtAlgorithms = []string{jwt.SigningMethodRS256.Alg()}
+	}
+	return cfg
+}
+
+func (p *Provider) AddUser(username string, user User) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.users[username] = user
+}
+
+// Rotate подписывать новым ключом; keepPrevious - продолжать публиковать прежние ключи
+func (p *Provider) Rotate(keepPrevious bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var key = p.newKey()
+	if keepPrevious {
+		p.keys = append([]signingKey{key}, p.keys...)
+	} else {
+		p.keys = []signingKey{key}
+	}
+}
+
+// Sign подписать claims текущим ключом; пустые iss и exp заполняются провайдером
+func (p *Provider) Sign(claims *web.IdmClaims) string {
+	if claims.Issuer == "" {
+		claims.Issuer = p.Issuer()
+	}
+	if claims.ExpiresAt == nil {
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(p.TokenTtl))
+	}
+	p.mu.RLock()
+	var key = p.keys[0]
+	p.mu.RUnlock()
+	var token = jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = key.id
+	signed, err := token.SignedString(key.private)
+	if err != nil {
+		panic(fmt.Sprintf("failed token signing: %v", err))
+	}
+	return signed
+}
+
+// Token токен для subject с ролями roles без обращения к эндпоинту выдачи
+func (p *Provider) Token(subject string, roles ...string) string {
+	return p.Sign(&web.IdmClaims{
+		RealmAccess:      web.RealmAccessClaims{Roles: roles},
+		RegisteredClaims: jwt.RegisteredClaims{Subject: subject, IssuedAt: jwt.NewNumericDate(time.Now())},
+	})
+}
+
+// Login получить токен по паролю так же, как это делает клиент Keycloak
+func (p *Provider) Login(username string, password string) (string, error) {
+	resp, err := http.PostForm(p.TokenUrl(), url.Values{
+		"grant_type": {"password"},
+		"client_id":  {"idm"},
+		"username":   {username},
+		"password":   {password},
+	})
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var body struct {
+		AccessToken string `json:"access_token"`
+		Error       string `json:"error"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request failed with status %d: %s", resp.StatusCode, body.Error)
+	}
+	return body.AccessToken, nil
+}
+
+func (p *Provider) newKey() signingKey {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(fmt.Sprintf("failed key generation: %v", err))
+	}
+	p.counter++
+	return signingKey{id: fmt.Sprintf("key-%d", p.counter), private: private}
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJson(w, http.StatusOK, map[string]any{
+		"issuer":                                p.Issuer(),
+		"jwks_uri":                              p.JwksUrl(),
+		"token_endpoint":                        p.TokenUrl(),
+		"grant_types_supported":                 []string{"password"},
+		"id_token_signing_alg_values_supported": []string{jwt.SigningMethodRS256.Alg()},
+	})
+}
+
+func (p *Provider) jwks(w http.ResponseWriter, _ *http.Request) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	var keys []map[string]string
+	for _, key := range p.keys {
+		keys = append(keys, map[string]string{
+			"kty": "RSA",
+			"alg": jwt.SigningMethodRS256.Alg(),
+			"use": "sig",
+			"kid": key.id,
+			"n":   base64.RawURLEncoding.EncodeToString(key.private.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.private.E)).Bytes()),
+		})
+	}
+	writeJson(w, http.StatusOK, map[string]any{"keys": keys})
+}
+
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJson(w, http.StatusMethodNotAllowed, map[string]string{"error": "invalid_request"})
+		return
+	}
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "password" {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+	p.mu.RLock()
+	user, found := p.users[r.PostForm.Get("username")]
+	p.mu.RUnlock()
+	if !found || user.Password != r.PostForm.Get("password") {
+		writeJson(w, http.StatusUnauthorized, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	var subject = user.Subject
+	if subject == "" {
+		subject = strings.ToLower(r.PostForm.Get("username"))
+	}
+	var token = p.Sign(&web.IdmClaims{
+		RealmAccess: web.RealmAccessClaims{Roles: user.Roles},
+		Department:  user.Department,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:  subject,
+			Audience: jwt.ClaimStrings{r.PostForm.Get("client_id")},
+			IssuedAt: jwt.NewNumericDate(time.Now()),
+		},
+	})
+	writeJson(w, http.StatusOK, map[string]any{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   int(p.TokenTtl.Seconds()),
+	})
+}
+
+func writeJson(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
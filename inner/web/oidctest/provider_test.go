@@ -0,0 +1,48 @@
+package oidctest
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"idm/inner/common"
+	"idm/inner/web"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProvider(t *testing.T) {
+	var a = assert.New(t)
+	var provider = NewProvider()
+	defer provider.Close()
+	provider.AddUser("alice", User{Password: "secret", Subject: "kc-alice", Roles: []string{web.IdmAdmin}})
+	app := fiber.New()
+	app.Use(web.AuthMiddleware(&common.Logger{Logger: zap.NewNop()}, provider.Config(common.Config{})))
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(c.Locals(web.JwtKey).(*jwt.Token).Claims.(*web.IdmClaims).Subject)
+	})
+	var call = func(token string) int {
+		request := httptest.NewRequest(fiber.MethodGet, "/", nil)
+		request.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(request)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+	t.Run("password grant", func(t *testing.T) {
+		token, err := provider.Login("alice", "secret")
+		a.Nil(err)
+		a.Equal(http.StatusOK, call(token))
+	})
+	t.Run("wrong password", func(t *testing.T) {
+		_, err := provider.Login("alice", "wrong")
+		a.ErrorContains(err, "invalid_grant")
+	})
+	t.Run("rotated key is fetched, dropped key is rejected", func(t *testing.T) {
+		var old = provider.Token("kc-alice", web.IdmAdmin)
+		provider.Rotate(false)
+		a.Equal(http.StatusOK, call(provider.Token("kc-alice", web.IdmAdmin)))
+		a.Equal(http.StatusUnauthorized, call(old))
+	})
+}
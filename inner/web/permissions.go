@@ -0,0 +1,110 @@
+package web
+
+import (
+	"slices"
+)
+
+// PermissionBreakGlass право запросить экстренный доступ; его дают не роли IDM, а роль дежурных из настроек
+const PermissionBreakGlass = "break-glass:activate"
+
+// права, которые дают роли IDM; список соответствует проверкам ролей в контроллерах
+var rolePermissions = map[string][]string{
+	IdmAdmin: {
+		"employee:create",
+		"employee:read",
+		"employee:delete",
+		"employee:link-subject",
+		"employee:update-department",
+		"employee:assign-role",
+		"role:manage",
+		"role:sensitivity",
+		"relation:manage",
+		"delegation:manage",
+		"birthright:manage",
+		"break-glass:read",
+		"certification:manage",
+		"certification:review",
+		"access-request:read",
+		"access:explain",
+		"policy:reload",
+		"application:manage",
+		"application:read",
+		"account:import",
+		"account:read",
+		"reconciliation:manage",
+		"api-key:manage",
+	},
+	IdmUser: {
+		"employee:read",
+		"certification:review",
+		"access-request:read",
+		"access:explain",
+		"application:read",
+		"account:read",
+	},
+}
+
+// права, которые дают делегирования на подразделение
+var departmentPermissions = []string{
+	"employee:create",
+	"employee:delete",
+	"employee:link-subject",
+	"employee:update-department",
+}
+
+// права, которые дают делегирования набора ролей: только назначение и отзыв этих ролей
+var roleSetPermissions = []string{
+	"employee:assign-role",
+}
+
+// права любого аутентифицированного пользователя
+var commonPermissions = []string{
+	"me:read",
+	"role:read",
+	"access-request:create",
+}
+
+// Permissions вернуть отсортированный список прав, которые дают роли из токена,
+// вместе с правами granted, полученными иначе: делегированием или экстренным доступом
+func Permissions(roles []string, granted ...string) []string {
+	var permissions = slices.Concat(commonPermissions, granted)
+	for _, role := range roles {
+		permissions = append(permissions, rolePermissions[role]...)
+	}
+	slices.Sort(permissions)
+	return slices.Compact(permissions)
+}
+
+// DelegatedPermissions права, которые дают делегирования на подразделения и наборы ролей
+func DelegatedPermissions(departments bool, roleSets bool) []string {
+	var permissions []string
+	if departments {
+		permissions = append(permissions, departmentPermissions...)
+	}
+	if roleSets {
+		permissions = append(permissions, roleSetPermissions...)
+	}
+	return permissions
+}
+
+// RolesWithPermission роли IDM, которые дают право permission
+func RolesWithPermission(permission string) []string {
+	var roles []string
+	for role, permissions := range rolePermissions {
+		if slices.Contains(permissions, permission) {
+			roles = append(roles, role)
+		}
+	}
+	slices.Sort(roles)
+	return roles
+}
+
+// DepartmentDelegated можно ли получить право permission через делегирование на подразделение
+func DepartmentDelegated(permission string) bool {
+	return slices.Contains(departmentPermissions, permission)
+}
+
+// RoleSetDelegated можно ли получить право permission через делегирование набора ролей
+func RoleSetDelegated(permission string) bool {
+	return slices.Contains(roleSetPermissions, permission)
+}
@@ -0,0 +1,69 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPermissions(t *testing.T) {
+	var a = assert.New(t)
+	a.Equal([]string{"access-request:create", "me:read", "role:read"}, Permissions(nil))
+	a.Equal([]string{
+		"access-request:create",
+		"access-request:read",
+		"access:explain",
+		"account:read",
+		"application:read",
+		"certification:review",
+		"employee:read",
+		"me:read",
+		"role:read",
+	}, Permissions([]string{IdmUser, "OTHER"}))
+	a.Equal([]string{
+		"access-request:create",
+		"access-request:read",
+		"access:explain",
+		"account:import",
+		"account:read",
+		"api-key:manage",
+		"application:manage",
+		"application:read",
+		"birthright:manage",
+		"break-glass:read",
+		"certification:manage",
+		"certification:review",
+		"delegation:manage",
+		"employee:assign-role",
+		"employee:create",
+		"employee:delete",
+		"employee:link-subject",
+		"employee:read",
+		"employee:update-department",
+		"me:read",
+		"policy:reload",
+		"reconciliation:manage",
+		"relation:manage",
+		"role:manage",
+		"role:read",
+		"role:sensitivity",
+	}, Permissions([]string{IdmAdmin, IdmUser}))
+	a.Equal([]string{
+		"access-request:create",
+		"break-glass:activate",
+		"employee:assign-role",
+		"me:read",
+		"role:read",
+	}, Permissions(nil, append(DelegatedPermissions(false, true), PermissionBreakGlass)...))
+	a.Nil(DelegatedPermissions(false, false))
+}
+
+func TestRolesWithPermission(t *testing.T) {
+	var a = assert.New(t)
+	a.Equal([]string{IdmAdmin, IdmUser}, RolesWithPermission("employee:read"))
+	a.Equal([]string{IdmAdmin}, RolesWithPermission("employee:delete"))
+	a.Empty(RolesWithPermission("GitLab:push"))
+	a.True(DepartmentDelegated("employee:delete"))
+	a.False(RoleSetDelegated("employee:delete"))
+	a.True(RoleSetDelegated("employee:assign-role"))
+	a.False(DepartmentDelegated("policy:reload"))
+}
@@ -0,0 +1,83 @@
+package web
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+// DefaultRoleClaim путь к ролям в токене Keycloak
+const DefaultRoleClaim = "realm_access.roles"
+
+// RoleMapping как получить роли IDM из claims токена
+type RoleMapping struct {
+	// Claims пути к ролям через точку: realm_access.roles, resource_access.<client>.roles, groups и т.п.;
+	// пустой список - только realm_access.roles
+	Claims []string
+	// StripPrefix префикс, который отрезается от ролей, например "/idm/" у групп
+	StripPrefix string
+	// Rename переименование ролей (после отрезания префикса) в роли IDM
+	Rename map[string]string
+}
+
+// Map роли IDM из всех путей Claims, без повторов
+func (m RoleMapping) Map(claims *IdmClaims) []string {
+	var paths = m.Claims
+	if len(paths) == 0 {
+		paths = []string{DefaultRoleClaim}
+	}
+	var roles []string
+	for _, path := range paths {
+		for _, role := range claims.values(path) {
+			role = strings.TrimPrefix(role, m.StripPrefix)
+			if renamed, found := m.Rename[role]; found {
+				role = renamed
+			}
+			if role != "" && !slices.Contains(roles, role) {
+				roles = append(roles, role)
+			}
+		}
+	}
+	return roles
+}
+
+// UnmarshalJSON помимо известных полей сохранить все claims, чтобы роли можно было взять по любому пути
+func (c *IdmClaims) UnmarshalJSON(data []byte) error {
+	type plain IdmClaims
+	if err := json.Unmarshal(data, (*plain)(c)); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &c.raw)
+}
+
+// values строки по пути через точку: список строк или одна строка.
+// У claims, собранных в коде, а не разобранных из токена, есть только realm_access.roles
+func (c *IdmClaims) values(path string) []string {
+	if c.raw == nil {
+		if path == DefaultRoleClaim {
+			return c.RealmAccess.Roles
+		}
+		return nil
+	}
+	var value any = c.raw
+	for _, name := range strings.Split(path, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = object[name]
+	}
+	switch value := value.(type) {
+	case string:
+		return []string{value}
+	case []any:
+		var values []string
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package web
+
+import (
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestRoleMapping(t *testing.T) {
+	var a = assert.New(t)
+	var parse = func(raw string) *IdmClaims {
+		var claims IdmClaims
+		require.NoError(t, claims.UnmarshalJSON([]byte(raw)))
+		return &claims
+	}
+	var claims = parse(`{
+		"sub": "alice",
+		"realm_access": {"roles": ["offline_access", "IDM_USER"]},
+		"resource_access": {"idm": {"roles": ["idm-admin"]}, "account": {"roles": ["view-profile"]}},
+		"groups": ["/idm/approvers", "/idm/IDM_USER", "/other/team"],
+		"ext": {"idm": {"role": "idm-auditor"}}
+	}`)
+	t.Run("realm roles by default", func(t *testing.T) {
+		a.Equal([]string{"offline_access", IdmUser}, RoleMapping{}.Map(claims))
+	})
+	t.Run("client roles, groups and custom path with prefix and renames", func(t *testing.T) {
+		var mapping = RoleMapping{
+			Claims:      []string{"resource_access.idm.roles", "groups", "ext.idm.role"},
+			StripPrefix: "/idm/",
+			Rename:      map[string]string{"idm-admin": IdmAdmin, "approvers": "IDM_APPROVER"},
+		}
+		a.Equal([]string{IdmAdmin, "IDM_APPROVER", IdmUser, "/other/team", "idm-auditor"}, mapping.Map(claims))
+	})
+	t.Run("missing or non-string claims give no roles", func(t *testing.T) {
+		var mapping = RoleMapping{Claims: []string{"resource_access.crm.roles", "sub.roles", "realm_access"}}
+		a.Empty(mapping.Map(claims))
+	})
+	t.Run("claims built in code keep their roles", func(t *testing.T) {
+		var built = &IdmClaims{RealmAccess: RealmAccessClaims{Roles: []string{IdmAdmin}}}
+		a.Equal([]string{IdmAdmin}, RoleMapping{}.Map(built))
+		a.Empty(RoleMapping{Claims: []string{"groups"}}.Map(built))
+	})
+	t.Run("parsed token gets mapped roles", func(t *testing.T) {
+		var key = []byte("0123456789abcdef0123456789abcdef")
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"sub":             "alice",
+			"resource_access": map[string]any{"idm": map[string]any{"roles": []string{"idm-admin"}}},
+		}).SignedString(key)
+		require.NoError(t, err)
+		var rules = TokenRules{
+			Algorithms: []string{"HS256"},
+			Roles: RoleMapping{
+				Claims: []string{DefaultRoleClaim, "resource_access.idm.roles"},
+				Rename: map[string]string{"idm-admin": IdmAdmin},
+			},
+		}
+		token, err := rules.Parse(signed, func(token *jwt.Token) (interface{}, error) { return key, nil })
+		require.NoError(t, err)
+		a.Equal([]string{IdmAdmin}, token.Claims.(*IdmClaims).RealmAccess.Roles)
+	})
+}
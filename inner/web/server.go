@@ -2,7 +2,11 @@ package web
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/helmet"
 	_ "idm/docs"
+	"idm/inner/common"
+	"strings"
 )
 
 type Server struct {
@@ -16,7 +20,37 @@ type AuthMiddlewareInterface interface {
 }
 
 func NewServer() *Server {
-	app := fiber.New()
+	return newServer(fiber.New())
+}
+
+// NewServerWithCfg сервер с ограничениями запросов, заголовками безопасности и CORS из cfg.
+// Заголовки и CORS подключаются ко всему приложению, поэтому действуют и для /api, и для /internal, и для /swagger
+func NewServerWithCfg(cfg common.Config) *Server {
+	app := fiber.New(fiber.Config{
+		BodyLimit:      cfg.BodyLimit,
+		ReadBufferSize: cfg.HeaderLimit,
+		ReadTimeout:    cfg.ReadTimeout,
+		WriteTimeout:   cfg.WriteTimeout,
+		IdleTimeout:    cfg.IdleTimeout,
+	})
+	app.Use(helmet.New(helmet.Config{
+		ContentSecurityPolicy: cfg.ContentSecurityPolicy,
+		HSTSMaxAge:            int(cfg.HstsMaxAge.Seconds()),
+	}))
+	if len(cfg.CorsAllowOrigins) > 0 {
+		app.Use(cors.New(cors.Config{
+			AllowOrigins:     strings.Join(cfg.CorsAllowOrigins, ","),
+			AllowMethods:     strings.Join(cfg.CorsAllowMethods, ","),
+			AllowHeaders:     strings.Join(cfg.CorsAllowHeaders, ","),
+			AllowCredentials: cfg.CorsAllowCredentials,
+			ExposeHeaders:    "X-Request-ID,Retry-After,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset",
+			MaxAge:           int(cfg.CorsMaxAge.Seconds()),
+		}))
+	}
+	return newServer(app)
+}
+
+func newServer(app *fiber.App) *Server {
 	groupInternal := app.Group("/internal")
 	groupApi := app.Group("/api")
 	groupApiV1 := groupApi.Group("/v1")
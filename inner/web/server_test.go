@@ -5,10 +5,14 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/gofiber/fiber/v2/middleware/requestid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"idm/inner/common"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestRecoverMiddleware(t *testing.T) {
@@ -65,3 +69,80 @@ func TestRequestMiddleware(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, requestID, string(body))
 }
+
+func TestNewServerWithCfg(t *testing.T) {
+	var a = assert.New(t)
+	var cfg = common.Config{
+		CorsAllowOrigins:      []string{"https://portal.example.com"},
+		CorsAllowMethods:      []string{"GET", "POST"},
+		CorsAllowHeaders:      []string{"Authorization", "Content-Type"},
+		CorsMaxAge:            10 * time.Minute,
+		HstsMaxAge:            24 * time.Hour,
+		ContentSecurityPolicy: "default-src 'self'",
+		BodyLimit:             16,
+		HeaderLimit:           8 * 1024,
+		ReadTimeout:           5 * time.Second,
+		WriteTimeout:          5 * time.Second,
+		IdleTimeout:           time.Minute,
+	}
+	var server = NewServerWithCfg(cfg)
+	var ok = func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) }
+	server.GroupApiV1.Post("/employees", ok)
+	server.GroupInternal.Get("/health", ok)
+	server.App.Get("/swagger/*", ok)
+	var call = func(request *http.Request) *http.Response {
+		resp, err := server.App.Test(request)
+		require.NoError(t, err)
+		return resp
+	}
+	t.Run("security headers on every group", func(t *testing.T) {
+		for _, path := range []string{"/internal/health", "/swagger/index.html"} {
+			request := httptest.NewRequest(http.MethodGet, path, nil)
+			request.Header.Set(fiber.HeaderXForwardedProto, "https")
+			resp := call(request)
+			a.Equal(http.StatusOK, resp.StatusCode)
+			a.Equal("default-src 'self'", resp.Header.Get(fiber.HeaderContentSecurityPolicy))
+			a.Equal("max-age=86400; includeSubDomains", resp.Header.Get(fiber.HeaderStrictTransportSecurity))
+			a.Equal("nosniff", resp.Header.Get(fiber.HeaderXContentTypeOptions))
+		}
+		resp := call(httptest.NewRequest(http.MethodPost, "/api/v1/employees", strings.NewReader("{}")))
+		a.Equal("default-src 'self'", resp.Header.Get(fiber.HeaderContentSecurityPolicy))
+		a.Empty(resp.Header.Get(fiber.HeaderStrictTransportSecurity))
+	})
+	t.Run("CORS preflight for allowed origin only", func(t *testing.T) {
+		var preflight = func(origin string) *http.Response {
+			request := httptest.NewRequest(http.MethodOptions, "/api/v1/employees", nil)
+			request.Header.Set(fiber.HeaderOrigin, origin)
+			request.Header.Set(fiber.HeaderAccessControlRequestMethod, http.MethodPost)
+			return call(request)
+		}
+		resp := preflight("https://portal.example.com")
+		a.Equal(http.StatusNoContent, resp.StatusCode)
+		a.Equal("https://portal.example.com", resp.Header.Get(fiber.HeaderAccessControlAllowOrigin))
+		a.Equal("GET,POST", resp.Header.Get(fiber.HeaderAccessControlAllowMethods))
+		a.Equal("600", resp.Header.Get(fiber.HeaderAccessControlMaxAge))
+		resp = preflight("https://evil.example.com")
+		a.Empty(resp.Header.Get(fiber.HeaderAccessControlAllowOrigin))
+	})
+	t.Run("body limit and timeouts", func(t *testing.T) {
+		// сервер закрывает соединение, не дочитывая тело
+		_, err := server.App.Test(httptest.NewRequest(http.MethodPost, "/api/v1/employees",
+			strings.NewReader(`{"name": "too long body"}`)))
+		a.ErrorContains(err, "body size exceeds the given limit")
+		var config = server.App.Config()
+		a.Equal(8*1024, config.ReadBufferSize)
+		a.Equal(5*time.Second, config.ReadTimeout)
+		a.Equal(5*time.Second, config.WriteTimeout)
+		a.Equal(time.Minute, config.IdleTimeout)
+	})
+	t.Run("CORS is off without origins", func(t *testing.T) {
+		var closed = NewServerWithCfg(common.Config{})
+		closed.GroupApiV1.Post("/employees", ok)
+		request := httptest.NewRequest(http.MethodOptions, "/api/v1/employees", nil)
+		request.Header.Set(fiber.HeaderOrigin, "https://portal.example.com")
+		request.Header.Set(fiber.HeaderAccessControlRequestMethod, http.MethodPost)
+		resp, err := closed.App.Test(request)
+		require.NoError(t, err)
+		a.Empty(resp.Header.Get(fiber.HeaderAccessControlAllowOrigin))
+	})
+}
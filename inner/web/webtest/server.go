@@ -0,0 +1,23 @@
+// Package webtest сервер для тестов контроллеров: вместо проверки токена в контекст запроса
+// кладутся готовые claims
+package webtest
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"idm/inner/web"
+)
+
+// NewServer сервер, в котором каждый запрос к /api/v1 выполняется от пользователя subject с ролями roles
+func NewServer(subject string, roles ...string) *web.Server {
+	var claims = &web.IdmClaims{
+		RealmAccess:      web.RealmAccessClaims{Roles: roles},
+		RegisteredClaims: jwt.RegisteredClaims{Subject: subject},
+	}
+	server := web.NewServer()
+	server.GroupApiV1.Use(func(c *fiber.Ctx) error {
+		c.Locals(web.JwtKey, &jwt.Token{Claims: claims})
+		return c.Next()
+	})
+	return server
+}
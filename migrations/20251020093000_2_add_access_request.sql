@@ -0,0 +1,55 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE role
+    ADD COLUMN owner TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE employee_role
+(
+    id          BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    employee_id BIGINT REFERENCES employee (id) ON DELETE CASCADE NOT NULL,
+    role_id     BIGINT REFERENCES role (id) ON DELETE CASCADE     NOT NULL,
+    source      TEXT                                              NOT NULL,
+    created_by  TEXT                                              NOT NULL,
+    created_at  TIMESTAMPTZ                                       NOT NULL DEFAULT NOW(),
+    UNIQUE (employee_id, role_id, source)
+);
+
+CREATE INDEX employee_role_role_id_idx ON employee_role (role_id);
+
+CREATE TABLE access_request
+(
+    id            BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    employee_id   BIGINT REFERENCES employee (id) ON DELETE CASCADE NOT NULL,
+    role_id       BIGINT REFERENCES role (id) ON DELETE CASCADE     NOT NULL,
+    justification TEXT                                              NOT NULL,
+    state         TEXT                                              NOT NULL DEFAULT 'pending',
+    requested_by  TEXT                                              NOT NULL,
+    decided_by    TEXT                                              NOT NULL DEFAULT '',
+    expires_at    TIMESTAMPTZ                                       NOT NULL,
+    created_at    TIMESTAMPTZ                                       NOT NULL DEFAULT NOW(),
+    updated_at    TIMESTAMPTZ                                       NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX access_request_state_idx ON access_request (state, expires_at);
+
+CREATE TABLE access_request_history
+(
+    id         BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    request_id BIGINT REFERENCES access_request (id) ON DELETE CASCADE NOT NULL,
+    state      TEXT                                                    NOT NULL,
+    actor      TEXT                                                    NOT NULL,
+    comment    TEXT                                                    NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ                                             NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX access_request_history_request_id_idx ON access_request_history (request_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS access_request_history;
+DROP TABLE IF EXISTS access_request;
+DROP TABLE IF EXISTS employee_role;
+ALTER TABLE role
+    DROP COLUMN IF EXISTS owner;
+-- +goose StatementEnd
@@ -0,0 +1,52 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE employee
+    ADD COLUMN department TEXT NOT NULL DEFAULT '';
+
+-- основная роль сотрудника тоже хранится как назначение, чтобы её можно было пересмотреть и отозвать
+INSERT INTO employee_role (employee_id, role_id, source, created_by)
+SELECT id, role_id, 'primary', 'system'
+FROM employee
+ON CONFLICT (employee_id, role_id, source) DO NOTHING;
+
+CREATE TABLE certification_campaign
+(
+    id          BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    name        TEXT        NOT NULL,
+    scope_type  TEXT        NOT NULL,
+    scope_value TEXT        NOT NULL,
+    reviewer    TEXT        NOT NULL,
+    state       TEXT        NOT NULL DEFAULT 'open',
+    due_at      TIMESTAMPTZ NOT NULL,
+    created_by  TEXT        NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    closed_by   TEXT        NOT NULL DEFAULT '',
+    closed_at   TIMESTAMPTZ
+);
+
+CREATE TABLE certification_item
+(
+    id          BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    campaign_id BIGINT REFERENCES certification_campaign (id) ON DELETE CASCADE NOT NULL,
+    employee_id BIGINT REFERENCES employee (id) ON DELETE CASCADE               NOT NULL,
+    role_id     BIGINT REFERENCES role (id) ON DELETE CASCADE                   NOT NULL,
+    reviewer    TEXT                                                            NOT NULL,
+    decision    TEXT                                                            NOT NULL DEFAULT 'pending',
+    comment     TEXT                                                            NOT NULL DEFAULT '',
+    decided_by  TEXT                                                            NOT NULL DEFAULT '',
+    decided_at  TIMESTAMPTZ,
+    applied     BOOLEAN                                                         NOT NULL DEFAULT FALSE,
+    UNIQUE (campaign_id, employee_id, role_id)
+);
+
+CREATE INDEX certification_item_reviewer_idx ON certification_item (campaign_id, reviewer);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS certification_item;
+DROP TABLE IF EXISTS certification_campaign;
+DELETE FROM employee_role WHERE source = 'primary';
+ALTER TABLE employee
+    DROP COLUMN IF EXISTS department;
+-- +goose StatementEnd
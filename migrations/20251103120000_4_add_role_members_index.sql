@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- покрывающий индекс для выборки сотрудников по роли
+CREATE INDEX employee_role_role_id_employee_id_idx ON employee_role (role_id, employee_id);
+DROP INDEX IF EXISTS employee_role_role_id_idx;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE INDEX employee_role_role_id_idx ON employee_role (role_id);
+DROP INDEX IF EXISTS employee_role_role_id_employee_id_idx;
+-- +goose StatementEnd
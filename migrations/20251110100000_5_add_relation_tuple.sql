@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- отношения (subject, relation, object), например (user:alice, owner, role:42) или (user:bob, manager, employee:7)
+CREATE TABLE IF NOT EXISTS relation_tuple
+(
+    id         BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    subject    TEXT        NOT NULL,
+    relation   TEXT        NOT NULL,
+    object     TEXT        NOT NULL,
+    created_by TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (subject, relation, object)
+);
+-- проверка идёт от объекта к субъекту
+CREATE INDEX relation_tuple_object_relation_idx ON relation_tuple (object, relation);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS relation_tuple;
+-- +goose StatementEnd
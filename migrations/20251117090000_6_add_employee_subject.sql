@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- идентификатор пользователя в Keycloak (claim sub); пустая строка - сотрудник не привязан
+ALTER TABLE employee ADD COLUMN subject TEXT NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX employee_subject_idx ON employee (subject) WHERE subject <> '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS employee_subject_idx;
+ALTER TABLE employee DROP COLUMN IF EXISTS subject;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- делегированное администрирование: subject управляет сотрудниками подразделения либо набором ролей до expires_at
+CREATE TABLE IF NOT EXISTS delegation
+(
+    id         BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    subject    TEXT        NOT NULL,
+    department TEXT        NOT NULL DEFAULT '',
+    role_ids   BIGINT[]    NOT NULL DEFAULT '{}',
+    granted_by TEXT        NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_by TEXT        NOT NULL DEFAULT '',
+    revoked_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT delegation_scope_check CHECK ((department = '') <> (cardinality(role_ids) = 0))
+);
+CREATE INDEX IF NOT EXISTS delegation_subject_idx ON delegation (subject);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS delegation;
+-- +goose StatementEnd
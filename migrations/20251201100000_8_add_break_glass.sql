@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+-- экстренный доступ: привилегированная роль выдаётся до expires_at и отзывается фоновой задачей
+CREATE TABLE IF NOT EXISTS break_glass
+(
+    id          BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    employee_id BIGINT REFERENCES employee (id) ON DELETE CASCADE NOT NULL,
+    role_id     BIGINT REFERENCES role (id) ON DELETE CASCADE     NOT NULL,
+    subject     TEXT                                              NOT NULL,
+    reason      TEXT                                              NOT NULL,
+    expires_at  TIMESTAMPTZ                                       NOT NULL,
+    revoked_by  TEXT                                              NOT NULL DEFAULT '',
+    revoked_at  TIMESTAMPTZ                                       NULL,
+    created_at  TIMESTAMPTZ                                       NOT NULL DEFAULT NOW()
+);
+CREATE INDEX break_glass_active_idx ON break_glass (expires_at) WHERE revoked_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS audit_event
+(
+    id         BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    severity   TEXT        NOT NULL,
+    action     TEXT        NOT NULL,
+    actor      TEXT        NOT NULL,
+    object     TEXT        NOT NULL,
+    details    TEXT        NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX audit_event_created_at_idx ON audit_event (created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS audit_event;
+DROP TABLE IF EXISTS break_glass;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- правила выдачи ролей по атрибутам: сотрудники подразделения department (пустое - все) получают role_id;
+-- выданные правилами роли хранятся в employee_role с source = 'birthright'
+CREATE TABLE IF NOT EXISTS birthright_rule
+(
+    id         BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    department TEXT                                          NOT NULL DEFAULT '',
+    role_id    BIGINT REFERENCES role (id) ON DELETE CASCADE NOT NULL,
+    created_by TEXT                                          NOT NULL,
+    created_at TIMESTAMPTZ                                   NOT NULL DEFAULT NOW(),
+    UNIQUE (department, role_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM employee_role WHERE source = 'birthright';
+DROP TABLE IF EXISTS birthright_rule;
+-- +goose StatementEnd
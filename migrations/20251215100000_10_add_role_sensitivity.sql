@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE role
+    ADD COLUMN sensitivity TEXT NOT NULL DEFAULT 'normal' CHECK (sensitivity IN ('normal', 'privileged', 'critical'));
+
+-- пары ролей, которые нельзя совмещать (SoD); пара хранится один раз, меньший id первым
+CREATE TABLE IF NOT EXISTS role_conflict
+(
+    id                  BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    role_id             BIGINT REFERENCES role (id) ON DELETE CASCADE NOT NULL,
+    conflicting_role_id BIGINT REFERENCES role (id) ON DELETE CASCADE NOT NULL,
+    created_at          TIMESTAMPTZ                                   NOT NULL DEFAULT NOW(),
+    UNIQUE (role_id, conflicting_role_id),
+    CHECK (role_id < conflicting_role_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS role_conflict;
+ALTER TABLE role
+    DROP COLUMN IF EXISTS sensitivity;
+-- +goose StatementEnd
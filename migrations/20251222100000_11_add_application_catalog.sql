@@ -0,0 +1,44 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS application
+(
+    id          BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    name        TEXT        NOT NULL UNIQUE,
+    description TEXT        NOT NULL DEFAULT '',
+    owner       TEXT        NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- права, которые предоставляет приложение: группы или отдельные разрешения
+CREATE TABLE IF NOT EXISTS entitlement
+(
+    id             BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    application_id BIGINT REFERENCES application (id) ON DELETE CASCADE NOT NULL,
+    name           TEXT                                                 NOT NULL,
+    type           TEXT                                                 NOT NULL CHECK (type IN ('group', 'permission')),
+    description    TEXT                                                 NOT NULL DEFAULT '',
+    created_at     TIMESTAMPTZ                                          NOT NULL DEFAULT NOW(),
+    UNIQUE (application_id, name)
+);
+
+-- права, которые входят в роль
+CREATE TABLE IF NOT EXISTS role_entitlement
+(
+    id             BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    role_id        BIGINT REFERENCES role (id) ON DELETE CASCADE        NOT NULL,
+    entitlement_id BIGINT REFERENCES entitlement (id) ON DELETE CASCADE NOT NULL,
+    created_by     TEXT                                                 NOT NULL,
+    created_at     TIMESTAMPTZ                                          NOT NULL DEFAULT NOW(),
+    UNIQUE (role_id, entitlement_id)
+);
+
+CREATE INDEX role_entitlement_entitlement_idx ON role_entitlement (entitlement_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS role_entitlement;
+DROP TABLE IF EXISTS entitlement;
+DROP TABLE IF EXISTS application;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+-- учётные записи сотрудников в приложениях; last_employee_id остаётся после удаления сотрудника,
+-- чтобы отличать учётные записи уволенных от никогда не связанных
+CREATE TABLE IF NOT EXISTS account
+(
+    id               BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    application_id   BIGINT REFERENCES application (id) ON DELETE CASCADE NOT NULL,
+    login            TEXT                                                 NOT NULL,
+    employee_id      BIGINT REFERENCES employee (id) ON DELETE SET NULL,
+    last_employee_id BIGINT,
+    status           TEXT                                                 NOT NULL CHECK (status IN ('active', 'disabled')),
+    last_login_at    TIMESTAMPTZ,
+    last_sync_at     TIMESTAMPTZ                                          NOT NULL DEFAULT NOW(),
+    created_at       TIMESTAMPTZ                                          NOT NULL DEFAULT NOW(),
+    UNIQUE (application_id, login)
+);
+
+CREATE INDEX account_employee_idx ON account (employee_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS account;
+-- +goose StatementEnd
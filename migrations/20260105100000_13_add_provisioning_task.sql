@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- задачи на исправление расхождений, найденных сверкой выгрузки приложения с ролями
+CREATE TABLE IF NOT EXISTS provisioning_task
+(
+    id             BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    application_id BIGINT REFERENCES application (id) ON DELETE CASCADE NOT NULL,
+    employee_id    BIGINT REFERENCES employee (id) ON DELETE SET NULL,
+    login          TEXT                                                 NOT NULL DEFAULT '',
+    entitlement    TEXT                                                 NOT NULL DEFAULT '',
+    action         TEXT                                                 NOT NULL CHECK (action IN ('grant', 'revoke', 'enable')),
+    state          TEXT                                                 NOT NULL DEFAULT 'open',
+    created_by     TEXT                                                 NOT NULL,
+    created_at     TIMESTAMPTZ                                          NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX provisioning_task_application_idx ON provisioning_task (application_id, state);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS provisioning_task;
+-- +goose StatementEnd
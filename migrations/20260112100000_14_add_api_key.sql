@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+-- ключи для сервисных клиентов: хранится только солёный хеш секрета, prefix - открытая часть ключа для поиска
+CREATE TABLE IF NOT EXISTS api_key
+(
+    id           BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    name         TEXT        NOT NULL,
+    owner        TEXT        NOT NULL,
+    prefix       TEXT        NOT NULL UNIQUE,
+    salt         TEXT        NOT NULL,
+    hash         TEXT        NOT NULL,
+    scopes       TEXT[]      NOT NULL DEFAULT '{}',
+    expires_at   TIMESTAMPTZ NOT NULL,
+    last_used_at TIMESTAMPTZ NULL,
+    revoked_by   TEXT        NOT NULL DEFAULT '',
+    revoked_at   TIMESTAMPTZ NULL,
+    created_by   TEXT        NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS api_key;
+-- +goose StatementEnd
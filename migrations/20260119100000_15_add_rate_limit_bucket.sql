@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- корзины token bucket для лимитов запросов, общие для всех реплик
+CREATE TABLE IF NOT EXISTS rate_limit_bucket
+(
+    key        TEXT PRIMARY KEY,
+    tokens     DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMPTZ      NOT NULL
+);
+CREATE INDEX IF NOT EXISTS rate_limit_bucket_updated_at_idx ON rate_limit_bucket (updated_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS rate_limit_bucket;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- у сотрудника не больше одного действующего экстренного доступа
+CREATE UNIQUE INDEX IF NOT EXISTS break_glass_active_employee_idx ON break_glass (employee_id) WHERE revoked_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS break_glass_active_employee_idx;
+-- +goose StatementEnd
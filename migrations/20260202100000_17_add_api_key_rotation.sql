@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- кто и когда последним заменил секрет ключа
+ALTER TABLE api_key ADD COLUMN IF NOT EXISTS rotated_by TEXT NOT NULL DEFAULT '';
+ALTER TABLE api_key ADD COLUMN IF NOT EXISTS rotated_at TIMESTAMPTZ NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE api_key DROP COLUMN IF EXISTS rotated_at;
+ALTER TABLE api_key DROP COLUMN IF EXISTS rotated_by;
+-- +goose StatementEnd
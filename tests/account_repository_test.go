@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"idm/inner/account"
+	"idm/inner/application"
+	"idm/inner/database"
+	"idm/inner/employee"
+	"idm/inner/role"
+	"idm/inner/validator"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAccountImport(t *testing.T) {
+	a := assert.New(t)
+	var db = database.ConnectDb()
+	var clearDatabase = func() {
+		db.MustExec("DELETE FROM application")
+		db.MustExec("DELETE FROM employee")
+		db.MustExec("DELETE FROM role")
+	}
+	defer clearDatabase()
+	var emplFixture = Fixture{employees: employee.NewRepository(db), db: db}
+	_ = emplFixture.CreateDatabase(db)
+	data, _ := os.ReadFile("./scripts/application.sql")
+	db.MustExec(string(data))
+	var roleId = NewRoleFixture(role.NewRepository(db)).Role("Developer")
+	var v = validator.New()
+	var ctx = context.Background()
+	app, err := application.NewService(application.NewRepository(db), v).
+		Create(ctx, application.CreateRequest{Name: "GitLab"})
+	a.Nil(err)
+	var accounts = account.NewService(account.NewRepository(db), v, 30*24*time.Hour)
+	var ownerId = emplFixture.Employee("Test Owner", roleId)
+	var leaverId = emplFixture.Employee("Test Leaver", roleId)
+	var recently = time.Now().Add(-time.Hour)
+	got, err := accounts.Import(ctx, account.ImportRequest{
+		ApplicationId: app.Id,
+		Rows: []account.ImportRow{
+			{Login: "owner", EmployeeId: ownerId, Status: account.StatusActive, LastLoginAt: &recently},
+			{Login: "leaver", EmployeeId: leaverId, Status: account.StatusActive, LastLoginAt: &recently},
+			{Login: "svc-backup", Status: account.StatusActive},
+		},
+	})
+	a.Nil(err)
+	a.Equal(account.ImportResponse{Total: 3, Linked: 2, Unlinked: 1}, got)
+	db.MustExec("DELETE FROM employee WHERE id = $1", leaverId)
+	t.Run("orphans include unowned and terminated accounts", func(t *testing.T) {
+		orphans, err := accounts.FindOrphans()
+		a.Nil(err)
+		var reasons = map[string]string{}
+		for _, item := range orphans {
+			reasons[item.Login] = item.OrphanReason
+		}
+		a.Equal(map[string]string{"leaver": account.OrphanTerminated, "svc-backup": account.OrphanUnowned}, reasons)
+	})
+	t.Run("re-import without owner columns keeps the link", func(t *testing.T) {
+		got, err := accounts.Import(ctx, account.ImportRequest{
+			ApplicationId: app.Id,
+			Rows:          []account.ImportRow{{Login: "owner", Status: account.StatusActive}},
+		})
+		a.Nil(err)
+		a.Equal(account.ImportResponse{Total: 1, Linked: 1}, got)
+		owned, err := accounts.FindByEmployee(account.IdRequest{Id: ownerId})
+		a.Nil(err)
+		a.Len(owned, 1)
+	})
+	t.Run("accounts without recent logins are dormant", func(t *testing.T) {
+		db.MustExec("UPDATE account SET created_at = NOW() - INTERVAL '60 days'")
+		dormant, err := accounts.FindDormant()
+		a.Nil(err)
+		a.Len(dormant, 1)
+		a.Equal("svc-backup", dormant[0].Login)
+	})
+}
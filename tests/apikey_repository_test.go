@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"idm/inner/apikey"
+	"idm/inner/database"
+	"idm/inner/validator"
+	"idm/inner/web"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestApiKeyLifecycle(t *testing.T) {
+	a := assert.New(t)
+	var db = database.ConnectDb()
+	defer db.MustExec("DELETE FROM api_key")
+	data, _ := os.ReadFile("./scripts/api_key.sql")
+	db.MustExec(string(data))
+	var keys = apikey.NewService(apikey.NewRepository(db), validator.New())
+	var ctx = context.Background()
+	created, err := keys.Create(ctx, apikey.CreateRequest{
+		Name:      "ci",
+		Owner:     "platform-team",
+		Scopes:    []string{web.IdmUser},
+		ExpiresAt: time.Now().Add(time.Hour),
+		CreatedBy: "admin",
+	})
+	a.Nil(err)
+	t.Run("key authenticates and usage is tracked", func(t *testing.T) {
+		claims, err := keys.Authenticate(ctx, created.Key)
+		a.Nil(err)
+		a.Equal([]string{web.IdmUser}, claims.RealmAccess.Roles)
+		found, err := keys.FindAll()
+		a.Nil(err)
+		a.Len(found, 1)
+		a.NotNil(found[0].LastUsedAt)
+		a.True(found[0].Active)
+	})
+	t.Run("rotation invalidates previous key", func(t *testing.T) {
+		rotated, err := keys.Rotate(ctx, apikey.RotateRequest{Id: created.Id, RotatedBy: "admin"})
+		a.Nil(err)
+		_, err = keys.Authenticate(ctx, created.Key)
+		a.ErrorIs(err, apikey.ErrInvalidKey)
+		_, err = keys.Authenticate(ctx, rotated.Key)
+		a.Nil(err)
+		found, err := keys.FindAll()
+		a.Nil(err)
+		a.Equal("admin", found[0].RotatedBy)
+		a.NotNil(found[0].RotatedAt)
+		a.Nil(keys.Revoke(ctx, apikey.RevokeRequest{Id: created.Id, RevokedBy: "admin"}))
+		_, err = keys.Authenticate(ctx, rotated.Key)
+		a.ErrorIs(err, apikey.ErrRevokedKey)
+	})
+}
@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"idm/inner/application"
+	"idm/inner/database"
+	"idm/inner/employee"
+	"idm/inner/role"
+	"idm/inner/validator"
+	"os"
+	"testing"
+)
+
+func TestApplicationEntitlements(t *testing.T) {
+	a := assert.New(t)
+	var db = database.ConnectDb()
+	var clearDatabase = func() {
+		db.MustExec("DELETE FROM application")
+		db.MustExec("DELETE FROM employee")
+		db.MustExec("DELETE FROM role")
+	}
+	defer clearDatabase()
+	var emplFixture = Fixture{employees: employee.NewRepository(db), db: db}
+	_ = emplFixture.CreateDatabase(db)
+	data, _ := os.ReadFile("./scripts/application.sql")
+	db.MustExec(string(data))
+	var roleFixture = NewRoleFixture(role.NewRepository(db))
+	var developerId = roleFixture.Role("Developer")
+	var reviewerId = roleFixture.Role("Reviewer")
+	var applications = application.NewService(application.NewRepository(db), validator.New())
+	var ctx = context.Background()
+	created, err := applications.Create(ctx, application.CreateRequest{Name: "GitLab"})
+	a.Nil(err)
+	push, err := applications.CreateEntitlement(ctx, application.EntitlementRequest{
+		ApplicationId: created.Id,
+		Name:          "push",
+		Type:          application.EntitlementPermission,
+	})
+	a.Nil(err)
+	reviewers, err := applications.CreateEntitlement(ctx, application.EntitlementRequest{
+		ApplicationId: created.Id,
+		Name:          "reviewers",
+		Type:          application.EntitlementGroup,
+	})
+	a.Nil(err)
+	a.Nil(applications.Bundle(ctx, application.BundleRequest{
+		RoleId:         developerId,
+		EntitlementIds: []int64{push.Id, reviewers.Id},
+		CreatedBy:      "admin",
+	}))
+	a.Nil(applications.Bundle(ctx, application.BundleRequest{
+		RoleId:         reviewerId,
+		EntitlementIds: []int64{reviewers.Id},
+		CreatedBy:      "admin",
+	}))
+	t.Run("employee holds entitlements of every assigned role", func(t *testing.T) {
+		var employeeId = emplFixture.Employee("Test Developer", developerId)
+		db.MustExec("INSERT INTO employee_role (employee_id, role_id, source, created_by) VALUES ($1, $2, 'request', 'test')",
+			employeeId, reviewerId)
+		got, err := applications.FindEffective(application.IdRequest{Id: employeeId})
+		a.Nil(err)
+		a.Len(got, 2)
+		a.Equal("push", got[0].EntitlementName)
+		a.Equal([]int64{developerId}, got[0].RoleIds)
+		a.Equal("reviewers", got[1].EntitlementName)
+		a.ElementsMatch([]int64{developerId, reviewerId}, got[1].RoleIds)
+	})
+	t.Run("bundling unknown entitlement fails", func(t *testing.T) {
+		err := applications.Bundle(ctx, application.BundleRequest{
+			RoleId:         reviewerId,
+			EntitlementIds: []int64{push.Id, push.Id + 100},
+			CreatedBy:      "admin",
+		})
+		a.NotNil(err)
+		found, err := applications.FindByRole(application.IdRequest{Id: reviewerId})
+		a.Nil(err)
+		a.Len(found, 1)
+	})
+}
@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"idm/inner/assignment"
+	"idm/inner/birthright"
+	"idm/inner/database"
+	"idm/inner/employee"
+	"idm/inner/role"
+	"idm/inner/validator"
+	"testing"
+)
+
+func TestBirthrightRules(t *testing.T) {
+	a := assert.New(t)
+	var db = database.ConnectDb()
+	var clearDatabase = func() {
+		db.MustExec("DELETE FROM birthright_rule")
+		db.MustExec("DELETE FROM employee")
+		db.MustExec("DELETE FROM role")
+	}
+	defer clearDatabase()
+	var employeeRepository = employee.NewRepository(db)
+	var emplFixture = Fixture{employees: employeeRepository, db: db}
+	_ = emplFixture.CreateDatabase(db)
+	var roleFixture = NewRoleFixture(role.NewRepository(db))
+	var staffId = roleFixture.Role("Staff")
+	var developerId = roleFixture.Role("Developer")
+	var v = validator.New()
+	var birthrightRepository = birthright.NewRepository(db)
+	var rules = birthright.NewService(birthrightRepository, v)
+	var employees = employee.NewService(employeeRepository, birthrightRepository, assignment.NewRepository(db), v)
+	var assignments = assignment.NewRepository(db)
+	var derivedRoles = func(employeeId int64) []int64 {
+		found, err := assignments.FindByEmployeeId(employeeId)
+		a.Nil(err)
+		var roleIds []int64
+		for _, item := range found {
+			if item.Source == assignment.SourceBirthright {
+				roleIds = append(roleIds, item.RoleId)
+			}
+		}
+		return roleIds
+	}
+	_, err := rules.Create(context.Background(), birthright.CreateRequest{RoleId: staffId, CreatedBy: "admin"})
+	a.Nil(err)
+	_, err = rules.Create(context.Background(), birthright.CreateRequest{
+		Department: "Engineering",
+		RoleId:     developerId,
+		CreatedBy:  "admin",
+	})
+	a.Nil(err)
+	t.Run("new employee gets rule-derived roles next to the primary one", func(t *testing.T) {
+		created, err := employees.Save(context.Background(), employee.CreateRequest{
+			Name:       "Test Engineer",
+			RoleId:     staffId,
+			Department: "Engineering",
+		})
+		a.Nil(err)
+		a.ElementsMatch([]int64{staffId, developerId}, derivedRoles(created.Id))
+		found, err := assignments.FindByEmployeeId(created.Id)
+		a.Nil(err)
+		a.Len(found, 3)
+	})
+	t.Run("department change re-evaluates rule-derived roles", func(t *testing.T) {
+		created, err := employees.Save(context.Background(), employee.CreateRequest{
+			Name:       "Test Mover",
+			RoleId:     staffId,
+			Department: "Engineering",
+		})
+		a.Nil(err)
+		err = employees.UpdateDepartment(context.Background(), employee.UpdateDepartmentRequest{
+			Id:         created.Id,
+			Department: "Sales",
+		})
+		a.Nil(err)
+		a.Equal([]int64{staffId}, derivedRoles(created.Id))
+	})
+}
@@ -5,9 +5,17 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"idm/inner/assignment"
+	"idm/inner/audit"
+	"idm/inner/birthright"
+	"idm/inner/breakglass"
 	"idm/inner/common"
 	"idm/inner/database"
+	"idm/inner/delegation"
 	"idm/inner/employee"
+	"idm/inner/policy"
+	"idm/inner/relation"
 	"idm/inner/role"
 	"idm/inner/validator"
 	"idm/inner/web"
@@ -16,6 +24,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
 )
 
 func TestEmployeeControllerFindWithOffset(t *testing.T) {
@@ -42,7 +51,7 @@ func TestEmployeeControllerFindWithOffset(t *testing.T) {
 	var newRoleId = roleFixture.Role("Test Name")
 	_ = emplFixture.CreateDatabase(db)
 	v := validator.New()
-	var employeeService = employee.NewService(employeeRepository, v)
+	var employeeService = employee.NewService(employeeRepository, birthright.NewRepository(db), assignment.NewRepository(db), v)
 	var claims = &web.IdmClaims{
 		RealmAccess: web.RealmAccessClaims{Roles: []string{web.IdmAdmin}},
 	}
@@ -52,7 +61,12 @@ func TestEmployeeControllerFindWithOffset(t *testing.T) {
 	}
 	server := web.NewServer()
 	server.GroupApiV1.Use(auth)
-	var employeeController = employee.NewController(server, employeeService)
+	var employeeController = employee.NewController(server, employeeService, policy.NewEngine(&common.Logger{Logger: zap.NewNop()}),
+		relation.NewService(relation.NewRepository(db), v),
+		delegation.NewService(delegation.NewRepository(db), v, []string{web.IdmAdmin}),
+		breakglass.NewService(breakglass.NewRepository(db), assignment.NewRepository(db), audit.NewRepository(db), v,
+			&common.Logger{Logger: zap.NewNop()}, "", time.Hour, nil),
+	)
 	employeeController.RegisterRoutes()
 	t.Run("get employees with offset - page 0, size 3", func(t *testing.T) {
 		_ = emplFixture.Employee("Test Name", newRoleId)
@@ -116,6 +116,40 @@ func TestEmployeeRepository(t *testing.T) {
 		a.Equal("Test Name 4", got[1].Name)
 		clearDatabase()
 	})
+	t.Run("find employees by role with offset and filter", func(t *testing.T) {
+		var otherRoleId = roleFixture.Role("Other Role")
+		_ = emplFixture.Employee("Test Name", newRoleId)
+		_ = emplFixture.Employee("Test Name 1", otherRoleId)
+		_ = emplFixture.Employee("Test Name 2", newRoleId)
+		_ = emplFixture.Employee("Another", newRoleId)
+		got, err := employeeRepository.FindByRoleWithOffset(newRoleId, 0, 10, "test")
+		a.Nil(err)
+		a.Equal(2, len(got))
+		a.Equal("Test Name", got[0].Name)
+		a.Equal("Test Name 2", got[1].Name)
+		total, err := employeeRepository.CountByRole(newRoleId, "test")
+		a.Nil(err)
+		a.Equal(int64(2), total)
+		clearDatabase()
+	})
+	t.Run("link subject and find employee with roles by subject", func(t *testing.T) {
+		var id = emplFixture.Employee("Test Name", newRoleId)
+		updated, err := employeeRepository.UpdateSubject(id, "kc-subject-1")
+		a.Nil(err)
+		a.True(updated)
+		got, err := employeeRepository.FindBySubject("kc-subject-1")
+		a.Nil(err)
+		a.Equal(id, got.Id)
+		roles, err := employeeRepository.FindRoles(id)
+		a.Nil(err)
+		a.Equal(1, len(roles))
+		a.Equal(newRoleId, roles[0].RoleId)
+		a.Equal("primary", roles[0].Source)
+		updated, err = employeeRepository.UpdateSubject(id+1000, "kc-subject-2")
+		a.Nil(err)
+		a.False(updated)
+		clearDatabase()
+	})
 	t.Run("find by name and save employee in one tx", func(t *testing.T) {
 		tx, err := employeeRepository.BeginTransaction()
 		a.NoError(err)
@@ -140,4 +174,22 @@ func TestEmployeeRepository(t *testing.T) {
 		a.NotEmpty(found)
 		a.True(found)
 	})
+	t.Run("risk score counts sensitive roles and conflicts", func(t *testing.T) {
+		var criticalRoleId = roleFixture.Role("Critical Role")
+		_, err := roleRepository.UpdateSensitivity(criticalRoleId, role.SensitivityCritical)
+		a.NoError(err)
+		var plainId = emplFixture.Employee("Plain Employee", newRoleId)
+		var riskyId = emplFixture.Employee("Risky Employee", criticalRoleId)
+		db.MustExec("INSERT INTO employee_role (employee_id, role_id, source, created_by) VALUES ($1, $2, 'request', 'test')",
+			riskyId, newRoleId)
+		a.NoError(roleRepository.SaveConflict(criticalRoleId, newRoleId))
+		got, err := employeeRepository.FindRiskiest(50)
+		a.NoError(err)
+		a.Len(got, 2)
+		a.Equal(riskyId, got[0].Id)
+		a.Equal(int64(25+5+20), got[0].RiskScore)
+		a.Equal(plainId, got[1].Id)
+		a.Equal(int64(0), got[1].RiskScore)
+		clearDatabase()
+	})
 }
@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"idm/inner/application"
+	"idm/inner/database"
+	"idm/inner/employee"
+	"idm/inner/explain"
+	"idm/inner/role"
+	"idm/inner/validator"
+	"os"
+	"testing"
+)
+
+func TestExplainAccess(t *testing.T) {
+	a := assert.New(t)
+	var db = database.ConnectDb()
+	var clearDatabase = func() {
+		db.MustExec("DELETE FROM relation_tuple")
+		db.MustExec("DELETE FROM application")
+		db.MustExec("DELETE FROM employee")
+		db.MustExec("DELETE FROM role")
+	}
+	defer clearDatabase()
+	var emplFixture = Fixture{employees: employee.NewRepository(db), db: db}
+	_ = emplFixture.CreateDatabase(db)
+	for _, script := range []string{"./scripts/application.sql", "./scripts/relation.sql"} {
+		data, _ := os.ReadFile(script)
+		db.MustExec(string(data))
+	}
+	var roleFixture = NewRoleFixture(role.NewRepository(db))
+	var developerId = roleFixture.Role("Developer")
+	var engineerId = roleFixture.Role("Engineer")
+	var operatorId = roleFixture.Role("Operator")
+	var applications = application.NewService(application.NewRepository(db), validator.New())
+	var ctx = context.Background()
+	created, err := applications.Create(ctx, application.CreateRequest{Name: "GitLab"})
+	a.Nil(err)
+	push, err := applications.CreateEntitlement(ctx, application.EntitlementRequest{
+		ApplicationId: created.Id,
+		Name:          "push",
+		Type:          application.EntitlementPermission,
+	})
+	a.Nil(err)
+	for _, roleId := range []int64{developerId, engineerId, operatorId} {
+		a.Nil(applications.Bundle(ctx, application.BundleRequest{
+			RoleId:         roleId,
+			EntitlementIds: []int64{push.Id},
+			CreatedBy:      "owner",
+		}))
+	}
+	var employeeId = emplFixture.Employee("Test Developer", developerId)
+	var ruleId int64
+	a.Nil(db.Get(&ruleId, "INSERT INTO birthright_rule (role_id, created_by) VALUES ($1, 'admin') RETURNING id", engineerId))
+	db.MustExec("INSERT INTO employee_role (employee_id, role_id, source, created_by) VALUES ($1, $2, 'birthright', 'rule:' || $3::BIGINT)",
+		employeeId, engineerId, ruleId)
+	// отношение member роль не выдаёт, поэтому Operator в объяснении не появляется
+	db.MustExec("INSERT INTO relation_tuple (subject, relation, object, created_by) VALUES ('employee:' || $1::BIGINT, 'member', 'role:' || $2::BIGINT, 'lead')",
+		employeeId, operatorId)
+	var explainer = explain.NewService(explain.NewRepository(db), validator.New())
+	t.Run("every path granting entitlement", func(t *testing.T) {
+		got, err := explainer.Explain(explain.Request{EmployeeId: employeeId, Permission: "GitLab:push"})
+		a.Nil(err)
+		a.True(got.Granted)
+		var types = map[string]int64{}
+		for _, path := range got.Paths {
+			types[path.Type] = path.RoleId
+		}
+		a.Equal(map[string]int64{
+			explain.PathDirect:     developerId,
+			explain.PathBirthright: engineerId,
+		}, types)
+		for _, path := range got.Paths {
+			if path.Type == explain.PathBirthright {
+				a.Equal(explain.StepRule, path.Steps[0].Kind)
+				a.Equal("admin", path.Steps[0].Actor)
+			}
+		}
+	})
+	t.Run("entitlement of other application is not granted", func(t *testing.T) {
+		got, err := explainer.Explain(explain.Request{EmployeeId: employeeId, Permission: "Jira:push"})
+		a.Nil(err)
+		a.False(got.Granted)
+	})
+}
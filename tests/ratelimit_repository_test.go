@@ -0,0 +1,39 @@
+package tests
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"idm/inner/database"
+	"idm/inner/ratelimit"
+	"testing"
+	"time"
+)
+
+func TestRateLimitRepository(t *testing.T) {
+	a := assert.New(t)
+	var db = database.ConnectDb()
+	defer db.MustExec("DELETE FROM rate_limit_bucket")
+	var store = ratelimit.NewRepository(db)
+	var ctx = context.Background()
+	var rule = ratelimit.Rule{Requests: 2, Period: time.Minute}
+	var now = time.Now().Truncate(time.Microsecond)
+	t.Run("bucket is shared and refills over time", func(t *testing.T) {
+		for _, allowed := range []bool{true, true, false} {
+			decision, err := store.Take(ctx, "api:write:sub:alice", rule, now)
+			a.Nil(err)
+			a.Equal(allowed, decision.Allowed)
+		}
+		decision, err := store.Take(ctx, "api:write:sub:alice", rule, now.Add(30*time.Second))
+		a.Nil(err)
+		a.True(decision.Allowed)
+		decision, err = store.Take(ctx, "api:write:sub:bob", rule, now)
+		a.Nil(err)
+		a.Equal(1, decision.Remaining)
+	})
+	t.Run("purge removes stale buckets", func(t *testing.T) {
+		a.Nil(store.Purge(ctx, now.Add(time.Second)))
+		var count int
+		a.Nil(db.Get(&count, "SELECT COUNT(*) FROM rate_limit_bucket"))
+		a.Equal(1, count)
+	})
+}
@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"github.com/stretchr/testify/assert"
+	"idm/inner/database"
+	"idm/inner/employee"
+	"idm/inner/relation"
+	"idm/inner/role"
+	"idm/inner/validator"
+	"os"
+	"testing"
+)
+
+func TestRelationRepositoryCheck(t *testing.T) {
+	a := assert.New(t)
+	var db = database.ConnectDb()
+	var clearDatabase = func() {
+		db.MustExec("DELETE FROM relation_tuple")
+		db.MustExec("DELETE FROM employee")
+		db.MustExec("DELETE FROM role")
+	}
+	defer clearDatabase()
+	var roleRepository = role.NewRepository(db)
+	_ = NewRoleFixture(roleRepository).CreateDatabase(db)
+	_ = NewFixture(employee.NewRepository(db)).CreateDatabase(db)
+	data, _ := os.ReadFile("./scripts/relation.sql")
+	db.MustExec(string(data))
+	var relationRepository = relation.NewRepository(db)
+	var relationService = relation.NewService(relationRepository, validator.New())
+	var tuple = func(subject string, rel string, object string) {
+		_, err := relationRepository.Save(relation.Entity{Subject: subject, Relation: rel, Object: object, CreatedBy: "test"})
+		if err != nil {
+			panic(err)
+		}
+	}
+	t.Run("owner manages role", func(t *testing.T) {
+		tuple("user:alice", relation.RelationOwner, "role:42")
+		got, err := relationService.Check(t.Context(), "user:alice", relation.PermissionManage, "role:42")
+		a.Nil(err)
+		a.True(got)
+		got, err = relationService.Check(t.Context(), "user:bob", relation.PermissionManage, "role:42")
+		a.Nil(err)
+		a.False(got)
+	})
+	t.Run("owner column of role counts as owner relation", func(t *testing.T) {
+		roleId, err := roleRepository.Save(role.Entity{Name: "Owned", Owner: "carol"})
+		a.Nil(err)
+		got, err := relationService.Check(t.Context(), "user:carol", relation.PermissionManage, relation.Role(roleId))
+		a.Nil(err)
+		a.True(got)
+	})
+	t.Run("manager of manager views report", func(t *testing.T) {
+		tuple("user:dave", relation.RelationManager, "employee:5")
+		tuple("employee:5", relation.RelationManager, "employee:9")
+		got, err := relationService.Check(t.Context(), "user:dave", relation.PermissionView, "employee:9")
+		a.Nil(err)
+		a.True(got)
+	})
+	t.Run("manager chain continues through linked user", func(t *testing.T) {
+		roleId, err := roleRepository.Save(role.Entity{Name: "Staff"})
+		a.Nil(err)
+		var bobId int64
+		err = db.Get(&bobId, "INSERT INTO employee (name, role_id, subject) VALUES ('bob', $1, 'bob') RETURNING id", roleId)
+		a.Nil(err)
+		tuple("user:frank", relation.RelationManager, relation.Employee(bobId))
+		tuple("user:bob", relation.RelationManager, "employee:11")
+		got, err := relationService.Check(t.Context(), "user:frank", relation.PermissionView, "employee:11")
+		a.Nil(err)
+		a.True(got)
+		got, err = relationService.Check(t.Context(), relation.Employee(bobId), relation.PermissionView, "employee:11")
+		a.Nil(err)
+		a.True(got)
+	})
+	t.Run("non transitive relations do not chain", func(t *testing.T) {
+		tuple("user:erin", relation.RelationMember, "group:ops")
+		tuple("group:ops", relation.RelationMember, "role:7")
+		got, err := relationService.Check(t.Context(), "user:erin", relation.PermissionView, "role:7")
+		a.Nil(err)
+		a.False(got)
+	})
+	t.Run("cycles terminate", func(t *testing.T) {
+		tuple("employee:1", relation.RelationManager, "employee:2")
+		tuple("employee:2", relation.RelationManager, "employee:1")
+		got, err := relationService.Check(t.Context(), "user:nobody", relation.PermissionView, "employee:1")
+		a.Nil(err)
+		a.False(got)
+	})
+}
@@ -3,6 +3,7 @@ package tests
 import (
 	"github.com/stretchr/testify/assert"
 	"idm/inner/database"
+	"idm/inner/employee"
 	"idm/inner/role"
 	"testing"
 )
@@ -97,3 +98,39 @@ func TestRoleRepository(t *testing.T) {
 		clearDatabase()
 	})
 }
+
+func TestRoleRepositoryFindWithOffset(t *testing.T) {
+	a := assert.New(t)
+	var db = database.ConnectDb()
+	var clearDatabase = func() {
+		db.MustExec("DELETE FROM employee")
+		db.MustExec("DELETE FROM role")
+	}
+	defer clearDatabase()
+	var roleRepository = role.NewRepository(db)
+	var roleFixture = NewRoleFixture(roleRepository)
+	var emplFixture = Fixture{
+		employees: employee.NewRepository(db),
+		db:        db,
+	}
+	_ = emplFixture.CreateDatabase(db)
+	t.Run("find roles with offset, filter and member count", func(t *testing.T) {
+		var adminId = roleFixture.Role("Admin")
+		var auditorId = roleFixture.Role("Auditor")
+		_ = roleFixture.Role("Administrator")
+		_ = emplFixture.Employee("Test Name", adminId)
+		_ = emplFixture.Employee("Test Name 1", adminId)
+		_ = emplFixture.Employee("Test Name 2", auditorId)
+		got, err := roleRepository.FindWithOffset(0, 2, "adm", "member_count", "desc")
+		a.Nil(err)
+		a.Len(got, 2)
+		a.Equal("Admin", got[0].Name)
+		a.Equal(int64(2), got[0].MemberCount)
+		a.Equal("Administrator", got[1].Name)
+		a.Equal(int64(0), got[1].MemberCount)
+		total, err := roleRepository.CountWithFilter("adm")
+		a.Nil(err)
+		a.Equal(int64(2), total)
+		clearDatabase()
+	})
+}
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS api_key
+(
+    id           BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    name         TEXT        NOT NULL,
+    owner        TEXT        NOT NULL,
+    prefix       TEXT        NOT NULL UNIQUE,
+    salt         TEXT        NOT NULL,
+    hash         TEXT        NOT NULL,
+    scopes       TEXT[]      NOT NULL DEFAULT '{}',
+    expires_at   TIMESTAMPTZ NOT NULL,
+    last_used_at TIMESTAMPTZ NULL,
+    rotated_by   TEXT        NOT NULL DEFAULT '',
+    rotated_at   TIMESTAMPTZ NULL,
+    revoked_by   TEXT        NOT NULL DEFAULT '',
+    revoked_at   TIMESTAMPTZ NULL,
+    created_by   TEXT        NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
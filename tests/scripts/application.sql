@@ -0,0 +1,53 @@
+CREATE TABLE IF NOT EXISTS application
+(
+    id          BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    name        TEXT        NOT NULL UNIQUE,
+    description TEXT        NOT NULL DEFAULT '',
+    owner       TEXT        NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE TABLE IF NOT EXISTS entitlement
+(
+    id             BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    application_id BIGINT REFERENCES application (id) ON DELETE CASCADE NOT NULL,
+    name           TEXT                                                 NOT NULL,
+    type           TEXT                                                 NOT NULL,
+    description    TEXT                                                 NOT NULL DEFAULT '',
+    created_at     TIMESTAMPTZ                                          NOT NULL DEFAULT NOW(),
+    UNIQUE (application_id, name)
+);
+CREATE TABLE IF NOT EXISTS role_entitlement
+(
+    id             BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    role_id        BIGINT REFERENCES role (id) ON DELETE CASCADE        NOT NULL,
+    entitlement_id BIGINT REFERENCES entitlement (id) ON DELETE CASCADE NOT NULL,
+    created_by     TEXT                                                 NOT NULL,
+    created_at     TIMESTAMPTZ                                          NOT NULL DEFAULT NOW(),
+    UNIQUE (role_id, entitlement_id)
+);
+CREATE TABLE IF NOT EXISTS account
+(
+    id               BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    application_id   BIGINT REFERENCES application (id) ON DELETE CASCADE NOT NULL,
+    login            TEXT                                                 NOT NULL,
+    employee_id      BIGINT REFERENCES employee (id) ON DELETE SET NULL,
+    last_employee_id BIGINT,
+    status           TEXT                                                 NOT NULL,
+    last_login_at    TIMESTAMPTZ,
+    last_sync_at     TIMESTAMPTZ                                          NOT NULL DEFAULT NOW(),
+    created_at       TIMESTAMPTZ                                          NOT NULL DEFAULT NOW(),
+    UNIQUE (application_id, login)
+);
+CREATE TABLE IF NOT EXISTS provisioning_task
+(
+    id             BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    application_id BIGINT REFERENCES application (id) ON DELETE CASCADE NOT NULL,
+    employee_id    BIGINT REFERENCES employee (id) ON DELETE SET NULL,
+    login          TEXT                                                 NOT NULL DEFAULT '',
+    entitlement    TEXT                                                 NOT NULL DEFAULT '',
+    action         TEXT                                                 NOT NULL,
+    state          TEXT                                                 NOT NULL DEFAULT 'open',
+    created_by     TEXT                                                 NOT NULL,
+    created_at     TIMESTAMPTZ                                          NOT NULL DEFAULT NOW()
+);
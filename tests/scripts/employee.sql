@@ -1,9 +1,11 @@
 CREATE TABLE IF NOT EXISTS role
 (
-    id         BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
-    name       TEXT        NOT NULL,
-    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    id          BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    name        TEXT        NOT NULL,
+    owner       TEXT        NOT NULL DEFAULT '',
+    sensitivity TEXT        NOT NULL DEFAULT 'normal',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
 
 CREATE TABLE IF NOT EXISTS employee
@@ -12,5 +14,64 @@ CREATE TABLE IF NOT EXISTS employee
     name       TEXT                        NOT NULL,
     created_at TIMESTAMPTZ                 NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMPTZ                 NOT NULL DEFAULT NOW(),
-    role_id    BIGINT REFERENCES role (id) NOT NULL
-);
\ No newline at end of file
+    role_id    BIGINT REFERENCES role (id) NOT NULL,
+    department TEXT                        NOT NULL DEFAULT '',
+    subject    TEXT                        NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS employee_role
+(
+    id          BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    employee_id BIGINT REFERENCES employee (id) ON DELETE CASCADE NOT NULL,
+    role_id     BIGINT REFERENCES role (id) ON DELETE CASCADE     NOT NULL,
+    source      TEXT                                              NOT NULL,
+    created_by  TEXT                                              NOT NULL,
+    created_at  TIMESTAMPTZ                                       NOT NULL DEFAULT NOW(),
+    UNIQUE (employee_id, role_id, source)
+);
+CREATE TABLE IF NOT EXISTS birthright_rule
+(
+    id         BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    department TEXT                                          NOT NULL DEFAULT '',
+    role_id    BIGINT REFERENCES role (id) ON DELETE CASCADE NOT NULL,
+    created_by TEXT                                          NOT NULL,
+    created_at TIMESTAMPTZ                                   NOT NULL DEFAULT NOW(),
+    UNIQUE (department, role_id)
+);
+CREATE TABLE IF NOT EXISTS role_conflict
+(
+    id                  BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    role_id             BIGINT REFERENCES role (id) ON DELETE CASCADE NOT NULL,
+    conflicting_role_id BIGINT REFERENCES role (id) ON DELETE CASCADE NOT NULL,
+    created_at          TIMESTAMPTZ                                   NOT NULL DEFAULT NOW(),
+    UNIQUE (role_id, conflicting_role_id),
+    CHECK (role_id < conflicting_role_id)
+);
+CREATE TABLE IF NOT EXISTS certification_campaign
+(
+    id          BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    name        TEXT        NOT NULL,
+    scope_type  TEXT        NOT NULL,
+    scope_value TEXT        NOT NULL,
+    reviewer    TEXT        NOT NULL,
+    state       TEXT        NOT NULL DEFAULT 'open',
+    due_at      TIMESTAMPTZ NOT NULL,
+    created_by  TEXT        NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    closed_by   TEXT        NOT NULL DEFAULT '',
+    closed_at   TIMESTAMPTZ
+);
+CREATE TABLE IF NOT EXISTS certification_item
+(
+    id          BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    campaign_id BIGINT REFERENCES certification_campaign (id) ON DELETE CASCADE NOT NULL,
+    employee_id BIGINT REFERENCES employee (id) ON DELETE CASCADE               NOT NULL,
+    role_id     BIGINT REFERENCES role (id) ON DELETE CASCADE                   NOT NULL,
+    reviewer    TEXT                                                            NOT NULL,
+    decision    TEXT                                                            NOT NULL DEFAULT 'pending',
+    comment     TEXT                                                            NOT NULL DEFAULT '',
+    decided_by  TEXT                                                            NOT NULL DEFAULT '',
+    decided_at  TIMESTAMPTZ,
+    applied     BOOLEAN                                                         NOT NULL DEFAULT FALSE,
+    UNIQUE (campaign_id, employee_id, role_id)
+);
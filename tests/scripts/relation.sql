@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS relation_tuple
+(
+    id         BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    subject    TEXT        NOT NULL,
+    relation   TEXT        NOT NULL,
+    object     TEXT        NOT NULL,
+    created_by TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (subject, relation, object)
+);
@@ -1,7 +1,9 @@
 CREATE TABLE IF NOT EXISTS role
 (
-    id         BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
-    name       TEXT        NOT NULL,
-    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    id          BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    name        TEXT        NOT NULL,
+    owner       TEXT        NOT NULL DEFAULT '',
+    sensitivity TEXT        NOT NULL DEFAULT 'normal',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
\ No newline at end of file